@@ -0,0 +1,208 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ed25519
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+type zeroReader struct{}
+
+func (zeroReader) Read(buf []byte) (int, error) {
+	for i := range buf {
+		buf[i] = 0
+	}
+	return len(buf), nil
+}
+
+var signTests = []struct {
+	seed, publicKey, message, signature string
+}{
+	{
+		// RFC 8032, Section 7.1, Test 1.
+		"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		"",
+		"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		// RFC 8032, Section 7.1, Test 2.
+		"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		"72",
+		"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+	{
+		// RFC 8032, Section 7.1, Test 3.
+		"c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+		"fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+		"af82",
+		"6291d657deec24024827e69c3abe01a30ce548a284743a445e3680d7db5ac3ac18ff9b538d16f290ae67f760984dc6594a7c15e9716ed28dc027beceea1ec40a",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"3b6a27bcceb6a42d62a3a8d02a6f0d73653215771de243a63ac048a18b59da29",
+		hex.EncodeToString([]byte("Go Authors")),
+		"5c74de8c9a65afdd0c95c45d001745d2311863d66f1c33f53f56f741642a08034fad0ce2d95e460e5d317f8b30aecb0ef7e6766a4bd51ba9cfebad974327520e",
+	},
+	{
+		"833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42",
+		"ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf",
+		hex.EncodeToString([]byte(strings.Repeat("a", 300))),
+		"2555c1a6b5f9bbaa44b5f1ba28d60f1d483d043933a625d3018d8f7d432dda2c87d5599087c5fdeffed5c5d4050d22e5614094b33aa20df96130b3422fdabb01",
+	},
+}
+
+func TestSignVerify(t *testing.T) {
+	for i, test := range signTests {
+		seed, _ := hex.DecodeString(test.seed)
+		wantPublicKey, _ := hex.DecodeString(test.publicKey)
+		message, _ := hex.DecodeString(test.message)
+		wantSignature, _ := hex.DecodeString(test.signature)
+
+		priv := NewKeyFromSeed(seed)
+		pub := priv.Public().(PublicKey)
+		if !bytes.Equal(pub, wantPublicKey) {
+			t.Errorf("#%d: got public key %x, want %x", i, pub, wantPublicKey)
+			continue
+		}
+		if !bytes.Equal(priv.Seed(), seed) {
+			t.Errorf("#%d: Seed returned %x, want %x", i, priv.Seed(), seed)
+		}
+
+		sig := Sign(priv, message)
+		if !bytes.Equal(sig, wantSignature) {
+			t.Errorf("#%d: got signature %x, want %x", i, sig, wantSignature)
+		}
+		if !Verify(pub, message, sig) {
+			t.Errorf("#%d: valid signature rejected", i)
+		}
+
+		wrongMessage := append([]byte{'x'}, message...)
+		if Verify(pub, wrongMessage, sig) {
+			t.Errorf("#%d: signature of different message accepted", i)
+		}
+		for _, j := range []int{0, 31, 32, 62} {
+			sig[j] ^= 0x01
+			if Verify(pub, message, sig) {
+				t.Errorf("#%d: signature with byte %d flipped accepted", i, j)
+			}
+			sig[j] ^= 0x01
+		}
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	public, private, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(public) != PublicKeySize || len(private) != PrivateKeySize {
+		t.Fatalf("got key sizes %d and %d", len(public), len(private))
+	}
+
+	message := []byte("test message")
+	sig := Sign(private, message)
+	if !Verify(public, message, sig) {
+		t.Errorf("valid signature rejected")
+	}
+
+	public2, private2, err := GenerateKey(zeroReader{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(private2, NewKeyFromSeed(make([]byte, SeedSize))) {
+		t.Errorf("GenerateKey(zeroReader) returned %x", private2)
+	}
+	if Verify(public2, message, sig) {
+		t.Errorf("signature accepted by unrelated key")
+	}
+}
+
+func TestCryptoSigner(t *testing.T) {
+	var zero zeroReader
+	public, private, _ := GenerateKey(zero)
+
+	signer := crypto.Signer(private)
+
+	publicInterface := signer.Public()
+	public2, ok := publicInterface.(PublicKey)
+	if !ok {
+		t.Fatalf("expected PublicKey from Public() but got %T", publicInterface)
+	}
+
+	if !bytes.Equal(public, public2) {
+		t.Errorf("public keys do not match: original:%x vs Public():%x", public, public2)
+	}
+
+	message := []byte("message")
+	var noHash crypto.Hash
+	signature, err := signer.Sign(zero, message, noHash)
+	if err != nil {
+		t.Fatalf("error from Sign(): %s", err)
+	}
+
+	if !Verify(public, message, signature) {
+		t.Errorf("Verify failed on signature from Sign()")
+	}
+
+	if _, err := signer.Sign(zero, message, crypto.SHA256); err == nil {
+		t.Errorf("Sign accepted a pre-hashed message")
+	}
+}
+
+func TestMalleability(t *testing.T) {
+	// The s value of a signature must be reduced, and Verify rejects values
+	// with any of the top three bits set. See RFC 8032, Section 5.1.7.
+	_, private, _ := GenerateKey(zeroReader{})
+	public := private.Public().(PublicKey)
+	message := []byte("malleable")
+	sig := Sign(private, message)
+	sig[63] |= 0xe0
+	if Verify(public, message, sig) {
+		t.Errorf("signature with unreduced s accepted")
+	}
+}
+
+func BenchmarkKeyGeneration(b *testing.B) {
+	var zero zeroReader
+	for i := 0; i < b.N; i++ {
+		if _, _, err := GenerateKey(zero); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSigning(b *testing.B) {
+	var zero zeroReader
+	_, priv, err := GenerateKey(zero)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("Hello, world!")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Sign(priv, message)
+	}
+}
+
+func BenchmarkVerification(b *testing.B) {
+	var zero zeroReader
+	pub, priv, err := GenerateKey(zero)
+	if err != nil {
+		b.Fatal(err)
+	}
+	message := []byte("Hello, world!")
+	signature := Sign(priv, message)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Verify(pub, message, signature)
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package edwards25519 implements group logic for the twisted Edwards curve
+//
+//     -x^2 + y^2 = 1 + -(121665/121666)*x^2*y^2
+//
+// This is better known as the Edwards curve equivalent to Curve25519, and is
+// the curve used by the Ed25519 signature scheme. See RFC 8032.
+//
+// The field and group arithmetic follows TweetNaCl. Nothing in this package
+// branches on, or indexes memory with, secret values.
+package edwards25519
+
+// fieldElement and the functions feCarry, feCSwap, feFromBytes, feToBytes,
+// feAdd, feSub, feMul, feSquare and feInvert deliberately duplicate those of
+// the vendored golang.org/x/crypto/curve25519 package, which mirrors an
+// external repository and so cannot share code with the standard library.
+// Fixes to either copy must be made to both.
+
+// fieldElement represents an element of the field GF(2^255-19). Limb i holds
+// bits 16*i to 16*i+15 of the value, but may temporarily exceed that range
+// between carries.
+type fieldElement [16]int64
+
+// feCarry propagates the carries of h, so that every limb is reduced to 16
+// bits (except limb 0, which absorbs the 2^256 = 38 wrap-around).
+func feCarry(h *fieldElement) {
+	for i := 0; i < 16; i++ {
+		h[i] += 1 << 16
+		c := h[i] >> 16
+		if i < 15 {
+			h[i+1] += c - 1
+		} else {
+			h[0] += 38 * (c - 1)
+		}
+		h[i] -= c << 16
+	}
+}
+
+// feCSwap swaps f and g if b == 1 and leaves them unchanged if b == 0, in
+// constant time.
+func feCSwap(f, g *fieldElement, b int64) {
+	mask := ^(b - 1)
+	for i := range f {
+		t := mask & (f[i] ^ g[i])
+		f[i] ^= t
+		g[i] ^= t
+	}
+}
+
+// feFromBytes sets h to the little-endian value of s, ignoring the top bit.
+func feFromBytes(h *fieldElement, s *[32]byte) {
+	for i := range h {
+		h[i] = int64(s[2*i]) | int64(s[2*i+1])<<8
+	}
+	h[15] &= 0x7fff
+}
+
+// feToBytes writes the canonical little-endian encoding of h to s.
+func feToBytes(s *[32]byte, h *fieldElement) {
+	t := *h
+	feCarry(&t)
+	feCarry(&t)
+	feCarry(&t)
+
+	// Subtract p = 2^255-19 twice, keeping the result only if it does not
+	// go negative, to fully reduce t.
+	var m fieldElement
+	for j := 0; j < 2; j++ {
+		m[0] = t[0] - 0xffed
+		for i := 1; i < 15; i++ {
+			m[i] = t[i] - 0xffff - ((m[i-1] >> 16) & 1)
+			m[i-1] &= 0xffff
+		}
+		m[15] = t[15] - 0x7fff - ((m[14] >> 16) & 1)
+		b := (m[15] >> 16) & 1
+		m[14] &= 0xffff
+		feCSwap(&t, &m, 1-b)
+	}
+
+	for i := range t {
+		s[2*i] = byte(t[i])
+		s[2*i+1] = byte(t[i] >> 8)
+	}
+}
+
+// feIsNegative returns the least significant bit of the canonical encoding
+// of f, which RFC 8032 uses as its sign.
+func feIsNegative(f *fieldElement) byte {
+	var s [32]byte
+	feToBytes(&s, f)
+	return s[0] & 1
+}
+
+// feEqual returns whether f and g represent the same field element.
+func feEqual(f, g *fieldElement) bool {
+	var s, t [32]byte
+	feToBytes(&s, f)
+	feToBytes(&t, g)
+	return s == t
+}
+
+// feAdd sets h = f + g.
+func feAdd(h, f, g *fieldElement) {
+	for i := range h {
+		h[i] = f[i] + g[i]
+	}
+}
+
+// feSub sets h = f - g.
+func feSub(h, f, g *fieldElement) {
+	for i := range h {
+		h[i] = f[i] - g[i]
+	}
+}
+
+// feMul sets h = f * g. h may alias f or g.
+func feMul(h, f, g *fieldElement) {
+	var t [31]int64
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			t[i+j] += f[i] * g[j]
+		}
+	}
+	// 2^256 = 38 (mod p), so fold the high limbs back into the low ones.
+	for i := 0; i < 15; i++ {
+		t[i] += 38 * t[i+16]
+	}
+	copy(h[:], t[:16])
+	feCarry(h)
+	feCarry(h)
+}
+
+// feSquare sets h = f * f.
+func feSquare(h, f *fieldElement) {
+	feMul(h, f, f)
+}
+
+// feInvert sets out = z^-1, computed as z^(p-2).
+func feInvert(out, z *fieldElement) {
+	c := *z
+	for i := 253; i >= 0; i-- {
+		feSquare(&c, &c)
+		if i != 2 && i != 4 {
+			feMul(&c, &c, z)
+		}
+	}
+	*out = c
+}
+
+// fePow22523 sets out = z^((p-5)/8) = z^(2^252-3).
+func fePow22523(out, z *fieldElement) {
+	c := *z
+	for i := 250; i >= 0; i-- {
+		feSquare(&c, &c)
+		if i != 1 {
+			feMul(&c, &c, z)
+		}
+	}
+	*out = c
+}
+
+var (
+	// d is the curve constant -121665/121666.
+	d = fieldElement{0x78a3, 0x1359, 0x4dca, 0x75eb, 0xd8ab, 0x4141, 0x0a4d, 0x0070, 0xe898, 0x7779, 0x4079, 0x8cc7, 0xfe73, 0x2b6f, 0x6cee, 0x5203}
+	// d2 is 2*d.
+	d2 = fieldElement{0xf159, 0x26b2, 0x9b94, 0xebd6, 0xb156, 0x8283, 0x149a, 0x00e0, 0xd130, 0xeef3, 0x80f2, 0x198e, 0xfce7, 0x56df, 0xd9dc, 0x2406}
+	// sqrtM1 is a square root of -1.
+	sqrtM1 = fieldElement{0xa0b0, 0x4a0e, 0x1b27, 0xc4ee, 0xe478, 0xad2f, 0x1806, 0x2f43, 0xd7a7, 0x3dfb, 0x0099, 0x2b4d, 0xdf0b, 0x4fc1, 0x2480, 0x2b83}
+
+	// baseX and baseY are the affine coordinates of the generator B.
+	baseX = fieldElement{0xd51a, 0x8f25, 0x2d60, 0xc956, 0xa7b2, 0x9525, 0xc760, 0x692c, 0xdc5c, 0xfdd6, 0xe231, 0xc0a4, 0x53fe, 0xcd6e, 0x36d3, 0x2169}
+	baseY = fieldElement{0x6658, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666, 0x6666}
+)
+
+// ExtendedGroupElement is a point on the curve in extended coordinates
+// (X:Y:Z:T), where x = X/Z, y = Y/Z and x*y = T/Z.
+type ExtendedGroupElement struct {
+	x, y, z, t fieldElement
+}
+
+// Zero sets p to the neutral element.
+func (p *ExtendedGroupElement) Zero() {
+	*p = ExtendedGroupElement{}
+	p.y[0] = 1
+	p.z[0] = 1
+}
+
+// Add sets p = q + r, using the unified addition formula, which also works
+// for doubling.
+func (p *ExtendedGroupElement) Add(q, r *ExtendedGroupElement) {
+	var a, b, c, dd, e, f, g, h, t fieldElement
+
+	feSub(&a, &q.y, &q.x)
+	feSub(&t, &r.y, &r.x)
+	feMul(&a, &a, &t)
+	feAdd(&b, &q.x, &q.y)
+	feAdd(&t, &r.x, &r.y)
+	feMul(&b, &b, &t)
+	feMul(&c, &q.t, &r.t)
+	feMul(&c, &c, &d2)
+	feMul(&dd, &q.z, &r.z)
+	feAdd(&dd, &dd, &dd)
+	feSub(&e, &b, &a)
+	feSub(&f, &dd, &c)
+	feAdd(&g, &dd, &c)
+	feAdd(&h, &b, &a)
+
+	feMul(&p.x, &e, &f)
+	feMul(&p.y, &h, &g)
+	feMul(&p.z, &g, &f)
+	feMul(&p.t, &e, &h)
+}
+
+// Neg sets p = -q.
+func (p *ExtendedGroupElement) Neg(q *ExtendedGroupElement) {
+	var zero fieldElement
+	feSub(&p.x, &zero, &q.x)
+	p.y = q.y
+	p.z = q.z
+	feSub(&p.t, &zero, &q.t)
+}
+
+// cswap swaps p and q if b == 1 and leaves them unchanged if b == 0, in
+// constant time.
+func cswap(p, q *ExtendedGroupElement, b int64) {
+	feCSwap(&p.x, &q.x, b)
+	feCSwap(&p.y, &q.y, b)
+	feCSwap(&p.z, &q.z, b)
+	feCSwap(&p.t, &q.t, b)
+}
+
+// ToBytes writes the 32-byte encoding of p to s, as specified in RFC 8032,
+// Section 5.1.2.
+func (p *ExtendedGroupElement) ToBytes(s *[32]byte) {
+	var recip, x, y fieldElement
+
+	feInvert(&recip, &p.z)
+	feMul(&x, &p.x, &recip)
+	feMul(&y, &p.y, &recip)
+	feToBytes(s, &y)
+	s[31] ^= feIsNegative(&x) << 7
+}
+
+// FromBytes sets p to the point encoded in s, as specified in RFC 8032,
+// Section 5.1.3. It returns false if s is not a valid encoding.
+func (p *ExtendedGroupElement) FromBytes(s *[32]byte) bool {
+	var u, v, v3, vxx, check fieldElement
+
+	feFromBytes(&p.y, s)
+	p.z = fieldElement{1}
+	feSquare(&u, &p.y)
+	feMul(&v, &u, &d)
+	feSub(&u, &u, &p.z) // u = y^2-1
+	feAdd(&v, &v, &p.z) // v = dy^2+1
+
+	// x = u * v^3 * (u * v^7)^((p-5)/8)
+	feSquare(&v3, &v)
+	feMul(&v3, &v3, &v) // v3 = v^3
+	feSquare(&p.x, &v3)
+	feMul(&p.x, &p.x, &v)
+	feMul(&p.x, &p.x, &u) // x = uv^7
+	fePow22523(&p.x, &p.x)
+	feMul(&p.x, &p.x, &v3)
+	feMul(&p.x, &p.x, &u)
+
+	feSquare(&vxx, &p.x)
+	feMul(&vxx, &vxx, &v)
+	if !feEqual(&vxx, &u) {
+		feSub(&check, &fieldElement{}, &u)
+		if !feEqual(&vxx, &check) {
+			return false
+		}
+		feMul(&p.x, &p.x, &sqrtM1)
+	}
+
+	if feIsNegative(&p.x) != s[31]>>7 {
+		feSub(&p.x, &fieldElement{}, &p.x)
+	}
+
+	feMul(&p.t, &p.x, &p.y)
+	return true
+}
+
+// ScalarMult sets p = a*q, where a is a little-endian 256-bit scalar, using a
+// constant-time double-and-add ladder.
+func (p *ExtendedGroupElement) ScalarMult(a *[32]byte, q *ExtendedGroupElement) {
+	r := *q
+	p.Zero()
+	for i := 255; i >= 0; i-- {
+		b := int64(a[i>>3]>>uint(i&7)) & 1
+		cswap(p, &r, b)
+		r.Add(&r, p)
+		p.Add(p, p)
+		cswap(p, &r, b)
+	}
+}
+
+// ScalarBaseMult sets p = a*B, where a is a little-endian 256-bit scalar and
+// B is the generator of the group.
+func (p *ExtendedGroupElement) ScalarBaseMult(a *[32]byte) {
+	var base ExtendedGroupElement
+	base.x = baseX
+	base.y = baseY
+	base.z = fieldElement{1}
+	feMul(&base.t, &baseX, &baseY)
+	p.ScalarMult(a, &base)
+}
+
+// order is the order of the group, l = 2^252 +
+// 27742317777372353535851937790883648493, in little-endian form.
+var order = [32]int64{
+	0xed, 0xd3, 0xf5, 0x5c, 0x1a, 0x63, 0x12, 0x58,
+	0xd6, 0x9c, 0xf7, 0xa2, 0xde, 0xf9, 0xde, 0x14,
+	0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0x10,
+}
+
+// modL sets out = x mod l, where x holds 64 signed radix-2^8 limbs.
+func modL(out *[32]byte, x *[64]int64) {
+	for i := 63; i >= 32; i-- {
+		var carry int64
+		j := i - 32
+		for ; j < i-12; j++ {
+			x[j] += carry - 16*x[i]*order[j-(i-32)]
+			carry = (x[j] + 128) >> 8
+			x[j] -= carry << 8
+		}
+		x[j] += carry
+		x[i] = 0
+	}
+
+	var carry int64
+	for j := 0; j < 32; j++ {
+		x[j] += carry - (x[31]>>4)*order[j]
+		carry = x[j] >> 8
+		x[j] &= 255
+	}
+	for j := 0; j < 32; j++ {
+		x[j] -= carry * order[j]
+	}
+	for i := 0; i < 32; i++ {
+		x[i+1] += x[i] >> 8
+		out[i] = byte(x[i])
+	}
+}
+
+// ScReduce sets out = s mod l, where s is a 512-bit little-endian integer.
+func ScReduce(out *[32]byte, s *[64]byte) {
+	var x [64]int64
+	for i := range s {
+		x[i] = int64(s[i])
+	}
+	modL(out, &x)
+}
+
+// ScMulAdd sets s = (a*b + c) mod l, where all values are 256-bit
+// little-endian integers.
+func ScMulAdd(s, a, b, c *[32]byte) {
+	var x [64]int64
+	for i := 0; i < 32; i++ {
+		x[i] = int64(c[i])
+	}
+	for i := 0; i < 32; i++ {
+		for j := 0; j < 32; j++ {
+			x[i+j] += int64(a[i]) * int64(b[j])
+		}
+	}
+	modL(s, &x)
+}
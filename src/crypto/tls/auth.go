@@ -0,0 +1,191 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// isRSAPSS reports whether sigAndHash is one of the RSASSA-PSS signature
+// schemes with rsaEncryption public keys.
+func isRSAPSS(sigAndHash signatureAndHash) bool {
+	if sigAndHash.hash != hashIntrinsic {
+		return false
+	}
+	switch sigAndHash.signature {
+	case signatureRSAPSSSHA256, signatureRSAPSSSHA384, signatureRSAPSSSHA512:
+		return true
+	}
+	return false
+}
+
+// lookupSignatureHash returns the hash function used by the given signature
+// algorithm, which is fixed by the scheme itself for RSASSA-PSS. It returns
+// zero for Ed25519, which signs the message itself rather than a digest.
+func lookupSignatureHash(sigAndHash signatureAndHash) (crypto.Hash, error) {
+	if sigAndHash.hash != hashIntrinsic {
+		return lookupTLSHash(sigAndHash.hash)
+	}
+	switch sigAndHash.signature {
+	case signatureRSAPSSSHA256:
+		return crypto.SHA256, nil
+	case signatureRSAPSSSHA384:
+		return crypto.SHA384, nil
+	case signatureRSAPSSSHA512:
+		return crypto.SHA512, nil
+	case signatureEd25519:
+		return 0, nil
+	default:
+		return 0, errors.New("tls: unsupported signature algorithm")
+	}
+}
+
+// The TLS 1.3 CertificateVerify signature covers 64 spaces, a context string
+// and the transcript hash. See RFC 8446, section 4.4.3.
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify\x00"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify\x00"
+)
+
+var signaturePadding = []byte{
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+	0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20, 0x20,
+}
+
+// signedMessage returns the digest of the message to be signed by
+// certificate keys in a TLS 1.3 CertificateVerify. If sigHash is zero, the
+// message is returned unhashed.
+func signedMessage(sigHash crypto.Hash, context string, transcript hash.Hash) []byte {
+	if sigHash == 0 {
+		msg := append([]byte(nil), signaturePadding...)
+		msg = append(msg, context...)
+		return append(msg, transcript.Sum(nil)...)
+	}
+	h := sigHash.New()
+	h.Write(signaturePadding)
+	io.WriteString(h, context)
+	h.Write(transcript.Sum(nil))
+	return h.Sum(nil)
+}
+
+// signatureAlgorithmsForKeyTLS13 returns the TLS 1.3 signature schemes that
+// can be used with the given public key, in preference order. RSASSA-PKCS1-v1_5
+// is not allowed in TLS 1.3 handshake signatures, and ECDSA schemes are bound
+// to a specific curve.
+func signatureAlgorithmsForKeyTLS13(pub crypto.PublicKey) []signatureAndHash {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		// RSASSA-PSS with a salt as long as the hash needs a modulus of
+		// at least 2*hLen+2 bytes.
+		size := (pub.N.BitLen() + 7) / 8
+		var algs []signatureAndHash
+		for _, alg := range []signatureAndHash{
+			{hashIntrinsic, signatureRSAPSSSHA256},
+			{hashIntrinsic, signatureRSAPSSSHA384},
+			{hashIntrinsic, signatureRSAPSSSHA512},
+		} {
+			h, _ := lookupSignatureHash(alg)
+			if size >= 2*h.Size()+2 {
+				algs = append(algs, alg)
+			}
+		}
+		return algs
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return []signatureAndHash{{hashSHA256, signatureECDSA}}
+		case elliptic.P384():
+			return []signatureAndHash{{hashSHA384, signatureECDSA}}
+		case elliptic.P521():
+			return []signatureAndHash{{hashSHA512, signatureECDSA}}
+		}
+	case ed25519.PublicKey:
+		return []signatureAndHash{{hashIntrinsic, signatureEd25519}}
+	}
+	return nil
+}
+
+// selectSignatureSchemeTLS13 picks a signature scheme supported by the peer
+// for signing with the given public key, in the peer's preference order.
+func selectSignatureSchemeTLS13(pub crypto.PublicKey, peerAlgs []signatureAndHash) (signatureAndHash, error) {
+	supported := signatureAlgorithmsForKeyTLS13(pub)
+	if len(supported) == 0 {
+		return signatureAndHash{}, fmt.Errorf("tls: unsupported signing key type (%T)", pub)
+	}
+	for _, alg := range peerAlgs {
+		if isSupportedSignatureAndHash(alg, supported) {
+			return alg, nil
+		}
+	}
+	return signatureAndHash{}, errors.New("tls: peer doesn't support any of the certificate's signature algorithms")
+}
+
+// signHandshakeTLS13 signs the digest of a TLS 1.3 CertificateVerify message
+// with key, using the given signature scheme.
+func signHandshakeTLS13(rand io.Reader, key crypto.Signer, sigAndHash signatureAndHash, digest []byte) ([]byte, error) {
+	sigHash, err := lookupSignatureHash(sigAndHash)
+	if err != nil {
+		return nil, err
+	}
+	var opts crypto.SignerOpts = sigHash
+	if isRSAPSS(sigAndHash) {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: sigHash}
+	}
+	return key.Sign(rand, digest, opts)
+}
+
+// verifyHandshakeSignatureTLS13 checks a TLS 1.3 CertificateVerify
+// signature over the given transcript.
+func verifyHandshakeSignatureTLS13(sigAndHash signatureAndHash, pub crypto.PublicKey, context string, transcript hash.Hash, sig []byte) error {
+	if !isSupportedSignatureAndHash(sigAndHash, signatureAlgorithmsForKeyTLS13(pub)) {
+		return errors.New("tls: invalid signature algorithm for the certificate key")
+	}
+	sigHash, err := lookupSignatureHash(sigAndHash)
+	if err != nil {
+		return err
+	}
+	digest := signedMessage(sigHash, context, transcript)
+
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ecdsaSig := new(ecdsaSignature)
+		if _, err := asn1.Unmarshal(sig, ecdsaSig); err != nil {
+			return err
+		}
+		if ecdsaSig.R.Sign() <= 0 || ecdsaSig.S.Sign() <= 0 {
+			return errors.New("ECDSA signature contained zero or negative values")
+		}
+		if !ecdsa.Verify(pub, digest, ecdsaSig.R, ecdsaSig.S) {
+			return errors.New("ECDSA verification failure")
+		}
+	case *rsa.PublicKey:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		if err := rsa.VerifyPSS(pub, sigHash, digest, sig, opts); err != nil {
+			return err
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pub, digest, sig) {
+			return errors.New("Ed25519 verification failure")
+		}
+	default:
+		return fmt.Errorf("tls: unsupported public key type (%T)", pub)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ const (
 	VersionTLS10 = 0x0301
 	VersionTLS11 = 0x0302
 	VersionTLS12 = 0x0303
+	VersionTLS13 = 0x0304
 )
 
 const (
@@ -33,9 +34,14 @@ const (
 	maxHandshake    = 65536        // maximum handshake we support (protocol max is 16 MB)
 
 	minVersion = VersionTLS10
-	maxVersion = VersionTLS12
+	maxVersion = VersionTLS13
 )
 
+// maxSessionTicketLifetime is the maximum allowed lifetime of a TLS 1.3
+// session ticket, and the lifetime of the tickets we issue. See RFC 8446,
+// section 4.6.1.
+const maxSessionTicketLifetime = 7 * 24 * time.Hour
+
 // TLS record types.
 type recordType uint8
 
@@ -48,18 +54,21 @@ const (
 
 // TLS handshake message types.
 const (
-	typeClientHello        uint8 = 1
-	typeServerHello        uint8 = 2
-	typeNewSessionTicket   uint8 = 4
-	typeCertificate        uint8 = 11
-	typeServerKeyExchange  uint8 = 12
-	typeCertificateRequest uint8 = 13
-	typeServerHelloDone    uint8 = 14
-	typeCertificateVerify  uint8 = 15
-	typeClientKeyExchange  uint8 = 16
-	typeFinished           uint8 = 20
-	typeCertificateStatus  uint8 = 22
-	typeNextProtocol       uint8 = 67 // Not IANA assigned
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeCertificateStatus   uint8 = 22
+	typeKeyUpdate           uint8 = 24
+	typeNextProtocol        uint8 = 67  // Not IANA assigned
+	typeMessageHash         uint8 = 254 // synthetic message
 )
 
 // TLS compression types.
@@ -69,16 +78,22 @@ const (
 
 // TLS extension numbers
 const (
-	extensionServerName          uint16 = 0
-	extensionStatusRequest       uint16 = 5
-	extensionSupportedCurves     uint16 = 10
-	extensionSupportedPoints     uint16 = 11
-	extensionSignatureAlgorithms uint16 = 13
-	extensionALPN                uint16 = 16
-	extensionSCT                 uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
-	extensionSessionTicket       uint16 = 35
-	extensionNextProtoNeg        uint16 = 13172 // not IANA assigned
-	extensionRenegotiationInfo   uint16 = 0xff01
+	extensionServerName             uint16 = 0
+	extensionStatusRequest          uint16 = 5
+	extensionSupportedCurves        uint16 = 10
+	extensionSupportedPoints        uint16 = 11
+	extensionSignatureAlgorithms    uint16 = 13
+	extensionALPN                   uint16 = 16
+	extensionSCT                    uint16 = 18 // https://tools.ietf.org/html/rfc6962#section-6
+	extensionSessionTicket          uint16 = 35
+	extensionPreSharedKey           uint16 = 41
+	extensionSupportedVersions      uint16 = 43
+	extensionCookie                 uint16 = 44
+	extensionPSKModes               uint16 = 45
+	extensionCertificateAuthorities uint16 = 47
+	extensionKeyShare               uint16 = 51
+	extensionNextProtoNeg           uint16 = 13172 // not IANA assigned
+	extensionRenegotiationInfo      uint16 = 0xff01
 )
 
 // TLS signaling cipher suite values
@@ -86,6 +101,43 @@ const (
 	scsvRenegotiation uint16 = 0x00ff
 )
 
+// TLS 1.3 PSK Key Exchange Modes. See RFC 8446, section 4.2.9.
+const (
+	pskModePlain uint8 = 0
+	pskModeDHE   uint8 = 1
+)
+
+// TLS 1.3 Key Share. See RFC 8446, section 4.2.8.
+type keyShare struct {
+	group CurveID
+	data  []byte
+}
+
+// TLS 1.3 PSK Identity. Can be a Session Ticket, or a reference to a saved
+// session. See RFC 8446, section 4.2.11.
+type pskIdentity struct {
+	label               []byte
+	obfuscatedTicketAge uint32
+}
+
+// helloRetryRequestRandom is set as the Random value of a ServerHello
+// to signal that the message is actually a HelloRetryRequest. See RFC 8446,
+// section 4.1.3.
+var helloRetryRequestRandom = []byte{
+	0xCF, 0x21, 0xAD, 0x74, 0xE5, 0x9A, 0x61, 0x11,
+	0xBE, 0x1D, 0x8C, 0x02, 0x1E, 0x65, 0xB8, 0x91,
+	0xC2, 0xA2, 0x11, 0x16, 0x7A, 0xBB, 0x8C, 0x5E,
+	0x07, 0x9E, 0x09, 0xE2, 0xC8, 0xA8, 0x33, 0x9C,
+}
+
+const (
+	// downgradeCanaryTLS12 or downgradeCanaryTLS11 is embedded in the server
+	// random as a downgrade protection if the server would be capable of
+	// negotiating a higher version. See RFC 8446, section 4.1.3.
+	downgradeCanaryTLS12 = "DOWNGRD\x01"
+	downgradeCanaryTLS11 = "DOWNGRD\x00"
+)
+
 // CurveID is the type of a TLS identifier for an elliptic curve. See
 // http://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-8
 type CurveID uint16
@@ -94,6 +146,7 @@ const (
 	CurveP256 CurveID = 23
 	CurveP384 CurveID = 24
 	CurveP521 CurveID = 25
+	X25519    CurveID = 29
 )
 
 // TLS Elliptic Curve Point Formats
@@ -127,6 +180,7 @@ const (
 	hashSHA1   uint8 = 2
 	hashSHA256 uint8 = 4
 	hashSHA384 uint8 = 5
+	hashSHA512 uint8 = 6
 )
 
 // Signature algorithms for TLS 1.2 (See RFC 5246, section A.4.1)
@@ -135,6 +189,19 @@ const (
 	signatureECDSA uint8 = 3
 )
 
+// TLS 1.3 replaces the SignatureAndHashAlgorithm pair with a single
+// SignatureScheme, but keeps its two byte encoding. The RSASSA-PSS and Ed25519
+// schemes, which fix their own hash, use 8 in the hash position. See RFC 8446,
+// section 4.2.3, and RFC 8422, section 5.1.3.
+const (
+	hashIntrinsic uint8 = 8
+
+	signatureRSAPSSSHA256 uint8 = 4
+	signatureRSAPSSSHA384 uint8 = 5
+	signatureRSAPSSSHA512 uint8 = 6
+	signatureEd25519      uint8 = 7
+)
+
 // signatureAndHash mirrors the TLS 1.2, SignatureAndHashAlgorithm struct. See
 // RFC 5246, section A.4.1.
 type signatureAndHash struct {
@@ -153,6 +220,27 @@ var supportedSignatureAlgorithms = []signatureAndHash{
 	{hashSHA1, signatureECDSA},
 }
 
+// supportedSignatureAlgorithmsTLS13 contains the signature schemes that the
+// code advertises as supported in a ClientHello offering TLS 1.3 and in a
+// CertificateRequest sent by a server that supports TLS 1.3. The
+// RSASSA-PKCS1-v1_5 and SHA-1 entries are only acceptable in certificates, and
+// in a TLS 1.2 handshake negotiated from such a ClientHello. Ed25519 is only
+// offered here, so it is only used by endpoints that also support TLS 1.3.
+var supportedSignatureAlgorithmsTLS13 = []signatureAndHash{
+	{hashIntrinsic, signatureRSAPSSSHA256},
+	{hashSHA256, signatureECDSA},
+	{hashIntrinsic, signatureEd25519},
+	{hashIntrinsic, signatureRSAPSSSHA384},
+	{hashSHA384, signatureECDSA},
+	{hashIntrinsic, signatureRSAPSSSHA512},
+	{hashSHA512, signatureECDSA},
+	{hashSHA256, signatureRSA},
+	{hashSHA384, signatureRSA},
+	{hashSHA512, signatureRSA},
+	{hashSHA1, signatureRSA},
+	{hashSHA1, signatureECDSA},
+}
+
 // ConnectionState records basic TLS details about the connection.
 type ConnectionState struct {
 	Version                     uint16                // TLS version used by the connection (e.g. VersionTLS12)
@@ -172,7 +260,8 @@ type ConnectionState struct {
 	// because resumption does not include enough context (see
 	// https://secure-resumption.com/#channelbindings). This will change in
 	// future versions of Go once the TLS master-secret fix has been
-	// standardized and implemented.
+	// standardized and implemented. It is also nil for TLS 1.3
+	// connections, for which tls-unique is not defined.
 	TLSUnique []byte
 }
 
@@ -194,9 +283,15 @@ type ClientSessionState struct {
 	sessionTicket      []uint8               // Encrypted ticket used for session resumption with server
 	vers               uint16                // SSL/TLS version negotiated for the session
 	cipherSuite        uint16                // Ciphersuite negotiated for the session
-	masterSecret       []byte                // MasterSecret generated by client on a full handshake
+	masterSecret       []byte                // Full handshake MasterSecret, or TLS 1.3 resumption_master_secret
 	serverCertificates []*x509.Certificate   // Certificate chain presented by the server
 	verifiedChains     [][]*x509.Certificate // Certificate chains we built for verification
+
+	// TLS 1.3 fields.
+	receivedAt time.Time // When the session ticket was received from the server
+	nonce      []byte    // Ticket nonce sent by the server, to derive PSK
+	useBy      time.Time // Expiration of the ticket lifetime as set by the server
+	ageAdd     uint32    // Random obfuscation factor for sending the ticket age
 }
 
 // ClientSessionCache is a cache of ClientSessionState objects that can be used
@@ -307,8 +402,10 @@ type Config struct {
 	// This should be used only for testing.
 	InsecureSkipVerify bool
 
-	// CipherSuites is a list of supported cipher suites. If CipherSuites
-	// is nil, TLS uses a list of suites supported by the implementation.
+	// CipherSuites is a list of supported cipher suites for TLS versions up
+	// to TLS 1.2. If CipherSuites is nil, TLS uses a list of suites
+	// supported by the implementation. The TLS 1.3 cipher suites are not
+	// configurable.
 	CipherSuites []uint16
 
 	// PreferServerCipherSuites controls whether the server selects the
@@ -341,12 +438,13 @@ type Config struct {
 
 	// MaxVersion contains the maximum SSL/TLS version that is acceptable.
 	// If zero, then the maximum version supported by this package is used,
-	// which is currently TLS 1.2.
+	// which is currently TLS 1.3.
 	MaxVersion uint16
 
 	// CurvePreferences contains the elliptic curves that will be used in
 	// an ECDHE handshake, in preference order. If empty, the default will
-	// be used.
+	// be used. In TLS 1.3 a client only sends a key share for the first
+	// curve in the list.
 	CurvePreferences []CurveID
 
 	serverInitOnce sync.Once // guards calling (*Config).serverInit
@@ -474,7 +572,7 @@ func (c *Config) maxVersion() uint16 {
 	return c.MaxVersion
 }
 
-var defaultCurvePreferences = []CurveID{CurveP256, CurveP384, CurveP521}
+var defaultCurvePreferences = []CurveID{X25519, CurveP256, CurveP384, CurveP521}
 
 func (c *Config) curvePreferences() []CurveID {
 	if c == nil || len(c.CurvePreferences) == 0 {
@@ -483,13 +581,55 @@ func (c *Config) curvePreferences() []CurveID {
 	return c.CurvePreferences
 }
 
+var supportedVersions = []uint16{
+	VersionTLS13,
+	VersionTLS12,
+	VersionTLS11,
+	VersionTLS10,
+	VersionSSL30,
+}
+
+// supportedVersions returns the protocol versions enabled by c, in
+// preference order. Clients never offer SSL 3.0.
+func (c *Config) supportedVersions(isClient bool) []uint16 {
+	versions := make([]uint16, 0, len(supportedVersions))
+	for _, v := range supportedVersions {
+		if v < c.minVersion() || v > c.maxVersion() {
+			continue
+		}
+		if isClient && v < VersionTLS10 {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions
+}
+
+// mutualVersionFromList returns the protocol version to use given the list
+// of versions advertised by the peer in a supported_versions extension. It
+// picks the one the local side prefers.
+func (c *Config) mutualVersionFromList(isClient bool, peerVersions []uint16) (uint16, bool) {
+	for _, v := range c.supportedVersions(isClient) {
+		for _, peerVersion := range peerVersions {
+			if v == peerVersion {
+				return v, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // mutualVersion returns the protocol version to use given the advertised
 // version of the peer.
 func (c *Config) mutualVersion(vers uint16) (uint16, bool) {
 	minVersion := c.minVersion()
 	maxVersion := c.maxVersion()
 
-	if vers < minVersion {
+	// TLS 1.3 can only be negotiated with the supported_versions extension.
+	if maxVersion > VersionTLS12 {
+		maxVersion = VersionTLS12
+	}
+	if vers < minVersion || minVersion > maxVersion {
 		return 0, false
 	}
 	if vers > maxVersion {
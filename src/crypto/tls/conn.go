@@ -47,6 +47,9 @@ type Conn struct {
 	// firstFinished contains the first Finished hash sent during the
 	// handshake. This is the "tls-unique" channel binding value.
 	firstFinished [12]byte
+	// resumptionSecret is the resumption_master_secret for handling
+	// NewSessionTicket messages. nil if config.SessionTicketsDisabled.
+	resumptionSecret []byte
 
 	clientProtocol         string
 	clientProtocolFallback bool
@@ -57,6 +60,12 @@ type Conn struct {
 	input    *block       // application data waiting to be read
 	hand     bytes.Buffer // handshake data waiting to be read
 
+	// buffering, when true, makes writeRecord accumulate records in
+	// sendBuf instead of writing them to the network, so that a whole
+	// handshake flight is sent with a single write. See flush.
+	buffering bool
+	sendBuf   []byte
+
 	// activeCall is an atomic int32; the low bit is whether Close has
 	// been called. the rest of the bits are the number of goroutines
 	// in Conn.Write.
@@ -117,6 +126,8 @@ type halfConn struct {
 
 	// used to save allocating a new buffer for each MAC.
 	inDigestBuf, outDigestBuf []byte
+
+	trafficSecret []byte // current TLS 1.3 traffic secret
 }
 
 func (hc *halfConn) setErrorLocked(err error) error {
@@ -139,6 +150,18 @@ func (hc *halfConn) prepareCipherSpec(version uint16, cipher interface{}, mac ma
 	hc.nextMac = mac
 }
 
+// setTrafficSecret sets the TLS 1.3 traffic secret and the keys derived from
+// it, and resets the sequence number. Unlike with prepareCipherSpec, the new
+// keys are used starting with the next record.
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, secret []byte) {
+	hc.trafficSecret = secret
+	key, iv := suite.trafficKey(secret)
+	hc.version = VersionTLS13
+	hc.cipher = suite.aead(key, iv)
+	hc.mac = nil
+	hc.resetSeq()
+}
+
 // changeCipherSpec changes the encryption and MAC states
 // to the ones previously passed to prepareCipherSpec.
 func (hc *halfConn) changeCipherSpec() error {
@@ -243,11 +266,26 @@ type cbcMode interface {
 
 // decrypt checks and strips the mac and decrypts the data in b. Returns a
 // success boolean, the number of bytes to skip from the start of the record in
-// order to get the application payload, and an optional alert value.
+// order to get the application payload, and an optional alert value. In TLS
+// 1.3 the real content type of the record is recovered from the encrypted
+// payload and stored in the record header.
 func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert) {
 	// pull out payload
 	payload := b.data[recordHeaderLen:]
 
+	isTLS13 := hc.version == VersionTLS13 && hc.cipher != nil
+	if isTLS13 {
+		switch recordType(b.data[0]) {
+		case recordTypeApplicationData:
+		case recordTypeChangeCipherSpec:
+			// Change cipher spec records are never encrypted in TLS
+			// 1.3, and are dropped by the caller.
+			return true, recordHeaderLen, 0
+		default:
+			return false, 0, alertUnexpectedMessage
+		}
+	}
+
 	macSize := 0
 	if hc.mac != nil {
 		macSize = hc.mac.Size()
@@ -261,21 +299,32 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 		switch c := hc.cipher.(type) {
 		case cipher.Stream:
 			c.XORKeyStream(payload, payload)
-		case cipher.AEAD:
-			explicitIVLen = 8
+		case aead:
+			explicitIVLen = c.explicitNonceLen()
 			if len(payload) < explicitIVLen {
 				return false, 0, alertBadRecordMAC
 			}
-			nonce := payload[:8]
-			payload = payload[8:]
-
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			n := len(payload) - c.Overhead()
-			hc.additionalData[11] = byte(n >> 8)
-			hc.additionalData[12] = byte(n)
+			nonce := payload[:explicitIVLen]
+			payload = payload[explicitIVLen:]
+			if len(nonce) == 0 {
+				// The nonce is derived from the sequence number.
+				nonce = hc.seq[:]
+			}
+
+			var additionalData []byte
+			if isTLS13 {
+				// The additional data is the record header.
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				n := len(payload) - c.Overhead()
+				hc.additionalData[11] = byte(n >> 8)
+				hc.additionalData[12] = byte(n)
+				additionalData = hc.additionalData[:]
+			}
 			var err error
-			payload, err = c.Open(payload[:0], nonce, payload, hc.additionalData[:])
+			payload, err = c.Open(payload[:0], nonce, payload, additionalData)
 			if err != nil {
 				return false, 0, alertBadRecordMAC
 			}
@@ -336,6 +385,20 @@ func (hc *halfConn) decrypt(b *block) (ok bool, prefixLen int, alertValue alert)
 		}
 		hc.inDigestBuf = localMAC
 	}
+
+	if isTLS13 {
+		// Strip the zero padding, and take the content type from the
+		// last non-zero byte. See RFC 8446, section 5.4.
+		i := len(b.data) - 1
+		for i >= recordHeaderLen && b.data[i] == 0 {
+			i--
+		}
+		if i < recordHeaderLen {
+			return false, 0, alertUnexpectedMessage
+		}
+		b.data[0] = b.data[i]
+		b.resize(i)
+	}
 	hc.incSeq()
 
 	return true, recordHeaderLen + explicitIVLen, 0
@@ -377,19 +440,32 @@ func (hc *halfConn) encrypt(b *block, explicitIVLen int) (bool, alert) {
 		switch c := hc.cipher.(type) {
 		case cipher.Stream:
 			c.XORKeyStream(payload, payload)
-		case cipher.AEAD:
+		case aead:
 			payloadLen := len(b.data) - recordHeaderLen - explicitIVLen
 			b.resize(len(b.data) + c.Overhead())
 			nonce := b.data[recordHeaderLen : recordHeaderLen+explicitIVLen]
+			if len(nonce) == 0 {
+				nonce = hc.seq[:]
+			}
 			payload := b.data[recordHeaderLen+explicitIVLen:]
 			payload = payload[:payloadLen]
 
-			copy(hc.additionalData[:], hc.seq[:])
-			copy(hc.additionalData[8:], b.data[:3])
-			hc.additionalData[11] = byte(payloadLen >> 8)
-			hc.additionalData[12] = byte(payloadLen)
+			var additionalData []byte
+			if hc.version == VersionTLS13 {
+				// The additional data is the final record header.
+				n := len(b.data) - recordHeaderLen
+				b.data[3] = byte(n >> 8)
+				b.data[4] = byte(n)
+				additionalData = b.data[:recordHeaderLen]
+			} else {
+				copy(hc.additionalData[:], hc.seq[:])
+				copy(hc.additionalData[8:], b.data[:3])
+				hc.additionalData[11] = byte(payloadLen >> 8)
+				hc.additionalData[12] = byte(payloadLen)
+				additionalData = hc.additionalData[:]
+			}
 
-			c.Seal(payload[:0], nonce, payload, hc.additionalData[:])
+			c.Seal(payload[:0], nonce, payload, additionalData)
 		case cbcMode:
 			blockSize := c.BlockSize()
 			if explicitIVLen > 0 {
@@ -541,7 +617,9 @@ func (c *Conn) readRecord(want recordType) error {
 		c.sendAlert(alertInternalError)
 		return c.in.setErrorLocked(errors.New("tls: unknown record type requested"))
 	case recordTypeHandshake, recordTypeChangeCipherSpec:
-		if c.handshakeComplete {
+		// In TLS 1.3 a post-handshake message can span several
+		// records, read through readHandshake.
+		if c.handshakeComplete && !(want == recordTypeHandshake && c.vers == VersionTLS13) {
 			c.sendAlert(alertInternalError)
 			return c.in.setErrorLocked(errors.New("tls: handshake or ChangeCipherSpec requested after handshake complete"))
 		}
@@ -584,7 +662,9 @@ Again:
 
 	vers := uint16(b.data[1])<<8 | uint16(b.data[2])
 	n := int(b.data[3])<<8 | int(b.data[4])
-	if c.haveVers && vers != c.vers {
+	// The record version is frozen at TLS 1.2 in TLS 1.3, and must be
+	// ignored.
+	if c.haveVers && c.vers != VersionTLS13 && vers != c.vers {
 		c.sendAlert(alertProtocolVersion)
 		msg := fmt.Sprintf("received record with version %x when expecting version %x", vers, c.vers)
 		return c.in.setErrorLocked(c.newRecordHeaderError(msg))
@@ -620,6 +700,7 @@ Again:
 	if !ok {
 		c.in.setErrorLocked(c.sendAlert(err))
 	}
+	typ = recordType(b.data[0])
 	b.off = off
 	data := b.data[b.off:]
 	if len(data) > maxPlaintext {
@@ -641,6 +722,12 @@ Again:
 			c.in.setErrorLocked(io.EOF)
 			break
 		}
+		if c.vers == VersionTLS13 {
+			// All alerts other than close_notify are fatal in TLS
+			// 1.3, whatever their level.
+			c.in.setErrorLocked(&net.OpError{Op: "remote error", Err: alert(data[1])})
+			break
+		}
 		switch data[0] {
 		case alertLevelWarning:
 			// drop on the floor
@@ -653,7 +740,18 @@ Again:
 		}
 
 	case recordTypeChangeCipherSpec:
-		if typ != want || len(data) != 1 || data[0] != 1 {
+		if len(data) != 1 || data[0] != 1 {
+			c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
+			break
+		}
+		// In TLS 1.3 a change_cipher_spec record may be sent during the
+		// handshake for middlebox compatibility, and must be ignored.
+		// See RFC 8446, Appendix D.4.
+		if c.vers == VersionTLS13 && !c.handshakeComplete {
+			c.in.freeBlock(b)
+			goto Again
+		}
+		if typ != want {
 			c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 			break
 		}
@@ -672,7 +770,8 @@ Again:
 
 	case recordTypeHandshake:
 		// TODO(rsc): Should at least pick off connection close.
-		if typ != want {
+		// TLS 1.3 post-handshake messages are handled by Read.
+		if typ != want && !(c.vers == VersionTLS13 && c.handshakeComplete) {
 			return c.in.setErrorLocked(c.sendAlert(alertNoRenegotiation))
 		}
 		c.hand.Write(data)
@@ -695,6 +794,9 @@ func (c *Conn) sendAlertLocked(err alert) error {
 	}
 	c.tmp[1] = byte(err)
 	c.writeRecord(recordTypeAlert, c.tmp[0:2])
+	// Make sure the alert and any buffered handshake messages
+	// are sent before the connection fails.
+	c.flush()
 	// closeNotify is a special case in that it isn't an error:
 	if err != alertCloseNotify {
 		return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
@@ -710,6 +812,27 @@ func (c *Conn) sendAlert(err alert) error {
 	return c.sendAlertLocked(err)
 }
 
+// write writes data to the connection, or appends it to c.sendBuf if
+// c.buffering is set.
+func (c *Conn) write(data []byte) (int, error) {
+	if c.buffering {
+		c.sendBuf = append(c.sendBuf, data...)
+		return len(data), nil
+	}
+	return c.conn.Write(data)
+}
+
+// flush writes any buffered records to the connection and stops buffering.
+func (c *Conn) flush() (int, error) {
+	c.buffering = false
+	if len(c.sendBuf) == 0 {
+		return 0, nil
+	}
+	n, err := c.conn.Write(c.sendBuf)
+	c.sendBuf = nil
+	return n, err
+}
+
 // writeRecord writes a TLS record with the given type and payload
 // to the connection and updates the record layer state.
 // c.out.Mutex <= L.
@@ -731,15 +854,16 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 			}
 		}
 		if explicitIVLen == 0 {
-			if _, ok := c.out.cipher.(cipher.AEAD); ok {
-				explicitIVLen = 8
+			if c, ok := c.out.cipher.(aead); ok {
+				explicitIVLen = c.explicitNonceLen()
+
 				// The AES-GCM construction in TLS has an
 				// explicit nonce so that the nonce can be
 				// random. However, the nonce is only 8 bytes
 				// which is too small for a secure, random
 				// nonce. Therefore we use the sequence number
 				// as the nonce.
-				explicitIVIsSeq = true
+				explicitIVIsSeq = explicitIVLen > 0
 			}
 		}
 		b.resize(recordHeaderLen + explicitIVLen + m)
@@ -749,6 +873,10 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 			// Some TLS servers fail if the record version is
 			// greater than TLS 1.0 for the initial ClientHello.
 			vers = VersionTLS10
+		} else if vers == VersionTLS13 {
+			// TLS 1.3 froze the record layer version to 1.2.
+			// See RFC 8446, section 5.1.
+			vers = VersionTLS12
 		}
 		b.data[1] = byte(vers >> 8)
 		b.data[2] = byte(vers)
@@ -765,8 +893,16 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 			}
 		}
 		copy(b.data[recordHeaderLen+explicitIVLen:], data)
+		if c.out.version == VersionTLS13 && c.out.cipher != nil {
+			// Encrypted TLS 1.3 records carry their real content
+			// type at the end of the plaintext, and are all sent
+			// as application data.
+			b.resize(len(b.data) + 1)
+			b.data[len(b.data)-1] = byte(typ)
+			b.data[0] = byte(recordTypeApplicationData)
+		}
 		c.out.encrypt(b, explicitIVLen)
-		_, err = c.conn.Write(b.data)
+		_, err = c.write(b.data)
 		if err != nil {
 			break
 		}
@@ -775,7 +911,7 @@ func (c *Conn) writeRecord(typ recordType, data []byte) (n int, err error) {
 	}
 	c.out.freeBlock(b)
 
-	if typ == recordTypeChangeCipherSpec {
+	if typ == recordTypeChangeCipherSpec && c.vers != VersionTLS13 {
 		err = c.out.changeCipherSpec()
 		if err != nil {
 			// Cannot call sendAlert directly,
@@ -823,12 +959,24 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	case typeServerHello:
 		m = new(serverHelloMsg)
 	case typeNewSessionTicket:
-		m = new(newSessionTicketMsg)
+		if c.vers == VersionTLS13 {
+			m = new(newSessionTicketMsgTLS13)
+		} else {
+			m = new(newSessionTicketMsg)
+		}
 	case typeCertificate:
-		m = new(certificateMsg)
+		if c.vers == VersionTLS13 {
+			m = new(certificateMsgTLS13)
+		} else {
+			m = new(certificateMsg)
+		}
 	case typeCertificateRequest:
-		m = &certificateRequestMsg{
-			hasSignatureAndHash: c.vers >= VersionTLS12,
+		if c.vers == VersionTLS13 {
+			m = new(certificateRequestMsgTLS13)
+		} else {
+			m = &certificateRequestMsg{
+				hasSignatureAndHash: c.vers >= VersionTLS12,
+			}
 		}
 	case typeCertificateStatus:
 		m = new(certificateStatusMsg)
@@ -846,6 +994,10 @@ func (c *Conn) readHandshake() (interface{}, error) {
 		m = new(nextProtoMsg)
 	case typeFinished:
 		m = new(finishedMsg)
+	case typeEncryptedExtensions:
+		m = new(encryptedExtensionsMsg)
+	case typeKeyUpdate:
+		m = new(keyUpdateMsg)
 	default:
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
@@ -861,6 +1013,56 @@ func (c *Conn) readHandshake() (interface{}, error) {
 	return m, nil
 }
 
+// handlePostHandshakeMessage processes a handshake message that arrived
+// after the handshake is complete, which only happens in TLS 1.3.
+// c.in.Mutex <= L.
+func (c *Conn) handlePostHandshakeMessage() error {
+	msg, err := c.readHandshake()
+	if err != nil {
+		return err
+	}
+
+	switch msg := msg.(type) {
+	case *newSessionTicketMsgTLS13:
+		return c.handleNewSessionTicket(msg)
+	case *keyUpdateMsg:
+		return c.handleKeyUpdate(msg)
+	default:
+		c.sendAlert(alertUnexpectedMessage)
+		return c.in.setErrorLocked(fmt.Errorf("tls: received unexpected handshake message of type %T", msg))
+	}
+}
+
+// handleKeyUpdate switches to the next read traffic secret and, if the peer
+// asked for it, updates the write traffic secret too.
+// c.in.Mutex <= L.
+func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
+	cipherSuite := cipherSuiteTLS13ByID(c.cipherSuite)
+	if cipherSuite == nil {
+		return c.in.setErrorLocked(c.sendAlert(alertInternalError))
+	}
+
+	newSecret := cipherSuite.nextTrafficSecret(c.in.trafficSecret)
+	c.in.setTrafficSecret(cipherSuite, newSecret)
+
+	if keyUpdate.updateRequested {
+		c.out.Lock()
+		defer c.out.Unlock()
+
+		msg := &keyUpdateMsg{}
+		if _, err := c.writeRecord(recordTypeHandshake, msg.marshal()); err != nil {
+			// Surface the error at the next write.
+			c.out.setErrorLocked(err)
+			return nil
+		}
+
+		newSecret := cipherSuite.nextTrafficSecret(c.out.trafficSecret)
+		c.out.setTrafficSecret(cipherSuite, newSecret)
+	}
+
+	return nil
+}
+
 var errClosed = errors.New("crypto/tls: use of closed connection")
 
 // Write writes data to the connection.
@@ -940,6 +1142,13 @@ func (c *Conn) Read(b []byte) (n int, err error) {
 				// Soft error, like EAGAIN
 				return 0, err
 			}
+			if c.hand.Len() > 0 {
+				// We received handshake bytes, indicating a
+				// post-handshake message (TLS 1.3 only).
+				if err := c.handlePostHandshakeMessage(); err != nil {
+					return 0, err
+				}
+			}
 		}
 		if err := c.in.err; err != nil {
 			return 0, err
@@ -1055,7 +1264,7 @@ func (c *Conn) ConnectionState() ConnectionState {
 		state.ServerName = c.serverName
 		state.SignedCertificateTimestamps = c.scts
 		state.OCSPResponse = c.ocspResponse
-		if !c.didResume {
+		if !c.didResume && c.vers != VersionTLS13 {
 			state.TLSUnique = c.firstFinished[:]
 		}
 	}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -16,6 +17,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"time"
 )
 
 type clientHandshakeState struct {
@@ -69,6 +71,11 @@ func (c *Conn) clientHandshake() error {
 		secureRenegotiation: true,
 		alpnProtocols:       c.config.NextProtos,
 	}
+	if hello.vers > VersionTLS12 {
+		// TLS 1.3 is offered in the supported_versions extension, and
+		// the legacy version field is frozen at TLS 1.2.
+		hello.vers = VersionTLS12
+	}
 
 	possibleCipherSuites := c.config.cipherSuites()
 	hello.cipherSuites = make([]uint16, 0, len(possibleCipherSuites))
@@ -99,8 +106,35 @@ NextCipherSuite:
 		hello.signatureAndHashes = supportedSignatureAlgorithms
 	}
 
+	var ecdheParams ecdheParameters
+	if c.config.maxVersion() >= VersionTLS13 {
+		hello.supportedVersions = c.config.supportedVersions(true)
+		hello.cipherSuites = append(hello.cipherSuites, defaultCipherSuitesTLS13()...)
+		hello.signatureAndHashes = supportedSignatureAlgorithmsTLS13
+		hello.pskModes = []uint8{pskModeDHE}
+
+		// A non-empty legacy session ID is sent to look like a TLS 1.2
+		// resumption to middleboxes. See RFC 8446, Appendix D.4.
+		hello.sessionId = make([]byte, 32)
+		if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
+			c.sendAlert(alertInternalError)
+			return errors.New("tls: short read from Rand: " + err.Error())
+		}
+
+		// Only a key share for the most preferred curve is sent. The
+		// server can ask for another one with a HelloRetryRequest.
+		curveID := hello.supportedCurves[0]
+		ecdheParams, err = generateECDHEParameters(c.config.rand(), curveID)
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		hello.keyShares = []keyShare{{group: curveID, data: ecdheParams.PublicKey()}}
+	}
+
 	var session *ClientSessionState
 	var cacheKey string
+	var earlySecret, binderKey []byte
 	sessionCache := c.config.ClientSessionCache
 	if c.config.SessionTicketsDisabled {
 		sessionCache = nil
@@ -126,21 +160,31 @@ NextCipherSuite:
 
 			versOk := candidateSession.vers >= c.config.minVersion() &&
 				candidateSession.vers <= c.config.maxVersion()
+
+			// TLS 1.3 session tickets have a lifetime set by the
+			// server.
+			if candidateSession.vers == VersionTLS13 && c.config.time().After(candidateSession.useBy) {
+				versOk = false
+			}
 			if versOk && cipherSuiteOk {
 				session = candidateSession
 			}
 		}
 	}
 
-	if session != nil {
+	if session != nil && session.vers == VersionTLS13 {
+		earlySecret, binderKey = c.offerSessionTLS13(hello, session)
+	} else if session != nil {
 		hello.sessionTicket = session.sessionTicket
 		// A random session ID is used to detect when the
 		// server accepted the ticket and is resuming a session
 		// (see RFC 5077).
-		hello.sessionId = make([]byte, 16)
-		if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
-			c.sendAlert(alertInternalError)
-			return errors.New("tls: short read from Rand: " + err.Error())
+		if hello.sessionId == nil {
+			hello.sessionId = make([]byte, 16)
+			if _, err := io.ReadFull(c.config.rand(), hello.sessionId); err != nil {
+				c.sendAlert(alertInternalError)
+				return errors.New("tls: short read from Rand: " + err.Error())
+			}
 		}
 	}
 
@@ -156,15 +200,54 @@ NextCipherSuite:
 		return unexpectedMessageError(serverHello, msg)
 	}
 
-	vers, ok := c.config.mutualVersion(serverHello.vers)
+	vers := serverHello.vers
+	if serverHello.supportedVersion != 0 {
+		// The supported_versions extension can only be used to
+		// negotiate TLS 1.3, and only if we offered it.
+		vers = serverHello.supportedVersion
+		ok = vers == VersionTLS13 && len(hello.supportedVersions) > 0
+	} else {
+		vers, ok = c.config.mutualVersion(serverHello.vers)
+	}
 	if !ok || vers < VersionTLS10 {
 		// TLS 1.0 is the minimum version supported as a client.
 		c.sendAlert(alertProtocolVersion)
-		return fmt.Errorf("tls: server selected unsupported protocol version %x", serverHello.vers)
+		return fmt.Errorf("tls: server selected unsupported protocol version %x", vers)
 	}
+
+	// A TLS 1.3 capable server embeds a canary in the server random when
+	// it negotiates an earlier version, which must be rejected if we
+	// could have negotiated a later one. See RFC 8446, section 4.1.3.
+	maxVers := c.config.maxVersion()
+	tls12Downgrade := string(serverHello.random[24:]) == downgradeCanaryTLS12
+	tls11Downgrade := string(serverHello.random[24:]) == downgradeCanaryTLS11
+	if maxVers >= VersionTLS13 && vers <= VersionTLS12 && (tls12Downgrade || tls11Downgrade) ||
+		maxVers == VersionTLS12 && vers <= VersionTLS11 && tls11Downgrade {
+		c.sendAlert(alertIllegalParameter)
+		return errors.New("tls: downgrade attempt detected, possibly due to a MitM attack or a broken middlebox")
+	}
+
 	c.vers = vers
 	c.haveVers = true
 
+	if vers == VersionTLS13 {
+		hs := &clientHandshakeStateTLS13{
+			c:           c,
+			serverHello: serverHello,
+			hello:       hello,
+			ecdheParams: ecdheParams,
+			session:     session,
+			earlySecret: earlySecret,
+			binderKey:   binderKey,
+		}
+		return hs.handshake()
+	}
+
+	// A TLS 1.3 session can't be resumed with an earlier version.
+	if session != nil && session.vers == VersionTLS13 {
+		session = nil
+	}
+
 	suite := mutualCipherSuite(hello.cipherSuites, serverHello.cipherSuite)
 	if suite == nil {
 		c.sendAlert(alertHandshakeFailure)
@@ -251,47 +334,10 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	}
 	hs.finishedHash.Write(certMsg.marshal())
 
-	certs := make([]*x509.Certificate, len(certMsg.certificates))
-	for i, asn1Data := range certMsg.certificates {
-		cert, err := x509.ParseCertificate(asn1Data)
-		if err != nil {
-			c.sendAlert(alertBadCertificate)
-			return errors.New("tls: failed to parse certificate from server: " + err.Error())
-		}
-		certs[i] = cert
-	}
-
-	if !c.config.InsecureSkipVerify {
-		opts := x509.VerifyOptions{
-			Roots:         c.config.RootCAs,
-			CurrentTime:   c.config.time(),
-			DNSName:       c.config.ServerName,
-			Intermediates: x509.NewCertPool(),
-		}
-
-		for i, cert := range certs {
-			if i == 0 {
-				continue
-			}
-			opts.Intermediates.AddCert(cert)
-		}
-		c.verifiedChains, err = certs[0].Verify(opts)
-		if err != nil {
-			c.sendAlert(alertBadCertificate)
-			return err
-		}
-	}
-
-	switch certs[0].PublicKey.(type) {
-	case *rsa.PublicKey, *ecdsa.PublicKey:
-		break
-	default:
-		c.sendAlert(alertUnsupportedCertificate)
-		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
+	if err := c.verifyServerCertificate(certMsg.certificates); err != nil {
+		return err
 	}
 
-	c.peerCertificates = certs
-
 	if hs.serverHello.ocspStapling {
 		msg, err = c.readHandshake()
 		if err != nil {
@@ -319,7 +365,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 	skx, ok := msg.(*serverKeyExchangeMsg)
 	if ok {
 		hs.finishedHash.Write(skx.marshal())
-		err = keyAgreement.processServerKeyExchange(c.config, hs.hello, hs.serverHello, certs[0], skx)
+		err = keyAgreement.processServerKeyExchange(c.config, hs.hello, hs.serverHello, c.peerCertificates[0], skx)
 		if err != nil {
 			c.sendAlert(alertUnexpectedMessage)
 			return err
@@ -359,48 +405,15 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 				ecdsaAvail = true
 			}
 		}
+		// Ed25519 certificates share the ecdsa_sign certificate type, but
+		// can only be used if the server accepts Ed25519 signatures.
+		ed25519Avail := ecdsaAvail && c.vers >= VersionTLS12 &&
+			isSupportedSignatureAndHash(signatureAndHash{hashIntrinsic, signatureEd25519}, certReq.signatureAndHashes)
 
-		// We need to search our list of client certs for one
-		// where SignatureAlgorithm is acceptable to the server and the
-		// Issuer is in certReq.certificateAuthorities
-	findCert:
-		for i, chain := range c.config.Certificates {
-			if !rsaAvail && !ecdsaAvail {
-				continue
-			}
-
-			for j, cert := range chain.Certificate {
-				x509Cert := chain.Leaf
-				// parse the certificate if this isn't the leaf
-				// node, or if chain.Leaf was nil
-				if j != 0 || x509Cert == nil {
-					if x509Cert, err = x509.ParseCertificate(cert); err != nil {
-						c.sendAlert(alertInternalError)
-						return errors.New("tls: failed to parse client certificate #" + strconv.Itoa(i) + ": " + err.Error())
-					}
-				}
-
-				switch {
-				case rsaAvail && x509Cert.PublicKeyAlgorithm == x509.RSA:
-				case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.ECDSA:
-				default:
-					continue findCert
-				}
-
-				if len(certReq.certificateAuthorities) == 0 {
-					// they gave us an empty list, so just take the
-					// first cert from c.config.Certificates
-					chainToSend = &chain
-					break findCert
-				}
-
-				for _, ca := range certReq.certificateAuthorities {
-					if bytes.Equal(x509Cert.RawIssuer, ca) {
-						chainToSend = &chain
-						break findCert
-					}
-				}
-			}
+		chainToSend, err = c.getClientCertificate(rsaAvail, ecdsaAvail, ed25519Avail, certReq.certificateAuthorities)
+		if err != nil {
+			c.sendAlert(alertInternalError)
+			return err
 		}
 
 		msg, err = c.readHandshake()
@@ -428,7 +441,7 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		c.writeRecord(recordTypeHandshake, certMsg.marshal())
 	}
 
-	preMasterSecret, ckx, err := keyAgreement.generateClientKeyExchange(c.config, hs.hello, certs[0])
+	preMasterSecret, ckx, err := keyAgreement.generateClientKeyExchange(c.config, hs.hello, c.peerCertificates[0])
 	if err != nil {
 		c.sendAlert(alertInternalError)
 		return err
@@ -455,6 +468,8 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 			signatureType = signatureECDSA
 		case *rsa.PublicKey:
 			signatureType = signatureRSA
+		case ed25519.PublicKey:
+			signatureType = signatureEd25519
 		default:
 			c.sendAlert(alertInternalError)
 			return fmt.Errorf("tls: failed to sign handshake with client certificate: unknown client certificate key type: %T", key)
@@ -641,6 +656,122 @@ func (hs *clientHandshakeState) sendFinished(out []byte) error {
 	return nil
 }
 
+// offerSessionTLS13 adds a TLS 1.3 session to hello as a pre-shared key, and
+// returns the early secret and the binder key derived from it. See RFC 8446,
+// section 4.2.11.
+func (c *Conn) offerSessionTLS13(hello *clientHelloMsg, session *ClientSessionState) (earlySecret, binderKey []byte) {
+	suite := cipherSuiteTLS13ByID(session.cipherSuite)
+
+	ticketAge := uint32(c.config.time().Sub(session.receivedAt) / time.Millisecond)
+	hello.pskIdentities = []pskIdentity{{
+		label:               session.sessionTicket,
+		obfuscatedTicketAge: ticketAge + session.ageAdd,
+	}}
+	hello.pskBinders = [][]byte{make([]byte, suite.hash.Size())}
+
+	psk := suite.expandLabel(session.masterSecret, "resumption", session.nonce, suite.hash.Size())
+	earlySecret = suite.extract(psk, nil)
+	binderKey = suite.deriveSecret(earlySecret, resumptionBinderLabel, nil)
+
+	transcript := suite.hash.New()
+	transcript.Write(hello.marshalWithoutBinders())
+	hello.updateBinders([][]byte{suite.finishedHash(binderKey, transcript)})
+	return
+}
+
+// verifyServerCertificate parses and, unless InsecureSkipVerify is set,
+// verifies the certificate chain sent by the server. On success the chain is
+// stored in c.peerCertificates.
+func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
+	certs := make([]*x509.Certificate, len(certificates))
+	for i, asn1Data := range certificates {
+		cert, err := x509.ParseCertificate(asn1Data)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return errors.New("tls: failed to parse certificate from server: " + err.Error())
+		}
+		certs[i] = cert
+	}
+
+	if !c.config.InsecureSkipVerify {
+		opts := x509.VerifyOptions{
+			Roots:         c.config.RootCAs,
+			CurrentTime:   c.config.time(),
+			DNSName:       c.config.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+
+		for i, cert := range certs {
+			if i == 0 {
+				continue
+			}
+			opts.Intermediates.AddCert(cert)
+		}
+		var err error
+		c.verifiedChains, err = certs[0].Verify(opts)
+		if err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	}
+
+	switch certs[0].PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		break
+	default:
+		c.sendAlert(alertUnsupportedCertificate)
+		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
+	}
+
+	c.peerCertificates = certs
+	return nil
+}
+
+// getClientCertificate searches c.config.Certificates for a chain whose
+// public key algorithm is acceptable to the server and whose issuer is in
+// certificateAuthorities. It returns nil if there is no such chain.
+func (c *Conn) getClientCertificate(rsaAvail, ecdsaAvail, ed25519Avail bool, certificateAuthorities [][]byte) (*Certificate, error) {
+	if !rsaAvail && !ecdsaAvail && !ed25519Avail {
+		return nil, nil
+	}
+
+findCert:
+	for i, chain := range c.config.Certificates {
+		for j, cert := range chain.Certificate {
+			x509Cert := chain.Leaf
+			// parse the certificate if this isn't the leaf
+			// node, or if chain.Leaf was nil
+			if j != 0 || x509Cert == nil {
+				var err error
+				if x509Cert, err = x509.ParseCertificate(cert); err != nil {
+					return nil, errors.New("tls: failed to parse client certificate #" + strconv.Itoa(i) + ": " + err.Error())
+				}
+			}
+
+			switch {
+			case rsaAvail && x509Cert.PublicKeyAlgorithm == x509.RSA:
+			case ecdsaAvail && x509Cert.PublicKeyAlgorithm == x509.ECDSA:
+			case ed25519Avail && x509Cert.PublicKeyAlgorithm == x509.Ed25519:
+			default:
+				continue findCert
+			}
+
+			if len(certificateAuthorities) == 0 {
+				// they gave us an empty list, so just take the
+				// first cert from c.config.Certificates
+				return &chain, nil
+			}
+
+			for _, ca := range certificateAuthorities {
+				if bytes.Equal(x509Cert.RawIssuer, ca) {
+					return &chain, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
 // clientSessionCacheKey returns a key used to cache sessionTickets that could
 // be used to resume previously negotiated TLS sessions with a server.
 func clientSessionCacheKey(serverAddr net.Addr, config *Config) string {
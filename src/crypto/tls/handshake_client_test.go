@@ -7,6 +7,7 @@ package tls
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/base64"
@@ -49,8 +50,9 @@ type clientTest struct {
 	// cert, if not empty, contains a DER-encoded certificate for the
 	// reference server.
 	cert []byte
-	// key, if not nil, contains either a *rsa.PrivateKey or
-	// *ecdsa.PrivateKey which is the private key for the reference server.
+	// key, if not nil, contains either a *rsa.PrivateKey, a
+	// *ecdsa.PrivateKey or an ed25519.PrivateKey which is the private key
+	// for the reference server.
 	key interface{}
 	// extensions, if not nil, contains a list of extension data to be returned
 	// from the ServerHello. The data should be in standard TLS format with
@@ -84,21 +86,28 @@ func (test *clientTest) connFromCommand() (conn *recordingConn, child *exec.Cmd,
 	var derBytes []byte
 	switch key := key.(type) {
 	case *rsa.PrivateKey:
-		pemType = "RSA"
+		pemType = "RSA PRIVATE KEY"
 		derBytes = x509.MarshalPKCS1PrivateKey(key)
 	case *ecdsa.PrivateKey:
-		pemType = "EC"
+		pemType = "EC PRIVATE KEY"
 		var err error
 		derBytes, err = x509.MarshalECPrivateKey(key)
 		if err != nil {
 			panic(err)
 		}
+	case ed25519.PrivateKey:
+		pemType = "PRIVATE KEY"
+		var err error
+		derBytes, err = x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			panic(err)
+		}
 	default:
 		panic("unknown key type")
 	}
 
 	var pemOut bytes.Buffer
-	pem.Encode(&pemOut, &pem.Block{Type: pemType + " PRIVATE KEY", Bytes: derBytes})
+	pem.Encode(&pemOut, &pem.Block{Type: pemType, Bytes: derBytes})
 
 	keyPath := tempFile(string(pemOut.Bytes()))
 	defer os.Remove(keyPath)
@@ -288,6 +297,24 @@ func runClientTestTLS12(t *testing.T, template *clientTest) {
 	runClientTestForVersion(t, template, "TLSv12-", "-tls1_2")
 }
 
+func runClientTestTLS13(t *testing.T, template *clientTest) {
+	test := *template
+	config := testConfig
+	if test.config != nil {
+		config = test.config
+	}
+	config13 := *config
+	config13.MaxVersion = VersionTLS13
+	test.config = &config13
+	if len(test.command) == 0 {
+		test.command = defaultServerCommand
+	}
+	// Session tickets would be sent by the server after the handshake, racing
+	// with the client closing the connection.
+	test.command = append(append([]string(nil), test.command...), "-num_tickets", "0")
+	runClientTestForVersion(t, &test, "TLSv13-", "-tls1_3")
+}
+
 func TestHandshakeClientRSARC4(t *testing.T) {
 	test := &clientTest{
 		name:    "RSA-RC4",
@@ -314,6 +341,71 @@ func TestHandshakeClientRSAAES256GCM(t *testing.T) {
 	runClientTestTLS12(t, test)
 }
 
+func TestHandshakeClientTLS13AES128(t *testing.T) {
+	test := &clientTest{
+		name:    "AES128-SHA256",
+		command: []string{"openssl", "s_server", "-ciphersuites", "TLS_AES_128_GCM_SHA256"},
+	}
+	runClientTestTLS13(t, test)
+}
+
+func TestHandshakeClientTLS13AES256(t *testing.T) {
+	test := &clientTest{
+		name:    "AES256-SHA384",
+		command: []string{"openssl", "s_server", "-ciphersuites", "TLS_AES_256_GCM_SHA384"},
+	}
+	runClientTestTLS13(t, test)
+}
+
+func TestHandshakeClientTLS13ChaCha20(t *testing.T) {
+	test := &clientTest{
+		name:    "CHACHA20-SHA256",
+		command: []string{"openssl", "s_server", "-ciphersuites", "TLS_CHACHA20_POLY1305_SHA256"},
+	}
+	runClientTestTLS13(t, test)
+}
+
+func TestHandshakeClientTLS13ECDSA(t *testing.T) {
+	test := &clientTest{
+		name: "ECDSA",
+		cert: testECDSACertificate,
+		key:  testECDSAPrivateKey,
+	}
+	runClientTestTLS13(t, test)
+}
+
+func TestHandshakeClientTLS13HelloRetryRequest(t *testing.T) {
+	test := &clientTest{
+		name: "HelloRetryRequest",
+		// The client only sends a P-256 key share, so the server has to
+		// ask for a P-384 one.
+		command: []string{"openssl", "s_server", "-groups", "P-384"},
+	}
+	runClientTestTLS13(t, test)
+}
+
+func TestHandshakeClientTLS13ClientCert(t *testing.T) {
+	config := *testConfig
+	cert, _ := X509KeyPair([]byte(clientCertificatePEM), []byte(clientKeyPEM))
+	config.Certificates = []Certificate{cert}
+
+	test := &clientTest{
+		name:    "ClientCert-RSA-RSA",
+		command: []string{"openssl", "s_server", "-verify", "1"},
+		config:  &config,
+	}
+	runClientTestTLS13(t, test)
+
+	test = &clientTest{
+		name:    "ClientCert-RSA-ECDSA",
+		command: []string{"openssl", "s_server", "-verify", "1"},
+		config:  &config,
+		cert:    testECDSACertificate,
+		key:     testECDSAPrivateKey,
+	}
+	runClientTestTLS13(t, test)
+}
+
 func TestHandshakeClientECDHERSAAES(t *testing.T) {
 	test := &clientTest{
 		name:    "ECDHE-RSA-AES",
@@ -336,6 +428,22 @@ func TestHandshakeClientECDHEECDSAAES(t *testing.T) {
 	runClientTestTLS12(t, test)
 }
 
+func TestHandshakeClientEd25519(t *testing.T) {
+	// Ed25519 is only offered by clients that support TLS 1.3.
+	config := *testConfig
+	config.MaxVersion = VersionTLS13
+
+	test := &clientTest{
+		name:    "Ed25519",
+		command: []string{"openssl", "s_server", "-cipher", "ECDHE-ECDSA-AES128-GCM-SHA256"},
+		config:  &config,
+		cert:    testEd25519Certificate,
+		key:     testEd25519PrivateKey,
+	}
+	runClientTestTLS12(t, test)
+	runClientTestTLS13(t, test)
+}
+
 func TestHandshakeClientECDHEECDSAAESGCM(t *testing.T) {
 	test := &clientTest{
 		name:    "ECDHE-ECDSA-AES-GCM",
@@ -356,6 +464,45 @@ func TestHandshakeClientAES256GCMSHA384(t *testing.T) {
 	runClientTestTLS12(t, test)
 }
 
+func TestHandshakeClientECDHERSAChaCha20(t *testing.T) {
+	config := *testConfig
+	config.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305}
+
+	test := &clientTest{
+		name:    "ECDHE-RSA-CHACHA20-POLY1305",
+		command: []string{"openssl", "s_server", "-cipher", "ECDHE-RSA-CHACHA20-POLY1305"},
+		config:  &config,
+	}
+	runClientTestTLS12(t, test)
+}
+
+func TestHandshakeClientECDHEECDSAChaCha20(t *testing.T) {
+	config := *testConfig
+	config.CipherSuites = []uint16{TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305}
+
+	test := &clientTest{
+		name:    "ECDHE-ECDSA-CHACHA20-POLY1305",
+		command: []string{"openssl", "s_server", "-cipher", "ECDHE-ECDSA-CHACHA20-POLY1305"},
+		cert:    testECDSACertificate,
+		key:     testECDSAPrivateKey,
+		config:  &config,
+	}
+	runClientTestTLS12(t, test)
+}
+
+func TestHandshakeClientX25519(t *testing.T) {
+	config := *testConfig
+	config.CurvePreferences = []CurveID{X25519}
+
+	test := &clientTest{
+		name:    "X25519-ECDHE",
+		command: []string{"openssl", "s_server", "-cipher", "ECDHE-RSA-AES128-GCM-SHA256", "-ciphersuites", "TLS_AES_128_GCM_SHA256"},
+		config:  &config,
+	}
+	runClientTestTLS12(t, test)
+	runClientTestTLS13(t, test)
+}
+
 func TestHandshakeClientCertRSA(t *testing.T) {
 	config := *testConfig
 	cert, _ := X509KeyPair([]byte(clientCertificatePEM), []byte(clientKeyPEM))
@@ -418,10 +565,28 @@ func TestHandshakeClientCertECDSA(t *testing.T) {
 	runClientTestTLS12(t, test)
 }
 
+func TestHandshakeClientCertEd25519(t *testing.T) {
+	config := *testConfig
+	config.Certificates = []Certificate{{
+		Certificate: [][]byte{testEd25519Certificate},
+		PrivateKey:  testEd25519PrivateKey,
+	}}
+
+	test := &clientTest{
+		name:    "ClientCert-Ed25519-RSA",
+		command: []string{"openssl", "s_server", "-cipher", "ECDHE-RSA-AES128-GCM-SHA256", "-verify", "1"},
+		config:  &config,
+	}
+
+	runClientTestTLS12(t, test)
+	runClientTestTLS13(t, test)
+}
+
 func TestClientResumption(t *testing.T) {
 	serverConfig := &Config{
 		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
 		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS12,
 	}
 
 	issuer, err := x509.ParseCertificate(testRSACertificateIssuer)
@@ -440,7 +605,7 @@ func TestClientResumption(t *testing.T) {
 	}
 
 	testResumeState := func(test string, didResume bool) {
-		_, hs, err := testHandshake(clientConfig, serverConfig)
+		_, hs, err := testHandshake(t, clientConfig, serverConfig)
 		if err != nil {
 			t.Fatalf("%s: handshake failed: %s", test, err)
 		}
@@ -492,6 +657,64 @@ func TestClientResumption(t *testing.T) {
 	testResumeState("WithoutSessionCache", false)
 }
 
+func TestClientResumptionTLS13(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		ClientSessionCache: NewLRUClientSessionCache(32),
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+		ServerName:         "example.golang",
+	}
+
+	// The session ticket is only processed when the client reads
+	// application data, so have the server send a byte after the handshake.
+	testResumeState := func(test string, didResume bool) {
+		c, s := localPipe(t)
+		done := make(chan error)
+		go func() {
+			server := Server(s, serverConfig)
+			_, err := server.Write([]byte("x"))
+			server.Close()
+			done <- err
+		}()
+		client := Client(c, clientConfig)
+		if _, err := client.Read(make([]byte, 1)); err != nil {
+			t.Fatalf("%s: client read failed: %s", test, err)
+		}
+		hs := client.ConnectionState()
+		client.Close()
+		if err := <-done; err != nil {
+			t.Fatalf("%s: server write failed: %s", test, err)
+		}
+		if hs.Version != VersionTLS13 {
+			t.Fatalf("%s: version %x, expected %x", test, hs.Version, VersionTLS13)
+		}
+		if hs.DidResume != didResume {
+			t.Fatalf("%s resumed: %v, expected: %v", test, hs.DidResume, didResume)
+		}
+		if len(hs.PeerCertificates) == 0 {
+			t.Fatalf("%s: no peer certificates", test)
+		}
+	}
+
+	testResumeState("Handshake", false)
+	testResumeState("Resume", true)
+
+	var key [32]byte
+	if _, err := io.ReadFull(serverConfig.rand(), key[:]); err != nil {
+		t.Fatalf("Failed to read new SessionTicketKey: %s", err)
+	}
+	serverConfig.SetSessionTicketKeys([][32]byte{key})
+	testResumeState("InvalidSessionTicketKey", false)
+	testResumeState("ResumeAfterInvalidSessionTicketKey", true)
+
+	clientConfig.ClientSessionCache = nil
+	testResumeState("WithoutSessionCache", false)
+}
+
 func TestLRUClientSessionCache(t *testing.T) {
 	// Initialize cache of capacity 4.
 	cache := NewLRUClientSessionCache(4)
@@ -557,6 +780,7 @@ func TestHandshakeClientALPNMatch(t *testing.T) {
 		},
 	}
 	runClientTestTLS12(t, test)
+	runClientTestTLS13(t, test)
 }
 
 func TestHandshakeClientALPNNoMatch(t *testing.T) {
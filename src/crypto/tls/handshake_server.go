@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/subtle"
 	"crypto/x509"
@@ -42,10 +43,24 @@ func (c *Conn) serverHandshake() error {
 	// encrypt the tickets with.
 	config.serverInitOnce.Do(config.serverInit)
 
+	clientHello, err := c.readClientHello()
+	if err != nil {
+		return err
+	}
+
+	if c.vers == VersionTLS13 {
+		hs := serverHandshakeStateTLS13{
+			c:           c,
+			clientHello: clientHello,
+		}
+		return hs.handshake()
+	}
+
 	hs := serverHandshakeState{
-		c: c,
+		c:           c,
+		clientHello: clientHello,
 	}
-	isResume, err := hs.readClientHello()
+	isResume, err := hs.processClientHello()
 	if err != nil {
 		return err
 	}
@@ -98,29 +113,43 @@ func (c *Conn) serverHandshake() error {
 	return nil
 }
 
-// readClientHello reads a ClientHello message from the client and decides
-// whether we will perform session resumption.
-func (hs *serverHandshakeState) readClientHello() (isResume bool, err error) {
-	config := hs.c.config
-	c := hs.c
-
+// readClientHello reads a ClientHello message from the client and selects
+// the protocol version.
+func (c *Conn) readClientHello() (*clientHelloMsg, error) {
 	msg, err := c.readHandshake()
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	var ok bool
-	hs.clientHello, ok = msg.(*clientHelloMsg)
+	clientHello, ok := msg.(*clientHelloMsg)
 	if !ok {
 		c.sendAlert(alertUnexpectedMessage)
-		return false, unexpectedMessageError(hs.clientHello, msg)
+		return nil, unexpectedMessageError(clientHello, msg)
 	}
-	c.vers, ok = config.mutualVersion(hs.clientHello.vers)
-	if !ok {
-		c.sendAlert(alertProtocolVersion)
-		return false, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", hs.clientHello.vers)
+
+	if len(clientHello.supportedVersions) > 0 {
+		c.vers, ok = c.config.mutualVersionFromList(false, clientHello.supportedVersions)
+		if !ok {
+			c.sendAlert(alertProtocolVersion)
+			return nil, fmt.Errorf("tls: client offered only unsupported versions: %x", clientHello.supportedVersions)
+		}
+	} else {
+		c.vers, ok = c.config.mutualVersion(clientHello.vers)
+		if !ok {
+			c.sendAlert(alertProtocolVersion)
+			return nil, fmt.Errorf("tls: client offered an unsupported, maximum protocol version of %x", clientHello.vers)
+		}
 	}
 	c.haveVers = true
 
+	return clientHello, nil
+}
+
+// processClientHello decides, based on the ClientHello, whether we will
+// perform session resumption, and prepares the ServerHello.
+func (hs *serverHandshakeState) processClientHello() (isResume bool, err error) {
+	config := hs.c.config
+	c := hs.c
+
 	hs.hello = new(serverHelloMsg)
 
 	supportedCurve := false
@@ -165,6 +194,17 @@ Curves:
 		c.sendAlert(alertInternalError)
 		return false, err
 	}
+
+	// A TLS 1.3 server that negotiates an earlier version signals it in
+	// the last eight bytes of the random, so that a TLS 1.3 client can
+	// detect a downgrade. See RFC 8446, section 4.1.3.
+	if config.maxVersion() >= VersionTLS13 {
+		if c.vers == VersionTLS12 {
+			copy(hs.hello.random[24:], downgradeCanaryTLS12)
+		} else {
+			copy(hs.hello.random[24:], downgradeCanaryTLS11)
+		}
+	}
 	hs.hello.secureRenegotiation = hs.clientHello.secureRenegotiation
 	hs.hello.compressionMethod = compressionNone
 	if len(hs.clientHello.serverName) > 0 {
@@ -206,6 +246,12 @@ Curves:
 			hs.ecdsaOk = true
 		case *rsa.PublicKey:
 			hs.rsaSignOk = true
+		case ed25519.PublicKey:
+			// Ed25519 keys sign ServerKeyExchange messages of the
+			// ECDHE_ECDSA cipher suites, but only in TLS 1.2 and only
+			// if the client supports it.
+			hs.ecdsaOk = c.vers >= VersionTLS12 &&
+				isSupportedSignatureAndHash(signatureAndHash{hashIntrinsic, signatureEd25519}, hs.clientHello.signatureAndHashes)
 		default:
 			c.sendAlert(alertInternalError)
 			return false, fmt.Errorf("crypto/tls: unsupported signing key type (%T)", priv.Public())
@@ -268,9 +314,12 @@ func (hs *serverHandshakeState) checkForResumption() bool {
 		return false
 	}
 
-	var ok bool
-	var sessionTicket = append([]uint8{}, hs.clientHello.sessionTicket...)
-	if hs.sessionState, ok = c.decryptTicket(sessionTicket); !ok {
+	plaintext, usedOldKey := c.decryptTicket(hs.clientHello.sessionTicket)
+	if plaintext == nil {
+		return false
+	}
+	hs.sessionState = &sessionState{usedOldKey: usedOldKey}
+	if ok := hs.sessionState.unmarshal(plaintext); !ok {
 		return false
 	}
 
@@ -325,9 +374,10 @@ func (hs *serverHandshakeState) doResumeHandshake() error {
 	c.writeRecord(recordTypeHandshake, hs.hello.marshal())
 
 	if len(hs.sessionState.certificates) > 0 {
-		if _, err := hs.processCertsFromClient(hs.sessionState.certificates); err != nil {
+		if _, err := c.processCertsFromClient(hs.sessionState.certificates); err != nil {
 			return err
 		}
+		hs.certsFromClient = hs.sessionState.certificates
 	}
 
 	hs.masterSecret = hs.sessionState.masterSecret
@@ -380,6 +430,13 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		c.writeRecord(recordTypeHandshake, skx.marshal())
 	}
 
+	// A server that supports TLS 1.3 accepts the same client signature
+	// algorithms in TLS 1.2, including RSASSA-PSS and Ed25519.
+	certReqAlgs := supportedSignatureAlgorithms
+	if config.maxVersion() >= VersionTLS13 {
+		certReqAlgs = supportedSignatureAlgorithmsTLS13
+	}
+
 	if config.ClientAuth >= RequestClientCert {
 		// Request a client certificate
 		certReq := new(certificateRequestMsg)
@@ -389,7 +446,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		}
 		if c.vers >= VersionTLS12 {
 			certReq.hasSignatureAndHash = true
-			certReq.signatureAndHashes = supportedSignatureAlgorithms
+			certReq.signatureAndHashes = certReqAlgs
 		}
 
 		// An empty list of certificateAuthorities signals to
@@ -434,10 +491,11 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			}
 		}
 
-		pub, err = hs.processCertsFromClient(certMsg.certificates)
+		pub, err = c.processCertsFromClient(certMsg.certificates)
 		if err != nil {
 			return err
 		}
+		hs.certsFromClient = certMsg.certificates
 
 		msg, err = c.readHandshake()
 		if err != nil {
@@ -481,7 +539,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 		var signatureAndHash signatureAndHash
 		if certVerify.hasSignatureAndHash {
 			signatureAndHash = certVerify.signatureAndHash
-			if !isSupportedSignatureAndHash(signatureAndHash, supportedSignatureAlgorithms) {
+			if !isSupportedSignatureAndHash(signatureAndHash, certReqAlgs) {
 				return errors.New("tls: unsupported hash function for client certificate")
 			}
 		} else {
@@ -518,7 +576,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 				err = errors.New("ECDSA verification failure")
 			}
 		case *rsa.PublicKey:
-			if signatureAndHash.signature != signatureRSA {
+			if signatureAndHash.signature != signatureRSA && !isRSAPSS(signatureAndHash) {
 				err = errors.New("bad signature type for client's RSA certificate")
 				break
 			}
@@ -527,7 +585,24 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 			if digest, hashFunc, err = hs.finishedHash.hashForClientCertificate(signatureAndHash, hs.masterSecret); err != nil {
 				break
 			}
-			err = rsa.VerifyPKCS1v15(key, hashFunc, digest, certVerify.signature)
+			if isRSAPSS(signatureAndHash) {
+				opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+				err = rsa.VerifyPSS(key, hashFunc, digest, certVerify.signature, opts)
+			} else {
+				err = rsa.VerifyPKCS1v15(key, hashFunc, digest, certVerify.signature)
+			}
+		case ed25519.PublicKey:
+			if signatureAndHash.hash != hashIntrinsic || signatureAndHash.signature != signatureEd25519 {
+				err = errors.New("bad signature type for client's Ed25519 certificate")
+				break
+			}
+			var signed []byte
+			if signed, _, err = hs.finishedHash.hashForClientCertificate(signatureAndHash, hs.masterSecret); err != nil {
+				break
+			}
+			if !ed25519.Verify(key, signed, certVerify.signature) {
+				err = errors.New("Ed25519 verification failure")
+			}
 		}
 		if err != nil {
 			c.sendAlert(alertBadCertificate)
@@ -626,7 +701,7 @@ func (hs *serverHandshakeState) sendSessionTicket() error {
 		masterSecret: hs.masterSecret,
 		certificates: hs.certsFromClient,
 	}
-	m.ticket, err = c.encryptTicket(&state)
+	m.ticket, err = c.encryptTicket(state.marshal())
 	if err != nil {
 		return err
 	}
@@ -654,12 +729,9 @@ func (hs *serverHandshakeState) sendFinished(out []byte) error {
 }
 
 // processCertsFromClient takes a chain of client certificates either from a
-// Certificates message or from a sessionState and verifies them. It returns
+// Certificates message or from a session ticket and verifies them. It returns
 // the public key of the leaf certificate.
-func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
-	c := hs.c
-
-	hs.certsFromClient = certificates
+func (c *Conn) processCertsFromClient(certificates [][]byte) (crypto.PublicKey, error) {
 	certs := make([]*x509.Certificate, len(certificates))
 	var err error
 	for i, asn1Data := range certificates {
@@ -693,7 +765,7 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 	if len(certs) > 0 {
 		var pub crypto.PublicKey
 		switch key := certs[0].PublicKey.(type) {
-		case *ecdsa.PublicKey, *rsa.PublicKey:
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
 			pub = key
 		default:
 			c.sendAlert(alertUnsupportedCertificate)
@@ -7,8 +7,10 @@ package tls
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
@@ -46,6 +48,21 @@ func allCipherSuites() []uint16 {
 	return ids
 }
 
+// recordedCipherSuites returns the cipher suites, excluding the
+// ChaCha20-Poly1305 ones, that were supported when most of the reference
+// connections in testdata were recorded. Those recordings only replay if the
+// handshake messages match byte for byte.
+func recordedCipherSuites() []uint16 {
+	var ids []uint16
+	for _, id := range allCipherSuites() {
+		if id == TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305 || id == TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305 {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func init() {
 	testConfig = &Config{
 		Time:               func() time.Time { return time.Unix(0, 0) },
@@ -54,7 +71,10 @@ func init() {
 		InsecureSkipVerify: true,
 		MinVersion:         VersionSSL30,
 		MaxVersion:         VersionTLS12,
-		CipherSuites:       allCipherSuites(),
+		CipherSuites:       recordedCipherSuites(),
+		// X25519 is likewise left out to keep the recorded ClientHellos
+		// and key exchanges valid.
+		CurvePreferences: []CurveID{CurveP256, CurveP384, CurveP521},
 	}
 	testConfig.Certificates[0].Certificate = [][]byte{testRSACertificate}
 	testConfig.Certificates[0].PrivateKey = testRSAPrivateKey
@@ -303,8 +323,8 @@ func TestClose(t *testing.T) {
 	}
 }
 
-func testHandshake(clientConfig, serverConfig *Config) (serverState, clientState ConnectionState, err error) {
-	c, s := net.Pipe()
+func testHandshake(t *testing.T, clientConfig, serverConfig *Config) (serverState, clientState ConnectionState, err error) {
+	c, s := localPipe(t)
 	done := make(chan bool)
 	go func() {
 		cli := Client(c, clientConfig)
@@ -331,7 +351,7 @@ func TestVersion(t *testing.T) {
 	clientConfig := &Config{
 		InsecureSkipVerify: true,
 	}
-	state, _, err := testHandshake(clientConfig, serverConfig)
+	state, _, err := testHandshake(t, clientConfig, serverConfig)
 	if err != nil {
 		t.Fatalf("handshake failed: %s", err)
 	}
@@ -340,6 +360,140 @@ func TestVersion(t *testing.T) {
 	}
 }
 
+func TestVersionTLS13(t *testing.T) {
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+	}
+	serverState, clientState, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if serverState.Version != VersionTLS13 || clientState.Version != VersionTLS13 {
+		t.Fatalf("Incorrect version %x/%x, should be %x", serverState.Version, clientState.Version, VersionTLS13)
+	}
+	if cipherSuiteTLS13ByID(serverState.CipherSuite) == nil {
+		t.Fatalf("Negotiated non-TLS 1.3 cipher suite %x", serverState.CipherSuite)
+	}
+
+	// A TLS 1.2 client talking to a TLS 1.3 server must not trip over the
+	// downgrade protection sentinel.
+	clientConfig.MaxVersion = VersionTLS12
+	serverState, clientState, err = testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if serverState.Version != VersionTLS12 || clientState.Version != VersionTLS12 {
+		t.Fatalf("Incorrect version %x/%x, should be %x", serverState.Version, clientState.Version, VersionTLS12)
+	}
+}
+
+func TestClientAuthTLS13(t *testing.T) {
+	cert, err := X509KeyPair([]byte(clientCertificatePEM), []byte(clientKeyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &Config{
+		Certificates: testConfig.Certificates,
+		ClientAuth:   RequireAnyClientCert,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig := &Config{
+		Certificates:       []Certificate{cert},
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS13,
+	}
+	serverState, _, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if serverState.Version != VersionTLS13 {
+		t.Fatalf("Incorrect version %x, should be %x", serverState.Version, VersionTLS13)
+	}
+	if len(serverState.PeerCertificates) != 1 || !bytes.Equal(serverState.PeerCertificates[0].Raw, cert.Certificate[0]) {
+		t.Fatalf("Server did not receive the client certificate")
+	}
+
+	clientConfig.Certificates = nil
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Fatalf("handshake succeeded without a client certificate")
+	}
+}
+
+func TestEd25519Handshake(t *testing.T) {
+	ed25519Cert := Certificate{
+		Certificate: [][]byte{testEd25519Certificate},
+		PrivateKey:  testEd25519PrivateKey,
+	}
+
+	// A TLS 1.2 server can use an Ed25519 certificate with a client that
+	// advertises Ed25519 support.
+	serverConfig := &Config{
+		Certificates: []Certificate{ed25519Cert},
+		MaxVersion:   VersionTLS12,
+	}
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+	}
+	_, clientState, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if clientState.Version != VersionTLS12 {
+		t.Fatalf("Incorrect version %x, should be %x", clientState.Version, VersionTLS12)
+	}
+	if clientState.PeerCertificates[0].PublicKeyAlgorithm != x509.Ed25519 {
+		t.Fatalf("Client did not receive the Ed25519 certificate")
+	}
+
+	// A client that doesn't offer Ed25519 can't connect to it.
+	clientConfig.MaxVersion = VersionTLS12
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Fatalf("handshake succeeded without Ed25519 support in the client")
+	}
+
+	// A TLS 1.2 client can authenticate with an Ed25519 certificate to a
+	// server that accepts it.
+	serverConfig = &Config{
+		Certificates: testConfig.Certificates,
+		ClientAuth:   RequireAnyClientCert,
+	}
+	clientConfig = &Config{
+		Certificates:       []Certificate{ed25519Cert},
+		InsecureSkipVerify: true,
+		MaxVersion:         VersionTLS12,
+	}
+	serverState, _, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if len(serverState.PeerCertificates) != 1 || !bytes.Equal(serverState.PeerCertificates[0].Raw, testEd25519Certificate) {
+		t.Fatalf("Server did not receive the client certificate")
+	}
+
+	// Both sides can use Ed25519 in TLS 1.3.
+	serverConfig = &Config{
+		Certificates: []Certificate{ed25519Cert},
+		ClientAuth:   RequireAnyClientCert,
+		MaxVersion:   VersionTLS13,
+	}
+	clientConfig.MaxVersion = VersionTLS13
+	serverState, clientState, err = testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("handshake failed: %s", err)
+	}
+	if serverState.Version != VersionTLS13 {
+		t.Fatalf("Incorrect version %x, should be %x", serverState.Version, VersionTLS13)
+	}
+	if len(serverState.PeerCertificates) != 1 || clientState.PeerCertificates[0].PublicKeyAlgorithm != x509.Ed25519 {
+		t.Fatalf("Ed25519 certificates were not exchanged")
+	}
+}
+
 func TestCipherSuitePreference(t *testing.T) {
 	serverConfig := &Config{
 		CipherSuites: []uint16{TLS_RSA_WITH_RC4_128_SHA, TLS_RSA_WITH_AES_128_CBC_SHA, TLS_ECDHE_RSA_WITH_RC4_128_SHA},
@@ -350,7 +504,7 @@ func TestCipherSuitePreference(t *testing.T) {
 		CipherSuites:       []uint16{TLS_RSA_WITH_AES_128_CBC_SHA, TLS_RSA_WITH_RC4_128_SHA},
 		InsecureSkipVerify: true,
 	}
-	state, _, err := testHandshake(clientConfig, serverConfig)
+	state, _, err := testHandshake(t, clientConfig, serverConfig)
 	if err != nil {
 		t.Fatalf("handshake failed: %s", err)
 	}
@@ -360,7 +514,7 @@ func TestCipherSuitePreference(t *testing.T) {
 	}
 
 	serverConfig.PreferServerCipherSuites = true
-	state, _, err = testHandshake(clientConfig, serverConfig)
+	state, _, err = testHandshake(t, clientConfig, serverConfig)
 	if err != nil {
 		t.Fatalf("handshake failed: %s", err)
 	}
@@ -381,7 +535,7 @@ func TestSCTHandshake(t *testing.T) {
 	clientConfig := &Config{
 		InsecureSkipVerify: true,
 	}
-	_, state, err := testHandshake(clientConfig, serverConfig)
+	_, state, err := testHandshake(t, clientConfig, serverConfig)
 	if err != nil {
 		t.Fatalf("handshake failed: %s", err)
 	}
@@ -622,6 +776,18 @@ func runServerTestTLS12(t *testing.T, template *serverTest) {
 	runServerTestForVersion(t, template, "TLSv12-", "-tls1_2")
 }
 
+func runServerTestTLS13(t *testing.T, template *serverTest) {
+	test := *template
+	config := testConfig
+	if test.config != nil {
+		config = test.config
+	}
+	config13 := *config
+	config13.MaxVersion = VersionTLS13
+	test.config = &config13
+	runServerTestForVersion(t, &test, "TLSv13-", "-tls1_3")
+}
+
 func TestHandshakeServerRSARC4(t *testing.T) {
 	test := &serverTest{
 		name:    "RSA-RC4",
@@ -669,6 +835,58 @@ func TestHandshakeServerAES256GCMSHA384(t *testing.T) {
 	runServerTestTLS12(t, test)
 }
 
+func TestHandshakeServerTLS13AES128(t *testing.T) {
+	test := &serverTest{
+		name:    "AES128-SHA256",
+		command: []string{"openssl", "s_client", "-ciphersuites", "TLS_AES_128_GCM_SHA256", "-groups", "P-256"},
+	}
+	runServerTestTLS13(t, test)
+}
+
+func TestHandshakeServerTLS13AES256(t *testing.T) {
+	test := &serverTest{
+		name:    "AES256-SHA384",
+		command: []string{"openssl", "s_client", "-ciphersuites", "TLS_AES_256_GCM_SHA384", "-groups", "P-256"},
+	}
+	runServerTestTLS13(t, test)
+}
+
+func TestHandshakeServerTLS13ChaCha20(t *testing.T) {
+	test := &serverTest{
+		name:    "CHACHA20-SHA256",
+		command: []string{"openssl", "s_client", "-ciphersuites", "TLS_CHACHA20_POLY1305_SHA256", "-groups", "P-256"},
+	}
+	runServerTestTLS13(t, test)
+}
+
+func TestHandshakeServerTLS13ECDSA(t *testing.T) {
+	config := *testConfig
+	config.Certificates = make([]Certificate, 1)
+	config.Certificates[0].Certificate = [][]byte{testECDSACertificate}
+	config.Certificates[0].PrivateKey = testECDSAPrivateKey
+	config.BuildNameToCertificate()
+
+	test := &serverTest{
+		name:    "ECDSA",
+		command: []string{"openssl", "s_client", "-groups", "P-256"},
+		config:  &config,
+	}
+	runServerTestTLS13(t, test)
+}
+
+func TestHandshakeServerTLS13HelloRetryRequest(t *testing.T) {
+	config := *testConfig
+	config.CurvePreferences = []CurveID{CurveP384}
+
+	test := &serverTest{
+		name: "HelloRetryRequest",
+		// The client only sends a key share for the first group.
+		command: []string{"openssl", "s_client", "-groups", "P-256:P-384"},
+		config:  &config,
+	}
+	runServerTestTLS13(t, test)
+}
+
 func TestHandshakeServerECDHEECDSAAES(t *testing.T) {
 	config := *testConfig
 	config.Certificates = make([]Certificate, 1)
@@ -685,6 +903,63 @@ func TestHandshakeServerECDHEECDSAAES(t *testing.T) {
 	runServerTestTLS12(t, test)
 }
 
+func TestHandshakeServerEd25519(t *testing.T) {
+	config := *testConfig
+	config.Certificates = make([]Certificate, 1)
+	config.Certificates[0].Certificate = [][]byte{testEd25519Certificate}
+	config.Certificates[0].PrivateKey = testEd25519PrivateKey
+	config.BuildNameToCertificate()
+
+	test := &serverTest{
+		name:    "Ed25519",
+		command: []string{"openssl", "s_client", "-no_ticket", "-cipher", "ECDHE-ECDSA-AES128-GCM-SHA256"},
+		config:  &config,
+	}
+	runServerTestTLS12(t, test)
+	runServerTestTLS13(t, test)
+}
+
+func TestHandshakeServerECDHERSAChaCha20(t *testing.T) {
+	config := *testConfig
+	config.CipherSuites = allCipherSuites()
+
+	test := &serverTest{
+		name:    "ECDHE-RSA-CHACHA20-POLY1305",
+		command: []string{"openssl", "s_client", "-no_ticket", "-cipher", "ECDHE-RSA-CHACHA20-POLY1305"},
+		config:  &config,
+	}
+	runServerTestTLS12(t, test)
+}
+
+func TestHandshakeServerECDHEECDSAChaCha20(t *testing.T) {
+	config := *testConfig
+	config.CipherSuites = allCipherSuites()
+	config.Certificates = make([]Certificate, 1)
+	config.Certificates[0].Certificate = [][]byte{testECDSACertificate}
+	config.Certificates[0].PrivateKey = testECDSAPrivateKey
+	config.BuildNameToCertificate()
+
+	test := &serverTest{
+		name:    "ECDHE-ECDSA-CHACHA20-POLY1305",
+		command: []string{"openssl", "s_client", "-no_ticket", "-cipher", "ECDHE-ECDSA-CHACHA20-POLY1305"},
+		config:  &config,
+	}
+	runServerTestTLS12(t, test)
+}
+
+func TestHandshakeServerX25519(t *testing.T) {
+	config := *testConfig
+	config.CurvePreferences = []CurveID{X25519}
+
+	test := &serverTest{
+		name:    "X25519-ECDHE",
+		command: []string{"openssl", "s_client", "-no_ticket", "-cipher", "ECDHE-RSA-AES128-GCM-SHA256", "-ciphersuites", "TLS_AES_128_GCM_SHA256", "-groups", "X25519"},
+		config:  &config,
+	}
+	runServerTestTLS12(t, test)
+	runServerTestTLS13(t, test)
+}
+
 func TestHandshakeServerALPN(t *testing.T) {
 	config := *testConfig
 	config.NextProtos = []string{"proto1", "proto2"}
@@ -704,6 +979,7 @@ func TestHandshakeServerALPN(t *testing.T) {
 		},
 	}
 	runServerTestTLS12(t, test)
+	runServerTestTLS13(t, test)
 }
 
 func TestHandshakeServerALPNNoMatch(t *testing.T) {
@@ -1035,6 +1311,8 @@ var testRSACertificateIssuer = fromHex("3082024d308201b6a003020102020827326bd913
 
 var testECDSACertificate = fromHex("3082020030820162020900b8bf2d47a0d2ebf4300906072a8648ce3d04013045310b3009060355040613024155311330110603550408130a536f6d652d53746174653121301f060355040a1318496e7465726e6574205769646769747320507479204c7464301e170d3132313132323135303633325a170d3232313132303135303633325a3045310b3009060355040613024155311330110603550408130a536f6d652d53746174653121301f060355040a1318496e7465726e6574205769646769747320507479204c746430819b301006072a8648ce3d020106052b81040023038186000400c4a1edbe98f90b4873367ec316561122f23d53c33b4d213dcd6b75e6f6b0dc9adf26c1bcb287f072327cb3642f1c90bcea6823107efee325c0483a69e0286dd33700ef0462dd0da09c706283d881d36431aa9e9731bd96b068c09b23de76643f1a5c7fe9120e5858b65f70dd9bd8ead5d7f5d5ccb9b69f30665b669a20e227e5bffe3b300906072a8648ce3d040103818c0030818802420188a24febe245c5487d1bacf5ed989dae4770c05e1bb62fbdf1b64db76140d311a2ceee0b7e927eff769dc33b7ea53fcefa10e259ec472d7cacda4e970e15a06fd00242014dfcbe67139c2d050ebd3fa38c25c13313830d9406bbd4377af6ec7ac9862eddd711697f857c56defb31782be4c7780daecbbe9e4e3624317b6a0f399512078f2a")
 
+var testEd25519Certificate = fromHex("308201a430820156a003020102020101300506032b6570302b31143012060355040a0c0b476f6c616e6720544553543113301106035504030c0a476f2045643235353139301e170d3236313031383133323835365a170d3336313031353133323835365a302b31143012060355040a0c0b476f6c616e6720544553543113301106035504030c0a476f2045643235353139302a300506032b65700321004581e1e07f3f1dbde1649d90fd07852d0006ab37776f21012cc3048eec145d8da3819e30819b301d0603551d0e04160414a6c54566f0b87f939261412a05a72e508800f0cd301f0603551d23041830168014a6c54566f0b87f939261412a05a72e508800f0cd300f0603551d130101ff040530030101ff300e0603551d0f0101ff040403020780301d0603551d250416301406082b0601050507030106082b0601050507030230190603551d1104123010820e6578616d706c652e676f6c616e67300506032b657003410026451873db78574934f5a82dab6a561ecdb45c87f991c2f93485c19b7317d08f342a1a835dca915dfadbecb3cdde7dd8339806ae56cfc285ef5b95ae10b2200f")
+
 var testSNICertificate = fromHex("308201f23082015da003020102020100300b06092a864886f70d01010530283110300e060355040a130741636d6520436f311430120603550403130b736e69746573742e636f6d301e170d3132303431313137343033355a170d3133303431313137343533355a30283110300e060355040a130741636d6520436f311430120603550403130b736e69746573742e636f6d30819d300b06092a864886f70d01010103818d0030818902818100bb79d6f517b5e5bf4610d0dc69bee62b07435ad0032d8a7a4385b71452e7a5654c2c78b8238cb5b482e5de1f953b7e62a52ca533d6fe125c7a56fcf506bffa587b263fb5cd04d3d0c921964ac7f4549f5abfef427100fe1899077f7e887d7df10439c4a22edb51c97ce3c04c3b326601cfafb11db8719a1ddbdb896baeda2d790203010001a3323030300e0603551d0f0101ff0404030200a0300d0603551d0e0406040401020304300f0603551d2304083006800401020304300b06092a864886f70d0101050381810089c6455f1c1f5ef8eb1ab174ee2439059f5c4259bb1a8d86cdb1d056f56a717da40e95ab90f59e8deaf627c157995094db0802266eb34fc6842dea8a4b68d9c1389103ab84fb9e1f85d9b5d23ff2312c8670fbb540148245a4ebafe264d90c8a4cf4f85b0fac12ac2fc4a3154bad52462868af96c62c6525d652b6e31845bdcc")
 
 var testRSAPrivateKey = &rsa.PrivateKey{
@@ -1049,6 +1327,8 @@ var testRSAPrivateKey = &rsa.PrivateKey{
 	},
 }
 
+var testEd25519PrivateKey = ed25519.PrivateKey(fromHex("6b84b9fe574c94fb5d047cd01fc880b291b29de3817702b4b73fe7df4b4e17a04581e1e07f3f1dbde1649d90fd07852d0006ab37776f21012cc3048eec145d8d"))
+
 var testECDSAPrivateKey = &ecdsa.PrivateKey{
 	PublicKey: ecdsa.PublicKey{
 		Curve: elliptic.P521(),
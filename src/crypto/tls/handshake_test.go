@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"testing"
 )
 
 // TLS reference tests run a connection against a reference implementation
@@ -155,6 +156,39 @@ func parseTestData(r io.Reader) (flows [][]byte, err error) {
 }
 
 // tempFile creates a temp file containing contents and returns its path.
+// localPipe returns a pair of connected TCP connections over the loopback
+// interface. Unlike net.Pipe, writes don't block until the peer reads, so
+// either side may send data the other never reads, as TLS 1.3 servers do
+// with post-handshake messages.
+func localPipe(t testing.TB) (net.Conn, net.Conn) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %s", err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		c, err := ln.Accept()
+		accepted <- result{c, err}
+	}()
+
+	c1, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %s", err)
+	}
+	r := <-accepted
+	if r.err != nil {
+		c1.Close()
+		t.Fatalf("Failed to accept: %s", r.err)
+	}
+	return c1, r.conn
+}
+
 func tempFile(contents string) string {
 	file, err := ioutil.TempFile("", "go-tls-test")
 	if err != nil {
@@ -189,6 +189,8 @@ func lookupTLSHash(hash uint8) (crypto.Hash, error) {
 		return crypto.SHA256, nil
 	case hashSHA384:
 		return crypto.SHA384, nil
+	case hashSHA512:
+		return crypto.SHA512, nil
 	default:
 		return 0, errors.New("tls: unsupported hash algorithm")
 	}
@@ -319,7 +321,13 @@ func (h finishedHash) selectClientCertSignatureAlgorithm(serverList []signatureA
 	}
 
 	for _, v := range serverList {
-		if v.signature == sigType && isSupportedSignatureAndHash(v, supportedSignatureAlgorithms) {
+		if v.signature != sigType {
+			continue
+		}
+		if sigType == signatureEd25519 && v.hash == hashIntrinsic {
+			return v, nil
+		}
+		if isSupportedSignatureAndHash(v, supportedSignatureAlgorithms) {
 			return v, nil
 		}
 	}
@@ -345,10 +353,14 @@ func (h finishedHash) hashForClientCertificate(signatureAndHash signatureAndHash
 		return finishedSum30(md5Hash, sha1Hash, masterSecret, nil), crypto.MD5SHA1, nil
 	}
 	if h.version >= VersionTLS12 {
-		hashAlg, err := lookupTLSHash(signatureAndHash.hash)
+		hashAlg, err := lookupSignatureHash(signatureAndHash)
 		if err != nil {
 			return nil, 0, err
 		}
+		if hashAlg == 0 {
+			// Ed25519 signs the handshake messages directly.
+			return h.buffer, hashAlg, nil
+		}
 		hash := hashAlg.New()
 		hash.Write(h.buffer)
 		return hash.Sum(nil), hashAlg, nil
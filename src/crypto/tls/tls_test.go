@@ -315,6 +315,70 @@ func TestTLSUniqueMatches(t *testing.T) {
 	}
 }
 
+func TestKeyUpdateTLS13(t *testing.T) {
+	ln := newLocalListener(t)
+	defer ln.Close()
+
+	serverConfig := *testConfig
+	serverConfig.MaxVersion = VersionTLS13
+	errChan := make(chan error, 1)
+	go func() {
+		sconn, err := ln.Accept()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		srv := Server(sconn, &serverConfig)
+		defer srv.Close()
+		buf := make([]byte, 6)
+		if _, err := io.ReadFull(srv, buf); err != nil {
+			errChan <- err
+			return
+		}
+		_, err = srv.Write(buf)
+		errChan <- err
+	}()
+
+	clientConfig := *testConfig
+	clientConfig.MaxVersion = VersionTLS13
+	conn, err := Dial("tcp", ln.Addr().String(), &clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if state.Version != VersionTLS13 {
+		t.Fatalf("Incorrect version %x, should be %x", state.Version, VersionTLS13)
+	}
+
+	// Send a KeyUpdate asking the server to update its keys too, and check
+	// that data still flows in both directions afterwards.
+	suite := cipherSuiteTLS13ByID(state.CipherSuite)
+	conn.out.Lock()
+	msg := &keyUpdateMsg{updateRequested: true}
+	if _, err := conn.writeRecord(recordTypeHandshake, msg.marshal()); err != nil {
+		conn.out.Unlock()
+		t.Fatal(err)
+	}
+	conn.out.setTrafficSecret(suite, suite.nextTrafficSecret(conn.out.trafficSecret))
+	conn.out.Unlock()
+
+	if _, err := conn.Write([]byte("foobar")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 6)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "foobar" {
+		t.Errorf("Read %q, want foobar", buf)
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("server: %s", err)
+	}
+}
+
 func TestVerifyHostname(t *testing.T) {
 	testenv.MustHaveExternalNetwork(t)
 
@@ -0,0 +1,836 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ocsp parses and creates OCSP requests and responses, as specified
+// in RFC 6960.
+//
+// Fetching responses from a responder is left to the caller; the package
+// only deals with the encoded messages.
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+var idPKIXOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// ResponseStatus contains the result of an OCSP request. See
+// https://tools.ietf.org/html/rfc6960#section-2.3
+type ResponseStatus int
+
+const (
+	Success       ResponseStatus = 0
+	Malformed     ResponseStatus = 1
+	InternalError ResponseStatus = 2
+	TryLater      ResponseStatus = 3
+	// Status code four is unused in OCSP. See
+	// https://tools.ietf.org/html/rfc6960#section-4.2.1
+	SignatureRequired ResponseStatus = 5
+	Unauthorized      ResponseStatus = 6
+)
+
+func (r ResponseStatus) String() string {
+	switch r {
+	case Success:
+		return "success"
+	case Malformed:
+		return "malformed"
+	case InternalError:
+		return "internal error"
+	case TryLater:
+		return "try later"
+	case SignatureRequired:
+		return "signature required"
+	case Unauthorized:
+		return "unauthorized"
+	}
+	return "unknown OCSP status: " + strconv.Itoa(int(r))
+}
+
+// ResponseError is an error that may be returned by ParseResponse to indicate
+// that the response itself is an error, not just that it's indicating that a
+// certificate is revoked, unknown, etc.
+type ResponseError struct {
+	Status ResponseStatus
+}
+
+func (r ResponseError) Error() string {
+	return "ocsp: error from server: " + r.Status.String()
+}
+
+// ParseError results from an invalid OCSP request or response.
+type ParseError string
+
+func (p ParseError) Error() string {
+	return string(p)
+}
+
+// The status values that can be expressed in OCSP.
+const (
+	// Good means that the certificate is valid.
+	Good = iota
+	// Revoked means that the certificate has been deliberately revoked.
+	Revoked
+	// Unknown means that the OCSP responder doesn't know about the
+	// certificate.
+	Unknown
+)
+
+// The enumerated reasons for revoking a certificate. See RFC 5280,
+// section 5.3.1.
+const (
+	Unspecified          = 0
+	KeyCompromise        = 1
+	CACompromise         = 2
+	AffiliationChanged   = 3
+	Superseded           = 4
+	CessationOfOperation = 5
+	CertificateHold      = 6
+
+	RemoveFromCRL      = 8
+	PrivilegeWithdrawn = 9
+	AACompromise       = 10
+)
+
+// These are internal structures that reflect the ASN.1 structure of an OCSP
+// request and response.
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version       int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList   []request
+}
+
+type request struct {
+	Cert certID
+}
+
+type responseASN1 struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Version            int         `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID        responderID `asn1:"choice"`
+	ProducedAt         time.Time   `asn1:"generalized"`
+	Responses          []singleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// responderID is the ResponderID CHOICE. ByName holds the whole [1]
+// element, so its Bytes are the DER-encoded Name.
+type responderID struct {
+	ByName *asn1.RawValue `asn1:"tag:1"`
+	ByKey  *[]byte        `asn1:"explicit,tag:2"`
+}
+
+type singleResponse struct {
+	CertID           certID
+	Status           certStatus       `asn1:"choice"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+// certStatus is the CertStatus CHOICE.
+type certStatus struct {
+	Good    *asn1.Flag   `asn1:"tag:0"`
+	Revoked *revokedInfo `asn1:"tag:1"`
+	Unknown *asn1.Flag   `asn1:"tag:2"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+var (
+	oidSignatureSHA1WithRSA     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 5}
+	oidSignatureSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidSignatureSHA384WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidSignatureSHA512WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidSignatureDSAWithSHA1     = asn1.ObjectIdentifier{1, 2, 840, 10040, 4, 3}
+	oidSignatureDSAWithSHA256   = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 4, 3, 2}
+	oidSignatureECDSAWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 1}
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSignatureECDSAWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidSignatureECDSAWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidSignatureEd25519         = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+// signatureAlgorithmDetails maps the signature algorithms that OCSP
+// responses may use to their OIDs. The crypto/x509 package keeps its own
+// table, which is not exported.
+var signatureAlgorithmDetails = []struct {
+	algo       x509.SignatureAlgorithm
+	oid        asn1.ObjectIdentifier
+	pubKeyAlgo x509.PublicKeyAlgorithm
+	hash       crypto.Hash
+}{
+	{x509.SHA1WithRSA, oidSignatureSHA1WithRSA, x509.RSA, crypto.SHA1},
+	{x509.SHA256WithRSA, oidSignatureSHA256WithRSA, x509.RSA, crypto.SHA256},
+	{x509.SHA384WithRSA, oidSignatureSHA384WithRSA, x509.RSA, crypto.SHA384},
+	{x509.SHA512WithRSA, oidSignatureSHA512WithRSA, x509.RSA, crypto.SHA512},
+	{x509.DSAWithSHA1, oidSignatureDSAWithSHA1, x509.DSA, crypto.SHA1},
+	{x509.DSAWithSHA256, oidSignatureDSAWithSHA256, x509.DSA, crypto.SHA256},
+	{x509.ECDSAWithSHA1, oidSignatureECDSAWithSHA1, x509.ECDSA, crypto.SHA1},
+	{x509.ECDSAWithSHA256, oidSignatureECDSAWithSHA256, x509.ECDSA, crypto.SHA256},
+	{x509.ECDSAWithSHA384, oidSignatureECDSAWithSHA384, x509.ECDSA, crypto.SHA384},
+	{x509.ECDSAWithSHA512, oidSignatureECDSAWithSHA512, x509.ECDSA, crypto.SHA512},
+	{x509.PureEd25519, oidSignatureEd25519, x509.Ed25519, crypto.Hash(0) /* no pre-hashing */},
+}
+
+func getSignatureAlgorithmFromOID(oid asn1.ObjectIdentifier) x509.SignatureAlgorithm {
+	for _, details := range signatureAlgorithmDetails {
+		if oid.Equal(details.oid) {
+			return details.algo
+		}
+	}
+	return x509.UnknownSignatureAlgorithm
+}
+
+// signingParamsForPublicKey returns the parameters to use for signing with
+// the private key matching pub. If requestedSigAlgo is not zero then it
+// overrides the default signature algorithm.
+func signingParamsForPublicKey(pub interface{}, requestedSigAlgo x509.SignatureAlgorithm) (hashFunc crypto.Hash, sigAlgo pkix.AlgorithmIdentifier, err error) {
+	var pubType x509.PublicKeyAlgorithm
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		pubType = x509.RSA
+		hashFunc = crypto.SHA256
+		sigAlgo.Algorithm = oidSignatureSHA256WithRSA
+		sigAlgo.Parameters = asn1.RawValue{
+			Tag: asn1.TagNull,
+		}
+
+	case *ecdsa.PublicKey:
+		pubType = x509.ECDSA
+
+		switch pub.Curve {
+		case elliptic.P224(), elliptic.P256():
+			hashFunc = crypto.SHA256
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA256
+		case elliptic.P384():
+			hashFunc = crypto.SHA384
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA384
+		case elliptic.P521():
+			hashFunc = crypto.SHA512
+			sigAlgo.Algorithm = oidSignatureECDSAWithSHA512
+		default:
+			err = errors.New("ocsp: unknown elliptic curve")
+		}
+
+	case ed25519.PublicKey:
+		pubType = x509.Ed25519
+		sigAlgo.Algorithm = oidSignatureEd25519
+
+	default:
+		err = errors.New("ocsp: only RSA, ECDSA and Ed25519 keys supported")
+	}
+
+	if err != nil || requestedSigAlgo == 0 {
+		return
+	}
+
+	for _, details := range signatureAlgorithmDetails {
+		if details.algo == requestedSigAlgo {
+			if details.pubKeyAlgo != pubType {
+				err = errors.New("ocsp: requested SignatureAlgorithm does not match private key type")
+				return
+			}
+			sigAlgo.Algorithm, hashFunc = details.oid, details.hash
+			if pubType != x509.RSA {
+				sigAlgo.Parameters = asn1.RawValue{}
+			}
+			return
+		}
+	}
+	err = errors.New("ocsp: unsupported SignatureAlgorithm")
+	return
+}
+
+// hashOIDs maps the hash functions that may identify a certificate in a
+// request or response to their OIDs.
+var hashOIDs = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26},
+	crypto.SHA256: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1},
+	crypto.SHA384: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2},
+	crypto.SHA512: asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3},
+}
+
+func getHashAlgorithmFromOID(target asn1.ObjectIdentifier) crypto.Hash {
+	for hash, oid := range hashOIDs {
+		if oid.Equal(target) {
+			return hash
+		}
+	}
+	return crypto.Hash(0)
+}
+
+// issuerHashes returns the hashes of the issuer's name and public key that
+// identify certificates issued by it.
+func issuerHashes(issuer *x509.Certificate, hashFunc crypto.Hash) (nameHash, keyHash []byte, err error) {
+	if !hashFunc.Available() {
+		return nil, nil, x509.ErrUnsupportedAlgorithm
+	}
+	var publicKeyInfo struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &publicKeyInfo); err != nil {
+		return nil, nil, err
+	}
+
+	h := hashFunc.New()
+	h.Write(publicKeyInfo.PublicKey.RightAlign())
+	keyHash = h.Sum(nil)
+
+	h.Reset()
+	h.Write(issuer.RawSubject)
+	nameHash = h.Sum(nil)
+	return nameHash, keyHash, nil
+}
+
+// Request represents an OCSP request. See RFC 6960.
+type Request struct {
+	HashAlgorithm  crypto.Hash
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+// Marshal marshals the OCSP request to ASN.1 DER encoded form.
+func (req *Request) Marshal() ([]byte, error) {
+	hashOID, ok := hashOIDs[req.HashAlgorithm]
+	if !ok {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+	return asn1.Marshal(ocspRequest{
+		tbsRequest{
+			RequestList: []request{{
+				Cert: certID{
+					pkix.AlgorithmIdentifier{
+						Algorithm:  hashOID,
+						Parameters: asn1.RawValue{Tag: asn1.TagNull},
+					},
+					req.IssuerNameHash,
+					req.IssuerKeyHash,
+					req.SerialNumber,
+				},
+			}},
+		},
+	})
+}
+
+// ParseRequest parses an OCSP request in DER form. It only supports
+// requests for a single certificate. Signed requests are not supported.
+// If a request includes a signature, it will result in a ParseError.
+func ParseRequest(der []byte) (*Request, error) {
+	var req ocspRequest
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ParseError("trailing data in OCSP request")
+	}
+
+	if len(req.TBSRequest.RequestList) == 0 {
+		return nil, ParseError("OCSP request contains no request body")
+	}
+	innerRequest := req.TBSRequest.RequestList[0]
+
+	hashFunc := getHashAlgorithmFromOID(innerRequest.Cert.HashAlgorithm.Algorithm)
+	if hashFunc == 0 {
+		return nil, ParseError("OCSP request uses unknown hash function")
+	}
+
+	return &Request{
+		HashAlgorithm:  hashFunc,
+		IssuerNameHash: innerRequest.Cert.NameHash,
+		IssuerKeyHash:  innerRequest.Cert.IssuerKeyHash,
+		SerialNumber:   innerRequest.Cert.SerialNumber,
+	}, nil
+}
+
+// RequestOptions contains options for constructing OCSP requests.
+type RequestOptions struct {
+	// Hash contains the hash function that should be used when
+	// constructing the OCSP request. If zero, SHA-1 will be used.
+	Hash crypto.Hash
+}
+
+func (opts *RequestOptions) hash() crypto.Hash {
+	if opts == nil || opts.Hash == 0 {
+		// SHA-1 is nearly universally used in OCSP.
+		return crypto.SHA1
+	}
+	return opts.Hash
+}
+
+// CreateRequest returns a DER-encoded, OCSP request for the status of cert. If
+// opts is nil then sensible defaults are used.
+func CreateRequest(cert, issuer *x509.Certificate, opts *RequestOptions) ([]byte, error) {
+	hashFunc := opts.hash()
+	if _, ok := hashOIDs[hashFunc]; !ok {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, hashFunc)
+	if err != nil {
+		return nil, err
+	}
+	req := &Request{
+		HashAlgorithm:  hashFunc,
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   cert.SerialNumber,
+	}
+	return req.Marshal()
+}
+
+// Response represents an OCSP response containing a single SingleResponse.
+// See RFC 6960.
+type Response struct {
+	// Status is one of {Good, Revoked, Unknown}
+	Status                                        int
+	SerialNumber                                  *big.Int
+	ProducedAt, ThisUpdate, NextUpdate, RevokedAt time.Time
+	RevocationReason                              int
+
+	// Certificate is the certificate that signed the response, if the
+	// responder included it because it isn't the issuer itself.
+	Certificate *x509.Certificate
+
+	// TBSResponseData contains the raw bytes of the signed response. If
+	// Certificate is nil then this can be used to verify Signature.
+	TBSResponseData    []byte
+	Signature          []byte
+	SignatureAlgorithm x509.SignatureAlgorithm
+
+	// IssuerHash is the hash used to compute the IssuerNameHash and
+	// IssuerKeyHash. Valid values are crypto.SHA1, crypto.SHA256,
+	// crypto.SHA384 and crypto.SHA512. If zero, crypto.SHA1 is used by
+	// CreateResponse.
+	IssuerHash     crypto.Hash
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+
+	// RawResponderName optionally contains the DER-encoded subject of the
+	// responder certificate. Exactly one of RawResponderName and
+	// ResponderKeyHash is set in a parsed response.
+	RawResponderName []byte
+	// ResponderKeyHash optionally contains the SHA-1 hash of the
+	// responder's public key.
+	ResponderKeyHash []byte
+
+	// Extensions contains raw X.509 extensions from the singleExtensions
+	// field of the OCSP response. When parsing certificates, this can be
+	// used to extract non-critical extensions that are not parsed by this
+	// package. When marshaling OCSP responses, the Extensions field is
+	// ignored, see ExtraExtensions.
+	Extensions []pkix.Extension
+
+	// ExtraExtensions contains extensions to be copied, raw, into any
+	// marshaled OCSP response (in the singleExtensions field). Values
+	// override any extensions that would otherwise be produced based on
+	// the other fields. The ExtraExtensions field is not populated when
+	// parsing certificates, see Extensions.
+	ExtraExtensions []pkix.Extension
+}
+
+// CheckSignatureFrom checks that the signature in resp is a valid signature
+// from issuer. This should only be used if resp.Certificate is nil.
+// Otherwise, the OCSP response contained an intermediate certificate that
+// created the signature. That signature is checked by ParseResponse and
+// only resp.Certificate remains to be validated.
+func (resp *Response) CheckSignatureFrom(issuer *x509.Certificate) error {
+	return issuer.CheckSignature(resp.SignatureAlgorithm, resp.TBSResponseData, resp.Signature)
+}
+
+// ErrNoMatch is returned by ParseResponseForCert when the response does not
+// contain a status for the certificate.
+var ErrNoMatch = errors.New("ocsp: response does not contain a status for the certificate")
+
+// ParseResponse parses an OCSP response in DER form. The response must
+// contain only one certificate status. To parse the status of a specific
+// certificate from a response which may contain multiple statuses, use
+// ParseResponseForCert instead.
+//
+// If the response contains an embedded certificate, then that certificate
+// must have been signed by the issuer certificate and have the OCSP signing
+// extended key usage. It is then used to check the response's signature.
+//
+// If issuer is not nil, it is used to check the signature on the response.
+//
+// Invalid responses and parse failures will result in a ParseError. Error
+// responses will result in a ResponseError.
+func ParseResponse(der []byte, issuer *x509.Certificate) (*Response, error) {
+	return ParseResponseForCert(der, nil, issuer)
+}
+
+// ParseResponseForCert acts identically to ParseResponse, except it supports
+// parsing responses that contain multiple statuses. If cert is not nil, the
+// status for cert is returned, or ErrNoMatch if there is none. If cert is
+// nil, the response must contain exactly one status.
+//
+// If issuer is not nil, the status must also identify issuer by the hashes
+// of its name and key. When cert is not nil, statuses for certificates with
+// the same serial number from other issuers are skipped.
+func ParseResponseForCert(der []byte, cert, issuer *x509.Certificate) (*Response, error) {
+	var resp responseASN1
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ParseError("trailing data in OCSP response")
+	}
+
+	if status := ResponseStatus(resp.Status); status != Success {
+		return nil, ResponseError{status}
+	}
+
+	if !resp.Response.ResponseType.Equal(idPKIXOCSPBasic) {
+		return nil, ParseError("bad OCSP response type")
+	}
+
+	var basicResp basicResponse
+	rest, err = asn1.Unmarshal(resp.Response.Response, &basicResp)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, ParseError("trailing data in OCSP response")
+	}
+
+	var data responseData
+	if _, err = asn1.Unmarshal(basicResp.TBSResponseData.FullBytes, &data); err != nil {
+		return nil, err
+	}
+
+	if n := len(data.Responses); n == 0 || cert == nil && n > 1 {
+		return nil, ParseError("OCSP response contains bad number of responses")
+	}
+
+	var singleResp singleResponse
+	if cert == nil {
+		singleResp = data.Responses[0]
+	} else {
+		match, serialMatch := false, false
+		for _, r := range data.Responses {
+			if r.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+				continue
+			}
+			serialMatch = true
+			if issuer == nil || certIDMatches(&r.CertID, issuer) {
+				singleResp = r
+				match = true
+				break
+			}
+		}
+		if !match {
+			if serialMatch {
+				return nil, ParseError("OCSP response is for a certificate from a different issuer")
+			}
+			return nil, ErrNoMatch
+		}
+	}
+
+	ret := &Response{
+		TBSResponseData:    basicResp.TBSResponseData.FullBytes,
+		Signature:          basicResp.Signature.RightAlign(),
+		SignatureAlgorithm: getSignatureAlgorithmFromOID(basicResp.SignatureAlgorithm.Algorithm),
+		Extensions:         singleResp.SingleExtensions,
+		SerialNumber:       singleResp.CertID.SerialNumber,
+		ProducedAt:         data.ProducedAt,
+		ThisUpdate:         singleResp.ThisUpdate,
+		NextUpdate:         singleResp.NextUpdate,
+		IssuerNameHash:     singleResp.CertID.NameHash,
+		IssuerKeyHash:      singleResp.CertID.IssuerKeyHash,
+	}
+
+	switch id := data.ResponderID; {
+	case id.ByName != nil:
+		ret.RawResponderName = id.ByName.Bytes
+	case id.ByKey != nil:
+		ret.ResponderKeyHash = *id.ByKey
+	}
+
+	for _, ext := range singleResp.SingleExtensions {
+		if ext.Critical {
+			return nil, ParseError("unsupported critical extension")
+		}
+	}
+
+	switch status := singleResp.Status; {
+	case status.Good != nil:
+		ret.Status = Good
+	case status.Revoked != nil:
+		ret.Status = Revoked
+		ret.RevokedAt = status.Revoked.RevocationTime
+		ret.RevocationReason = int(status.Revoked.Reason)
+	default:
+		ret.Status = Unknown
+	}
+
+	ret.IssuerHash = getHashAlgorithmFromOID(singleResp.CertID.HashAlgorithm.Algorithm)
+	if ret.IssuerHash == 0 {
+		return nil, ParseError("OCSP response uses unknown hash function")
+	}
+
+	if len(basicResp.Certificates) > 0 {
+		ret.Certificate, err = x509.ParseCertificate(basicResp.Certificates[0].FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		if err := ret.CheckSignatureFrom(ret.Certificate); err != nil {
+			return nil, ParseError("bad OCSP signature: " + err.Error())
+		}
+		// Some responders include the issuer itself, which needs no
+		// delegation.
+		if issuer != nil && !bytes.Equal(ret.Certificate.Raw, issuer.Raw) {
+			if err := issuer.CheckSignature(ret.Certificate.SignatureAlgorithm, ret.Certificate.RawTBSCertificate, ret.Certificate.Signature); err != nil {
+				return nil, ParseError("bad signature on embedded certificate: " + err.Error())
+			}
+			if !hasOCSPSigning(ret.Certificate) {
+				return nil, ParseError("embedded certificate is not authorized to sign OCSP responses")
+			}
+		}
+	} else if issuer != nil {
+		if err := ret.CheckSignatureFrom(issuer); err != nil {
+			return nil, ParseError("bad OCSP signature: " + err.Error())
+		}
+	}
+
+	if issuer != nil {
+		nameHash, keyHash, err := issuerHashes(issuer, ret.IssuerHash)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(nameHash, ret.IssuerNameHash) || !bytes.Equal(keyHash, ret.IssuerKeyHash) {
+			return nil, ParseError("OCSP response is for a certificate from a different issuer")
+		}
+	}
+
+	return ret, nil
+}
+
+// certIDMatches reports whether id identifies issuer by the hashes of its
+// name and key.
+func certIDMatches(id *certID, issuer *x509.Certificate) bool {
+	hashFunc := getHashAlgorithmFromOID(id.HashAlgorithm.Algorithm)
+	if hashFunc == 0 {
+		return false
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, hashFunc)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(nameHash, id.NameHash) && bytes.Equal(keyHash, id.IssuerKeyHash)
+}
+
+func hasOCSPSigning(cert *x509.Certificate) bool {
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateResponse returns a DER-encoded OCSP response with the specified
+// contents. The fields in the response are populated as follows:
+//
+// The responder cert is used to populate the responder's key hash.
+//
+// The issuer cert is used to populate the IssuerNameHash and IssuerKeyHash
+// fields.
+//
+// The template is used to populate the SerialNumber, Status, RevokedAt,
+// RevocationReason, ThisUpdate, NextUpdate and ExtraExtensions fields. If
+// template.Certificate is not nil, it is included in the response so that
+// the signature can be checked by a client that only knows the issuer; it
+// is normally the responder certificate when that is not the issuer.
+//
+// The returned response is signed by priv, which must be the private key
+// of responderCert.
+func CreateResponse(issuer, responderCert *x509.Certificate, template Response, priv crypto.Signer) ([]byte, error) {
+	hashFunc := template.IssuerHash
+	if hashFunc == 0 {
+		hashFunc = crypto.SHA1
+	}
+	hashOID, ok := hashOIDs[hashFunc]
+	if !ok {
+		return nil, x509.ErrUnsupportedAlgorithm
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, hashFunc)
+	if err != nil {
+		return nil, err
+	}
+	_, responderKeyHash, err := issuerHashes(responderCert, crypto.SHA1)
+	if err != nil {
+		return nil, err
+	}
+
+	innerResponse := singleResponse{
+		CertID: certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  hashOID,
+				Parameters: asn1.RawValue{Tag: asn1.TagNull},
+			},
+			NameHash:      nameHash,
+			IssuerKeyHash: keyHash,
+			SerialNumber:  template.SerialNumber,
+		},
+		ThisUpdate:       template.ThisUpdate.UTC(),
+		SingleExtensions: template.ExtraExtensions,
+	}
+	if !template.NextUpdate.IsZero() {
+		innerResponse.NextUpdate = template.NextUpdate.UTC()
+	}
+
+	switch template.Status {
+	case Good:
+		innerResponse.Status.Good = new(asn1.Flag)
+	case Unknown:
+		innerResponse.Status.Unknown = new(asn1.Flag)
+	case Revoked:
+		innerResponse.Status.Revoked = &revokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+	default:
+		return nil, fmt.Errorf("ocsp: invalid status %d", template.Status)
+	}
+
+	tbsResponseDataDER, err := asn1.Marshal(responseData{
+		ResponderID: responderID{ByKey: &responderKeyHash},
+		ProducedAt:  time.Now().Truncate(time.Minute).UTC(),
+		Responses:   []singleResponse{innerResponse},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashFunc, signatureAlgorithm, err := signingParamsForPublicKey(priv.Public(), template.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := tbsResponseDataDER
+	if hashFunc != 0 {
+		h := hashFunc.New()
+		h.Write(signed)
+		signed = h.Sum(nil)
+	}
+	signature, err := priv.Sign(rand.Reader, signed, hashFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	response := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbsResponseDataDER},
+		SignatureAlgorithm: signatureAlgorithm,
+		Signature: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	}
+	if template.Certificate != nil {
+		response.Certificates = []asn1.RawValue{
+			{FullBytes: template.Certificate.Raw},
+		}
+	}
+	responseDER, err := asn1.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(responseASN1{
+		Status: asn1.Enumerated(Success),
+		Response: responseBytes{
+			ResponseType: idPKIXOCSPBasic,
+			Response:     responseDER,
+		},
+	})
+}
+
+// RevocationChecker returns a function, suitable for
+// x509.VerifyOptions.CheckRevocation, that checks certificates against the
+// given DER-encoded OCSP responses.
+//
+// A response is only consulted for a certificate if it is correctly signed
+// for the certificate's issuer, either directly or by a delegated responder.
+// A certificate with a Revoked status in any such response, current or not,
+// is rejected with an x509.CertificateInvalidError whose Reason is
+// x509.Revoked; revocation is permanent, so a Revoked status takes
+// precedence over any Good ones. Otherwise, a certificate whose only
+// responses are not current at the verification time is rejected with an
+// error, as its status is unknown. Certificates not covered by any of the
+// responses, or by current ones with a Good or Unknown status, are
+// accepted.
+func RevocationChecker(responses [][]byte) func(cert, issuer *x509.Certificate, now time.Time) error {
+	return func(cert, issuer *x509.Certificate, now time.Time) error {
+		covered, current := false, false
+		for _, der := range responses {
+			resp, err := ParseResponseForCert(der, cert, issuer)
+			if err != nil {
+				continue
+			}
+			if resp.Status == Revoked {
+				return x509.CertificateInvalidError{Cert: cert, Reason: x509.Revoked}
+			}
+			covered = true
+			if !now.Before(resp.ThisUpdate) && (resp.NextUpdate.IsZero() || !now.After(resp.NextUpdate)) {
+				current = true
+			}
+		}
+		if covered && !current {
+			return errors.New("ocsp: no current OCSP response for certificate")
+		}
+		return nil
+	}
+}
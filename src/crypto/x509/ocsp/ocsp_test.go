@@ -0,0 +1,398 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testCert generates a certificate from template, signed by parent and
+// parentKey, or self-signed if parent is nil.
+func testCert(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.NotBefore = time.Unix(1000, 0)
+	template.NotAfter = time.Unix(100000, 0)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+type testPKI struct {
+	issuer    *x509.Certificate
+	issuerKey *ecdsa.PrivateKey
+	leaf      *x509.Certificate
+	responder *x509.Certificate
+	respKey   *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	var p testPKI
+	p.issuer, p.issuerKey = testCert(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Issuer"},
+		BasicConstraintsValid: true,
+		IsCA:     true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}, nil, nil)
+	p.leaf, _ = testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf.example.com"},
+		DNSNames:     []string{"leaf.example.com"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, p.issuer, p.issuerKey)
+	p.responder, p.respKey = testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Responder"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+	}, p.issuer, p.issuerKey)
+	return &p
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	p := newTestPKI(t)
+	for _, hash := range []crypto.Hash{0, crypto.SHA1, crypto.SHA256, crypto.SHA512} {
+		der, err := CreateRequest(p.leaf, p.issuer, &RequestOptions{Hash: hash})
+		if err != nil {
+			t.Fatalf("%v: CreateRequest: %v", hash, err)
+		}
+		req, err := ParseRequest(der)
+		if err != nil {
+			t.Fatalf("%v: ParseRequest: %v", hash, err)
+		}
+		want := hash
+		if want == 0 {
+			want = crypto.SHA1
+		}
+		if req.HashAlgorithm != want {
+			t.Errorf("%v: got hash %v", hash, req.HashAlgorithm)
+		}
+		if req.SerialNumber.Cmp(p.leaf.SerialNumber) != 0 {
+			t.Errorf("%v: got serial %v, want %v", hash, req.SerialNumber, p.leaf.SerialNumber)
+		}
+		nameHash, keyHash, err := issuerHashes(p.issuer, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(req.IssuerNameHash, nameHash) || !bytes.Equal(req.IssuerKeyHash, keyHash) {
+			t.Errorf("%v: issuer hashes don't match", hash)
+		}
+		again, err := req.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(again, der) {
+			t.Errorf("%v: Marshal returned %x, want %x", hash, again, der)
+		}
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	p := newTestPKI(t)
+	thisUpdate := time.Unix(4000, 0).UTC()
+	nextUpdate := time.Unix(6000, 0).UTC()
+	revokedAt := time.Unix(3000, 0).UTC()
+
+	for _, status := range []int{Good, Revoked, Unknown} {
+		template := Response{
+			Status:           status,
+			SerialNumber:     p.leaf.SerialNumber,
+			ThisUpdate:       thisUpdate,
+			NextUpdate:       nextUpdate,
+			RevokedAt:        revokedAt,
+			RevocationReason: KeyCompromise,
+		}
+		der, err := CreateResponse(p.issuer, p.issuer, template, p.issuerKey)
+		if err != nil {
+			t.Fatalf("status %d: CreateResponse: %v", status, err)
+		}
+		resp, err := ParseResponse(der, p.issuer)
+		if err != nil {
+			t.Fatalf("status %d: ParseResponse: %v", status, err)
+		}
+		if resp.Status != status {
+			t.Errorf("got status %d, want %d", resp.Status, status)
+		}
+		if resp.SerialNumber.Cmp(p.leaf.SerialNumber) != 0 {
+			t.Errorf("status %d: got serial %v", status, resp.SerialNumber)
+		}
+		if !resp.ThisUpdate.Equal(thisUpdate) || !resp.NextUpdate.Equal(nextUpdate) {
+			t.Errorf("status %d: got updates %v, %v", status, resp.ThisUpdate, resp.NextUpdate)
+		}
+		if status == Revoked {
+			if !resp.RevokedAt.Equal(revokedAt) || resp.RevocationReason != KeyCompromise {
+				t.Errorf("got revocation %v, %d", resp.RevokedAt, resp.RevocationReason)
+			}
+		}
+		if resp.Certificate != nil {
+			t.Errorf("status %d: unexpected embedded certificate", status)
+		}
+		_, keyHash, _ := issuerHashes(p.issuer, crypto.SHA1)
+		if !bytes.Equal(resp.ResponderKeyHash, keyHash) {
+			t.Errorf("status %d: got responder key hash %x, want %x", status, resp.ResponderKeyHash, keyHash)
+		}
+	}
+}
+
+func TestResponseDelegated(t *testing.T) {
+	p := newTestPKI(t)
+	template := Response{
+		Status:       Good,
+		SerialNumber: p.leaf.SerialNumber,
+		ThisUpdate:   time.Unix(4000, 0),
+		Certificate:  p.responder,
+		IssuerHash:   crypto.SHA256,
+	}
+	der, err := CreateResponse(p.issuer, p.responder, template, p.respKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ParseResponseForCert(der, p.leaf, p.issuer)
+	if err != nil {
+		t.Fatalf("ParseResponseForCert: %v", err)
+	}
+	if resp.Certificate == nil || !bytes.Equal(resp.Certificate.Raw, p.responder.Raw) {
+		t.Errorf("embedded certificate not returned")
+	}
+	if resp.IssuerHash != crypto.SHA256 {
+		t.Errorf("got issuer hash %v", resp.IssuerHash)
+	}
+
+	// A responder without the OCSP signing usage is not trusted.
+	rogue, rogueKey := testCert(t, &x509.Certificate{
+		SerialNumber: big.NewInt(4),
+		Subject:      pkix.Name{CommonName: "Rogue"},
+	}, p.issuer, p.issuerKey)
+	template.Certificate = rogue
+	der, err = CreateResponse(p.issuer, rogue, template, rogueKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseResponse(der, p.issuer); err == nil {
+		t.Errorf("ParseResponse accepted a response from an unauthorized responder")
+	}
+
+	// Without the embedded certificate the signature cannot be checked
+	// against the issuer.
+	template.Certificate = nil
+	der, err = CreateResponse(p.issuer, p.responder, template, p.respKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseResponse(der, p.issuer); err == nil {
+		t.Errorf("ParseResponse accepted a response with a bad signature")
+	}
+}
+
+func TestResponseWrongIssuer(t *testing.T) {
+	p := newTestPKI(t)
+	other := newTestPKI(t)
+	der, err := CreateResponse(p.issuer, p.issuer, Response{
+		Status:       Good,
+		SerialNumber: p.leaf.SerialNumber,
+		ThisUpdate:   time.Unix(4000, 0),
+	}, p.issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseResponse(der, other.issuer); err == nil {
+		t.Errorf("ParseResponse accepted a response from a different issuer")
+	}
+	if _, err := ParseResponseForCert(der, p.responder, p.issuer); err != ErrNoMatch {
+		t.Errorf("ParseResponseForCert returned %v, want ErrNoMatch", err)
+	}
+}
+
+// createMultiResponse returns a response signed by p.issuer with a status
+// for each of the given issuers, all for p.leaf's serial number. The
+// first status is Revoked and the rest are Good.
+func createMultiResponse(t *testing.T, p *testPKI, issuers ...*x509.Certificate) []byte {
+	var responses []singleResponse
+	for i, issuer := range issuers {
+		nameHash, keyHash, err := issuerHashes(issuer, crypto.SHA1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := singleResponse{
+			CertID: certID{
+				HashAlgorithm: pkix.AlgorithmIdentifier{
+					Algorithm:  hashOIDs[crypto.SHA1],
+					Parameters: asn1.RawValue{Tag: asn1.TagNull},
+				},
+				NameHash:      nameHash,
+				IssuerKeyHash: keyHash,
+				SerialNumber:  p.leaf.SerialNumber,
+			},
+			ThisUpdate: time.Unix(4000, 0).UTC(),
+		}
+		if i == 0 {
+			r.Status.Revoked = &revokedInfo{RevocationTime: time.Unix(2000, 0).UTC()}
+		} else {
+			r.Status.Good = new(asn1.Flag)
+		}
+		responses = append(responses, r)
+	}
+	_, keyHash, err := issuerHashes(p.issuer, crypto.SHA1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbs, err := asn1.Marshal(responseData{
+		ResponderID: responderID{ByKey: &keyHash},
+		ProducedAt:  time.Unix(4000, 0).UTC(),
+		Responses:   responses,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashFunc, sigAlg, err := signingParamsForPublicKey(p.issuerKey.Public(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := hashFunc.New()
+	h.Write(tbs)
+	sig, err := p.issuerKey.Sign(rand.Reader, h.Sum(nil), hashFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	basic, err := asn1.Marshal(basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: sigAlg,
+		Signature:          asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(responseASN1{
+		Status:   asn1.Enumerated(Success),
+		Response: responseBytes{ResponseType: idPKIXOCSPBasic, Response: basic},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestResponseForCertSkipsOtherIssuers(t *testing.T) {
+	p := newTestPKI(t)
+	other := newTestPKI(t)
+
+	// The status for the same serial number from another issuer comes
+	// first, and must not hide the one for p.issuer.
+	der := createMultiResponse(t, p, other.issuer, p.issuer)
+	resp, err := ParseResponseForCert(der, p.leaf, p.issuer)
+	if err != nil {
+		t.Fatalf("ParseResponseForCert: %v", err)
+	}
+	if resp.Status != Good {
+		t.Errorf("got status %v, want Good", resp.Status)
+	}
+
+	der = createMultiResponse(t, p, other.issuer)
+	if _, err := ParseResponseForCert(der, p.leaf, p.issuer); err == nil || err == ErrNoMatch {
+		t.Errorf("ParseResponseForCert returned %v, want a different issuer error", err)
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	_, err := ParseResponse([]byte{0x30, 0x03, 0x0a, 0x01, 0x01}, nil)
+	respErr, ok := err.(ResponseError)
+	if !ok || respErr.Status != Malformed {
+		t.Fatalf("got %v, want a Malformed ResponseError", err)
+	}
+	if got, want := respErr.Error(), "ocsp: error from server: malformed"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRevocationChecker(t *testing.T) {
+	p := newTestPKI(t)
+	opts := x509.VerifyOptions{
+		DNSName:     "leaf.example.com",
+		Roots:       x509.NewCertPool(),
+		CurrentTime: time.Unix(5000, 0),
+	}
+	opts.Roots.AddCert(p.issuer)
+
+	response := func(status int, thisUpdate, nextUpdate int64) []byte {
+		der, err := CreateResponse(p.issuer, p.responder, Response{
+			Status:       status,
+			SerialNumber: p.leaf.SerialNumber,
+			ThisUpdate:   time.Unix(thisUpdate, 0),
+			NextUpdate:   time.Unix(nextUpdate, 0),
+			RevokedAt:    time.Unix(2000, 0),
+			Certificate:  p.responder,
+		}, p.respKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	const (
+		statusOK = iota
+		statusRevoked
+		statusStale
+	)
+	tests := []struct {
+		responses [][]byte
+		want      int
+	}{
+		{nil, statusOK},
+		{[][]byte{response(Good, 4000, 6000)}, statusOK},
+		{[][]byte{response(Unknown, 4000, 6000)}, statusOK},
+		{[][]byte{response(Revoked, 4000, 6000)}, statusRevoked},
+		{[][]byte{[]byte("garbage"), response(Revoked, 4000, 6000)}, statusRevoked},
+		// A Revoked response wins over a Good one, in either order.
+		{[][]byte{response(Good, 4500, 6000), response(Revoked, 4000, 6000)}, statusRevoked},
+		{[][]byte{response(Revoked, 4000, 6000), response(Good, 4500, 6000)}, statusRevoked},
+		// Revocation is final, however old the response.
+		{[][]byte{response(Revoked, 1000, 2000)}, statusRevoked},
+		{[][]byte{response(Good, 4500, 6000), response(Revoked, 1000, 2000)}, statusRevoked},
+		// Without a current response the status is unknown.
+		{[][]byte{response(Good, 1000, 2000)}, statusStale},
+		{[][]byte{response(Good, 6000, 7000)}, statusStale},
+		{[][]byte{response(Good, 1000, 2000), response(Good, 4500, 6000)}, statusOK},
+	}
+	for i, test := range tests {
+		opts.CheckRevocation = RevocationChecker(test.responses)
+		_, err := p.leaf.Verify(opts)
+		switch test.want {
+		case statusOK:
+			if err != nil {
+				t.Errorf("#%d: Verify: %v", i, err)
+			}
+		case statusRevoked:
+			invalid, ok := err.(x509.CertificateInvalidError)
+			if !ok || invalid.Reason != x509.Revoked || invalid.Cert != p.leaf {
+				t.Errorf("#%d: Verify returned %v, want a revocation error for the leaf", i, err)
+			}
+		case statusStale:
+			if _, ok := err.(x509.CertificateInvalidError); err == nil || ok {
+				t.Errorf("#%d: Verify returned %v, want a stale response error", i, err)
+			}
+		}
+	}
+}
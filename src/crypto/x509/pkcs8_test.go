@@ -5,7 +5,12 @@
 package x509
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"encoding/hex"
+	"reflect"
 	"testing"
 )
 
@@ -15,14 +20,55 @@ var pkcs8RSAPrivateKeyHex = `30820278020100300d06092a864886f70d01010105000482026
 //   openssl ecparam -genkey -name secp521r1 | openssl pkcs8 -topk8 -nocrypt
 var pkcs8ECPrivateKeyHex = `3081ed020100301006072a8648ce3d020106052b810400230481d53081d20201010441850d81618c5da1aec74c2eed608ba816038506975e6427237c2def150c96a3b13efbfa1f89f1be15cdf4d0ac26422e680e65a0ddd4ad3541ad76165fbf54d6e34ba18189038186000400da97bcedba1eb6d30aeb93c9f9a1454598fa47278df27d6f60ea73eb672d8dc528a9b67885b5b5dcef93c9824f7449ab512ee6a27e76142f56b94b474cfd697e810046c8ca70419365245c1d7d44d0db82c334073835d002232714548abbae6e5700f5ef315ee08b929d8581383dcf2d1c98c2f8a9fccbf79c9579f7b2fd8a90115ac2`
 
+// Generated using:
+//   openssl genpkey -algorithm ed25519
+var pkcs8Ed25519PrivateKeyHex = `302e020100300506032b657004220420bea587c5e81140aa40eb3dca36a2ac5e7ef136b7638425b3c9edc6d825c111de`
+
 func TestPKCS8(t *testing.T) {
-	derBytes, _ := hex.DecodeString(pkcs8RSAPrivateKeyHex)
-	if _, err := ParsePKCS8PrivateKey(derBytes); err != nil {
-		t.Errorf("failed to decode PKCS8 with RSA private key: %s", err)
+	tests := []struct {
+		name    string
+		keyHex  string
+		keyType reflect.Type
+	}{
+		{"RSA private key", pkcs8RSAPrivateKeyHex, reflect.TypeOf(&rsa.PrivateKey{})},
+		{"EC private key", pkcs8ECPrivateKeyHex, reflect.TypeOf(&ecdsa.PrivateKey{})},
+		{"Ed25519 private key", pkcs8Ed25519PrivateKeyHex, reflect.TypeOf(ed25519.PrivateKey{})},
+	}
+
+	for _, test := range tests {
+		derBytes, _ := hex.DecodeString(test.keyHex)
+		privKey, err := ParsePKCS8PrivateKey(derBytes)
+		if err != nil {
+			t.Errorf("%s: failed to decode PKCS8: %s", test.name, err)
+			continue
+		}
+		if reflect.TypeOf(privKey) != test.keyType {
+			t.Errorf("%s: decoded PKCS8 returned unexpected key type: %T", test.name, privKey)
+			continue
+		}
+		reserialised, err := MarshalPKCS8PrivateKey(privKey)
+		if err != nil {
+			t.Errorf("%s: failed to marshal into PKCS#8: %s", test.name, err)
+			continue
+		}
+		privKey2, err := ParsePKCS8PrivateKey(reserialised)
+		if err != nil {
+			t.Errorf("%s: failed to parse re-marshaled PKCS#8: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(privKey, privKey2) {
+			t.Errorf("%s: re-marshaled PKCS#8 decoded to a different key", test.name)
+		}
+	}
+
+	derBytes, _ := hex.DecodeString(pkcs8Ed25519PrivateKeyHex)
+	privKey, _ := ParsePKCS8PrivateKey(derBytes)
+	reserialised, _ := MarshalPKCS8PrivateKey(privKey)
+	if !bytes.Equal(reserialised, derBytes) {
+		t.Errorf("Ed25519 private key: re-marshaled PKCS#8 is %x, want %x", reserialised, derBytes)
 	}
 
-	derBytes, _ = hex.DecodeString(pkcs8ECPrivateKeyHex)
-	if _, err := ParsePKCS8PrivateKey(derBytes); err != nil {
-		t.Errorf("failed to decode PKCS8 with EC private key: %s", err)
+	if _, err := MarshalPKCS8PrivateKey(struct{}{}); err == nil {
+		t.Errorf("MarshalPKCS8PrivateKey accepted an unknown key type")
 	}
 }
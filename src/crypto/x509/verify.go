@@ -5,11 +5,13 @@
 package x509
 
 import (
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 )
@@ -33,6 +35,9 @@ const (
 	// IncompatibleUsage results when the certificate's key usage indicates
 	// that it may only be used for a different purpose.
 	IncompatibleUsage
+	// Revoked results when VerifyOptions.CheckRevocation reports that a
+	// certificate has been revoked.
+	Revoked
 )
 
 // CertificateInvalidError results when an odd error occurs. Users of this
@@ -54,6 +59,8 @@ func (e CertificateInvalidError) Error() string {
 		return "x509: too many intermediates for path length constraint"
 	case IncompatibleUsage:
 		return "x509: certificate specifies an incompatible key usage"
+	case Revoked:
+		return "x509: certificate has been revoked"
 	}
 	return "x509: unknown error"
 }
@@ -139,6 +146,13 @@ type VerifyOptions struct {
 	// constraint down the chain which mirrors Windows CryptoAPI behaviour,
 	// but not the spec. To accept any key usage, include ExtKeyUsageAny.
 	KeyUsages []ExtKeyUsage
+	// CheckRevocation, if not nil, is called for every certificate in each
+	// candidate chain other than the root, together with the certificate
+	// that issued it and the verification time. If it returns an error the
+	// chain is rejected. CRLRevocationChecker and the RevocationChecker
+	// function of the crypto/x509/ocsp package return suitable functions
+	// for checking against CRLs and OCSP responses obtained in advance.
+	CheckRevocation func(cert, issuer *Certificate, now time.Time) error
 }
 
 const (
@@ -213,7 +227,7 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 // If opts.Roots is nil and system roots are unavailable the returned error
 // will be of type SystemRootsError.
 //
-// WARNING: this doesn't do any revocation checking.
+// WARNING: revocation is only checked if opts.CheckRevocation is set.
 func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err error) {
 	// Platform-specific verification needs the ASN.1 contents so
 	// this makes the behaviour consistent across platforms.
@@ -230,7 +244,11 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 
 	// Use Windows's own verification and chain building.
 	if opts.Roots == nil && runtime.GOOS == "windows" {
-		return c.systemVerify(&opts)
+		chains, err = c.systemVerify(&opts)
+		if err != nil {
+			return
+		}
+		return checkChainsForRevocation(chains, &opts)
 	}
 
 	if len(c.UnhandledCriticalExtensions) > 0 {
@@ -269,8 +287,7 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 	// If any key usage is acceptable then we're done.
 	for _, usage := range keyUsages {
 		if usage == ExtKeyUsageAny {
-			chains = candidateChains
-			return
+			return checkChainsForRevocation(candidateChains, &opts)
 		}
 	}
 
@@ -282,9 +299,137 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 
 	if len(chains) == 0 {
 		err = CertificateInvalidError{c, IncompatibleUsage}
+		return
 	}
 
-	return
+	return checkChainsForRevocation(chains, &opts)
+}
+
+// checkChainsForRevocation returns the chains in which opts.CheckRevocation
+// accepts every certificate. If it rejects them all, the first error it
+// returned is returned instead.
+func checkChainsForRevocation(chains [][]*Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+	if opts.CheckRevocation == nil {
+		return chains, nil
+	}
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// Chains often share certificates, so each pair is only checked once.
+	type pair struct{ cert, issuer *Certificate }
+	results := make(map[pair]error)
+
+	var ret [][]*Certificate
+	var firstErr error
+nextChain:
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			p := pair{chain[i], chain[i+1]}
+			err, ok := results[p]
+			if !ok {
+				err = opts.CheckRevocation(p.cert, p.issuer, now)
+				results[p] = err
+			}
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue nextChain
+			}
+		}
+		ret = append(ret, chain)
+	}
+	if len(ret) == 0 {
+		return nil, firstErr
+	}
+	return ret, nil
+}
+
+// CRLRevocationChecker returns a function, suitable for
+// VerifyOptions.CheckRevocation, that checks certificates against crls.
+//
+// A CRL is only consulted for a certificate if it is signed by the
+// certificate's issuer. A certificate whose serial number appears in such a
+// CRL is rejected with a CertificateInvalidError whose Reason is Revoked.
+//
+// No extensions of CRLs or CRL entries are supported, so a CRL with a
+// critical extension, such as a delta CRL or one with an issuing
+// distribution point, cannot be used. Nor can a CRL that is not current at
+// the verification time. If all of the CRLs from a certificate's issuer are
+// unusable, the certificate is rejected with an error saying why rather than
+// being assumed to be unrevoked. Certificates whose issuer has no CRL among
+// crls are accepted.
+func CRLRevocationChecker(crls []*pkix.CertificateList) func(cert, issuer *Certificate, now time.Time) error {
+	type key struct {
+		crl    *pkix.CertificateList
+		issuer *Certificate
+	}
+	var mu sync.Mutex
+	signedBy := make(map[key]bool)
+
+	return func(cert, issuer *Certificate, now time.Time) error {
+		var usable bool
+		var unusableErr error
+		for _, crl := range crls {
+			k := key{crl, issuer}
+			mu.Lock()
+			ok, seen := signedBy[k]
+			mu.Unlock()
+			if !seen {
+				ok = issuer.CheckCRLSignature(crl) == nil
+				mu.Lock()
+				signedBy[k] = ok
+				mu.Unlock()
+			}
+			if !ok {
+				continue
+			}
+
+			tbs := &crl.TBSCertList
+			if err := checkCRLUsable(tbs, now); err != nil {
+				if unusableErr == nil {
+					unusableErr = err
+				}
+				continue
+			}
+			usable = true
+			for _, revoked := range tbs.RevokedCertificates {
+				if revoked.SerialNumber != nil && revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return CertificateInvalidError{cert, Revoked}
+				}
+			}
+		}
+		if !usable {
+			return unusableErr
+		}
+		return nil
+	}
+}
+
+// checkCRLUsable returns an error if tbs is not current at now or has a
+// critical extension, either on the CRL itself or on one of its entries.
+func checkCRLUsable(tbs *pkix.TBSCertificateList, now time.Time) error {
+	if now.Before(tbs.ThisUpdate) {
+		return fmt.Errorf("x509: CRL is not valid until %v", tbs.ThisUpdate)
+	}
+	if !tbs.NextUpdate.IsZero() && now.After(tbs.NextUpdate) {
+		return fmt.Errorf("x509: CRL expired at %v", tbs.NextUpdate)
+	}
+	for _, ext := range tbs.Extensions {
+		if ext.Critical {
+			return fmt.Errorf("x509: CRL has unhandled critical extension %v", ext.Id)
+		}
+	}
+	for _, revoked := range tbs.RevokedCertificates {
+		for _, ext := range revoked.Extensions {
+			if ext.Critical {
+				return fmt.Errorf("x509: CRL entry has unhandled critical extension %v", ext.Id)
+			}
+		}
+	}
+	return nil
 }
 
 func appendToFreshChain(chain []*Certificate, cert *Certificate) []*Certificate {
@@ -5,9 +5,14 @@
 package x509
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/pem"
 	"errors"
+	"math/big"
 	"runtime"
 	"strings"
 	"testing"
@@ -1132,3 +1137,177 @@ Nr4TDea9Y355e6cJDUCrat2PisP29owaQgVR1EX1n6diIWgVIEM8med8vSTYqZEX
 c4g/VhsxOBi0cQ+azcgOno4uG+GMmIPLHzHxREzGBHNJdmAPx/i9F4BrLunMTA5a
 mnkPIAou1Z5jJh5VkpTYghdae9C8x49OhgQ=
 -----END CERTIFICATE-----`
+
+// revocationTestChain returns a freshly generated root, intermediate and
+// leaf certificate, along with the intermediate's private key.
+func revocationTestChain(t *testing.T) (root, intermediate, leaf *Certificate, intermediateKey *ecdsa.PrivateKey) {
+	var certs [3]*Certificate
+	var keys [3]*ecdsa.PrivateKey
+	for i, name := range []string{"Root", "Intermediate", "leaf.example.com"} {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &Certificate{
+			SerialNumber: big.NewInt(int64(10 + i)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Unix(1000, 0),
+			NotAfter:     time.Unix(100000, 0),
+			SubjectKeyId: []byte{byte(i)},
+		}
+		if i < 2 {
+			template.BasicConstraintsValid = true
+			template.IsCA = true
+			template.KeyUsage = KeyUsageCertSign | KeyUsageCRLSign
+		} else {
+			template.DNSNames = []string{name}
+			template.ExtKeyUsage = []ExtKeyUsage{ExtKeyUsageServerAuth}
+		}
+		parent, signer := template, key
+		if i > 0 {
+			parent, signer = certs[i-1], keys[i-1]
+		}
+		der, err := CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if certs[i], err = ParseCertificate(der); err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return certs[0], certs[1], certs[2], keys[1]
+}
+
+func TestVerifyCheckRevocation(t *testing.T) {
+	root, intermediate, leaf, _ := revocationTestChain(t)
+	opts := VerifyOptions{
+		DNSName:       "leaf.example.com",
+		Roots:         NewCertPool(),
+		Intermediates: NewCertPool(),
+		CurrentTime:   time.Unix(5000, 0),
+	}
+	opts.Roots.AddCert(root)
+	opts.Intermediates.AddCert(intermediate)
+
+	type check struct{ cert, issuer *Certificate }
+	var checked []check
+	opts.CheckRevocation = func(cert, issuer *Certificate, now time.Time) error {
+		if !now.Equal(opts.CurrentTime) {
+			t.Errorf("CheckRevocation called with time %v, want %v", now, opts.CurrentTime)
+		}
+		checked = append(checked, check{cert, issuer})
+		return nil
+	}
+	chains, err := leaf.Verify(opts)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 3 {
+		t.Fatalf("unexpected chains: %v", chains)
+	}
+	want := []check{{leaf, intermediate}, {intermediate, root}}
+	if len(checked) != len(want) || checked[0] != want[0] || checked[1] != want[1] {
+		t.Errorf("CheckRevocation called with %v, want %v", checked, want)
+	}
+
+	hookErr := errors.New("revocation status unavailable")
+	opts.CheckRevocation = func(cert, issuer *Certificate, now time.Time) error {
+		if cert == intermediate {
+			return hookErr
+		}
+		return nil
+	}
+	if _, err := leaf.Verify(opts); err != hookErr {
+		t.Errorf("Verify returned %v, want %v", err, hookErr)
+	}
+
+	opts.KeyUsages = []ExtKeyUsage{ExtKeyUsageAny}
+	if _, err := leaf.Verify(opts); err != hookErr {
+		t.Errorf("Verify with ExtKeyUsageAny returned %v, want %v", err, hookErr)
+	}
+}
+
+func TestCRLRevocationChecker(t *testing.T) {
+	root, intermediate, leaf, intermediateKey := revocationTestChain(t)
+	opts := VerifyOptions{
+		DNSName:       "leaf.example.com",
+		Roots:         NewCertPool(),
+		Intermediates: NewCertPool(),
+		CurrentTime:   time.Unix(5000, 0),
+	}
+	opts.Roots.AddCert(root)
+	opts.Intermediates.AddCert(intermediate)
+
+	makeCRL := func(serial int64, thisUpdate, nextUpdate int64, exts ...pkix.Extension) *pkix.CertificateList {
+		der, err := CreateRevocationList(rand.Reader, &RevocationList{
+			RevokedCertificates: []pkix.RevokedCertificate{
+				{SerialNumber: big.NewInt(serial), RevocationTime: time.Unix(2000, 0)},
+			},
+			Number:          big.NewInt(1),
+			ThisUpdate:      time.Unix(thisUpdate, 0),
+			NextUpdate:      time.Unix(nextUpdate, 0),
+			ExtraExtensions: exts,
+		}, intermediate, intermediateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := ParseDERCRL(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crl
+	}
+
+	const (
+		crlOK = iota
+		crlRevoked
+		crlUnusable
+	)
+	revoking := makeCRL(leaf.SerialNumber.Int64(), 4000, 6000)
+	stale := makeCRL(99, 1000, 2000)
+	delta := makeCRL(99, 4000, 6000, pkix.Extension{
+		Id:       asn1.ObjectIdentifier{2, 5, 29, 27},
+		Critical: true,
+		Value:    []byte{2, 1, 1},
+	})
+	tests := []struct {
+		crls []*pkix.CertificateList
+		want int
+	}{
+		{nil, crlOK},
+		{[]*pkix.CertificateList{makeCRL(99, 4000, 6000)}, crlOK},
+		{[]*pkix.CertificateList{revoking}, crlRevoked},
+		{[]*pkix.CertificateList{makeCRL(99, 4000, 6000), revoking}, crlRevoked},
+		// Stale, future and delta CRLs can't be used, and with no usable
+		// CRL from the issuer, the leaf's status is unknown.
+		{[]*pkix.CertificateList{stale}, crlUnusable},
+		{[]*pkix.CertificateList{makeCRL(99, 6000, 7000)}, crlUnusable},
+		{[]*pkix.CertificateList{delta}, crlUnusable},
+		// They are ignored if a usable CRL is present.
+		{[]*pkix.CertificateList{stale, delta, makeCRL(99, 4000, 6000)}, crlOK},
+		{[]*pkix.CertificateList{stale, revoking}, crlRevoked},
+		// A CRL from the intermediate doesn't apply to certificates issued
+		// by the root, even if the serial number matches.
+		{[]*pkix.CertificateList{makeCRL(intermediate.SerialNumber.Int64(), 4000, 6000)}, crlOK},
+	}
+	for i, test := range tests {
+		opts.CheckRevocation = CRLRevocationChecker(test.crls)
+		_, err := leaf.Verify(opts)
+		switch test.want {
+		case crlOK:
+			if err != nil {
+				t.Errorf("#%d: Verify: %v", i, err)
+			}
+		case crlRevoked:
+			invalid, isInvalid := err.(CertificateInvalidError)
+			if !isInvalid || invalid.Reason != Revoked || invalid.Cert != leaf {
+				t.Errorf("#%d: Verify returned %v, want a revocation error for the leaf", i, err)
+			}
+		case crlUnusable:
+			if _, isInvalid := err.(CertificateInvalidError); err == nil || isInvalid {
+				t.Errorf("#%d: Verify returned %v, want an unusable CRL error", i, err)
+			}
+		}
+	}
+}
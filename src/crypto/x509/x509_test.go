@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto/dsa"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
@@ -74,6 +75,70 @@ func TestParsePKIXPublicKey(t *testing.T) {
 	}
 }
 
+// Generated using:
+//   openssl genpkey -algorithm ed25519 | openssl pkey -pubout
+var pemEd25519PublicKey = `-----BEGIN PUBLIC KEY-----
+MCowBQYDK2VwAyEAC8giFYsnn8jEpQLLTIdCYkBykskv9tLfjk8ld61Dj+I=
+-----END PUBLIC KEY-----
+`
+
+func TestParsePKIXEd25519PublicKey(t *testing.T) {
+	block, _ := pem.Decode([]byte(pemEd25519PublicKey))
+	pub, err := ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse Ed25519 public key: %s", err)
+	}
+	if _, ok := pub.(ed25519.PublicKey); !ok {
+		t.Fatalf("Value returned from ParsePKIXPublicKey was %T, want ed25519.PublicKey", pub)
+	}
+
+	pubBytes2, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 public key: %s", err)
+	}
+	if !bytes.Equal(pubBytes2, block.Bytes) {
+		t.Errorf("Reserialization of public key didn't match. got %x, want %x", pubBytes2, block.Bytes)
+	}
+}
+
+// ed25519Certificate is a self-signed certificate for the key in
+// pemEd25519PublicKey, generated with OpenSSL.
+const ed25519Certificate = `-----BEGIN CERTIFICATE-----
+MIIBMTCB5KADAgECAgEBMAUGAytlcDAXMRUwEwYDVQQDDAxFZDI1NTE5IHRlc3Qw
+IBcNMjYxMDE4MTMyMjAzWhgPMjEyNjA5MjQxMzIyMDNaMBcxFTATBgNVBAMMDEVk
+MjU1MTkgdGVzdDAqMAUGAytlcAMhAAvIIhWLJ5/IxKUCy0yHQmJAcpLJL/bS345P
+JXetQ4/io1MwUTAdBgNVHQ4EFgQUqxW2aOlczbEqxVuUmLrcwO8xfhYwHwYDVR0j
+BBgwFoAUqxW2aOlczbEqxVuUmLrcwO8xfhYwDwYDVR0TAQH/BAUwAwEB/zAFBgMr
+ZXADQQC4X94ogdWRaRSHSiDK46saZfD2NV7mZ7OLILncarjcS8uq4gNgWRymkHhT
+L3Hlp1AouMDEBqVVb9ITj1x31OQA
+-----END CERTIFICATE-----
+`
+
+func TestEd25519SelfSigned(t *testing.T) {
+	der, _ := pem.Decode([]byte(ed25519Certificate))
+	if der == nil {
+		t.Fatalf("Failed to decode certificate")
+	}
+	cert, err := ParseCertificate(der.Bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %s", err)
+	}
+	if cert.PublicKeyAlgorithm != Ed25519 {
+		t.Fatalf("Parsed key algorithm was not Ed25519")
+	}
+	if cert.SignatureAlgorithm != PureEd25519 {
+		t.Fatalf("Parsed signature algorithm was not PureEd25519")
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatalf("Signature check failed: %s", err)
+	}
+
+	cert.Signature[0] ^= 0x80
+	if err := cert.CheckSignatureFrom(cert); err == nil {
+		t.Fatalf("Signature check accepted a modified signature")
+	}
+}
+
 var pemPublicKey = `-----BEGIN PUBLIC KEY-----
 MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA3VoPN9PKUjKFLMwOge6+
 wnDi8sbETGIx2FKXGgqtAKpzmem53kRGEQg8WeqRmp12wgp74TGpkEXsGae7RS1k
@@ -325,6 +390,11 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		t.Fatalf("Failed to generate ECDSA key: %s", err)
 	}
 
+	ed25519Pub, ed25519Priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %s", err)
+	}
+
 	tests := []struct {
 		name      string
 		pub, priv interface{}
@@ -335,6 +405,9 @@ func TestCreateSelfSignedCertificate(t *testing.T) {
 		{"RSA/ECDSA", &rsaPriv.PublicKey, ecdsaPriv, false, ECDSAWithSHA384},
 		{"ECDSA/RSA", &ecdsaPriv.PublicKey, rsaPriv, false, SHA256WithRSA},
 		{"ECDSA/ECDSA", &ecdsaPriv.PublicKey, ecdsaPriv, true, ECDSAWithSHA1},
+		{"Ed25519/Ed25519", ed25519Pub, ed25519Priv, true, PureEd25519},
+		{"RSA/Ed25519", &rsaPriv.PublicKey, ed25519Priv, false, PureEd25519},
+		{"Ed25519/ECDSA", ed25519Pub, ecdsaPriv, false, ECDSAWithSHA256},
 	}
 
 	testExtKeyUsage := []ExtKeyUsage{ExtKeyUsageClientAuth, ExtKeyUsageServerAuth}
@@ -799,6 +872,122 @@ func TestCRLCreation(t *testing.T) {
 	}
 }
 
+func TestCreateRevocationList(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerTemplate := &Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CRL issuer"},
+		NotBefore:             time.Unix(1000, 0),
+		NotAfter:              time.Unix(100000, 0),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:         true,
+		SubjectKeyId: []byte{1, 2, 3, 4},
+	}
+	issuerDER, err := CreateCertificate(rand.Reader, issuerTemplate, issuerTemplate, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, err := ParseCertificate(issuerDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extraExt := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte{5, 0}}
+	template := &RevocationList{
+		RevokedCertificates: []pkix.RevokedCertificate{
+			{SerialNumber: big.NewInt(2), RevocationTime: time.Unix(2000, 0)},
+			{SerialNumber: big.NewInt(42), RevocationTime: time.Unix(3000, 0)},
+		},
+		Number:          big.NewInt(5),
+		ThisUpdate:      time.Unix(4000, 0),
+		NextUpdate:      time.Unix(5000, 0),
+		ExtraExtensions: []pkix.Extension{extraExt},
+	}
+	crlDER, err := CreateRevocationList(rand.Reader, template, issuer, priv)
+	if err != nil {
+		t.Fatalf("CreateRevocationList: %v", err)
+	}
+
+	crl, err := ParseDERCRL(crlDER)
+	if err != nil {
+		t.Fatalf("ParseDERCRL: %v", err)
+	}
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		t.Errorf("CheckCRLSignature: %v", err)
+	}
+	tbs := crl.TBSCertList
+	if tbs.Version != 1 {
+		t.Errorf("Version = %d, want 1", tbs.Version)
+	}
+	if !tbs.ThisUpdate.Equal(template.ThisUpdate) || !tbs.NextUpdate.Equal(template.NextUpdate) {
+		t.Errorf("got update times %v, %v", tbs.ThisUpdate, tbs.NextUpdate)
+	}
+	if len(tbs.RevokedCertificates) != 2 || tbs.RevokedCertificates[1].SerialNumber.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("unexpected revoked certificates: %v", tbs.RevokedCertificates)
+	}
+	issuerName, err := asn1.Marshal(tbs.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(issuerName, issuer.RawSubject) {
+		t.Errorf("CRL issuer %x does not match certificate subject %x", issuerName, issuer.RawSubject)
+	}
+
+	if len(tbs.Extensions) != 3 {
+		t.Fatalf("got %d extensions, want 3", len(tbs.Extensions))
+	}
+	var aki authKeyId
+	if !tbs.Extensions[0].Id.Equal(oidExtensionAuthorityKeyId) {
+		t.Errorf("first extension is %v, want authority key id", tbs.Extensions[0].Id)
+	} else if _, err := asn1.Unmarshal(tbs.Extensions[0].Value, &aki); err != nil || !bytes.Equal(aki.Id, issuer.SubjectKeyId) {
+		t.Errorf("bad authority key id %x: %v", aki.Id, err)
+	}
+	var number *big.Int
+	if !tbs.Extensions[1].Id.Equal(oidExtensionCRLNumber) {
+		t.Errorf("second extension is %v, want CRL number", tbs.Extensions[1].Id)
+	} else if _, err := asn1.Unmarshal(tbs.Extensions[1].Value, &number); err != nil || number.Cmp(template.Number) != 0 {
+		t.Errorf("bad CRL number %v: %v", number, err)
+	}
+	if !reflect.DeepEqual(tbs.Extensions[2], extraExt) {
+		t.Errorf("got extension %v, want %v", tbs.Extensions[2], extraExt)
+	}
+
+	// Without revoked certificates or a next update both are omitted.
+	crlDER, err = CreateRevocationList(rand.Reader, &RevocationList{Number: big.NewInt(1), ThisUpdate: time.Unix(4000, 0)}, issuer, priv)
+	if err != nil {
+		t.Fatalf("CreateRevocationList: %v", err)
+	}
+	if crl, err = ParseDERCRL(crlDER); err != nil {
+		t.Fatalf("ParseDERCRL: %v", err)
+	}
+	if len(crl.TBSCertList.RevokedCertificates) != 0 || !crl.TBSCertList.NextUpdate.IsZero() {
+		t.Errorf("unexpected contents: %+v", crl.TBSCertList)
+	}
+
+	noCRLSign := *issuer
+	noCRLSign.KeyUsage = KeyUsageCertSign
+	errorTests := []struct {
+		template *RevocationList
+		issuer   *Certificate
+	}{
+		{nil, issuer},
+		{template, nil},
+		{template, &noCRLSign},
+		{&RevocationList{ThisUpdate: time.Unix(4000, 0)}, issuer},
+		{&RevocationList{Number: big.NewInt(1), ThisUpdate: time.Unix(4000, 0), NextUpdate: time.Unix(3000, 0)}, issuer},
+		{&RevocationList{Number: big.NewInt(1), SignatureAlgorithm: SHA256WithRSA}, issuer},
+	}
+	for i, test := range errorTests {
+		if _, err := CreateRevocationList(rand.Reader, test.template, test.issuer, priv); err == nil {
+			t.Errorf("#%d: CreateRevocationList succeeded", i)
+		}
+	}
+}
+
 func fromBase64(in string) []byte {
 	out := make([]byte, base64.StdEncoding.DecodedLen(len(in)))
 	n, err := base64.StdEncoding.Decode(out, []byte(in))
@@ -895,6 +1084,11 @@ func TestCreateCertificateRequest(t *testing.T) {
 		t.Fatalf("Failed to generate ECDSA key: %s", err)
 	}
 
+	_, ed25519Priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %s", err)
+	}
+
 	tests := []struct {
 		name    string
 		priv    interface{}
@@ -904,6 +1098,7 @@ func TestCreateCertificateRequest(t *testing.T) {
 		{"ECDSA-256", ecdsa256Priv, ECDSAWithSHA1},
 		{"ECDSA-384", ecdsa384Priv, ECDSAWithSHA1},
 		{"ECDSA-521", ecdsa521Priv, ECDSAWithSHA1},
+		{"Ed25519", ed25519Priv, PureEd25519},
 	}
 
 	for _, test := range tests {
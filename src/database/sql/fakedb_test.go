@@ -5,11 +5,13 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -32,10 +34,14 @@ var _ = log.Printf
 //     where types are: "string", [u]int{8,16,32,64}, "bool"
 //   INSERT|<tablename>|col=val,col2=val2,col3=?
 //   SELECT|<tablename>|projectcol1,projectcol2|filtercol=?,filtercol2=?
+//   SELECT|<tablename>|projectcol1,projectcol2|filtercol=?param1,filtercol2=?param2
 //
 // Any of these can be preceded by PANIC|<method>|, to cause the
 // named method on fakeStmt to panic.
 //
+// Several SELECT statements may be separated by ';' to return
+// multiple result sets from a single query.
+//
 // When opening a fakeDriver's database, it starts empty with no
 // tables.  All tables and data are stored in memory only.
 type fakeDriver struct {
@@ -105,7 +111,8 @@ func (c *fakeConn) incrStat(v *int) {
 }
 
 type fakeTx struct {
-	c *fakeConn
+	c        *fakeConn
+	readOnly bool
 }
 
 type fakeStmt struct {
@@ -123,9 +130,19 @@ type fakeStmt struct {
 	colValue     []interface{} // used by INSERT (mix of strings and "?" for bound params)
 	placeholders int           // used by INSERT/SELECT: number of ? params
 
-	whereCol []string // used by SELECT (all placeholders)
+	whereCol []boundCol // used by SELECT (all placeholders)
 
 	placeholderConverter []driver.ValueConverter // used by INSERT
+
+	next *fakeStmt // used for returning multiple results.
+}
+
+// boundCol is a where column of a SELECT statement and the
+// placeholder it is compared with.
+type boundCol struct {
+	Column      string
+	Placeholder string // "?" or "?name"
+	Ordinal     int    // position of the placeholder in the query, starting from one
 }
 
 var fdriver driver.Driver = &fakeDriver{}
@@ -292,6 +309,22 @@ func (c *fakeConn) Begin() (driver.Tx, error) {
 	return c.currTx, nil
 }
 
+// BeginTx supports the default and serializable isolation levels,
+// and read-only transactions, which reject writes.
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	switch IsolationLevel(opts.Isolation) {
+	case LevelDefault, LevelSerializable:
+	default:
+		return nil, fmt.Errorf("fakedb: unsupported isolation level %v", IsolationLevel(opts.Isolation))
+	}
+	tx, err := c.Begin()
+	if err != nil {
+		return nil, err
+	}
+	tx.(*fakeTx).readOnly = opts.ReadOnly
+	return tx, nil
+}
+
 var hookPostCloseConn struct {
 	sync.Mutex
 	fn func(*fakeConn, error)
@@ -342,18 +375,28 @@ func (c *fakeConn) Close() (err error) {
 	return nil
 }
 
-func checkSubsetTypes(args []driver.Value) error {
-	for n, arg := range args {
-		switch arg.(type) {
+func checkSubsetTypes(args []driver.NamedValue) error {
+	for _, arg := range args {
+		switch arg.Value.(type) {
 		case int64, float64, bool, nil, []byte, string, time.Time:
 		default:
-			return fmt.Errorf("fakedb_test: invalid argument #%d: %v, type %T", n+1, arg, arg)
+			return fmt.Errorf("fakedb_test: invalid argument ordinal %d: %v, type %T", arg.Ordinal, arg.Value, arg.Value)
 		}
 	}
 	return nil
 }
 
-func (c *fakeConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+// valuesToNamedValues converts the arguments of the legacy
+// driver.Stmt methods to their named form.
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	nargs := make([]driver.NamedValue, len(args))
+	for n, v := range args {
+		nargs[n] = driver.NamedValue{Ordinal: n + 1, Value: v}
+	}
+	return nargs
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
 	// This is an optional interface, but it's implemented here
 	// just to check that all the args are of the proper types.
 	// ErrSkip is returned so the caller acts as if we didn't
@@ -365,7 +408,7 @@ func (c *fakeConn) Exec(query string, args []driver.Value) (driver.Result, error
 	return nil, driver.ErrSkip
 }
 
-func (c *fakeConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
 	// This is an optional interface, but it's implemented here
 	// just to check that all the args are of the proper types.
 	// ErrSkip is returned so the caller acts as if we didn't
@@ -381,10 +424,10 @@ func errf(msg string, args ...interface{}) error {
 	return errors.New("fakedb: " + fmt.Sprintf(msg, args...))
 }
 
-// parts are table|selectCol1,selectCol2|whereCol=?,whereCol2=?
+// parts are table|selectCol1,selectCol2|whereCol=?,whereCol2=?name
 // (note that where columns must always contain ? marks,
 //  just a limitation for fakedb)
-func (c *fakeConn) prepareSelect(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
+func (c *fakeConn) prepareSelect(stmt *fakeStmt, parts []string) (*fakeStmt, error) {
 	if len(parts) != 3 {
 		stmt.Close()
 		return nil, errf("invalid SELECT syntax with %d parts; want 3", len(parts))
@@ -406,19 +449,19 @@ func (c *fakeConn) prepareSelect(stmt *fakeStmt, parts []string) (driver.Stmt, e
 			stmt.Close()
 			return nil, errf("SELECT on table %q references non-existent column %q", stmt.table, column)
 		}
-		if value != "?" {
+		if !strings.HasPrefix(value, "?") {
 			stmt.Close()
 			return nil, errf("SELECT on table %q has pre-bound value for where column %q; need a question mark",
 				stmt.table, column)
 		}
-		stmt.whereCol = append(stmt.whereCol, column)
 		stmt.placeholders++
+		stmt.whereCol = append(stmt.whereCol, boundCol{Column: column, Placeholder: value, Ordinal: stmt.placeholders})
 	}
 	return stmt, nil
 }
 
 // parts are table|col=type,col2=type2
-func (c *fakeConn) prepareCreate(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
+func (c *fakeConn) prepareCreate(stmt *fakeStmt, parts []string) (*fakeStmt, error) {
 	if len(parts) != 2 {
 		stmt.Close()
 		return nil, errf("invalid CREATE syntax with %d parts; want 2", len(parts))
@@ -437,7 +480,7 @@ func (c *fakeConn) prepareCreate(stmt *fakeStmt, parts []string) (driver.Stmt, e
 }
 
 // parts are table|col=?,col2=val
-func (c *fakeConn) prepareInsert(stmt *fakeStmt, parts []string) (driver.Stmt, error) {
+func (c *fakeConn) prepareInsert(stmt *fakeStmt, parts []string) (*fakeStmt, error) {
 	if len(parts) != 2 {
 		stmt.Close()
 		return nil, errf("invalid INSERT syntax with %d parts; want 2", len(parts))
@@ -499,6 +542,35 @@ func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
 		return nil, driver.ErrBadConn
 	}
 
+	var firstStmt, prev *fakeStmt
+	for _, query := range strings.Split(query, ";") {
+		stmt, err := c.prepareOne(query)
+		if err != nil {
+			if firstStmt != nil {
+				firstStmt.Close()
+			}
+			return nil, err
+		}
+		if firstStmt == nil {
+			firstStmt = stmt
+		} else {
+			if stmt.cmd != "SELECT" || prev.cmd != "SELECT" {
+				firstStmt.Close()
+				stmt.Close()
+				return nil, errf("only SELECT statements may be combined with ';'")
+			}
+			// Number the placeholders across the whole query.
+			for i := range stmt.whereCol {
+				stmt.whereCol[i].Ordinal += firstStmt.NumInput()
+			}
+			prev.next = stmt
+		}
+		prev = stmt
+	}
+	return firstStmt, nil
+}
+
+func (c *fakeConn) prepareOne(query string) (*fakeStmt, error) {
 	parts := strings.Split(query, "|")
 	if len(parts) < 1 {
 		return nil, errf("empty query")
@@ -557,6 +629,9 @@ func (s *fakeStmt) Close() error {
 		s.c.incrStat(&s.c.stmtsClosed)
 		s.closed = true
 	}
+	if s.next != nil {
+		s.next.Close()
+	}
 	return nil
 }
 
@@ -566,6 +641,10 @@ var errClosed = errors.New("fakedb: statement has been closed")
 var hookExecBadConn func() bool
 
 func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *fakeStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
 	if s.panic == "Exec" {
 		panic(s.panic)
 	}
@@ -581,6 +660,12 @@ func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.next != nil {
+		return nil, errf("Exec of multiple statements is not supported")
+	}
+	if tx := s.c.currTx; tx != nil && tx.readOnly {
+		return nil, errf("%s in a read-only transaction", s.cmd)
+	}
 
 	db := s.c.db
 	switch s.cmd {
@@ -606,7 +691,7 @@ func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
 // When doInsert is true, add the row to the table.
 // When doInsert is false do prep-work and error checking, but don't
 // actually add the row to the table.
-func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result, error) {
+func (s *fakeStmt) execInsert(args []driver.NamedValue, doInsert bool) (driver.Result, error) {
 	db := s.c.db
 	if len(args) != s.placeholders {
 		panic("error in pkg db; should only get here if size is correct")
@@ -633,7 +718,7 @@ func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result
 		}
 		var val interface{}
 		if strvalue, ok := s.colValue[n].(string); ok && strvalue == "?" {
-			val = args[argPos]
+			val = args[argPos].Value
 			argPos++
 		} else {
 			val = s.colValue[n]
@@ -653,6 +738,10 @@ func (s *fakeStmt) execInsert(args []driver.Value, doInsert bool) (driver.Result
 var hookQueryBadConn func() bool
 
 func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *fakeStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
 	if s.panic == "Query" {
 		panic(s.panic)
 	}
@@ -669,22 +758,63 @@ func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 		return nil, err
 	}
 
-	db := s.c.db
-	if len(args) != s.placeholders {
+	if len(args) != s.NumInput() {
 		panic("error in pkg db; should only get here if size is correct")
 	}
 
+	cursor := &rowsCursor{
+		posRow: -1,
+		errPos: -1,
+	}
+	for ; s != nil; s = s.next {
+		mrows, colType, err := s.queryOne(args)
+		if err != nil {
+			return nil, err
+		}
+		cursor.rows = append(cursor.rows, mrows)
+		cursor.cols = append(cursor.cols, s.colName)
+		cursor.colType = append(cursor.colType, colType)
+	}
+	return cursor, nil
+}
+
+// queryOne returns the rows selected by a single SELECT statement
+// and the types of the selected columns.
+func (s *fakeStmt) queryOne(args []driver.NamedValue) ([]*row, []string, error) {
+	db := s.c.db
 	db.mu.Lock()
 	t, ok := db.table(s.table)
 	db.mu.Unlock()
 	if !ok {
-		return nil, fmt.Errorf("fakedb: table %q doesn't exist", s.table)
+		return nil, nil, fmt.Errorf("fakedb: table %q doesn't exist", s.table)
+	}
+
+	// Resolve each where column to its argument, by name if the
+	// placeholder has one and by position otherwise.
+	whereArgs := make([]interface{}, len(s.whereCol))
+	for widx, wcol := range s.whereCol {
+		found := false
+		if name := wcol.Placeholder[1:]; name != "" {
+			for _, arg := range args {
+				if arg.Name == name {
+					whereArgs[widx] = arg.Value
+					found = true
+					break
+				}
+			}
+		} else {
+			whereArgs[widx] = args[wcol.Ordinal-1].Value
+			found = true
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("fakedb: no argument for placeholder %q", wcol.Placeholder)
+		}
 	}
 
 	if s.table == "magicquery" {
-		if len(s.whereCol) == 2 && s.whereCol[0] == "op" && s.whereCol[1] == "millis" {
-			if args[0] == "sleep" {
-				time.Sleep(time.Duration(args[1].(int64)) * time.Millisecond)
+		if len(s.whereCol) == 2 && s.whereCol[0].Column == "op" && s.whereCol[1].Column == "millis" {
+			if whereArgs[0] == "sleep" {
+				time.Sleep(time.Duration(whereArgs[1].(int64)) * time.Millisecond)
 			}
 		}
 	}
@@ -693,12 +823,14 @@ func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
 	defer t.mu.Unlock()
 
 	colIdx := make(map[string]int) // select column name -> column index in table
-	for _, name := range s.colName {
+	colType := make([]string, len(s.colName))
+	for i, name := range s.colName {
 		idx := t.columnIndex(name)
 		if idx == -1 {
-			return nil, fmt.Errorf("fakedb: unknown column name %q", name)
+			return nil, nil, fmt.Errorf("fakedb: unknown column name %q", name)
 		}
 		colIdx[name] = idx
+		colType[i] = t.coltype[idx]
 	}
 
 	mrows := []*row{}
@@ -708,16 +840,16 @@ rows:
 		// and just uses fmt.Sprintf("%v") to test equality.  Good enough
 		// for test code.
 		for widx, wcol := range s.whereCol {
-			idx := t.columnIndex(wcol)
+			idx := t.columnIndex(wcol.Column)
 			if idx == -1 {
-				return nil, fmt.Errorf("db: invalid where clause column %q", wcol)
+				return nil, nil, fmt.Errorf("db: invalid where clause column %q", wcol.Column)
 			}
 			tcol := trow.cols[idx]
 			if bs, ok := tcol.([]byte); ok {
 				// lazy hack to avoid sprintf %v on a []byte
 				tcol = string(bs)
 			}
-			if fmt.Sprintf("%v", tcol) != fmt.Sprintf("%v", args[widx]) {
+			if fmt.Sprintf("%v", tcol) != fmt.Sprintf("%v", whereArgs[widx]) {
 				continue rows
 			}
 		}
@@ -727,21 +859,18 @@ rows:
 		}
 		mrows = append(mrows, mrow)
 	}
-
-	cursor := &rowsCursor{
-		pos:    -1,
-		rows:   mrows,
-		cols:   s.colName,
-		errPos: -1,
-	}
-	return cursor, nil
+	return mrows, colType, nil
 }
 
 func (s *fakeStmt) NumInput() int {
 	if s.panic == "NumInput" {
 		panic(s.panic)
 	}
-	return s.placeholders
+	n := 0
+	for ; s != nil; s = s.next {
+		n += s.placeholders
+	}
+	return n
 }
 
 // hook to simulate broken connections
@@ -767,10 +896,12 @@ func (tx *fakeTx) Rollback() error {
 }
 
 type rowsCursor struct {
-	cols   []string
-	pos    int
-	rows   []*row
-	closed bool
+	cols    [][]string
+	colType [][]string
+	posSet  int
+	posRow  int
+	rows    [][]*row
+	closed  bool
 
 	// errPos and err are for making Next return early with error.
 	errPos int
@@ -793,7 +924,19 @@ func (rc *rowsCursor) Close() error {
 }
 
 func (rc *rowsCursor) Columns() []string {
-	return rc.cols
+	return rc.cols[rc.posSet]
+}
+
+func (rc *rowsCursor) ColumnTypeScanType(index int) reflect.Type {
+	return colTypeToReflectType(rc.colType[rc.posSet][index])
+}
+
+func (rc *rowsCursor) ColumnTypeDatabaseTypeName(index int) string {
+	return strings.ToUpper(rc.colType[rc.posSet][index])
+}
+
+func (rc *rowsCursor) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return strings.HasPrefix(rc.colType[rc.posSet][index], "null"), true
 }
 
 var rowsCursorNextHook func(dest []driver.Value) error
@@ -806,14 +949,14 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	if rc.closed {
 		return errors.New("fakedb: cursor is closed")
 	}
-	rc.pos++
-	if rc.pos == rc.errPos {
+	rc.posRow++
+	if rc.posRow == rc.errPos {
 		return rc.err
 	}
-	if rc.pos >= len(rc.rows) {
+	if rc.posRow >= len(rc.rows[rc.posSet]) {
 		return io.EOF // per interface spec
 	}
-	for i, v := range rc.rows[rc.pos].cols {
+	for i, v := range rc.rows[rc.posSet][rc.posRow].cols {
 		// TODO(bradfitz): convert to subset types? naah, I
 		// think the subset types should only be input to
 		// driver, but the sql package should be able to handle
@@ -838,6 +981,19 @@ func (rc *rowsCursor) Next(dest []driver.Value) error {
 	return nil
 }
 
+func (rc *rowsCursor) HasNextResultSet() bool {
+	return rc.posSet < len(rc.rows)-1
+}
+
+func (rc *rowsCursor) NextResultSet() error {
+	if rc.HasNextResultSet() {
+		rc.posSet++
+		rc.posRow = -1
+		return nil
+	}
+	return io.EOF // per interface spec
+}
+
 // fakeDriverString is like driver.String, but indirects pointers like
 // DefaultValueConverter.
 //
@@ -889,3 +1045,31 @@ func converterForType(typ string) driver.ValueConverter {
 	}
 	panic("invalid fakedb column type of " + typ)
 }
+
+func colTypeToReflectType(typ string) reflect.Type {
+	switch typ {
+	case "bool":
+		return reflect.TypeOf(false)
+	case "nullbool":
+		return reflect.TypeOf(NullBool{})
+	case "int32":
+		return reflect.TypeOf(int32(0))
+	case "string":
+		return reflect.TypeOf("")
+	case "nullstring":
+		return reflect.TypeOf(NullString{})
+	case "int64":
+		return reflect.TypeOf(int64(0))
+	case "nullint64":
+		return reflect.TypeOf(NullInt64{})
+	case "float64":
+		return reflect.TypeOf(float64(0))
+	case "nullfloat64":
+		return reflect.TypeOf(NullFloat64{})
+	case "datetime":
+		return reflect.TypeOf(time.Time{})
+	case "blob":
+		return reflect.TypeOf([]byte(nil))
+	}
+	panic("invalid fakedb column type of " + typ)
+}
@@ -0,0 +1,15 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dbstats lets database/sql/sqlexpvar learn about the DBs
+// opened by database/sql without database/sql depending on expvar.
+package dbstats
+
+// Register, if non-nil, is called by sql.Open for every new DB with
+// the name of its driver and a function that returns the DB's
+// statistics. The returned function is called when the DB is closed.
+//
+// Register is set during package initialization and must not be
+// changed afterwards.
+var Register func(driverName string, stats func() interface{}) (unregister func())
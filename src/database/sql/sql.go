@@ -13,12 +13,16 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
+	"database/sql/internal/dbstats"
 	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -66,6 +70,98 @@ func Drivers() []string {
 	return list
 }
 
+// A NamedArg is a named argument. NamedArg values may be used as
+// arguments to Query or Exec and bind to the corresponding named
+// parameter in the SQL statement.
+//
+// For a more concise way to create NamedArg values, see
+// the Named function.
+type NamedArg struct {
+	_Named_Fields_Required struct{}
+
+	// Name is the name of the parameter placeholder.
+	//
+	// If empty, the ordinal position in the argument list will be
+	// used.
+	//
+	// Name must omit any symbol prefix.
+	Name string
+
+	// Value is the value of the parameter.
+	// It may be assigned the same value types as the query
+	// arguments.
+	Value interface{}
+}
+
+// Named provides a more concise way to create NamedArg values.
+//
+// Example usage:
+//
+//     db.ExecContext(ctx, `
+//         delete from Invoice
+//         where
+//             TimeCreated < @end
+//             and TimeCreated >= @start;`,
+//         sql.Named("start", startTime),
+//         sql.Named("end", endTime),
+//     )
+//
+// Drivers that do not implement driver.ExecerContext, driver.QueryerContext,
+// driver.StmtExecContext or driver.StmtQueryContext as appropriate
+// reject named arguments with an error.
+func Named(name string, value interface{}) NamedArg {
+	// This method exists because the go1compat promise
+	// doesn't guarantee that structs don't grow more fields,
+	// so unkeyed struct literals are a vet error. Thus, we don't
+	// want to allow sql.NamedArg{name, value}.
+	return NamedArg{Name: name, Value: value}
+}
+
+// IsolationLevel is the transaction isolation level used in TxOptions.
+type IsolationLevel int
+
+// Various isolation levels that drivers may support in BeginTx.
+// If a driver does not support a given isolation level an error may be returned.
+//
+// See https://en.wikipedia.org/wiki/Isolation_(database_systems)#Isolation_levels.
+const (
+	LevelDefault IsolationLevel = iota
+	LevelReadUncommitted
+	LevelReadCommitted
+	LevelWriteCommitted
+	LevelRepeatableRead
+	LevelSnapshot
+	LevelSerializable
+	LevelLinearizable
+)
+
+var isolationLevelNames = [...]string{
+	LevelDefault:         "Default",
+	LevelReadUncommitted: "Read Uncommitted",
+	LevelReadCommitted:   "Read Committed",
+	LevelWriteCommitted:  "Write Committed",
+	LevelRepeatableRead:  "Repeatable Read",
+	LevelSnapshot:        "Snapshot",
+	LevelSerializable:    "Serializable",
+	LevelLinearizable:    "Linearizable",
+}
+
+// String returns the name of the transaction isolation level.
+func (i IsolationLevel) String() string {
+	if i < 0 || int(i) >= len(isolationLevelNames) {
+		return "IsolationLevel(" + strconv.Itoa(int(i)) + ")"
+	}
+	return isolationLevelNames[i]
+}
+
+// TxOptions holds the transaction options to be used in DB.BeginTx.
+type TxOptions struct {
+	// Isolation is the transaction isolation level.
+	// If zero, the driver or database's default level is used.
+	Isolation IsolationLevel
+	ReadOnly  bool
+}
+
 // RawBytes is a byte slice that holds a reference to memory owned by
 // the database itself. After a Scan into a RawBytes, the slice is only
 // valid until the next call to Next, Scan, or Close.
@@ -228,6 +324,9 @@ type DB struct {
 	// closed connections. Stmt.openStmt checks it before cleaning closed
 	// connections in Stmt.css.
 	numClosed uint64
+	// waitDuration is an atomic counter of the total time waited for
+	// new connections, in nanoseconds.
+	waitDuration int64
 
 	mu           sync.Mutex // protects following fields
 	freeConn     []*driverConn
@@ -245,7 +344,15 @@ type DB struct {
 	maxIdle     int                    // zero means defaultMaxIdleConns; negative means 0
 	maxOpen     int                    // <= 0 means unlimited
 	maxLifetime time.Duration          // maximum amount of time a connection may be reused
+	maxIdleTime time.Duration          // maximum amount of time a connection may be idle before being closed
 	cleanerCh   chan struct{}
+
+	waitCount         int64 // total number of connections waited for
+	maxIdleClosed     int64 // total number of connections closed due to the idle connection limit
+	maxIdleTimeClosed int64 // total number of connections closed due to the idle time limit
+	maxLifetimeClosed int64 // total number of connections closed due to the connection lifetime limit
+
+	removeStats func() // undoes the registration with dbstats.Register, if any
 }
 
 // connReuseStrategy determines how (*DB).conn returns database connections.
@@ -267,6 +374,9 @@ const (
 type driverConn struct {
 	db        *DB
 	createdAt time.Time
+	// returnedAt is when the connection was last put in the idle
+	// pool; guarded by db.mu.
+	returnedAt time.Time
 
 	sync.Mutex  // guards following
 	ci          driver.Conn
@@ -297,6 +407,15 @@ func (dc *driverConn) expired(timeout time.Duration) bool {
 	return dc.createdAt.Add(timeout).Before(nowFunc())
 }
 
+// idleExpired reports whether dc has been idle for longer than timeout.
+// db.mu must be held.
+func (dc *driverConn) idleExpired(timeout time.Duration) bool {
+	if timeout <= 0 {
+		return false
+	}
+	return dc.returnedAt.Add(timeout).Before(nowFunc())
+}
+
 func (dc *driverConn) prepareLocked(query string) (driver.Stmt, error) {
 	si, err := dc.ci.Prepare(query)
 	if err == nil {
@@ -490,6 +609,9 @@ func Open(driverName, dataSourceName string) (*DB, error) {
 		openerCh: make(chan struct{}, connectionRequestQueueSize),
 		lastPut:  make(map[*driverConn]string),
 	}
+	if dbstats.Register != nil {
+		db.removeStats = dbstats.Register(driverName, func() interface{} { return db.Stats() })
+	}
 	go db.connectionOpener()
 	return db, nil
 }
@@ -500,7 +622,7 @@ func (db *DB) Ping() error {
 	// TODO(bradfitz): give drivers an optional hook to implement
 	// this in a more efficient or more reliable way, if they
 	// have one.
-	dc, err := db.conn(cachedOrNewConn)
+	dc, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		return err
 	}
@@ -533,6 +655,9 @@ func (db *DB) Close() error {
 		close(req)
 	}
 	db.mu.Unlock()
+	if db.removeStats != nil {
+		db.removeStats()
+	}
 	for _, fn := range fns {
 		err1 := fn()
 		if err1 != nil {
@@ -583,6 +708,7 @@ func (db *DB) SetMaxIdleConns(n int) {
 		closing = db.freeConn[maxIdle:]
 		db.freeConn = db.freeConn[:maxIdle]
 	}
+	db.maxIdleClosed += int64(len(closing))
 	db.mu.Unlock()
 	for _, c := range closing {
 		c.Close()
@@ -632,12 +758,49 @@ func (db *DB) SetConnMaxLifetime(d time.Duration) {
 	db.mu.Unlock()
 }
 
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be idle.
+//
+// Expired connections may be closed lazily before reuse.
+//
+// If d <= 0, connections are not closed due to a connection's idle time.
+func (db *DB) SetConnMaxIdleTime(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	db.mu.Lock()
+	// wake cleaner up when idle time is shortened.
+	if d > 0 && d < db.maxIdleTime && db.cleanerCh != nil {
+		select {
+		case db.cleanerCh <- struct{}{}:
+		default:
+		}
+	}
+	db.maxIdleTime = d
+	db.startCleanerLocked()
+	db.mu.Unlock()
+}
+
 // startCleanerLocked starts connectionCleaner if needed.
 func (db *DB) startCleanerLocked() {
-	if db.maxLifetime > 0 && db.numOpen > 0 && db.cleanerCh == nil {
+	if (db.maxLifetime > 0 || db.maxIdleTime > 0) && db.numOpen > 0 && db.cleanerCh == nil {
 		db.cleanerCh = make(chan struct{}, 1)
-		go db.connectionCleaner(db.maxLifetime)
+		go db.connectionCleaner(db.shortestIdleTimeLocked())
+	}
+}
+
+// shortestIdleTimeLocked returns the shorter of the connection
+// lifetime and idle time limits that are set, or zero if neither is.
+func (db *DB) shortestIdleTimeLocked() time.Duration {
+	if db.maxIdleTime <= 0 {
+		return db.maxLifetime
+	}
+	if db.maxLifetime <= 0 {
+		return db.maxIdleTime
+	}
+	if db.maxIdleTime < db.maxLifetime {
+		return db.maxIdleTime
 	}
+	return db.maxLifetime
 }
 
 func (db *DB) connectionCleaner(d time.Duration) {
@@ -651,30 +814,17 @@ func (db *DB) connectionCleaner(d time.Duration) {
 	for {
 		select {
 		case <-t.C:
-		case <-db.cleanerCh: // maxLifetime was changed or db was closed.
+		case <-db.cleanerCh: // maxLifetime or maxIdleTime was changed or db was closed.
 		}
 
 		db.mu.Lock()
-		d = db.maxLifetime
+		d = db.shortestIdleTimeLocked()
 		if db.closed || db.numOpen == 0 || d <= 0 {
 			db.cleanerCh = nil
 			db.mu.Unlock()
 			return
 		}
-
-		expiredSince := nowFunc().Add(-d)
-		var closing []*driverConn
-		for i := 0; i < len(db.freeConn); i++ {
-			c := db.freeConn[i]
-			if c.createdAt.Before(expiredSince) {
-				closing = append(closing, c)
-				last := len(db.freeConn) - 1
-				db.freeConn[i] = db.freeConn[last]
-				db.freeConn[last] = nil
-				db.freeConn = db.freeConn[:last]
-				i--
-			}
-		}
+		closing := db.connectionCleanerRunLocked()
 		db.mu.Unlock()
 
 		for _, c := range closing {
@@ -688,17 +838,65 @@ func (db *DB) connectionCleaner(d time.Duration) {
 	}
 }
 
+// connectionCleanerRunLocked removes the idle connections that have
+// exceeded the lifetime or idle time limits from the pool and returns
+// them for the caller to close.
+func (db *DB) connectionCleanerRunLocked() (closing []*driverConn) {
+	now := nowFunc()
+	for i := 0; i < len(db.freeConn); i++ {
+		c := db.freeConn[i]
+		switch {
+		case db.maxLifetime > 0 && c.createdAt.Before(now.Add(-db.maxLifetime)):
+			db.maxLifetimeClosed++
+		case db.maxIdleTime > 0 && c.returnedAt.Before(now.Add(-db.maxIdleTime)):
+			db.maxIdleTimeClosed++
+		default:
+			continue
+		}
+		closing = append(closing, c)
+		last := len(db.freeConn) - 1
+		db.freeConn[i] = db.freeConn[last]
+		db.freeConn[last] = nil
+		db.freeConn = db.freeConn[:last]
+		i--
+	}
+	return closing
+}
+
 // DBStats contains database statistics.
 type DBStats struct {
-	// OpenConnections is the number of open connections to the database.
-	OpenConnections int
+	MaxOpenConnections int // Maximum number of open connections to the database; zero means unlimited.
+
+	// Pool Status
+	OpenConnections int // The number of established connections both in use and idle.
+	InUse           int // The number of connections currently in use.
+	Idle            int // The number of idle connections.
+
+	// Counters
+	WaitCount         int64         // The total number of connections waited for.
+	WaitDuration      time.Duration // The total time blocked waiting for a new connection.
+	MaxIdleClosed     int64         // The total number of connections closed due to SetMaxIdleConns.
+	MaxIdleTimeClosed int64         // The total number of connections closed due to SetConnMaxIdleTime.
+	MaxLifetimeClosed int64         // The total number of connections closed due to SetConnMaxLifetime.
 }
 
 // Stats returns database statistics.
 func (db *DB) Stats() DBStats {
+	wait := atomic.LoadInt64(&db.waitDuration)
+
 	db.mu.Lock()
 	stats := DBStats{
+		MaxOpenConnections: db.maxOpen,
+
+		Idle:            len(db.freeConn),
 		OpenConnections: db.numOpen,
+		InUse:           db.numOpen - len(db.freeConn),
+
+		WaitCount:         db.waitCount,
+		WaitDuration:      time.Duration(wait),
+		MaxIdleClosed:     db.maxIdleClosed,
+		MaxIdleTimeClosed: db.maxIdleTimeClosed,
+		MaxLifetimeClosed: db.maxLifetimeClosed,
 	}
 	db.mu.Unlock()
 	return stats
@@ -774,12 +972,20 @@ type connRequest struct {
 var errDBClosed = errors.New("sql: database is closed")
 
 // conn returns a newly-opened or cached *driverConn.
-func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
+// If ctx is done before a connection becomes available, conn
+// returns ctx.Err().
+func (db *DB) conn(ctx context.Context, strategy connReuseStrategy) (*driverConn, error) {
 	db.mu.Lock()
 	if db.closed {
 		db.mu.Unlock()
 		return nil, errDBClosed
 	}
+	select {
+	case <-ctx.Done():
+		db.mu.Unlock()
+		return nil, ctx.Err()
+	default:
+	}
 	lifetime := db.maxLifetime
 
 	// Prefer a free connection, if possible.
@@ -789,11 +995,19 @@ func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
 		copy(db.freeConn, db.freeConn[1:])
 		db.freeConn = db.freeConn[:numFree-1]
 		conn.inUse = true
-		db.mu.Unlock()
 		if conn.expired(lifetime) {
+			db.maxLifetimeClosed++
+			db.mu.Unlock()
+			conn.Close()
+			return nil, driver.ErrBadConn
+		}
+		if conn.idleExpired(db.maxIdleTime) {
+			db.maxIdleTimeClosed++
+			db.mu.Unlock()
 			conn.Close()
 			return nil, driver.ErrBadConn
 		}
+		db.mu.Unlock()
 		return conn, nil
 	}
 
@@ -804,12 +1018,38 @@ func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
 		// connectionOpener doesn't block while waiting for the req to be read.
 		req := make(chan connRequest, 1)
 		db.connRequests = append(db.connRequests, req)
+		db.waitCount++
 		db.mu.Unlock()
-		ret, ok := <-req
+
+		waitStart := time.Now()
+		var ret connRequest
+		var ok bool
+		select {
+		case ret, ok = <-req:
+			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
+		case <-ctx.Done():
+			atomic.AddInt64(&db.waitDuration, int64(time.Since(waitStart)))
+			// Withdraw the request. If a connection was handed to
+			// us in the meantime, return it to the pool.
+			db.mu.Lock()
+			db.removeConnRequestLocked(req)
+			db.mu.Unlock()
+			select {
+			case ret, ok := <-req:
+				if ok && ret.err == nil {
+					db.putConn(ret.conn, nil)
+				}
+			default:
+			}
+			return nil, ctx.Err()
+		}
 		if !ok {
 			return nil, errDBClosed
 		}
 		if ret.err == nil && ret.conn.expired(lifetime) {
+			db.mu.Lock()
+			db.maxLifetimeClosed++
+			db.mu.Unlock()
 			ret.conn.Close()
 			return nil, driver.ErrBadConn
 		}
@@ -838,6 +1078,19 @@ func (db *DB) conn(strategy connReuseStrategy) (*driverConn, error) {
 	return dc, nil
 }
 
+// removeConnRequestLocked removes req from the list of pending
+// connection requests, if it is still there.
+func (db *DB) removeConnRequestLocked(req chan connRequest) {
+	for i, r := range db.connRequests {
+		if r == req {
+			copy(db.connRequests[i:], db.connRequests[i+1:])
+			db.connRequests[len(db.connRequests)-1] = nil
+			db.connRequests = db.connRequests[:len(db.connRequests)-1]
+			return
+		}
+	}
+}
+
 var (
 	errConnClosed = errors.New("database/sql: internal sentinel error: conn is closed")
 	errConnBusy   = errors.New("database/sql: internal sentinel error: conn is busy")
@@ -938,10 +1191,14 @@ func (db *DB) putConnDBLocked(dc *driverConn, err error) bool {
 			err:  err,
 		}
 		return true
-	} else if err == nil && !db.closed && db.maxIdleConnsLocked() > len(db.freeConn) {
-		db.freeConn = append(db.freeConn, dc)
-		db.startCleanerLocked()
-		return true
+	} else if err == nil && !db.closed {
+		if db.maxIdleConnsLocked() > len(db.freeConn) {
+			dc.returnedAt = nowFunc()
+			db.freeConn = append(db.freeConn, dc)
+			db.startCleanerLocked()
+			return true
+		}
+		db.maxIdleClosed++
 	}
 	return false
 }
@@ -978,61 +1235,106 @@ func (db *DB) prepare(query string, strategy connReuseStrategy) (*Stmt, error) {
 	// to a connection, and to execute this prepared statement
 	// we either need to use this connection (if it's free), else
 	// get a new connection + re-prepare + execute on that one.
-	dc, err := db.conn(strategy)
+	dc, err := db.conn(context.Background(), strategy)
 	if err != nil {
 		return nil, err
 	}
-	dc.Lock()
-	si, err := dc.prepareLocked(query)
-	dc.Unlock()
+	return db.prepareDC(context.Background(), dc, dc.releaseConn, nil, query)
+}
+
+// prepareDC prepares query on dc. If cg is nil the returned Stmt may
+// later run on other connections from the pool and dc is released;
+// otherwise the Stmt always runs on the connection held by cg.
+func (db *DB) prepareDC(ctx context.Context, dc *driverConn, release func(error), cg stmtConnGrabber, query string) (*Stmt, error) {
+	select {
+	default:
+	case <-ctx.Done():
+		release(ctx.Err())
+		return nil, ctx.Err()
+	}
+	var si driver.Stmt
+	var err error
+	withLock(dc, func() {
+		if cg == nil {
+			si, err = dc.prepareLocked(query)
+		} else {
+			si, err = dc.ci.Prepare(query)
+		}
+	})
 	if err != nil {
-		db.putConn(dc, err)
+		release(err)
 		return nil, err
 	}
+
 	stmt := &Stmt{
-		db:            db,
-		query:         query,
-		css:           []connStmt{{dc, si}},
-		lastNumClosed: atomic.LoadUint64(&db.numClosed),
+		db:    db,
+		query: query,
 	}
-	db.addDep(stmt, stmt)
-	db.putConn(dc, nil)
+	if cg == nil {
+		stmt.css = []connStmt{{dc, si}}
+		stmt.lastNumClosed = atomic.LoadUint64(&db.numClosed)
+		db.addDep(stmt, stmt)
+	} else {
+		stmt.cg = cg
+		stmt.cgds = &driverStmt{Locker: dc, si: si}
+	}
+	release(nil)
 	return stmt, nil
 }
 
 // Exec executes a query without returning any rows.
 // The args are for any placeholder parameters in the query.
 func (db *DB) Exec(query string, args ...interface{}) (Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+//
+// If ctx is done before a connection can be obtained from the pool,
+// ExecContext returns ctx.Err().
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
 	var res Result
 	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		res, err = db.exec(query, args, cachedOrNewConn)
+		res, err = db.exec(ctx, query, args, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.exec(query, args, alwaysNewConn)
+		return db.exec(ctx, query, args, alwaysNewConn)
 	}
 	return res, err
 }
 
-func (db *DB) exec(query string, args []interface{}, strategy connReuseStrategy) (res Result, err error) {
-	dc, err := db.conn(strategy)
+func (db *DB) exec(ctx context.Context, query string, args []interface{}, strategy connReuseStrategy) (Result, error) {
+	dc, err := db.conn(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
+	return db.execDC(ctx, dc, dc.releaseConn, query, args)
+}
+
+// execDC executes query on dc, calling release with the outcome once
+// it is done with the connection.
+func (db *DB) execDC(ctx context.Context, dc *driverConn, release func(error), query string, args []interface{}) (res Result, err error) {
 	defer func() {
-		db.putConn(dc, err)
+		release(err)
 	}()
 
-	if execer, ok := dc.ci.(driver.Execer); ok {
+	execerCtx, ok := dc.ci.(driver.ExecerContext)
+	var execer driver.Execer
+	if !ok {
+		execer, ok = dc.ci.(driver.Execer)
+	}
+	if ok {
 		dargs, err := driverArgs(nil, args)
 		if err != nil {
 			return nil, err
 		}
 		dc.Lock()
-		resi, err := execer.Exec(query, dargs)
+		resi, err := ctxDriverExec(ctx, execerCtx, execer, query, dargs)
 		dc.Unlock()
 		if err != driver.ErrSkip {
 			if err != nil {
@@ -1049,46 +1351,62 @@ func (db *DB) exec(query string, args []interface{}, strategy connReuseStrategy)
 		return nil, err
 	}
 	defer withLock(dc, func() { si.Close() })
-	return resultFromStatement(driverStmt{dc, si}, args...)
+	return resultFromStatement(ctx, driverStmt{dc, si}, args...)
 }
 
 // Query executes a query that returns rows, typically a SELECT.
 // The args are for any placeholder parameters in the query.
 func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+// The args are for any placeholder parameters in the query.
+//
+// If ctx is done before a connection can be obtained from the pool,
+// QueryContext returns ctx.Err(). If ctx is done while the returned
+// Rows are still open, the Rows are closed and Err reports ctx.Err().
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
 	var rows *Rows
 	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		rows, err = db.query(query, args, cachedOrNewConn)
+		rows, err = db.query(ctx, query, args, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.query(query, args, alwaysNewConn)
+		return db.query(ctx, query, args, alwaysNewConn)
 	}
 	return rows, err
 }
 
-func (db *DB) query(query string, args []interface{}, strategy connReuseStrategy) (*Rows, error) {
-	ci, err := db.conn(strategy)
+func (db *DB) query(ctx context.Context, query string, args []interface{}, strategy connReuseStrategy) (*Rows, error) {
+	ci, err := db.conn(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
 
-	return db.queryConn(ci, ci.releaseConn, query, args)
+	return db.queryConn(ctx, ci, ci.releaseConn, query, args)
 }
 
 // queryConn executes a query on the given connection.
 // The connection gets released by the releaseConn function.
-func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, args []interface{}) (*Rows, error) {
-	if queryer, ok := dc.ci.(driver.Queryer); ok {
+// The returned Rows are closed when ctx is done.
+func (db *DB) queryConn(ctx context.Context, dc *driverConn, releaseConn func(error), query string, args []interface{}) (*Rows, error) {
+	queryerCtx, ok := dc.ci.(driver.QueryerContext)
+	var queryer driver.Queryer
+	if !ok {
+		queryer, ok = dc.ci.(driver.Queryer)
+	}
+	if ok {
 		dargs, err := driverArgs(nil, args)
 		if err != nil {
 			releaseConn(err)
 			return nil, err
 		}
 		dc.Lock()
-		rowsi, err := queryer.Query(query, dargs)
+		rowsi, err := ctxDriverQuery(ctx, queryerCtx, queryer, query, dargs)
 		dc.Unlock()
 		if err != driver.ErrSkip {
 			if err != nil {
@@ -1102,6 +1420,7 @@ func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, a
 				releaseConn: releaseConn,
 				rowsi:       rowsi,
 			}
+			rows.initContextClose(ctx)
 			return rows, nil
 		}
 	}
@@ -1115,7 +1434,7 @@ func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, a
 	}
 
 	ds := driverStmt{dc, si}
-	rowsi, err := rowsiFromStatement(ds, args...)
+	rowsi, err := rowsiFromStatement(ctx, ds, args...)
 	if err != nil {
 		dc.Lock()
 		si.Close()
@@ -1132,6 +1451,7 @@ func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, a
 		rowsi:       rowsi,
 		closeStmt:   si,
 	}
+	rows.initContextClose(ctx)
 	return rows, nil
 }
 
@@ -1139,44 +1459,87 @@ func (db *DB) queryConn(dc *driverConn, releaseConn func(error), query string, a
 // QueryRow always returns a non-nil value. Errors are deferred until
 // Row's Scan method is called.
 func (db *DB) QueryRow(query string, args ...interface{}) *Row {
-	rows, err := db.Query(query, args...)
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+// QueryRowContext always returns a non-nil value. Errors are deferred until
+// Row's Scan method is called.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := db.QueryContext(ctx, query, args...)
 	return &Row{rows: rows, err: err}
 }
 
 // Begin starts a transaction. The isolation level is dependent on
 // the driver.
 func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginContext starts a transaction. The isolation level is dependent
+// on the driver. It is equivalent to BeginTx with nil options.
+func (db *DB) BeginContext(ctx context.Context) (*Tx, error) {
+	return db.BeginTx(ctx, nil)
+}
+
+// BeginTx starts a transaction.
+//
+// The provided context is used until the transaction is committed or
+// rolled back. If the context is done before then, the sql package
+// rolls back the transaction and Commit returns an error.
+//
+// The provided TxOptions is optional and may be nil if defaults should be used.
+// If a non-default isolation level is used that the driver doesn't support,
+// or a read-only transaction is requested from a driver that cannot honor
+// it, an error will be returned.
+func (db *DB) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
 	var tx *Tx
 	var err error
 	for i := 0; i < maxBadConnRetries; i++ {
-		tx, err = db.begin(cachedOrNewConn)
+		tx, err = db.begin(ctx, opts, cachedOrNewConn)
 		if err != driver.ErrBadConn {
 			break
 		}
 	}
 	if err == driver.ErrBadConn {
-		return db.begin(alwaysNewConn)
+		return db.begin(ctx, opts, alwaysNewConn)
 	}
 	return tx, err
 }
 
-func (db *DB) begin(strategy connReuseStrategy) (tx *Tx, err error) {
-	dc, err := db.conn(strategy)
+func (db *DB) begin(ctx context.Context, opts *TxOptions, strategy connReuseStrategy) (*Tx, error) {
+	dc, err := db.conn(ctx, strategy)
 	if err != nil {
 		return nil, err
 	}
+	return db.beginDC(ctx, dc, dc.releaseConn, opts)
+}
+
+// beginDC starts a transaction on dc. The transaction calls release
+// when it is committed or rolled back.
+func (db *DB) beginDC(ctx context.Context, dc *driverConn, release func(error), opts *TxOptions) (*Tx, error) {
 	dc.Lock()
-	txi, err := dc.ci.Begin()
+	txi, err := ctxDriverBegin(ctx, opts, dc.ci)
 	dc.Unlock()
 	if err != nil {
-		db.putConn(dc, err)
+		release(err)
 		return nil, err
 	}
-	return &Tx{
-		db:  db,
-		dc:  dc,
-		txi: txi,
-	}, nil
+
+	// Schedule the transaction to rollback when the context is
+	// canceled. The cancel function in Tx will be called after
+	// done is set to true.
+	ctx, cancel := context.WithCancel(ctx)
+	tx := &Tx{
+		db:          db,
+		dc:          dc,
+		releaseConn: release,
+		txi:         txi,
+		cancel:      cancel,
+		ctx:         ctx,
+	}
+	go tx.awaitDone()
+	return tx, nil
 }
 
 // Driver returns the database's underlying driver.
@@ -1184,6 +1547,215 @@ func (db *DB) Driver() driver.Driver {
 	return db.driver
 }
 
+// Conn returns a single connection by either opening a new connection
+// or returning an existing connection from the connection pool. Conn will
+// block until either a connection is returned or ctx is canceled.
+// Queries run on the same Conn will be run in the same database session.
+//
+// Every Conn must be returned to the database pool after use by
+// calling Conn.Close.
+func (db *DB) Conn(ctx context.Context) (*Conn, error) {
+	var dc *driverConn
+	var err error
+	for i := 0; i < maxBadConnRetries; i++ {
+		dc, err = db.conn(ctx, cachedOrNewConn)
+		if err != driver.ErrBadConn {
+			break
+		}
+	}
+	if err == driver.ErrBadConn {
+		dc, err = db.conn(ctx, alwaysNewConn)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Conn{
+		db: db,
+		dc: dc,
+	}
+	return conn, nil
+}
+
+// Conn represents a single database connection rather than a pool of database
+// connections. Prefer running queries from DB unless there is a specific
+// need for a continuous single database connection.
+//
+// A Conn must call Close to return the connection to the database pool
+// and may do so concurrently with a running query.
+//
+// After a call to Close, all operations on the
+// connection fail with ErrConnDone.
+//
+// The statements prepared on a Conn by calling its PrepareContext
+// method are closed by the call to Close.
+type Conn struct {
+	db *DB
+
+	// closemu prevents the connection from closing while there
+	// is an active query. It is held for read during queries
+	// and exclusively during close.
+	closemu sync.RWMutex
+
+	// dc is owned until Close, at which point
+	// it's returned to the connection pool.
+	dc *driverConn
+
+	// done transitions from 0 to 1 exactly once, on Close.
+	// Once done, all operations fail with ErrConnDone.
+	// Use atomic operations on value when checking value.
+	done int32
+
+	// All Stmts prepared on this connection. These will be closed
+	// when the connection is closed.
+	stmts struct {
+		sync.Mutex
+		v []*Stmt
+	}
+}
+
+// ErrConnDone is returned by any operation that is performed on a connection
+// that has already been returned to the connection pool.
+var ErrConnDone = errors.New("sql: connection is already closed")
+
+// grabConn returns the connection. On success, the caller must call
+// c.closemuRUnlockCondReleaseConn once it is done with the connection.
+func (c *Conn) grabConn() (*driverConn, error) {
+	// closemu.RLock must come before the check for done to
+	// prevent the Conn from closing while a query is executing.
+	c.closemu.RLock()
+	if atomic.LoadInt32(&c.done) != 0 {
+		c.closemu.RUnlock()
+		return nil, ErrConnDone
+	}
+	return c.dc, nil
+}
+
+// closemuRUnlockCondReleaseConn is used as the release function of
+// operations on c. A driver.ErrBadConn closes c, discarding the
+// connection.
+func (c *Conn) closemuRUnlockCondReleaseConn(err error) {
+	c.closemu.RUnlock()
+	if err == driver.ErrBadConn {
+		c.close(err)
+	}
+}
+
+func (c *Conn) grabStmtConn() (*driverConn, func(error), error) {
+	dc, err := c.grabConn()
+	if err != nil {
+		return nil, nil, err
+	}
+	return dc, c.closemuRUnlockCondReleaseConn, nil
+}
+
+func (c *Conn) txCtx() context.Context {
+	return context.Background()
+}
+
+// ExecContext executes a query without returning any rows.
+// The args are for any placeholder parameters in the query.
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (Result, error) {
+	dc, err := c.grabConn()
+	if err != nil {
+		return nil, err
+	}
+	return c.db.execDC(ctx, dc, c.closemuRUnlockCondReleaseConn, query, args)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT.
+// The args are for any placeholder parameters in the query.
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	dc, err := c.grabConn()
+	if err != nil {
+		return nil, err
+	}
+	return c.db.queryConn(ctx, dc, c.closemuRUnlockCondReleaseConn, query, args)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+// QueryRowContext always returns a non-nil value. Errors are deferred until
+// Row's Scan method is called.
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	rows, err := c.QueryContext(ctx, query, args...)
+	return &Row{rows: rows, err: err}
+}
+
+// PrepareContext creates a prepared statement for later queries or executions.
+// Multiple queries or executions may be run concurrently from the
+// returned statement.
+// The caller must call the statement's Close method
+// when the statement is no longer needed.
+//
+// The provided context is used for the preparation of the statement, not for the
+// execution of the statement. The returned statement always runs on c.
+func (c *Conn) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	dc, err := c.grabConn()
+	if err != nil {
+		return nil, err
+	}
+	stmt, err := c.db.prepareDC(ctx, dc, c.closemuRUnlockCondReleaseConn, c, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts.Lock()
+	c.stmts.v = append(c.stmts.v, stmt)
+	c.stmts.Unlock()
+	return stmt, nil
+}
+
+// BeginTx starts a transaction on c.
+//
+// The provided context is used until the transaction is committed or rolled back.
+// If the context is canceled, the sql package will roll back
+// the transaction. Tx.Commit will return an error if the context provided to
+// BeginTx is canceled.
+//
+// The provided TxOptions is optional and may be nil if defaults should be used.
+// If a non-default isolation level is used that the driver doesn't support,
+// an error will be returned.
+//
+// Close waits for the transaction to be committed or rolled back.
+func (c *Conn) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	dc, err := c.grabConn()
+	if err != nil {
+		return nil, err
+	}
+	return c.db.beginDC(ctx, dc, c.closemuRUnlockCondReleaseConn, opts)
+}
+
+func (c *Conn) close(err error) error {
+	if !atomic.CompareAndSwapInt32(&c.done, 0, 1) {
+		return ErrConnDone
+	}
+
+	// Lock around releasing the driver connection
+	// to ensure all queries have been stopped before doing so.
+	c.closemu.Lock()
+	defer c.closemu.Unlock()
+
+	c.stmts.Lock()
+	for _, stmt := range c.stmts.v {
+		stmt.Close()
+	}
+	c.stmts.v = nil
+	c.stmts.Unlock()
+
+	c.dc.releaseConn(err)
+	c.dc = nil
+	c.db = nil
+	return err
+}
+
+// Close returns the connection to the connection pool.
+// All operations after a Close will return with ErrConnDone.
+// Close is safe to call concurrently with other operations and will
+// block until all other operations finish. It may be useful to first
+// cancel any used context and then call close directly after.
+func (c *Conn) Close() error {
+	return c.close(nil)
+}
+
 // Tx is an in-progress database transaction.
 //
 // A transaction must end with a call to Commit or Rollback.
@@ -1197,15 +1769,22 @@ func (db *DB) Driver() driver.Driver {
 type Tx struct {
 	db *DB
 
+	// closemu prevents the transaction from closing while there
+	// is an active query. It is held for read during queries
+	// and exclusively during close.
+	closemu sync.RWMutex
+
 	// dc is owned exclusively until Commit or Rollback, at which point
-	// it's returned with putConn.
-	dc  *driverConn
-	txi driver.Tx
+	// it's returned with releaseConn.
+	dc          *driverConn
+	releaseConn func(error)
+	txi         driver.Tx
 
-	// done transitions from false to true exactly once, on Commit
+	// done transitions from 0 to 1 exactly once, on Commit
 	// or Rollback. once done, all operations fail with
 	// ErrTxDone.
-	done bool
+	// Use atomic operations on value when checking value.
+	done int32
 
 	// All Stmts prepared for this transaction.  These will be closed after the
 	// transaction has been committed or rolled back.
@@ -1213,27 +1792,88 @@ type Tx struct {
 		sync.Mutex
 		v []*Stmt
 	}
+
+	// cancel is called after done transitions from 0 to 1.
+	cancel func()
+
+	// ctx lives for the life of the transaction.
+	ctx context.Context
 }
 
 var ErrTxDone = errors.New("sql: Transaction has already been committed or rolled back")
 
+// awaitDone blocks until the context in Tx is canceled and rolls back
+// the transaction if it's not already done.
+func (tx *Tx) awaitDone() {
+	// Wait for either the transaction to be committed or rolled
+	// back, or for the associated context to be closed.
+	<-tx.ctx.Done()
+
+	// Discard and close the connection used to ensure the
+	// transaction is closed and the resources are released.  This
+	// rollback does nothing if the transaction has already been
+	// committed or rolled back.
+	tx.rollback(true)
+}
+
+func (tx *Tx) isDone() bool {
+	return atomic.LoadInt32(&tx.done) != 0
+}
+
+// close returns the connection to the pool and
+// must only be called by Tx.rollback or Tx.Commit.
 func (tx *Tx) close(err error) {
-	if tx.done {
-		panic("double close") // internal error
-	}
-	tx.done = true
-	tx.db.putConn(tx.dc, err)
+	tx.cancel()
+
+	tx.closemu.Lock()
+	defer tx.closemu.Unlock()
+
+	tx.releaseConn(err)
 	tx.dc = nil
 	tx.txi = nil
 }
 
+// grabConn returns the transaction's connection. On success, the
+// caller must call tx.closemu.RUnlock once it is done with the
+// connection.
 func (tx *Tx) grabConn() (*driverConn, error) {
-	if tx.done {
+	select {
+	case <-tx.ctx.Done():
+		if tx.isDone() {
+			return nil, ErrTxDone
+		}
+		return nil, tx.ctx.Err()
+	default:
+	}
+
+	// closemu.RLock must come before the check for isDone to
+	// prevent the Tx from closing while a query is executing.
+	tx.closemu.RLock()
+	if tx.isDone() {
+		tx.closemu.RUnlock()
 		return nil, ErrTxDone
 	}
 	return tx.dc, nil
 }
 
+// closemuRUnlockRelease is used as a func(error) method value in
+// queries that pass the transaction's connection on to Rows.
+func (tx *Tx) closemuRUnlockRelease(error) {
+	tx.closemu.RUnlock()
+}
+
+func (tx *Tx) grabStmtConn() (*driverConn, func(error), error) {
+	dc, err := tx.grabConn()
+	if err != nil {
+		return nil, nil, err
+	}
+	return dc, tx.closemuRUnlockRelease, nil
+}
+
+func (tx *Tx) txCtx() context.Context {
+	return tx.ctx
+}
+
 // Closes all Stmts prepared for this transaction.
 func (tx *Tx) closePrepared() {
 	tx.stmts.Lock()
@@ -1245,7 +1885,15 @@ func (tx *Tx) closePrepared() {
 
 // Commit commits the transaction.
 func (tx *Tx) Commit() error {
-	if tx.done {
+	select {
+	case <-tx.ctx.Done():
+		if tx.isDone() {
+			return ErrTxDone
+		}
+		return tx.ctx.Err()
+	default:
+	}
+	if !atomic.CompareAndSwapInt32(&tx.done, 0, 1) {
 		return ErrTxDone
 	}
 	tx.dc.Lock()
@@ -1258,9 +1906,10 @@ func (tx *Tx) Commit() error {
 	return err
 }
 
-// Rollback aborts the transaction.
-func (tx *Tx) Rollback() error {
-	if tx.done {
+// rollback aborts the transaction and optionally forces the pool to discard
+// the connection.
+func (tx *Tx) rollback(discardConn bool) error {
+	if !atomic.CompareAndSwapInt32(&tx.done, 0, 1) {
 		return ErrTxDone
 	}
 	tx.dc.Lock()
@@ -1269,10 +1918,18 @@ func (tx *Tx) Rollback() error {
 	if err != driver.ErrBadConn {
 		tx.closePrepared()
 	}
+	if discardConn {
+		err = driver.ErrBadConn
+	}
 	tx.close(err)
 	return err
 }
 
+// Rollback aborts the transaction.
+func (tx *Tx) Rollback() error {
+	return tx.rollback(false)
+}
+
 // Prepare creates a prepared statement for use within a transaction.
 //
 // The returned statement operates within the transaction and can no longer
@@ -1297,6 +1954,7 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer tx.closemu.RUnlock()
 
 	dc.Lock()
 	si, err := dc.ci.Prepare(query)
@@ -1307,8 +1965,8 @@ func (tx *Tx) Prepare(query string) (*Stmt, error) {
 
 	stmt := &Stmt{
 		db: tx.db,
-		tx: tx,
-		txsi: &driverStmt{
+		cg: tx,
+		cgds: &driverStmt{
 			Locker: dc,
 			si:     si,
 		},
@@ -1345,13 +2003,14 @@ func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
 	if err != nil {
 		return &Stmt{stickyErr: err}
 	}
+	defer tx.closemu.RUnlock()
 	dc.Lock()
 	si, err := dc.ci.Prepare(stmt.query)
 	dc.Unlock()
 	txs := &Stmt{
 		db: tx.db,
-		tx: tx,
-		txsi: &driverStmt{
+		cg: tx,
+		cgds: &driverStmt{
 			Locker: dc,
 			si:     si,
 		},
@@ -1371,32 +2030,7 @@ func (tx *Tx) Exec(query string, args ...interface{}) (Result, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	if execer, ok := dc.ci.(driver.Execer); ok {
-		dargs, err := driverArgs(nil, args)
-		if err != nil {
-			return nil, err
-		}
-		dc.Lock()
-		resi, err := execer.Exec(query, dargs)
-		dc.Unlock()
-		if err == nil {
-			return driverResult{dc, resi}, nil
-		}
-		if err != driver.ErrSkip {
-			return nil, err
-		}
-	}
-
-	dc.Lock()
-	si, err := dc.ci.Prepare(query)
-	dc.Unlock()
-	if err != nil {
-		return nil, err
-	}
-	defer withLock(dc, func() { si.Close() })
-
-	return resultFromStatement(driverStmt{dc, si}, args...)
+	return tx.db.execDC(tx.ctx, dc, tx.closemuRUnlockRelease, query, args)
 }
 
 // Query executes a query that returns rows, typically a SELECT.
@@ -1405,8 +2039,7 @@ func (tx *Tx) Query(query string, args ...interface{}) (*Rows, error) {
 	if err != nil {
 		return nil, err
 	}
-	releaseConn := func(error) {}
-	return tx.db.queryConn(dc, releaseConn, query, args)
+	return tx.db.queryConn(tx.ctx, dc, tx.closemuRUnlockRelease, query, args)
 }
 
 // QueryRow executes a query that is expected to return at most one row.
@@ -1433,17 +2066,17 @@ type Stmt struct {
 
 	closemu sync.RWMutex // held exclusively during close, for read otherwise.
 
-	// If in a transaction, else both nil:
-	tx   *Tx
-	txsi *driverStmt
+	// If in a transaction or prepared on a Conn, else both nil:
+	cg   stmtConnGrabber
+	cgds *driverStmt
 
 	mu     sync.Mutex // protects the rest of the fields
 	closed bool
 
 	// css is a list of underlying driver statement interfaces
 	// that are valid on particular connections.  This is only
-	// used if tx == nil and one is found that has idle
-	// connections.  If tx != nil, txsi is always used.
+	// used if cg == nil and one is found that has idle
+	// connections.  If cg != nil, cgds is always used.
 	css []connStmt
 
 	// lastNumClosed is copied from db.numClosed when Stmt is created
@@ -1467,7 +2100,7 @@ func (s *Stmt) Exec(args ...interface{}) (Result, error) {
 			return nil, err
 		}
 
-		res, err = resultFromStatement(driverStmt{dc, si}, args...)
+		res, err = resultFromStatement(s.context(), driverStmt{dc, si}, args...)
 		releaseConn(err)
 		if err != driver.ErrBadConn {
 			return res, err
@@ -1476,13 +2109,34 @@ func (s *Stmt) Exec(args ...interface{}) (Result, error) {
 	return nil, driver.ErrBadConn
 }
 
+// stmtConnGrabber is implemented by Tx and Conn, whose statements
+// always run on the single connection they hold.
+type stmtConnGrabber interface {
+	// grabStmtConn returns the held connection. On success, the
+	// caller must call release once it is done with the connection.
+	grabStmtConn() (dc *driverConn, release func(error), err error)
+
+	// txCtx returns the context that statements run with.
+	txCtx() context.Context
+}
+
+// context returns the context that statement executions run with:
+// that of the transaction, if s belongs to one, or else
+// context.Background.
+func (s *Stmt) context() context.Context {
+	if s.cg != nil {
+		return s.cg.txCtx()
+	}
+	return context.Background()
+}
+
 func driverNumInput(ds driverStmt) int {
 	ds.Lock()
 	defer ds.Unlock() // in case NumInput panics
 	return ds.si.NumInput()
 }
 
-func resultFromStatement(ds driverStmt, args ...interface{}) (Result, error) {
+func resultFromStatement(ctx context.Context, ds driverStmt, args ...interface{}) (Result, error) {
 	want := driverNumInput(ds)
 
 	// -1 means the driver doesn't know how to count the number of
@@ -1499,7 +2153,7 @@ func resultFromStatement(ds driverStmt, args ...interface{}) (Result, error) {
 
 	ds.Lock()
 	defer ds.Unlock()
-	resi, err := ds.si.Exec(dargs)
+	resi, err := ctxDriverStmtExec(ctx, ds.si, dargs)
 	if err != nil {
 		return nil, err
 	}
@@ -1546,23 +2200,22 @@ func (s *Stmt) connStmt() (ci *driverConn, releaseConn func(error), si driver.St
 		return
 	}
 
-	// In a transaction, we always use the connection that the
-	// transaction was created on.
-	if s.tx != nil {
+	// In a transaction or on a Conn, we always use the connection
+	// that the transaction or Conn holds.
+	if s.cg != nil {
 		s.mu.Unlock()
-		ci, err = s.tx.grabConn() // blocks, waiting for the connection.
+		ci, releaseConn, err = s.cg.grabStmtConn() // blocks, waiting for the connection.
 		if err != nil {
 			return
 		}
-		releaseConn = func(error) {}
-		return ci, releaseConn, s.txsi.si, nil
+		return ci, releaseConn, s.cgds.si, nil
 	}
 
 	s.removeClosedStmtLocked()
 	s.mu.Unlock()
 
 	// TODO(bradfitz): or always wait for one? make configurable later?
-	dc, err := s.db.conn(cachedOrNewConn)
+	dc, err := s.db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -1608,7 +2261,7 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 			return nil, err
 		}
 
-		rowsi, err = rowsiFromStatement(driverStmt{dc, si}, args...)
+		rowsi, err = rowsiFromStatement(s.context(), driverStmt{dc, si}, args...)
 		if err == nil {
 			// Note: ownership of ci passes to the *Rows, to be freed
 			// with releaseConn.
@@ -1622,6 +2275,7 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 				releaseConn(err)
 				s.db.removeDep(s, rows)
 			}
+			rows.initContextClose(s.context())
 			return rows, nil
 		}
 
@@ -1633,7 +2287,7 @@ func (s *Stmt) Query(args ...interface{}) (*Rows, error) {
 	return nil, driver.ErrBadConn
 }
 
-func rowsiFromStatement(ds driverStmt, args ...interface{}) (driver.Rows, error) {
+func rowsiFromStatement(ctx context.Context, ds driverStmt, args ...interface{}) (driver.Rows, error) {
 	ds.Lock()
 	want := ds.si.NumInput()
 	ds.Unlock()
@@ -1651,7 +2305,7 @@ func rowsiFromStatement(ds driverStmt, args ...interface{}) (driver.Rows, error)
 	}
 
 	ds.Lock()
-	rowsi, err := ds.si.Query(dargs)
+	rowsi, err := ctxDriverStmtQuery(ctx, ds.si, dargs)
 	ds.Unlock()
 	if err != nil {
 		return nil, err
@@ -1693,8 +2347,8 @@ func (s *Stmt) Close() error {
 	}
 	s.closed = true
 
-	if s.tx != nil {
-		err := s.txsi.Close()
+	if s.cg != nil {
+		err := s.cgds.Close()
 		s.mu.Unlock()
 		return err
 	}
@@ -1734,29 +2388,113 @@ type Rows struct {
 	dc          *driverConn // owned; must call releaseConn when closed to release
 	releaseConn func(error)
 	rowsi       driver.Rows
+	cancel      func() // called when Rows is closed, may be nil.
+
+	// closemu prevents Rows from closing while there
+	// is an active streaming result. It is held for read during non-close operations
+	// and exclusively during close.
+	//
+	// closemu guards lasterr and closed.
+	closemu sync.RWMutex
+	closed  bool
+	lasterr error // non-nil only if closed is true
 
-	closed    bool
 	lastcols  []driver.Value
-	lasterr   error       // non-nil only if closed is true
 	closeStmt driver.Stmt // if non-nil, statement to Close on close
 }
 
+// initContextClose arranges for rs to be closed when ctx is done.
+func (rs *Rows) initContextClose(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	ctx, rs.cancel = context.WithCancel(ctx)
+	go rs.awaitDone(ctx)
+}
+
+// awaitDone blocks until the rows are closed or the context is
+// canceled.
+func (rs *Rows) awaitDone(ctx context.Context) {
+	<-ctx.Done()
+	rs.close(ctx.Err())
+}
+
 // Next prepares the next result row for reading with the Scan method.  It
 // returns true on success, or false if there is no next result row or an error
 // happened while preparing it.  Err should be consulted to distinguish between
 // the two cases.
 //
 // Every call to Scan, even the first one, must be preceded by a call to Next.
+//
+// If the driver supports multiple result sets, reaching the end of one
+// result set does not close the Rows; call NextResultSet to advance to
+// the next one.
 func (rs *Rows) Next() bool {
+	var doClose, ok bool
+	withLock(rs.closemu.RLocker(), func() {
+		doClose, ok = rs.nextLocked()
+	})
+	if doClose {
+		rs.Close()
+	}
+	return ok
+}
+
+func (rs *Rows) nextLocked() (doClose, ok bool) {
 	if rs.closed {
-		return false
+		return false, false
 	}
 	if rs.lastcols == nil {
 		rs.lastcols = make([]driver.Value, len(rs.rowsi.Columns()))
 	}
 	rs.lasterr = rs.rowsi.Next(rs.lastcols)
 	if rs.lasterr != nil {
-		rs.Close()
+		// Close the connection if there is a driver error.
+		if rs.lasterr != io.EOF {
+			return true, false
+		}
+		nextResultSet, ok := rs.rowsi.(driver.RowsNextResultSet)
+		if !ok {
+			return true, false
+		}
+		// The driver is at the end of the current result set.
+		// Only close Rows if there is no further result set to read.
+		return !nextResultSet.HasNextResultSet(), false
+	}
+	return false, true
+}
+
+// NextResultSet prepares the next result set for reading. It reports whether
+// there is further result sets, or false if there is no further result set
+// or if there is an error advancing to it. The Err method should be consulted
+// to distinguish between the two cases.
+//
+// After calling NextResultSet, the Next method should always be called before
+// scanning. If there are further result sets they may not have rows in the result
+// set.
+func (rs *Rows) NextResultSet() bool {
+	var doClose bool
+	defer func() {
+		if doClose {
+			rs.Close()
+		}
+	}()
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
+
+	if rs.closed {
+		return false
+	}
+
+	rs.lastcols = nil
+	nextResultSet, ok := rs.rowsi.(driver.RowsNextResultSet)
+	if !ok {
+		doClose = true
+		return false
+	}
+	rs.lasterr = nextResultSet.NextResultSet()
+	if rs.lasterr != nil {
+		doClose = true
 		return false
 	}
 	return true
@@ -1765,6 +2503,8 @@ func (rs *Rows) Next() bool {
 // Err returns the error, if any, that was encountered during iteration.
 // Err may be called after an explicit or implicit Close.
 func (rs *Rows) Err() error {
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
 	if rs.lasterr == io.EOF {
 		return nil
 	}
@@ -1775,6 +2515,8 @@ func (rs *Rows) Err() error {
 // Columns returns an error if the rows are closed, or if the rows
 // are from QueryRow and there was a deferred error.
 func (rs *Rows) Columns() ([]string, error) {
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
 	if rs.closed {
 		return nil, errors.New("sql: Rows are closed")
 	}
@@ -1784,6 +2526,109 @@ func (rs *Rows) Columns() ([]string, error) {
 	return rs.rowsi.Columns(), nil
 }
 
+// ColumnTypes returns column information such as column type, length,
+// and nullable. Some information may not be available from some drivers.
+func (rs *Rows) ColumnTypes() ([]*ColumnType, error) {
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
+	if rs.closed {
+		return nil, errors.New("sql: Rows are closed")
+	}
+	if rs.rowsi == nil {
+		return nil, errors.New("sql: no Rows available")
+	}
+	return rowsColumnInfoSetup(rs.rowsi), nil
+}
+
+// ColumnType contains the name and type of a column.
+type ColumnType struct {
+	name string
+
+	hasNullable       bool
+	hasLength         bool
+	hasPrecisionScale bool
+
+	nullable     bool
+	length       int64
+	databaseType string
+	precision    int64
+	scale        int64
+	scanType     reflect.Type
+}
+
+// Name returns the name or alias of the column.
+func (ci *ColumnType) Name() string {
+	return ci.name
+}
+
+// Length returns the column type length for variable length column types such
+// as text and binary field types. If the type length is unbounded the value will
+// be math.MaxInt64 (any database limits will still apply).
+// If the column type is not variable length, such as an int, or if not supported
+// by the driver ok is false.
+func (ci *ColumnType) Length() (length int64, ok bool) {
+	return ci.length, ci.hasLength
+}
+
+// DecimalSize returns the scale and precision of a decimal type.
+// If not applicable or if not supported ok is false.
+func (ci *ColumnType) DecimalSize() (precision, scale int64, ok bool) {
+	return ci.precision, ci.scale, ci.hasPrecisionScale
+}
+
+// ScanType returns a Go type suitable for scanning into using Rows.Scan.
+// If a driver does not support this property ScanType will return
+// the type of an empty interface.
+func (ci *ColumnType) ScanType() reflect.Type {
+	return ci.scanType
+}
+
+// Nullable reports whether the column may be null.
+// If a driver does not support this property ok will be false.
+func (ci *ColumnType) Nullable() (nullable, ok bool) {
+	return ci.nullable, ci.hasNullable
+}
+
+// DatabaseTypeName returns the database system name of the column type. If an empty
+// string is returned the driver type name is not supported.
+// Consult your driver documentation for a list of driver data types. Length specifiers
+// are not included.
+// Common type names include "VARCHAR", "TEXT", "NVARCHAR", "DECIMAL", "BOOL", "INT", "BIGINT".
+func (ci *ColumnType) DatabaseTypeName() string {
+	return ci.databaseType
+}
+
+func rowsColumnInfoSetup(rowsi driver.Rows) []*ColumnType {
+	names := rowsi.Columns()
+
+	list := make([]*ColumnType, len(names))
+	for i := range list {
+		ci := &ColumnType{
+			name: names[i],
+		}
+		list[i] = ci
+
+		if prop, ok := rowsi.(driver.RowsColumnTypeScanType); ok {
+			ci.scanType = prop.ColumnTypeScanType(i)
+		} else {
+			ci.scanType = reflect.TypeOf(new(interface{})).Elem()
+		}
+		if prop, ok := rowsi.(driver.RowsColumnTypeDatabaseTypeName); ok {
+			ci.databaseType = prop.ColumnTypeDatabaseTypeName(i)
+		}
+		if prop, ok := rowsi.(driver.RowsColumnTypeLength); ok {
+			ci.length, ci.hasLength = prop.ColumnTypeLength(i)
+		}
+		if prop, ok := rowsi.(driver.RowsColumnTypeNullable); ok {
+			ci.nullable, ci.hasNullable = prop.ColumnTypeNullable(i)
+		}
+		if prop, ok := rowsi.(driver.RowsColumnTypePrecisionScale); ok {
+			ci.precision, ci.scale, ci.hasPrecisionScale = prop.ColumnTypePrecisionScale(i)
+		}
+	}
+	return list
+}
+
 // Scan copies the columns in the current row into the values pointed
 // at by dest. The number of values in dest must be the same as the
 // number of columns in Rows.
@@ -1836,6 +2681,8 @@ func (rs *Rows) Columns() ([]string, error) {
 // For scanning into *bool, the source may be true, false, 1, 0, or
 // string inputs parseable by strconv.ParseBool.
 func (rs *Rows) Scan(dest ...interface{}) error {
+	rs.closemu.RLock()
+	defer rs.closemu.RUnlock()
 	if rs.closed {
 		return errors.New("sql: Rows are closed")
 	}
@@ -1860,14 +2707,31 @@ var rowsCloseHook func(*Rows, *error)
 // false, the Rows are closed automatically and it will suffice to check the
 // result of Err. Close is idempotent and does not affect the result of Err.
 func (rs *Rows) Close() error {
+	return rs.close(nil)
+}
+
+// close closes the Rows, recording err (if non-nil) as the reason
+// for closing when no other error has been seen yet.
+func (rs *Rows) close(err error) error {
+	rs.closemu.Lock()
+	defer rs.closemu.Unlock()
+
 	if rs.closed {
 		return nil
 	}
 	rs.closed = true
-	err := rs.rowsi.Close()
+
+	if rs.lasterr == nil {
+		rs.lasterr = err
+	}
+
+	err = rs.rowsi.Close()
 	if fn := rowsCloseHook; fn != nil {
 		fn(rs, &err)
 	}
+	if rs.cancel != nil {
+		rs.cancel()
+	}
 	if rs.closeStmt != nil {
 		rs.closeStmt.Close()
 	}
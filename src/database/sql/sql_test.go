@@ -5,6 +5,7 @@
 package sql
 
 import (
+	"context"
 	"database/sql/driver"
 	"errors"
 	"fmt"
@@ -260,6 +261,497 @@ func TestQuery(t *testing.T) {
 	}
 }
 
+// TestMultiResultSetQuery checks that the sql package can read
+// multiple result sets from a single query.
+func TestMultiResultSetQuery(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	prepares0 := numPrepares(t, db)
+	rows, err := db.Query("SELECT|people|age,name|;SELECT|people|name|age=?", 2)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	type row1 struct {
+		age  int
+		name string
+	}
+	type row2 struct {
+		name string
+	}
+	got1 := []row1{}
+	for rows.Next() {
+		var r row1
+		err = rows.Scan(&r.age, &r.name)
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		got1 = append(got1, r)
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	want1 := []row1{
+		{age: 1, name: "Alice"},
+		{age: 2, name: "Bob"},
+		{age: 3, name: "Chris"},
+	}
+	if !reflect.DeepEqual(got1, want1) {
+		t.Errorf("mismatch.\n got1: %#v\nwant: %#v", got1, want1)
+	}
+
+	if !rows.NextResultSet() {
+		t.Errorf("expected another result set")
+	}
+
+	got2 := []row2{}
+	for rows.Next() {
+		var r row2
+		err = rows.Scan(&r.name)
+		if err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		got2 = append(got2, r)
+	}
+	err = rows.Err()
+	if err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	want2 := []row2{
+		{name: "Bob"},
+	}
+	if !reflect.DeepEqual(got2, want2) {
+		t.Errorf("mismatch.\n got: %#v\nwant: %#v", got2, want2)
+	}
+	if rows.NextResultSet() {
+		t.Errorf("unexpected next result set")
+	}
+
+	// And verify that the final rows.Next() call, which hit EOF,
+	// also closed the rows connection.
+	if n := db.numFreeConns(); n != 1 {
+		t.Fatalf("free conns after query hitting EOF = %d; want 1", n)
+	}
+	if prepares := numPrepares(t, db) - prepares0; prepares != 1 {
+		t.Errorf("executed %d Prepare statements; want 1", prepares)
+	}
+}
+
+func TestQueryNamedArg(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	rows, err := db.Query(
+		// Ensure the name and age parameters only match on placeholder name, not position.
+		"SELECT|people|age,name|name=?name,age=?age",
+		Named("age", 2),
+		Named("name", "Bob"),
+	)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var age int
+		var name string
+		if err := rows.Scan(&age, &name); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+		got = append(got, fmt.Sprintf("%s:%d", name, age))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("Err: %v", err)
+	}
+	if want := []string{"Bob:2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+
+	var name string
+	err = db.QueryRow("SELECT|people|name|age=?", Named("", 3)).Scan(&name)
+	if err != nil {
+		t.Fatalf("QueryRow with unnamed NamedArg: %v", err)
+	}
+	if name != "Chris" {
+		t.Errorf("name = %q; want Chris", name)
+	}
+
+	_, err = db.Query("SELECT|people|name|age=?", Named("1age", 1))
+	if err == nil || !strings.Contains(err.Error(), "does not begin with a letter") {
+		t.Errorf("Query with invalid argument name: err = %v; want name error", err)
+	}
+}
+
+func TestNamedArgUnsupported(t *testing.T) {
+	_, err := namedValueToValue([]driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Name: "b", Ordinal: 2, Value: int64(2)},
+	})
+	if err != errNamedNotSupported {
+		t.Errorf("err = %v; want %v", err, errNamedNotSupported)
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	prepares0 := numPrepares(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT|people|age,name|")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	type row struct {
+		age  int
+		name string
+	}
+	got := []row{}
+	index := 0
+	for rows.Next() {
+		if index == 2 {
+			cancel()
+			waitForRowsClose(t, rows, 5*time.Second)
+		}
+		var r row
+		err = rows.Scan(&r.age, &r.name)
+		if err != nil {
+			if index == 2 {
+				break
+			}
+			t.Fatalf("Scan: %v", err)
+		}
+		if index == 2 && err == nil {
+			t.Fatal("expected an error on last scan")
+		}
+		got = append(got, r)
+		index++
+	}
+	if err := rows.Err(); err != context.Canceled {
+		t.Fatalf("Err = %v; want context.Canceled", err)
+	}
+	want := []row{
+		{age: 1, name: "Alice"},
+		{age: 2, name: "Bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	// And verify that the canceled context also released the
+	// rows connection.
+	if n := db.numFreeConns(); n != 1 {
+		t.Fatalf("free conns after query canceled = %d; want 1", n)
+	}
+	if prepares := numPrepares(t, db) - prepares0; prepares != 1 {
+		t.Errorf("executed %d Prepare statements; want 1", prepares)
+	}
+}
+
+// waitCondition waits for fn to return true,
+// checking it every pollInterval until waitFor elapses.
+func waitCondition(waitFor, pollInterval time.Duration, fn func() bool) bool {
+	deadline := time.Now().Add(waitFor)
+	for time.Now().Before(deadline) {
+		if fn() {
+			return true
+		}
+		time.Sleep(pollInterval)
+	}
+	return false
+}
+
+func waitForRowsClose(t *testing.T, rows *Rows, maxWait time.Duration) {
+	if !waitCondition(maxWait, 5*time.Millisecond, func() bool {
+		rows.closemu.RLock()
+		defer rows.closemu.RUnlock()
+		return rows.closed
+	}) {
+		t.Fatal("failed to close rows")
+	}
+}
+
+func TestQueryContextWaitsForConn(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	// Hold the only connection.
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := db.QueryContext(ctx, "SELECT|people|age,name|"); err != context.DeadlineExceeded {
+		t.Fatalf("QueryContext error = %v; want context.DeadlineExceeded", err)
+	}
+	if _, err := db.ExecContext(ctx, "WIPE"); err != context.DeadlineExceeded {
+		t.Fatalf("ExecContext error = %v; want context.DeadlineExceeded", err)
+	}
+
+	// The abandoned requests must not leak the connection.
+	rows.Close()
+	db.mu.Lock()
+	nreq := len(db.connRequests)
+	db.mu.Unlock()
+	if nreq != 0 {
+		t.Errorf("pending conn requests = %d; want 0", nreq)
+	}
+	if err := db.QueryRow("SELECT|people|age,name|age=?", 3).Scan(new(int), new(string)); err != nil {
+		t.Fatalf("QueryRow after timeout: %v", err)
+	}
+}
+
+func TestTxContextRollback(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	if !waitCondition(5*time.Second, 5*time.Millisecond, tx.isDone) {
+		t.Fatal("transaction not rolled back after context cancelation")
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Commit after cancel = %v; want ErrTxDone", err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Eve,age=?", 5); err != ErrTxDone {
+		t.Errorf("Exec after cancel = %v; want ErrTxDone", err)
+	}
+}
+
+func TestTxContextCommit(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tx, err := db.BeginContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := tx.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatalf("no rows: %v", rows.Err())
+	}
+	// Committing with open Rows closes them.
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit = %v", err)
+	}
+	if rows.Next() {
+		t.Error("Rows.Next returned true after Commit")
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after Commit = %d; want 1", n)
+	}
+}
+
+func TestTxOptions(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, &TxOptions{Isolation: LevelSerializable})
+	if err != nil {
+		t.Fatalf("BeginTx with LevelSerializable: %v", err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Errorf("Exec in serializable transaction: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.BeginTx(ctx, &TxOptions{Isolation: LevelSnapshot})
+	if err == nil || !strings.Contains(err.Error(), "unsupported isolation level Snapshot") {
+		t.Errorf("BeginTx with LevelSnapshot: err = %v; want unsupported isolation level", err)
+	}
+
+	tx, err = db.BeginTx(ctx, &TxOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("BeginTx with ReadOnly: %v", err)
+	}
+	var name string
+	if err := tx.QueryRow("SELECT|people|name|age=?", 1).Scan(&name); err != nil {
+		t.Errorf("QueryRow in read-only transaction: %v", err)
+	}
+	if _, err := tx.Exec("INSERT|people|name=Dave,age=?", 4); err == nil {
+		t.Error("Exec of INSERT in read-only transaction succeeded")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns = %d; want 1", n)
+	}
+}
+
+// beginOnlyConn is a driver.Conn that does not implement
+// driver.ConnBeginTx.
+type beginOnlyConn struct {
+	driver.Conn
+}
+
+func TestTxOptionsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range []*TxOptions{
+		{Isolation: LevelReadCommitted},
+		{ReadOnly: true},
+	} {
+		ci := beginOnlyConn{&fakeConn{}}
+		if _, err := ctxDriverBegin(ctx, opts, ci); err == nil {
+			t.Errorf("ctxDriverBegin(%+v) succeeded for a driver without BeginTx", *opts)
+		}
+	}
+	for _, opts := range []*TxOptions{nil, {}} {
+		ci := beginOnlyConn{&fakeConn{}}
+		if _, err := ctxDriverBegin(ctx, opts, ci); err != nil {
+			t.Errorf("ctxDriverBegin(%v): %v", opts, err)
+		}
+	}
+}
+
+func TestIsolationLevelString(t *testing.T) {
+	for _, test := range []struct {
+		level IsolationLevel
+		want  string
+	}{
+		{LevelDefault, "Default"},
+		{LevelRepeatableRead, "Repeatable Read"},
+		{LevelLinearizable, "Linearizable"},
+		{LevelLinearizable + 1, "IsolationLevel(8)"},
+	} {
+		if got := test.level.String(); got != test.want {
+			t.Errorf("IsolationLevel(%d).String() = %q; want %q", int(test.level), got, test.want)
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := db.numFreeConns(); n != 0 {
+		t.Errorf("free conns with Conn open = %d; want 0", n)
+	}
+
+	// All work on the Conn happens on the same driver connection.
+	dc := conn.dc
+	if _, err := conn.ExecContext(ctx, "INSERT|people|name=Dave,age=?", 4); err != nil {
+		t.Fatalf("ExecContext: %v", err)
+	}
+	var name string
+	if err := conn.QueryRowContext(ctx, "SELECT|people|name|age=?", 4).Scan(&name); err != nil {
+		t.Fatalf("QueryRowContext: %v", err)
+	}
+	if name != "Dave" {
+		t.Errorf("name = %q; want Dave", name)
+	}
+
+	stmt, err := conn.PrepareContext(ctx, "SELECT|people|name|age=?")
+	if err != nil {
+		t.Fatalf("PrepareContext: %v", err)
+	}
+	if err := stmt.QueryRow(2).Scan(&name); err != nil {
+		t.Fatalf("Stmt.QueryRow: %v", err)
+	}
+	if name != "Bob" {
+		t.Errorf("name = %q; want Bob", name)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	if tx.dc != dc {
+		t.Error("Conn.BeginTx used a different driver connection")
+	}
+	if err := tx.QueryRow("SELECT|people|name|age=?", 3).Scan(&name); err != nil {
+		t.Fatalf("Tx.QueryRow: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.numFreeConns(); n != 0 {
+		t.Errorf("free conns after committing a Conn's Tx = %d; want 0", n)
+	}
+	if conn.dc != dc {
+		t.Error("Conn changed driver connection")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after Close = %d; want 1", n)
+	}
+
+	// The statement was closed along with the Conn, and the Conn
+	// can no longer be used.
+	if err := stmt.QueryRow(2).Scan(&name); err == nil {
+		t.Error("Stmt.QueryRow succeeded after Conn.Close")
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT|people|name=Eve,age=?", 5); err != ErrConnDone {
+		t.Errorf("ExecContext after Close: err = %v; want ErrConnDone", err)
+	}
+	if _, err := conn.BeginTx(ctx, nil); err != ErrConnDone {
+		t.Errorf("BeginTx after Close: err = %v; want ErrConnDone", err)
+	}
+	if err := conn.Close(); err != ErrConnDone {
+		t.Errorf("second Close: err = %v; want ErrConnDone", err)
+	}
+}
+
+func TestConnCloseWaitsForRows(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT|people|name|")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- conn.Close()
+	}()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned %v with Rows still open", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := db.numFreeConns(); n != 1 {
+		t.Errorf("free conns after Close = %d; want 1", n)
+	}
+}
+
 func TestByteOwnership(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -317,6 +809,82 @@ func TestRowsColumns(t *testing.T) {
 	}
 }
 
+func TestRowsColumnTypes(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	rows, err := db.Query("SELECT|people|age,name|")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	tt, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+
+	types := make([]reflect.Type, len(tt))
+	for i, tp := range tt {
+		st := tp.ScanType()
+		if st == nil {
+			t.Errorf("scantype is null for column %q", tp.Name())
+			continue
+		}
+		types[i] = st
+	}
+	values := make([]interface{}, len(tt))
+	for i := range values {
+		values[i] = reflect.New(types[i]).Interface()
+	}
+	ct := 0
+	for rows.Next() {
+		err = rows.Scan(values...)
+		if err != nil {
+			t.Fatalf("failed to scan values in %v", err)
+		}
+		ct++
+		if ct == 1 {
+			if age := *values[0].(*int32); age != 1 {
+				t.Errorf("Expected 1, got %v", age)
+			}
+			if name := *values[1].(*string); name != "Alice" {
+				t.Errorf("Expected Alice, got %v", name)
+			}
+		}
+	}
+	if ct != 3 {
+		t.Errorf("expected 3 rows, got %d", ct)
+	}
+
+	for i, want := range []struct {
+		name, dbType string
+	}{
+		{"age", "INT32"},
+		{"name", "STRING"},
+	} {
+		if got := tt[i].Name(); got != want.name {
+			t.Errorf("column %d: Name = %q; want %q", i, got, want.name)
+		}
+		if got := tt[i].DatabaseTypeName(); got != want.dbType {
+			t.Errorf("column %d: DatabaseTypeName = %q; want %q", i, got, want.dbType)
+		}
+		if nullable, ok := tt[i].Nullable(); nullable || !ok {
+			t.Errorf("column %d: Nullable = %t, %t; want false, true", i, nullable, ok)
+		}
+		if _, ok := tt[i].Length(); ok {
+			t.Errorf("column %d: Length reported as supported", i)
+		}
+		if _, _, ok := tt[i].DecimalSize(); ok {
+			t.Errorf("column %d: DecimalSize reported as supported", i)
+		}
+	}
+
+	if err := rows.Close(); err != nil {
+		t.Errorf("error closing rows: %s", err)
+	}
+	if _, err := rows.ColumnTypes(); err == nil {
+		t.Error("ColumnTypes on closed rows succeeded")
+	}
+}
+
 func TestQueryRow(t *testing.T) {
 	db := newTestDB(t, "people")
 	defer closeDB(t, db)
@@ -439,7 +1007,7 @@ func TestStatementClose(t *testing.T) {
 		msg  string
 	}{
 		{&Stmt{stickyErr: want}, "stickyErr not propagated"},
-		{&Stmt{tx: &Tx{}, txsi: &driverStmt{&sync.Mutex{}, stubDriverStmt{want}}}, "driverStmt.Close() error not propagated"},
+		{&Stmt{cg: &Tx{}, cgds: &driverStmt{&sync.Mutex{}, stubDriverStmt{want}}}, "driverStmt.Close() error not propagated"},
 	}
 	for _, test := range tests {
 		if err := test.stmt.Close(); err != want {
@@ -1159,17 +1727,17 @@ func TestMaxOpenConnsOnBusy(t *testing.T) {
 
 	db.SetMaxOpenConns(3)
 
-	conn0, err := db.conn(cachedOrNewConn)
+	conn0, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
 
-	conn1, err := db.conn(cachedOrNewConn)
+	conn1, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
 
-	conn2, err := db.conn(cachedOrNewConn)
+	conn2, err := db.conn(context.Background(), cachedOrNewConn)
 	if err != nil {
 		t.Fatalf("db open conn fail: %v", err)
 	}
@@ -1286,10 +1854,18 @@ func TestStats(t *testing.T) {
 		t.Errorf("stats.OpenConnections = %d; want 1", got)
 	}
 
+	if got := stats.Idle; got != 1 {
+		t.Errorf("stats.Idle = %d; want 1", got)
+	}
+
 	tx, err := db.Begin()
 	if err != nil {
 		t.Fatal(err)
 	}
+	stats = db.Stats()
+	if stats.InUse != 1 || stats.Idle != 0 {
+		t.Errorf("stats.InUse, stats.Idle in a transaction = %d, %d; want 1, 0", stats.InUse, stats.Idle)
+	}
 	tx.Commit()
 
 	closeDB(t, db)
@@ -1381,6 +1957,158 @@ func TestConnMaxLifetime(t *testing.T) {
 	if closes != 1 {
 		t.Errorf("closes = %d; want 1", closes)
 	}
+	if s := db.Stats(); s.MaxLifetimeClosed != 1 {
+		t.Errorf("MaxLifetimeClosed = %d; want 1", s.MaxLifetimeClosed)
+	}
+}
+
+func TestConnMaxIdleTime(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
+
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
+
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetMaxIdleConns(10)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	offset = 5 * time.Second
+	tx2.Commit()
+
+	// Only the connection idle for longer than the limit is closed,
+	// even though both are older than it.
+	offset = 12 * time.Second
+	db.mu.Lock()
+	db.maxIdleTime = 10 * time.Second
+	closing := db.connectionCleanerRunLocked()
+	db.mu.Unlock()
+	if len(closing) != 1 {
+		t.Fatalf("closing %d connections; want 1", len(closing))
+	}
+	for _, c := range closing {
+		c.Close()
+	}
+
+	s := db.Stats()
+	if s.MaxIdleTimeClosed != 1 || s.MaxLifetimeClosed != 0 {
+		t.Errorf("MaxIdleTimeClosed, MaxLifetimeClosed = %d, %d; want 1, 0", s.MaxIdleTimeClosed, s.MaxLifetimeClosed)
+	}
+	if s.Idle != 1 || s.OpenConnections != 1 {
+		t.Errorf("Idle, OpenConnections = %d, %d; want 1, 1", s.Idle, s.OpenConnections)
+	}
+}
+
+// Tests that a connection idle for too long is closed when it would
+// otherwise be reused, without waiting for the cleaner.
+func TestConnMaxIdleTimeReuse(t *testing.T) {
+	t0 := time.Unix(1000000, 0)
+	offset := time.Duration(0)
+
+	nowFunc = func() time.Time { return t0.Add(offset) }
+	defer func() { nowFunc = time.Now }()
+
+	db := newTestDB(t, "magicquery")
+	defer closeDB(t, db)
+	db.clearAllConns(t)
+	db.SetMaxIdleConns(10)
+
+	driver := db.driver.(*fakeDriver)
+	driver.mu.Lock()
+	opens0 := driver.openCount
+	closes0 := driver.closeCount
+	driver.mu.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	offset = 12 * time.Second
+	db.mu.Lock()
+	db.maxIdleTime = 10 * time.Second // no cleaner
+	db.mu.Unlock()
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	driver.mu.Lock()
+	opens := driver.openCount - opens0
+	closes := driver.closeCount - closes0
+	driver.mu.Unlock()
+	if opens != 2 || closes != 1 {
+		t.Errorf("opens, closes = %d, %d; want 2, 1", opens, closes)
+	}
+	if s := db.Stats(); s.MaxIdleTimeClosed != 1 {
+		t.Errorf("MaxIdleTimeClosed = %d; want 1", s.MaxIdleTimeClosed)
+	}
+}
+
+func TestStatsMaxIdleClosed(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxIdleConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+	tx2.Commit()
+	if s := db.Stats(); s.MaxIdleClosed != 1 || s.Idle != 1 {
+		t.Errorf("MaxIdleClosed, Idle = %d, %d; want 1, 1", s.MaxIdleClosed, s.Idle)
+	}
+
+	db.SetMaxIdleConns(-1)
+	if s := db.Stats(); s.MaxIdleClosed != 2 || s.Idle != 0 {
+		t.Errorf("after SetMaxIdleConns(-1): MaxIdleClosed, Idle = %d, %d; want 2, 0", s.MaxIdleClosed, s.Idle)
+	}
+}
+
+func TestStatsWait(t *testing.T) {
+	db := newTestDB(t, "people")
+	defer closeDB(t, db)
+	db.SetMaxOpenConns(1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := db.Stats(); s.MaxOpenConnections != 1 || s.WaitCount != 0 {
+		t.Errorf("MaxOpenConnections, WaitCount = %d, %d; want 1, 0", s.MaxOpenConnections, s.WaitCount)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := db.BeginTx(ctx, nil); err != context.DeadlineExceeded {
+		t.Fatalf("BeginTx with all connections busy: err = %v; want DeadlineExceeded", err)
+	}
+	tx.Commit()
+
+	s := db.Stats()
+	if s.WaitCount != 1 {
+		t.Errorf("WaitCount = %d; want 1", s.WaitCount)
+	}
+	if s.WaitDuration < 10*time.Millisecond {
+		t.Errorf("WaitDuration = %v; want at least 10ms", s.WaitDuration)
+	}
 }
 
 // golang.org/issue/5323
@@ -0,0 +1,59 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sqlexpvar publishes the connection pool statistics of
+// database/sql through package expvar.
+//
+// The package is typically only imported for the side effect of
+// publishing the expvar variable "sql". To enable it, import the
+// package:
+//
+//	import _ "database/sql/sqlexpvar"
+//
+// The variable maps a name for every sql.DB opened after the package
+// is initialized, made of the driver name and a sequence number, such
+// as "postgres#1", to the DB's sql.DBStats. A DB is listed, and kept
+// reachable, until it is closed.
+package sqlexpvar
+
+import (
+	"database/sql/internal/dbstats"
+	"expvar"
+	"strconv"
+	"sync"
+)
+
+var (
+	mu  sync.Mutex // guards seq and dbs
+	seq int
+	dbs = make(map[string]func() interface{})
+)
+
+func init() {
+	expvar.Publish("sql", expvar.Func(snapshot))
+	dbstats.Register = register
+}
+
+func register(driverName string, stats func() interface{}) (unregister func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	seq++
+	name := driverName + "#" + strconv.Itoa(seq)
+	dbs[name] = stats
+	return func() {
+		mu.Lock()
+		delete(dbs, name)
+		mu.Unlock()
+	}
+}
+
+func snapshot() interface{} {
+	mu.Lock()
+	defer mu.Unlock()
+	m := make(map[string]interface{}, len(dbs))
+	for name, stats := range dbs {
+		m[name] = stats()
+	}
+	return m
+}
@@ -0,0 +1,64 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlexpvar
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+)
+
+type nopDriver struct{}
+
+func (nopDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("sqlexpvar_test: no connections")
+}
+
+func init() {
+	sql.Register("sqlexpvartest", nopDriver{})
+}
+
+func published(t *testing.T) map[string]sql.DBStats {
+	v := expvar.Get("sql")
+	if v == nil {
+		t.Fatal(`expvar "sql" is not published`)
+	}
+	var m map[string]sql.DBStats
+	if err := json.Unmarshal([]byte(v.String()), &m); err != nil {
+		t.Fatalf("decoding %s: %v", v, err)
+	}
+	return m
+}
+
+func TestPublish(t *testing.T) {
+	db, err := sql.Open("sqlexpvartest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(3)
+
+	var name string
+	for n, stats := range published(t) {
+		if stats.MaxOpenConnections == 3 {
+			name = n
+		}
+	}
+	if name == "" {
+		t.Fatalf("DB not published in %v", published(t))
+	}
+	if want := "sqlexpvartest#"; len(name) <= len(want) || name[:len(want)] != want {
+		t.Errorf("published name = %q; want prefix %q", name, want)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := published(t)[name]; ok {
+		t.Errorf("%q still published after Close", name)
+	}
+}
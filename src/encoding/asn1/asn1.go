@@ -3,7 +3,8 @@
 // license that can be found in the LICENSE file.
 
 // Package asn1 implements parsing of DER-encoded ASN.1 data structures,
-// as defined in ITU-T Rec X.690.
+// as defined in ITU-T Rec X.690. BER-encoded input is accepted by
+// UnmarshalBER and NewBERParser, which normalize it to DER first.
 //
 // See also ``A Layman's Guide to a Subset of ASN.1, BER, and DER,''
 // http://luca.ntop.org/Teaching/Appunti/asn1.html.
@@ -22,10 +23,13 @@ package asn1
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
@@ -421,6 +425,133 @@ func parseUTF8String(bytes []byte) (ret string, err error) {
 	return string(bytes), nil
 }
 
+// BMPString
+
+// parseBMPString parses a ASN.1 BMPString (UCS-2, big-endian) from the given
+// byte slice and returns it as UTF-8.
+func parseBMPString(bytes []byte) (ret string, err error) {
+	if len(bytes)%2 != 0 {
+		return "", SyntaxError{"odd-length BMPString"}
+	}
+	s := make([]uint16, len(bytes)/2)
+	for i := range s {
+		s[i] = uint16(bytes[2*i])<<8 | uint16(bytes[2*i+1])
+	}
+	return string(utf16.Decode(s)), nil
+}
+
+// UniversalString
+
+// parseUniversalString parses a ASN.1 UniversalString (UCS-4, big-endian)
+// from the given byte slice and returns it as UTF-8.
+func parseUniversalString(bytes []byte) (ret string, err error) {
+	if len(bytes)%4 != 0 {
+		return "", SyntaxError{"UniversalString length not a multiple of four"}
+	}
+	s := make([]rune, len(bytes)/4)
+	for i := range s {
+		b := bytes[4*i : 4*i+4]
+		r := rune(b[0])<<24 | rune(b[1])<<16 | rune(b[2])<<8 | rune(b[3])
+		if !utf8.ValidRune(r) {
+			return "", SyntaxError{"UniversalString contains invalid character"}
+		}
+		s[i] = r
+	}
+	return string(s), nil
+}
+
+// REAL
+
+// parseReal parses a ASN.1 REAL from the given byte slice. Binary (bases 2,
+// 8 and 16), decimal and special real values are all accepted.
+func parseReal(bytes []byte) (float64, error) {
+	if len(bytes) == 0 {
+		return 0, nil
+	}
+	b := bytes[0]
+	switch {
+	case b&0x80 != 0:
+		// Binary encoding: sign, base, scale factor and exponent format.
+		var shift int64
+		switch (b >> 4) & 3 {
+		case 0:
+			shift = 1
+		case 1:
+			shift = 3
+		case 2:
+			shift = 4
+		default:
+			return 0, SyntaxError{"REAL has reserved base"}
+		}
+		scale := int64(b>>2) & 3
+		bytes = bytes[1:]
+		expLen := int(b&3) + 1
+		if expLen == 4 {
+			if len(bytes) == 0 {
+				return 0, SyntaxError{"truncated REAL"}
+			}
+			expLen = int(bytes[0])
+			bytes = bytes[1:]
+		}
+		if expLen == 0 || len(bytes) < expLen {
+			return 0, SyntaxError{"truncated REAL"}
+		}
+		if expLen > 4 {
+			return 0, StructuralError{"REAL exponent too large"}
+		}
+		exp := int64(int8(bytes[0]))
+		for _, c := range bytes[1:expLen] {
+			exp = exp<<8 | int64(c)
+		}
+		bytes = bytes[expLen:]
+		if len(bytes) == 0 {
+			return 0, SyntaxError{"REAL has no mantissa"}
+		}
+		mant := new(big.Float).SetInt(new(big.Int).SetBytes(bytes))
+		if b&0x40 != 0 {
+			mant.Neg(mant)
+		}
+		// Reject exponents far outside the range of a float64 before
+		// they can overflow an int.
+		exp = exp*shift + scale
+		if exp > 1<<20 || exp < -1<<20 {
+			return 0, StructuralError{"REAL exponent out of range"}
+		}
+		f, _ := new(big.Float).SetMantExp(mant, int(exp)).Float64()
+		if math.IsInf(f, 0) {
+			return 0, StructuralError{"REAL value out of range"}
+		}
+		return f, nil
+	case b&0x40 != 0:
+		// Special real values.
+		if len(bytes) != 1 {
+			return 0, SyntaxError{"invalid special REAL value"}
+		}
+		switch b {
+		case 0x40:
+			return math.Inf(1), nil
+		case 0x41:
+			return math.Inf(-1), nil
+		case 0x42:
+			return math.NaN(), nil
+		case 0x43:
+			return math.Copysign(0, -1), nil
+		}
+		return 0, SyntaxError{"invalid special REAL value"}
+	}
+	// Decimal encoding in ISO 6093 NR1, NR2 or NR3 form.
+	if form := b & 0x3f; form < 1 || form > 3 {
+		return 0, SyntaxError{"invalid REAL decimal form"}
+	}
+	s := strings.TrimLeft(string(bytes[1:]), " ")
+	s = strings.Replace(s, ",", ".", 1)
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, SyntaxError{"invalid decimal REAL"}
+	}
+	return f, nil
+}
+
 // A RawValue represents an undecoded ASN.1 object.
 type RawValue struct {
 	Class, Tag int
@@ -530,7 +661,7 @@ func parseSequenceOf(bytes []byte, sliceType reflect.Type, elemType reflect.Type
 			return
 		}
 		switch t.tag {
-		case TagIA5String, TagGeneralString, TagT61String, TagUTF8String:
+		case TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagBMPString, TagUniversalString:
 			// We pretend that various other string types are
 			// PRINTABLE STRINGs so that a sequence of them can be
 			// parsed into a []string.
@@ -635,8 +766,18 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 				result, err = parseT61String(innerBytes)
 			case TagUTF8String:
 				result, err = parseUTF8String(innerBytes)
+			case TagBMPString:
+				result, err = parseBMPString(innerBytes)
+			case TagUniversalString:
+				result, err = parseUniversalString(innerBytes)
 			case TagInteger:
 				result, err = parseInt64(innerBytes)
+			case TagEnum:
+				var i int32
+				i, err = parseInt32(innerBytes)
+				result = Enumerated(i)
+			case TagReal:
+				result, err = parseReal(innerBytes)
 			case TagBitString:
 				result, err = parseBitString(innerBytes)
 			case TagOID:
@@ -660,6 +801,11 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 		}
 		return
 	}
+
+	if params.choice {
+		return parseChoice(v, bytes, initOffset, params)
+	}
+
 	universalTag, compoundType, ok1 := getUniversalType(fieldType)
 	if !ok1 {
 		err = StructuralError{fmt.Sprintf("unknown Go type: %v", fieldType)}
@@ -711,8 +857,7 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 	// wire, we change the universal type to match.
 	if universalTag == TagPrintableString {
 		if t.class == ClassUniversal {
-			switch t.tag {
-			case TagIA5String, TagGeneralString, TagT61String, TagUTF8String:
+			if isStringTag(t.tag) {
 				universalTag = t.tag
 			}
 		} else if params.stringType != 0 {
@@ -730,6 +875,10 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 		universalTag = TagSet
 	}
 
+	if params.enumerated && universalTag == TagInteger {
+		universalTag = TagEnum
+	}
+
 	expectedClass := ClassUniversal
 	expectedTag := universalTag
 
@@ -833,6 +982,16 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 		}
 		return
 	// TODO(dfc) Add support for the remaining integer types
+	case reflect.Float32, reflect.Float64:
+		f, err1 := parseReal(innerBytes)
+		if err1 == nil && val.OverflowFloat(f) {
+			err1 = StructuralError{"REAL too large for " + fieldType.String()}
+		}
+		if err1 == nil {
+			val.SetFloat(f)
+		}
+		err = err1
+		return
 	case reflect.Struct:
 		structType := fieldType
 
@@ -887,6 +1046,10 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 			// that allow the encoding to change midstring and
 			// such. We give up and pass it as an 8-bit string.
 			v, err = parseT61String(innerBytes)
+		case TagBMPString:
+			v, err = parseBMPString(innerBytes)
+		case TagUniversalString:
+			v, err = parseUniversalString(innerBytes)
 		default:
 			err = SyntaxError{fmt.Sprintf("internal error: unknown string type %d", universalTag)}
 		}
@@ -899,6 +1062,133 @@ func parseField(v reflect.Value, bytes []byte, initOffset int, params fieldParam
 	return
 }
 
+// isStringTag reports whether tag is one of the universal string types that
+// can be written to a Go string.
+func isStringTag(tag int) bool {
+	switch tag {
+	case TagPrintableString, TagIA5String, TagGeneralString, TagT61String, TagUTF8String, TagBMPString, TagUniversalString:
+		return true
+	}
+	return false
+}
+
+// matchesTag reports whether an element with the given tag and length could
+// be parsed into a value of type t with the given field parameters.
+func matchesTag(t reflect.Type, params fieldParameters, tl tagAndLength) bool {
+	if params.tag != nil {
+		class := ClassContextSpecific
+		if params.application {
+			class = ClassApplication
+		}
+		return tl.class == class && tl.tag == *params.tag
+	}
+	if t == rawValueType || t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return true
+	}
+	if tl.class != ClassUniversal {
+		return false
+	}
+	tag, compound, ok := getUniversalType(t)
+	if !ok || compound != tl.isCompound {
+		return false
+	}
+	switch {
+	case params.set:
+		tag = TagSet
+	case params.enumerated && tag == TagInteger:
+		tag = TagEnum
+	case tag == TagPrintableString:
+		return isStringTag(tl.tag)
+	case tag == TagUTCTime:
+		return tl.tag == TagUTCTime || tl.tag == TagGeneralizedTime
+	}
+	return tl.tag == tag
+}
+
+// parseChoice parses an ASN.1 CHOICE into v, which must be a struct whose
+// exported fields are all pointers. The field whose type and tags match the
+// next element is allocated and filled in; all others are set to nil.
+func parseChoice(v reflect.Value, bytes []byte, initOffset int, params fieldParameters) (offset int, err error) {
+	structType := v.Type()
+	if structType.Kind() != reflect.Struct {
+		err = StructuralError{"CHOICE must be a struct: " + structType.String()}
+		return
+	}
+
+	offset = initOffset
+	if params.explicit {
+		expectedClass := ClassContextSpecific
+		if params.application {
+			expectedClass = ClassApplication
+		}
+		var t tagAndLength
+		t, offset, err = parseTagAndLength(bytes, offset)
+		if err != nil {
+			return
+		}
+		if t.class != expectedClass || t.tag != *params.tag || !t.isCompound {
+			if setDefaultValue(v, params) {
+				offset = initOffset
+			} else {
+				err = StructuralError{"explicitly tagged member didn't match"}
+			}
+			return
+		}
+		if invalidLength(offset, t.length, len(bytes)) {
+			err = SyntaxError{"data truncated"}
+			return
+		}
+		bytes = bytes[:offset+t.length]
+		if offset == len(bytes) {
+			err = StructuralError{"explicit tag has no child"}
+			return
+		}
+	}
+
+	t, _, err := parseTagAndLength(bytes, offset)
+	if err != nil {
+		return
+	}
+	v.Set(reflect.Zero(structType))
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Type.Kind() != reflect.Ptr {
+			err = StructuralError{"CHOICE alternative must be a pointer: " + field.Name}
+			return
+		}
+		fp := parseFieldParameters(field.Tag.Get("asn1"))
+		fp.optional = false
+		elemType := field.Type.Elem()
+		if field.Type == bigIntType {
+			elemType = field.Type
+		}
+		if !matchesTag(elemType, fp, t) {
+			continue
+		}
+		if field.Type == bigIntType {
+			offset, err = parseField(v.Field(i), bytes, offset, fp)
+		} else {
+			alt := reflect.New(elemType)
+			offset, err = parseField(alt.Elem(), bytes, offset, fp)
+			v.Field(i).Set(alt)
+		}
+		if err == nil && params.explicit && offset != len(bytes) {
+			err = SyntaxError{"trailing data inside explicit tag"}
+		}
+		return
+	}
+
+	if setDefaultValue(v, params) {
+		offset = initOffset
+	} else {
+		err = StructuralError{fmt.Sprintf("no CHOICE alternative of %s matches tag %d (class %d)", structType, t.tag, t.class)}
+	}
+	return
+}
+
 // canHaveDefaultValue reports whether k is a Kind that we will set a default
 // value for. (A signed integer, essentially.)
 func canHaveDefaultValue(k reflect.Kind) bool {
@@ -944,11 +1234,15 @@ func setDefaultValue(v reflect.Value, params fieldParameters) (ok bool) {
 // An ASN.1 OBJECT IDENTIFIER can be written to an
 // ObjectIdentifier.
 //
-// An ASN.1 ENUMERATED can be written to an Enumerated.
+// An ASN.1 ENUMERATED can be written to an Enumerated, or to an integer
+// field tagged "enumerated".
+//
+// An ASN.1 REAL can be written to a float32 or float64.
 //
 // An ASN.1 UTCTIME or GENERALIZEDTIME can be written to a time.Time.
 //
-// An ASN.1 PrintableString or IA5String can be written to a string.
+// An ASN.1 PrintableString, IA5String, T61String, UTF8String, GeneralString,
+// BMPString or UniversalString can be written to a string.
 //
 // Any of the above ASN.1 values can be written to an interface{}.
 // The value stored in the interface has the corresponding Go type.
@@ -961,10 +1255,17 @@ func setDefaultValue(v reflect.Value, params fieldParameters) (ok bool) {
 // if each of the elements in the sequence can be
 // written to the corresponding element in the struct.
 //
+// An ASN.1 CHOICE can be written to a struct field tagged "choice". Each
+// exported field of that struct must be a pointer and is one alternative,
+// distinguished by its type or its own tags. The alternative matching the
+// encoded element is allocated and the others are left nil.
+//
 // The following tags on struct fields have special meaning to Unmarshal:
 //
 //	application	specifies that a APPLICATION tag is used
+//	choice		marks the field as an ASN.1 CHOICE of its pointer fields
 //	default:x	sets the default value for optional integer fields
+//	enumerated	causes an integer field to be an ASN.1 ENUMERATED
 //	explicit	specifies that an additional, explicit tag wraps the implicit one
 //	optional	marks the field as ASN.1 OPTIONAL
 //	set		causes a SET, rather than a SEQUENCE type to be expected
@@ -993,3 +1294,24 @@ func UnmarshalWithParams(b []byte, val interface{}, params string) (rest []byte,
 	}
 	return b[offset:], nil
 }
+
+// UnmarshalBER is like Unmarshal but accepts any BER encoding of the value,
+// including indefinite lengths and constructed strings. The first element
+// of b is normalized to DER before being parsed, so RawValue and RawContent
+// fields receive DER rather than the original bytes. The returned rest is
+// a suffix of b.
+func UnmarshalBER(b []byte, val interface{}) (rest []byte, err error) {
+	return UnmarshalBERWithParams(b, val, "")
+}
+
+// UnmarshalBERWithParams is like UnmarshalWithParams but accepts BER.
+func UnmarshalBERWithParams(b []byte, val interface{}, params string) (rest []byte, err error) {
+	der, n, err := berToDER(nil, b, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = UnmarshalWithParams(der, val, params); err != nil {
+		return nil, err
+	}
+	return b[n:], nil
+}
@@ -7,6 +7,7 @@ package asn1
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"strings"
@@ -422,8 +423,12 @@ var parseFieldParametersTestData []parseFieldParametersTest = []parseFieldParame
 	{"default:42", fieldParameters{defaultValue: newInt64(42)}},
 	{"tag:17", fieldParameters{tag: newInt(17)}},
 	{"optional,explicit,default:42,tag:17", fieldParameters{optional: true, explicit: true, defaultValue: newInt64(42), tag: newInt(17)}},
-	{"optional,explicit,default:42,tag:17,rubbish1", fieldParameters{true, true, false, newInt64(42), newInt(17), 0, 0, false, false}},
+	{"optional,explicit,default:42,tag:17,rubbish1", fieldParameters{true, true, false, newInt64(42), newInt(17), 0, 0, false, false, false, false}},
 	{"set", fieldParameters{set: true}},
+	{"bmp", fieldParameters{stringType: TagBMPString}},
+	{"universal", fieldParameters{stringType: TagUniversalString}},
+	{"enumerated", fieldParameters{enumerated: true}},
+	{"explicit,tag:2,choice", fieldParameters{explicit: true, tag: newInt(2), choice: true}},
 }
 
 func TestParseFieldParameters(t *testing.T) {
@@ -969,3 +974,240 @@ func TestMarshalNilValue(t *testing.T) {
 		}
 	}
 }
+
+var realTestData = []struct {
+	in  []byte
+	ok  bool
+	out float64
+}{
+	{[]byte{}, true, 0},
+	{[]byte{0x80, 0x00, 0x01}, true, 1},
+	{[]byte{0xc0, 0x00, 0x03}, true, -3},
+	{[]byte{0x80, 0xff, 0x01}, true, 0.5},
+	{[]byte{0x90, 0x01, 0x01}, true, 8},
+	{[]byte{0xa4, 0x01, 0x03}, true, 96},
+	{[]byte{0x81, 0x01, 0x00, 0x01}, true, math.Ldexp(1, 256)},
+	{[]byte{0x83, 0x01, 0x02, 0x05}, true, 20},
+	{[]byte{0x40}, true, math.Inf(1)},
+	{[]byte{0x41}, true, math.Inf(-1)},
+	{[]byte{0x01, '1', '2'}, true, 12},
+	{[]byte{0x02, ' ', '2', ',', '5'}, true, 2.5},
+	{[]byte{0x03, '1', '.', '5', 'E', '1'}, true, 15},
+	{[]byte{0x80}, false, 0},
+	{[]byte{0x80, 0x00}, false, 0},
+	{[]byte{0x83, 0x05, 0, 0, 0, 0, 0, 1}, false, 0},
+	{[]byte{0xb0, 0x00, 0x01}, false, 0},
+	{[]byte{0x82, 0x7f, 0xff, 0xff, 0x01}, false, 0},
+	{[]byte{0x44}, false, 0},
+	{[]byte{0x40, 0x00}, false, 0},
+	{[]byte{0x04, '1'}, false, 0},
+	{[]byte{0x03, 'x'}, false, 0},
+}
+
+func TestParseReal(t *testing.T) {
+	for i, test := range realTestData {
+		ret, err := parseReal(test.in)
+		if (err == nil) != test.ok {
+			t.Errorf("#%d: Incorrect error result (did fail? %v, expected: %v)", i, err == nil, test.ok)
+		}
+		if test.ok && ret != test.out {
+			t.Errorf("#%d: Bad result: %v (expected %v)", i, ret, test.out)
+		}
+	}
+
+	if ret, err := parseReal([]byte{0x42}); err != nil || !math.IsNaN(ret) {
+		t.Errorf("NaN: got %v, %v", ret, err)
+	}
+	if ret, err := parseReal([]byte{0x43}); err != nil || ret != 0 || !math.Signbit(ret) {
+		t.Errorf("-0: got %v, %v", ret, err)
+	}
+}
+
+func TestRealRoundTrip(t *testing.T) {
+	values := []float64{
+		1, -1, 0.1, 3.141592653589793, -1e-300, 1e300,
+		math.MaxFloat64, math.SmallestNonzeroFloat64, 1 << 62,
+	}
+	for _, f := range values {
+		data, err := Marshal(f)
+		if err != nil {
+			t.Errorf("Marshal(%v): %v", f, err)
+			continue
+		}
+		var got float64
+		if _, err := Unmarshal(data, &got); err != nil {
+			t.Errorf("Unmarshal(%x): %v", data, err)
+			continue
+		}
+		if got != f {
+			t.Errorf("%v round-tripped to %v", f, got)
+		}
+	}
+
+	var f32 float32
+	if _, err := Unmarshal([]byte{0x09, 0x04, 0x81, 0x01, 0x00, 0x01}, &f32); err == nil {
+		t.Errorf("REAL too large for float32 was accepted")
+	}
+}
+
+func TestParseBMPAndUniversalString(t *testing.T) {
+	if s, err := parseBMPString([]byte{0x00, 'h', 0x00, 0xe9, 0x20, 0xac}); err != nil || s != "hé€" {
+		t.Errorf("parseBMPString: got %q, %v", s, err)
+	}
+	if _, err := parseBMPString([]byte{0x00}); err == nil {
+		t.Errorf("odd-length BMPString was accepted")
+	}
+	if s, err := parseUniversalString([]byte{0, 0, 0, 'a', 0, 1, 0xf6, 0x00}); err != nil || s != "a\U0001F600" {
+		t.Errorf("parseUniversalString: got %q, %v", s, err)
+	}
+	if _, err := parseUniversalString([]byte{0, 0, 0}); err == nil {
+		t.Errorf("truncated UniversalString was accepted")
+	}
+	if _, err := parseUniversalString([]byte{0, 0x11, 0, 0}); err == nil {
+		t.Errorf("UniversalString with invalid character was accepted")
+	}
+}
+
+func newFloat64(f float64) *float64 { return &f }
+
+var unmarshalNewTypesTestData = []struct {
+	in  []byte
+	out interface{}
+}{
+	{[]byte{0x09, 0x03, 0x80, 0xff, 0x01}, newFloat64(0.5)},
+	{[]byte{0x1e, 0x04, 0x00, 'h', 0x00, 0xe9}, newString("hé")},
+	{[]byte{0x1c, 0x04, 0x00, 0x00, 0x00, 'a'}, newString("a")},
+	{[]byte{0x30, 0x06, 0x1e, 0x04, 0x00, 'h', 0x00, 'i'}, &bmpStringTest{"hi"}},
+	{[]byte{0x30, 0x07, 0x1e, 0x02, 0x00, 'a', 0x13, 0x01, 'b'}, &[]string{"a", "b"}},
+	{[]byte{0x30, 0x03, 0x0a, 0x01, 0x02}, &enumeratedTest{2}},
+	{[]byte{0x30, 0x03, 0x02, 0x01, 0x05}, &choiceTest{choiceAlternatives{Int: newInt(5)}}},
+	{[]byte{0x30, 0x04, 0x81, 0x02, 'a', 'b'}, &choiceTest{choiceAlternatives{Str: newString("ab")}}},
+	{[]byte{0x30, 0x05, 0xa2, 0x03, 0x02, 0x01, 0x01}, &choiceTest{choiceAlternatives{Big: big.NewInt(1)}}},
+	{[]byte{0x30, 0x07, 0xa3, 0x05, 0x30, 0x03, 0x02, 0x01, 0x40}, &explicitChoiceTest{choiceAlternatives{Seq: &intStruct{64}}}},
+	{[]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x01, 0x01, 0xff}, &optionalChoiceTest{choiceAlternatives{Int: newInt(1)}, true}},
+	{[]byte{0x30, 0x03, 0x01, 0x01, 0xff}, &optionalChoiceTest{A: true}},
+}
+
+func TestUnmarshalNewTypes(t *testing.T) {
+	for i, test := range unmarshalNewTypesTestData {
+		pv := reflect.New(reflect.TypeOf(test.out).Elem())
+		val := pv.Interface()
+		_, err := Unmarshal(test.in, val)
+		if err != nil {
+			t.Errorf("#%d: Unmarshal failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(val, test.out) {
+			t.Errorf("#%d:\nhave %#v\nwant %#v", i, val, test.out)
+		}
+	}
+}
+
+func TestUnmarshalChoiceErrors(t *testing.T) {
+	var c choiceTest
+	if _, err := Unmarshal([]byte{0x30, 0x03, 0x01, 0x01, 0xff}, &c); err == nil {
+		t.Errorf("CHOICE with no matching alternative was accepted")
+	}
+	var e explicitChoiceTest
+	if _, err := Unmarshal([]byte{0x30, 0x08, 0xa3, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}, &e); err == nil {
+		t.Errorf("trailing data inside explicit CHOICE was accepted")
+	}
+	var bad struct {
+		C struct{ A int } `asn1:"choice"`
+	}
+	if _, err := Unmarshal([]byte{0x30, 0x03, 0x02, 0x01, 0x01}, &bad); err == nil {
+		t.Errorf("CHOICE with a non-pointer alternative was accepted")
+	}
+}
+
+func TestUnmarshalAnyNewTypes(t *testing.T) {
+	tests := []struct {
+		in  []byte
+		out interface{}
+	}{
+		{[]byte{0x0a, 0x01, 0x03}, Enumerated(3)},
+		{[]byte{0x09, 0x03, 0x80, 0x00, 0x01}, 1.0},
+		{[]byte{0x1e, 0x02, 0x00, 'a'}, "a"},
+		{[]byte{0x1c, 0x04, 0x00, 0x00, 0x00, 'a'}, "a"},
+	}
+	for i, test := range tests {
+		var v interface{}
+		if _, err := Unmarshal(test.in, &v); err != nil {
+			t.Errorf("#%d: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, test.out) {
+			t.Errorf("#%d: got %#v, want %#v", i, v, test.out)
+		}
+	}
+}
+
+var unmarshalBERTestData = []struct {
+	in  []byte
+	out interface{}
+}{
+	// Indefinite length.
+	{[]byte{0x30, 0x80, 0x02, 0x01, 0x05, 0x00, 0x00}, &intStruct{5}},
+	// Non-minimal length.
+	{[]byte{0x02, 0x81, 0x01, 0x05}, newInt(5)},
+	{[]byte{0x30, 0x83, 0x00, 0x00, 0x03, 0x02, 0x01, 0x05}, &intStruct{5}},
+	// Nested indefinite length inside a definite one.
+	{[]byte{0x30, 0x07, 0x30, 0x80, 0x02, 0x01, 0x07, 0x00, 0x00}, &nestedStruct{intStruct{7}}},
+	// Constructed strings.
+	{[]byte{0x24, 0x80, 0x04, 0x02, 0x01, 0x02, 0x04, 0x01, 0x03, 0x00, 0x00}, &[]byte{1, 2, 3}},
+	{[]byte{0x24, 0x09, 0x04, 0x01, 0x01, 0x24, 0x80, 0x04, 0x00, 0x00, 0x00}, &[]byte{1}},
+	{[]byte{0x33, 0x80, 0x13, 0x02, 'a', 'b', 0x13, 0x01, 'c', 0x00, 0x00}, newString("abc")},
+	{[]byte{0x23, 0x80, 0x03, 0x02, 0x00, 0xaa, 0x03, 0x02, 0x04, 0xb0, 0x00, 0x00}, &BitString{[]byte{0xaa, 0xb0}, 12}},
+	// Any non-zero BOOLEAN is true.
+	{[]byte{0x01, 0x01, 0x01}, newBool(true)},
+}
+
+func TestUnmarshalBER(t *testing.T) {
+	for i, test := range unmarshalBERTestData {
+		pv := reflect.New(reflect.TypeOf(test.out).Elem())
+		val := pv.Interface()
+		in := append(test.in[:len(test.in):len(test.in)], 0xff)
+		rest, err := UnmarshalBER(in, val)
+		if err != nil {
+			t.Errorf("#%d: UnmarshalBER failed: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(rest, []byte{0xff}) {
+			t.Errorf("#%d: rest is %x, want ff", i, rest)
+		}
+		if !reflect.DeepEqual(val, test.out) {
+			t.Errorf("#%d:\nhave %#v\nwant %#v", i, val, test.out)
+		}
+	}
+}
+
+func TestUnmarshalBERErrors(t *testing.T) {
+	deep := make([]byte, 0, 2*100+2)
+	for i := 0; i < 100; i++ {
+		deep = append(deep, 0x30, 0x80)
+	}
+	tests := [][]byte{
+		// Missing end-of-contents.
+		{0x30, 0x80, 0x02, 0x01, 0x05},
+		// Indefinite length on a primitive.
+		{0x04, 0x80, 0x00, 0x00},
+		// Truncated definite length.
+		{0x30, 0x05, 0x02, 0x01},
+		// Segment with the wrong tag.
+		{0x24, 0x80, 0x13, 0x01, 'A', 0x00, 0x00},
+		// Padding bits in a BIT STRING segment that isn't the last.
+		{0x23, 0x80, 0x03, 0x02, 0x04, 0xa0, 0x03, 0x02, 0x00, 0xbb, 0x00, 0x00},
+		deep,
+	}
+	for i, test := range tests {
+		var v interface{}
+		if _, err := UnmarshalBER(test, &v); err == nil {
+			t.Errorf("#%d: UnmarshalBER(%x) succeeded", i, test)
+		}
+	}
+
+	// DER decoding still rejects BER.
+	var v intStruct
+	if _, err := Unmarshal(unmarshalBERTestData[0].in, &v); err == nil {
+		t.Errorf("Unmarshal accepted an indefinite length")
+	}
+}
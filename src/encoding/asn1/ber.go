@@ -0,0 +1,214 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+// BER, the Basic Encoding Rules, permit several encodings of the same value
+// that DER forbids: indefinite lengths terminated by an end-of-contents
+// marker, lengths that are not minimally encoded, strings split into
+// constructed segments and any non-zero byte for a true BOOLEAN. Rather than
+// teach the parser about each of these, BER input is rewritten into DER and
+// then parsed as usual.
+//
+// The ordering of SET OF elements is not changed; the parser does not care
+// about it.
+
+// maxBERDepth limits the nesting of constructed BER elements.
+const maxBERDepth = 64
+
+// berHeader is the identifier and length of a BER element. A length of -1
+// means the indefinite form.
+type berHeader struct {
+	tagAndLength
+	headerLen int
+}
+
+// parseBERHeader parses the identifier and length octets at the start of b.
+func parseBERHeader(b []byte) (h berHeader, err error) {
+	if len(b) == 0 {
+		err = SyntaxError{"truncated tag or length"}
+		return
+	}
+	offset := 1
+	h.class = int(b[0] >> 6)
+	h.isCompound = b[0]&0x20 == 0x20
+	h.tag = int(b[0] & 0x1f)
+	if h.tag == 0x1f {
+		h.tag, offset, err = parseBase128Int(b, offset)
+		if err != nil {
+			return
+		}
+	}
+	if offset >= len(b) {
+		err = SyntaxError{"truncated tag or length"}
+		return
+	}
+	l := b[offset]
+	offset++
+	switch {
+	case l&0x80 == 0:
+		h.length = int(l)
+	case l == 0x80:
+		if !h.isCompound {
+			err = SyntaxError{"indefinite length on primitive element"}
+			return
+		}
+		h.length = -1
+	default:
+		numBytes := int(l & 0x7f)
+		for i := 0; i < numBytes; i++ {
+			if offset >= len(b) {
+				err = SyntaxError{"truncated tag or length"}
+				return
+			}
+			if h.length >= 1<<23 {
+				err = StructuralError{"length too large"}
+				return
+			}
+			h.length = h.length<<8 | int(b[offset])
+			offset++
+		}
+	}
+	h.headerLen = offset
+	return
+}
+
+// isBERStringTag reports whether a universal element with the given tag may
+// be split into constructed segments in BER.
+func isBERStringTag(tag int) bool {
+	switch tag {
+	case TagBitString, TagOctetString, TagUTCTime, TagGeneralizedTime:
+		return true
+	}
+	return isStringTag(tag)
+}
+
+// berToDER appends the DER form of the BER element at the start of b to out.
+// It returns the extended slice and the number of bytes of b consumed.
+func berToDER(out, b []byte, depth int) ([]byte, int, error) {
+	if depth > maxBERDepth {
+		return nil, 0, StructuralError{"BER nesting too deep"}
+	}
+	h, err := parseBERHeader(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if h.length >= 0 && invalidLength(h.headerLen, h.length, len(b)) {
+		return nil, 0, SyntaxError{"data truncated"}
+	}
+
+	if !h.isCompound {
+		body := b[h.headerLen : h.headerLen+h.length]
+		if h.class == ClassUniversal && h.tag == TagBoolean && len(body) == 1 && body[0] != 0 {
+			body = []byte{0xff}
+		}
+		out = appendTagAndLength(out, h.tagAndLength)
+		return append(out, body...), h.headerLen + h.length, nil
+	}
+
+	if h.class == ClassUniversal && isBERStringTag(h.tag) {
+		var body []byte
+		padding := -1
+		if h.tag == TagBitString {
+			body = []byte{0}
+			padding = 0
+		}
+		body, n, err := berStringSegments(body, b, h, depth, &padding)
+		if err != nil {
+			return nil, 0, err
+		}
+		if h.tag == TagBitString {
+			body[0] = byte(padding)
+		}
+		out = appendTagAndLength(out, tagAndLength{h.class, h.tag, len(body), false})
+		return append(out, body...), n, nil
+	}
+
+	var body []byte
+	n, err := berChildren(b, h, func(child []byte) (int, error) {
+		var m int
+		var err error
+		body, m, err = berToDER(body, child, depth+1)
+		return m, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	out = appendTagAndLength(out, tagAndLength{h.class, h.tag, len(body), true})
+	return append(out, body...), n, nil
+}
+
+// berChildren calls f for each child of the constructed element with header
+// h at the start of b. f is passed the remaining bytes and returns how many
+// it consumed. berChildren returns the length of the whole element.
+func berChildren(b []byte, h berHeader, f func(child []byte) (int, error)) (int, error) {
+	offset := h.headerLen
+	end := len(b)
+	if h.length >= 0 {
+		end = offset + h.length
+	}
+	for {
+		if h.length >= 0 && offset == end {
+			return end, nil
+		}
+		if offset >= end {
+			return 0, SyntaxError{"data truncated"}
+		}
+		if h.length < 0 && offset+1 < end && b[offset] == 0 && b[offset+1] == 0 {
+			return offset + 2, nil
+		}
+		n, err := f(b[offset:end])
+		if err != nil {
+			return 0, err
+		}
+		offset += n
+	}
+}
+
+// berStringSegments appends the contents of the possibly constructed string
+// element with header h at the start of b to out. For a BIT STRING, padding
+// holds the number of unused bits in the last segment seen; only the final
+// segment may have any.
+func berStringSegments(out, b []byte, h berHeader, depth int, padding *int) ([]byte, int, error) {
+	if depth > maxBERDepth {
+		return nil, 0, StructuralError{"BER nesting too deep"}
+	}
+	if !h.isCompound {
+		body := b[h.headerLen : h.headerLen+h.length]
+		if *padding >= 0 {
+			if *padding != 0 {
+				return nil, 0, SyntaxError{"padding bits in a BIT STRING segment other than the last"}
+			}
+			if len(body) == 0 {
+				return nil, 0, SyntaxError{"zero length BIT STRING"}
+			}
+			if body[0] > 7 || len(body) == 1 && body[0] != 0 {
+				return nil, 0, SyntaxError{"invalid padding bits in BIT STRING"}
+			}
+			*padding = int(body[0])
+			body = body[1:]
+		}
+		return append(out, body...), h.headerLen + h.length, nil
+	}
+	tag := h.tag
+	n, err := berChildren(b, h, func(child []byte) (int, error) {
+		ch, err := parseBERHeader(child)
+		if err != nil {
+			return 0, err
+		}
+		if ch.class != ClassUniversal || ch.tag != tag {
+			return 0, StructuralError{"constructed string segment has the wrong tag"}
+		}
+		if ch.length >= 0 && invalidLength(ch.headerLen, ch.length, len(child)) {
+			return 0, SyntaxError{"data truncated"}
+		}
+		var m int
+		out, m, err = berStringSegments(out, child, ch, depth+1, padding)
+		return m, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, n, nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+)
+
+// A Builder constructs DER-encoded ASN.1 data one element at a time. It is
+// intended for protocol code that is easier to write by hand than to
+// describe with struct tags. The zero value is an empty Builder ready to use.
+//
+// The first error encountered is remembered and returned by Bytes; the Add
+// methods do nothing after an error.
+type Builder struct {
+	out []byte
+	err error
+}
+
+// Bytes returns the encoded elements added so far, or the first error
+// encountered while adding them.
+func (b *Builder) Bytes() ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.out, nil
+}
+
+// appendTagAndLength appends the DER encoding of t to dst.
+func appendTagAndLength(dst []byte, t tagAndLength) []byte {
+	f := newForkableWriter()
+	// Writes to a bytes.Buffer cannot fail.
+	marshalTagAndLength(f, t)
+	return append(dst, f.Bytes()...)
+}
+
+// add appends an element with the given identifier whose body is written
+// by marshal.
+func (b *Builder) add(class, tag int, isCompound bool, marshal func(*forkableWriter) error) {
+	if b.err != nil {
+		return
+	}
+	body := newForkableWriter()
+	if err := marshal(body); err != nil {
+		b.err = err
+		return
+	}
+	var buf bytes.Buffer
+	body.writeTo(&buf)
+	b.out = appendTagAndLength(b.out, tagAndLength{class, tag, buf.Len(), isCompound})
+	b.out = append(b.out, buf.Bytes()...)
+}
+
+// addConstructed appends a constructed element whose contents are added to
+// a new Builder by f.
+func (b *Builder) addConstructed(class, tag int, f func(*Builder)) {
+	if b.err != nil {
+		return
+	}
+	var child Builder
+	f(&child)
+	if child.err != nil {
+		b.err = child.err
+		return
+	}
+	b.out = appendTagAndLength(b.out, tagAndLength{class, tag, len(child.out), true})
+	b.out = append(b.out, child.out...)
+}
+
+// AddBool appends a BOOLEAN.
+func (b *Builder) AddBool(v bool) {
+	b.add(ClassUniversal, TagBoolean, false, func(out *forkableWriter) error {
+		if v {
+			return out.WriteByte(0xff)
+		}
+		return out.WriteByte(0)
+	})
+}
+
+// AddInt64 appends an INTEGER.
+func (b *Builder) AddInt64(v int64) {
+	b.add(ClassUniversal, TagInteger, false, func(out *forkableWriter) error {
+		return marshalInt64(out, v)
+	})
+}
+
+// AddBigInt appends an INTEGER.
+func (b *Builder) AddBigInt(n *big.Int) {
+	b.add(ClassUniversal, TagInteger, false, func(out *forkableWriter) error {
+		return marshalBigInt(out, n)
+	})
+}
+
+// AddEnum appends an ENUMERATED.
+func (b *Builder) AddEnum(v int64) {
+	b.add(ClassUniversal, TagEnum, false, func(out *forkableWriter) error {
+		return marshalInt64(out, v)
+	})
+}
+
+// AddReal appends a REAL.
+func (b *Builder) AddReal(f float64) {
+	b.add(ClassUniversal, TagReal, false, func(out *forkableWriter) error {
+		return marshalReal(out, f)
+	})
+}
+
+// AddNull appends a NULL.
+func (b *Builder) AddNull() {
+	b.add(ClassUniversal, TagNull, false, func(*forkableWriter) error { return nil })
+}
+
+// AddOctetString appends an OCTET STRING.
+func (b *Builder) AddOctetString(v []byte) {
+	b.AddPrimitive(ClassUniversal, TagOctetString, v)
+}
+
+// AddBitString appends a BIT STRING.
+func (b *Builder) AddBitString(v BitString) {
+	b.add(ClassUniversal, TagBitString, false, func(out *forkableWriter) error {
+		return marshalBitString(out, v)
+	})
+}
+
+// AddObjectIdentifier appends an OBJECT IDENTIFIER.
+func (b *Builder) AddObjectIdentifier(oid ObjectIdentifier) {
+	b.add(ClassUniversal, TagOID, false, func(out *forkableWriter) error {
+		return marshalObjectIdentifier(out, oid)
+	})
+}
+
+// AddString appends s as the string type given by tag, which must be one of
+// TagPrintableString, TagIA5String, TagUTF8String, TagBMPString or
+// TagUniversalString.
+func (b *Builder) AddString(tag int, s string) {
+	var marshal func(*forkableWriter, string) error
+	switch tag {
+	case TagPrintableString:
+		marshal = marshalPrintableString
+	case TagIA5String:
+		marshal = marshalIA5String
+	case TagUTF8String:
+		marshal = marshalUTF8String
+	case TagBMPString:
+		marshal = marshalBMPString
+	case TagUniversalString:
+		marshal = marshalUniversalString
+	default:
+		if b.err == nil {
+			b.err = StructuralError{"unsupported string type"}
+		}
+		return
+	}
+	b.add(ClassUniversal, tag, false, func(out *forkableWriter) error {
+		return marshal(out, s)
+	})
+}
+
+// AddTime appends t as a UTCTime or GeneralizedTime, as given by tag.
+func (b *Builder) AddTime(tag int, t time.Time) {
+	var marshal func(*forkableWriter, time.Time) error
+	switch tag {
+	case TagUTCTime:
+		marshal = marshalUTCTime
+	case TagGeneralizedTime:
+		marshal = marshalGeneralizedTime
+	default:
+		if b.err == nil {
+			b.err = StructuralError{"unsupported time type"}
+		}
+		return
+	}
+	b.add(ClassUniversal, tag, false, func(out *forkableWriter) error {
+		return marshal(out, t)
+	})
+}
+
+// AddPrimitive appends a primitive element with the given class, tag and
+// contents. It can be used for implicitly tagged values.
+func (b *Builder) AddPrimitive(class, tag int, body []byte) {
+	b.add(class, tag, false, func(out *forkableWriter) error {
+		_, err := out.Write(body)
+		return err
+	})
+}
+
+// AddRawValue appends v. If v.FullBytes is set it is appended unchanged.
+func (b *Builder) AddRawValue(v RawValue) {
+	if b.err != nil {
+		return
+	}
+	if len(v.FullBytes) != 0 {
+		b.out = append(b.out, v.FullBytes...)
+		return
+	}
+	b.add(v.Class, v.Tag, v.IsCompound, func(out *forkableWriter) error {
+		_, err := out.Write(v.Bytes)
+		return err
+	})
+}
+
+// AddValue appends the encoding of val as produced by MarshalWithParams.
+func (b *Builder) AddValue(val interface{}, params string) {
+	if b.err != nil {
+		return
+	}
+	der, err := MarshalWithParams(val, params)
+	if err != nil {
+		b.err = err
+		return
+	}
+	b.out = append(b.out, der...)
+}
+
+// AddSequence appends a SEQUENCE whose elements are added by f.
+func (b *Builder) AddSequence(f func(*Builder)) {
+	b.addConstructed(ClassUniversal, TagSequence, f)
+}
+
+// AddSet appends a SET whose elements are added by f. The elements are
+// written in the order f adds them.
+func (b *Builder) AddSet(f func(*Builder)) {
+	b.addConstructed(ClassUniversal, TagSet, f)
+}
+
+// AddExplicit appends a context-specific, explicitly tagged element whose
+// contents are added by f.
+func (b *Builder) AddExplicit(tag int, f func(*Builder)) {
+	b.addConstructed(ClassContextSpecific, tag, f)
+}
+
+// AddConstructed appends a constructed element with the given class and tag
+// whose contents are added by f.
+func (b *Builder) AddConstructed(class, tag int, f func(*Builder)) {
+	b.addConstructed(class, tag, f)
+}
@@ -0,0 +1,263 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type builderTestStruct struct {
+	Version int
+	Flag    bool
+	OID     ObjectIdentifier
+	Name    string `asn1:"explicit,tag:0,utf8"`
+	Data    []byte
+	Bits    BitString
+	Kind    int `asn1:"enumerated"`
+	Ratio   float64
+	When    time.Time
+	Title   string `asn1:"bmp"`
+}
+
+var builderTestValue = builderTestStruct{
+	Version: 2,
+	Flag:    true,
+	OID:     ObjectIdentifier{1, 2, 3},
+	Name:    "hé",
+	Data:    []byte{1, 2},
+	Bits:    BitString{[]byte{0x80}, 1},
+	Kind:    3,
+	Ratio:   0.5,
+	When:    time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+	Title:   "ok",
+}
+
+func buildTestValue(b *Builder) {
+	b.AddSequence(func(b *Builder) {
+		b.AddInt64(2)
+		b.AddBool(true)
+		b.AddObjectIdentifier(ObjectIdentifier{1, 2, 3})
+		b.AddExplicit(0, func(b *Builder) {
+			b.AddString(TagUTF8String, "hé")
+		})
+		b.AddOctetString([]byte{1, 2})
+		b.AddBitString(BitString{[]byte{0x80}, 1})
+		b.AddEnum(3)
+		b.AddReal(0.5)
+		b.AddTime(TagUTCTime, time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC))
+		b.AddString(TagBMPString, "ok")
+	})
+}
+
+func TestBuilderMatchesMarshal(t *testing.T) {
+	var b Builder
+	buildTestValue(&b)
+	got, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Marshal(builderTestValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Builder produced %x, Marshal produced %x", got, want)
+	}
+}
+
+var builderTests = []struct {
+	build func(*Builder)
+	out   string // hex encoded
+}{
+	{func(b *Builder) { b.AddNull() }, "0500"},
+	{func(b *Builder) { b.AddBigInt(big.NewInt(-129)) }, "0202ff7f"},
+	{func(b *Builder) { b.AddString(TagUniversalString, "a") }, "1c0400000061"},
+	{func(b *Builder) { b.AddTime(TagGeneralizedTime, time.Unix(1258325776, 0).UTC()) }, "180f32303039313131353232353631365a"},
+	{func(b *Builder) { b.AddPrimitive(ClassContextSpecific, 1, []byte("ab")) }, "81026162"},
+	{func(b *Builder) { b.AddRawValue(RawValue{Class: ClassApplication, Tag: 2, Bytes: []byte{7}}) }, "420107"},
+	{func(b *Builder) { b.AddRawValue(RawValue{FullBytes: []byte{5, 0}}) }, "0500"},
+	{func(b *Builder) { b.AddValue(2, "enumerated") }, "0a0102"},
+	{func(b *Builder) { b.AddSet(func(b *Builder) { b.AddInt64(1) }) }, "3103020101"},
+	{func(b *Builder) { b.AddConstructed(ClassApplication, 40, func(*Builder) {}) }, "7f2800"},
+	{func(b *Builder) { b.AddInt64(1); b.AddInt64(2) }, "020101020102"},
+}
+
+func TestBuilder(t *testing.T) {
+	for i, test := range builderTests {
+		var b Builder
+		test.build(&b)
+		data, err := b.Bytes()
+		if err != nil {
+			t.Errorf("#%d failed: %s", i, err)
+		}
+		out, _ := hex.DecodeString(test.out)
+		if !bytes.Equal(out, data) {
+			t.Errorf("#%d got: %x want %x", i, data, out)
+		}
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	tests := []func(*Builder){
+		func(b *Builder) { b.AddString(TagPrintableString, "é") },
+		func(b *Builder) { b.AddString(TagOctetString, "a") },
+		func(b *Builder) { b.AddTime(TagInteger, time.Now()) },
+		func(b *Builder) { b.AddObjectIdentifier(ObjectIdentifier{3}) },
+		func(b *Builder) { b.AddValue(make(chan int), "") },
+		func(b *Builder) {
+			b.AddSequence(func(b *Builder) {
+				b.AddString(TagBMPString, "\U0001F600")
+			})
+		},
+	}
+	for i, build := range tests {
+		var b Builder
+		build(&b)
+		b.AddInt64(1)
+		if data, err := b.Bytes(); err == nil {
+			t.Errorf("#%d: got %x, want error", i, data)
+		}
+	}
+}
+
+func TestParser(t *testing.T) {
+	var b Builder
+	buildTestValue(&b)
+	data, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewParser(data)
+	seq, err := p.ReadSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Empty() {
+		t.Errorf("%d bytes left after the sequence", len(p.Remaining()))
+	}
+
+	var got builderTestStruct
+	var v int64
+	if v, err = seq.ReadInt64(); err != nil {
+		t.Fatal(err)
+	}
+	got.Version = int(v)
+	if got.Flag, err = seq.ReadBool(); err != nil {
+		t.Fatal(err)
+	}
+	if got.OID, err = seq.ReadObjectIdentifier(); err != nil {
+		t.Fatal(err)
+	}
+	name, present, err := seq.ReadOptionalExplicit(0)
+	if err != nil || !present {
+		t.Fatalf("ReadOptionalExplicit: %v, %v", present, err)
+	}
+	if got.Name, err = name.ReadString(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Data, err = seq.ReadOctetString(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Bits, err = seq.ReadBitString(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = seq.ReadEnum(); err != nil {
+		t.Fatal(err)
+	}
+	got.Kind = int(v)
+	if got.Ratio, err = seq.ReadReal(); err != nil {
+		t.Fatal(err)
+	}
+	if got.When, err = seq.ReadTime(); err != nil {
+		t.Fatal(err)
+	}
+	if got.Title, err = seq.ReadString(); err != nil {
+		t.Fatal(err)
+	}
+	if !seq.Empty() {
+		t.Errorf("%d bytes left in the sequence", len(seq.Remaining()))
+	}
+	if !reflect.DeepEqual(got, builderTestValue) {
+		t.Errorf("got %#v, want %#v", got, builderTestValue)
+	}
+}
+
+func TestParserMismatch(t *testing.T) {
+	p := NewParser([]byte{0x01, 0x01, 0xff, 0x05, 0x00, 0x02, 0x02, 0x00, 0x01})
+	if _, err := p.ReadInt64(); err == nil {
+		t.Errorf("ReadInt64 accepted a BOOLEAN")
+	}
+	if _, present, err := p.ReadOptionalExplicit(0); present || err != nil {
+		t.Errorf("ReadOptionalExplicit: %v, %v", present, err)
+	}
+	if !p.PeekTag(ClassUniversal, TagBoolean) {
+		t.Errorf("PeekTag didn't see the BOOLEAN")
+	}
+	if v, err := p.ReadBool(); err != nil || !v {
+		t.Errorf("ReadBool: %v, %v", v, err)
+	}
+	if err := p.ReadNull(); err != nil {
+		t.Errorf("ReadNull: %v", err)
+	}
+
+	// A malformed element is an error and is not consumed.
+	if _, err := p.ReadInt64(); err == nil {
+		t.Errorf("ReadInt64 accepted a non-minimal INTEGER")
+	}
+	if v, err := p.ReadElement(); err != nil || v.Tag != TagInteger {
+		t.Errorf("ReadElement: %+v, %v", v, err)
+	}
+	if !p.Empty() {
+		t.Errorf("Parser not empty")
+	}
+	if _, err := p.ReadElement(); err == nil {
+		t.Errorf("ReadElement succeeded on an empty Parser")
+	}
+}
+
+func TestParserUnmarshal(t *testing.T) {
+	p := NewParser([]byte{0x30, 0x03, 0x02, 0x01, 0x40, 0x0a, 0x01, 0x02})
+	var s intStruct
+	if err := p.Unmarshal(&s, ""); err != nil || s.A != 64 {
+		t.Errorf("Unmarshal: %v, %v", s, err)
+	}
+	var e int
+	if err := p.Unmarshal(&e, "enumerated"); err != nil || e != 2 {
+		t.Errorf("Unmarshal: %v, %v", e, err)
+	}
+	if !p.Empty() {
+		t.Errorf("Parser not empty")
+	}
+}
+
+func TestBERParser(t *testing.T) {
+	p, err := NewBERParser([]byte{
+		0x30, 0x80, 0x24, 0x80, 0x04, 0x01, 0x01, 0x04, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00,
+		0x02, 0x81, 0x01, 0x07,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq, err := p.ReadSequence()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := seq.ReadOctetString(); err != nil || !bytes.Equal(v, []byte{1, 2}) {
+		t.Errorf("ReadOctetString: %x, %v", v, err)
+	}
+	if v, err := p.ReadInt64(); err != nil || v != 7 {
+		t.Errorf("ReadInt64: %v, %v", v, err)
+	}
+
+	if _, err := NewBERParser([]byte{0x30, 0x80, 0x02, 0x01, 0x07}); err == nil {
+		t.Errorf("NewBERParser accepted a missing end-of-contents")
+	}
+}
@@ -24,7 +24,9 @@ const (
 	TagInteger         = 2
 	TagBitString       = 3
 	TagOctetString     = 4
+	TagNull            = 5
 	TagOID             = 6
+	TagReal            = 9
 	TagEnum            = 10
 	TagUTF8String      = 12
 	TagSequence        = 16
@@ -35,6 +37,8 @@ const (
 	TagUTCTime         = 23
 	TagGeneralizedTime = 24
 	TagGeneralString   = 27
+	TagUniversalString = 28
+	TagBMPString       = 30
 )
 
 // ASN.1 class types represent the namespace of the tag.
@@ -79,6 +83,8 @@ type fieldParameters struct {
 	timeType     int    // the time tag to use when marshaling.
 	set          bool   // true iff this should be encoded as a SET
 	omitEmpty    bool   // true iff this should be omitted if empty when marshaling.
+	enumerated   bool   // true iff an integer should be encoded as ENUMERATED.
+	choice       bool   // true iff the field is a CHOICE of its pointer fields.
 
 	// Invariants:
 	//   if explicit is set, tag is non-nil.
@@ -107,6 +113,10 @@ func parseFieldParameters(str string) (ret fieldParameters) {
 			ret.stringType = TagPrintableString
 		case part == "utf8":
 			ret.stringType = TagUTF8String
+		case part == "bmp":
+			ret.stringType = TagBMPString
+		case part == "universal":
+			ret.stringType = TagUniversalString
 		case strings.HasPrefix(part, "default:"):
 			i, err := strconv.ParseInt(part[8:], 10, 64)
 			if err == nil {
@@ -128,6 +138,10 @@ func parseFieldParameters(str string) (ret fieldParameters) {
 			}
 		case part == "omitempty":
 			ret.omitEmpty = true
+		case part == "enumerated":
+			ret.enumerated = true
+		case part == "choice":
+			ret.choice = true
 		}
 	}
 	return
@@ -153,6 +167,8 @@ func getUniversalType(t reflect.Type) (tagNumber int, isCompound, ok bool) {
 		return TagBoolean, false, true
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return TagInteger, false, true
+	case reflect.Float32, reflect.Float64:
+		return TagReal, false, true
 	case reflect.Struct:
 		return TagSequence, true, true
 	case reflect.Slice:
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"reflect"
 	"time"
@@ -287,6 +288,82 @@ func marshalUTF8String(out *forkableWriter, s string) (err error) {
 	return
 }
 
+func marshalBMPString(out *forkableWriter, s string) (err error) {
+	if !utf8.ValidString(s) {
+		return errors.New("asn1: string not valid UTF-8")
+	}
+	b := make([]byte, 0, 2*len(s))
+	for _, r := range s {
+		if r > 0xffff {
+			return StructuralError{"BMPString contains character outside the Basic Multilingual Plane"}
+		}
+		b = append(b, byte(r>>8), byte(r))
+	}
+	_, err = out.Write(b)
+	return
+}
+
+func marshalUniversalString(out *forkableWriter, s string) (err error) {
+	if !utf8.ValidString(s) {
+		return errors.New("asn1: string not valid UTF-8")
+	}
+	b := make([]byte, 0, 4*len(s))
+	for _, r := range s {
+		b = append(b, byte(r>>24), byte(r>>16), byte(r>>8), byte(r))
+	}
+	_, err = out.Write(b)
+	return
+}
+
+// marshalReal writes f in the DER form of a REAL: zero has an empty body,
+// infinities, NaN and negative zero use the special values, and everything
+// else is written in base 2 with an odd mantissa.
+func marshalReal(out *forkableWriter, f float64) (err error) {
+	switch {
+	case math.IsInf(f, 1):
+		return out.WriteByte(0x40)
+	case math.IsInf(f, -1):
+		return out.WriteByte(0x41)
+	case math.IsNaN(f):
+		return out.WriteByte(0x42)
+	case f == 0:
+		if math.Signbit(f) {
+			return out.WriteByte(0x43)
+		}
+		return nil
+	}
+
+	first := byte(0x80)
+	if f < 0 {
+		first |= 0x40
+		f = -f
+	}
+	frac, exp := math.Frexp(f)
+	mant := uint64(math.Ldexp(frac, 53))
+	exp -= 53
+	for mant&1 == 0 {
+		mant >>= 1
+		exp++
+	}
+
+	// Doubles have an exponent that always fits in two bytes.
+	first |= byte(int64Length(int64(exp)) - 1)
+	if err = out.WriteByte(first); err != nil {
+		return
+	}
+	if err = marshalInt64(out, int64(exp)); err != nil {
+		return
+	}
+	var buf [8]byte
+	n := len(buf)
+	for ; mant > 0; mant >>= 8 {
+		n--
+		buf[n] = byte(mant)
+	}
+	_, err = out.Write(buf[n:])
+	return
+}
+
 func marshalTwoDigits(out *forkableWriter, v int) (err error) {
 	err = out.WriteByte(byte('0' + (v/10)%10))
 	if err != nil {
@@ -436,6 +513,8 @@ func marshalBody(out *forkableWriter, value reflect.Value, params fieldParameter
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return marshalInt64(out, int64(v.Int()))
+	case reflect.Float32, reflect.Float64:
+		return marshalReal(out, v.Float())
 	case reflect.Struct:
 		t := v.Type()
 
@@ -497,6 +576,10 @@ func marshalBody(out *forkableWriter, value reflect.Value, params fieldParameter
 			return marshalIA5String(out, v.String())
 		case TagPrintableString:
 			return marshalPrintableString(out, v.String())
+		case TagBMPString:
+			return marshalBMPString(out, v.String())
+		case TagUniversalString:
+			return marshalUniversalString(out, v.String())
 		default:
 			return marshalUTF8String(out, v.String())
 		}
@@ -536,6 +619,10 @@ func marshalField(out *forkableWriter, v reflect.Value, params fieldParameters)
 		}
 	}
 
+	if params.choice {
+		return marshalChoice(out, v, params)
+	}
+
 	if v.Type() == rawValueType {
 		rv := v.Interface().(RawValue)
 		if len(rv.FullBytes) != 0 {
@@ -596,6 +683,13 @@ func marshalField(out *forkableWriter, v reflect.Value, params fieldParameters)
 		tag = TagSet
 	}
 
+	if params.enumerated {
+		if tag != TagInteger {
+			return StructuralError{"enumerated given to non-integer member"}
+		}
+		tag = TagEnum
+	}
+
 	tags, body := out.fork()
 
 	err = marshalBody(body, v, params)
@@ -633,20 +727,81 @@ func marshalField(out *forkableWriter, v reflect.Value, params fieldParameters)
 	return err
 }
 
+// marshalChoice writes the single non-nil alternative of the CHOICE struct
+// v, wrapped in an explicit tag if params asks for one.
+func marshalChoice(out *forkableWriter, v reflect.Value, params fieldParameters) (err error) {
+	t := v.Type()
+	if t.Kind() != reflect.Struct {
+		return StructuralError{"CHOICE must be a struct: " + t.String()}
+	}
+
+	alt := -1
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Type.Kind() != reflect.Ptr {
+			return StructuralError{"CHOICE alternative must be a pointer: " + field.Name}
+		}
+		if v.Field(i).IsNil() {
+			continue
+		}
+		if alt >= 0 {
+			return StructuralError{"more than one CHOICE alternative set in " + t.String()}
+		}
+		alt = i
+	}
+	if alt < 0 {
+		return StructuralError{"no CHOICE alternative set in " + t.String()}
+	}
+
+	fp := parseFieldParameters(t.Field(alt).Tag.Get("asn1"))
+	fp.optional = false
+	value := v.Field(alt)
+	if value.Type() != bigIntType {
+		value = value.Elem()
+	}
+	if !params.explicit {
+		return marshalField(out, value, fp)
+	}
+
+	tags, body := out.fork()
+	if err = marshalField(body, value, fp); err != nil {
+		return
+	}
+	class := ClassContextSpecific
+	if params.application {
+		class = ClassApplication
+	}
+	return marshalTagAndLength(tags, tagAndLength{class, *params.tag, body.Len(), true})
+}
+
 // Marshal returns the ASN.1 encoding of val.
 //
 // In addition to the struct tags recognised by Unmarshal, the following can be
 // used:
 //
+//	bmp:		causes strings to be marshaled as ASN.1, BMPString strings
 //	ia5:		causes strings to be marshaled as ASN.1, IA5 strings
 //	omitempty:	causes empty slices to be skipped
 //	printable:	causes strings to be marshaled as ASN.1, PrintableString strings.
+//	universal:	causes strings to be marshaled as ASN.1, UniversalString strings
 //	utf8:		causes strings to be marshaled as ASN.1, UTF8 strings
+//
+// Floating-point values are marshaled as ASN.1 REAL in their DER form.
+// Exactly one alternative of a field tagged "choice" must be non-nil.
 func Marshal(val interface{}) ([]byte, error) {
+	return MarshalWithParams(val, "")
+}
+
+// MarshalWithParams allows field parameters to be specified for the
+// top-level element. The form of the params is the same as the field tags.
+func MarshalWithParams(val interface{}, params string) ([]byte, error) {
 	var out bytes.Buffer
 	v := reflect.ValueOf(val)
 	f := newForkableWriter()
-	err := marshalField(f, v, fieldParameters{})
+	err := marshalField(f, v, parseFieldParameters(params))
 	if err != nil {
 		return nil, err
 	}
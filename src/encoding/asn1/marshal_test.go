@@ -7,7 +7,9 @@ package asn1
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 )
@@ -173,3 +175,110 @@ func TestInvalidUTF8(t *testing.T) {
 		t.Errorf("invalid UTF8 string was accepted")
 	}
 }
+
+type bmpStringTest struct {
+	A string `asn1:"bmp"`
+}
+
+type universalStringTest struct {
+	A string `asn1:"universal"`
+}
+
+type enumeratedTest struct {
+	A int `asn1:"enumerated"`
+}
+
+type choiceAlternatives struct {
+	Int *int
+	Str *string `asn1:"tag:1"`
+	Seq *intStruct
+	Big *big.Int `asn1:"explicit,tag:2"`
+}
+
+type choiceTest struct {
+	C choiceAlternatives `asn1:"choice"`
+}
+
+type explicitChoiceTest struct {
+	C choiceAlternatives `asn1:"explicit,tag:3,choice"`
+}
+
+type optionalChoiceTest struct {
+	C choiceAlternatives `asn1:"optional,choice"`
+	A bool
+}
+
+var newTypesMarshalTests = []marshalTest{
+	{0.0, "0900"},
+	{1.0, "0903800001"},
+	{0.5, "090380ff01"},
+	{-3.0, "0903c00003"},
+	{1024.0, "0903800a01"},
+	{float32(0.25), "090380fe01"},
+	{math.Inf(1), "090140"},
+	{math.Inf(-1), "090141"},
+	{math.NaN(), "090142"},
+	{math.Copysign(0, -1), "090143"},
+	{bmpStringTest{"hé"}, "30061e04006800e9"},
+	{universalStringTest{"a"}, "30061c0400000061"},
+	{enumeratedTest{2}, "30030a0102"},
+	{choiceTest{choiceAlternatives{Int: newInt(5)}}, "3003020105"},
+	{choiceTest{choiceAlternatives{Str: newString("ab")}}, "300481026162"},
+	{choiceTest{choiceAlternatives{Big: big.NewInt(1)}}, "3005a203020101"},
+	{explicitChoiceTest{choiceAlternatives{Seq: &intStruct{64}}}, "3007a3053003020140"},
+	{optionalChoiceTest{A: true}, "30030101ff"},
+}
+
+func TestMarshalNewTypes(t *testing.T) {
+	for i, test := range newTypesMarshalTests {
+		data, err := Marshal(test.in)
+		if err != nil {
+			t.Errorf("#%d failed: %s", i, err)
+		}
+		out, _ := hex.DecodeString(test.out)
+		if !bytes.Equal(out, data) {
+			t.Errorf("#%d got: %x want %x", i, data, out)
+		}
+	}
+}
+
+func TestMarshalWithParams(t *testing.T) {
+	data, err := MarshalWithParams(2, "enumerated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x0a, 0x01, 0x02}; !bytes.Equal(data, want) {
+		t.Errorf("got %x, want %x", data, want)
+	}
+	data, err = MarshalWithParams("a", "tag:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x81, 0x01, 'a'}; !bytes.Equal(data, want) {
+		t.Errorf("got %x, want %x", data, want)
+	}
+}
+
+var marshalErrorTests = []struct {
+	in  interface{}
+	err string
+}{
+	{choiceTest{}, "no CHOICE alternative set"},
+	{choiceTest{choiceAlternatives{Int: newInt(1), Str: newString("a")}}, "more than one CHOICE alternative set"},
+	{bmpStringTest{"\U0001F600"}, "outside the Basic Multilingual Plane"},
+	{struct {
+		A string `asn1:"enumerated"`
+	}{"a"}, "enumerated given to non-integer member"},
+	{struct {
+		A int `asn1:"choice"`
+	}{1}, "CHOICE must be a struct"},
+}
+
+func TestMarshalErrors(t *testing.T) {
+	for i, test := range marshalErrorTests {
+		_, err := Marshal(test.in)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("#%d: got error %v, want one containing %q", i, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asn1
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// A Parser reads DER-encoded ASN.1 elements one at a time. It is the
+// counterpart of Builder for protocol code that walks a structure by hand.
+//
+// Each Read method consumes one element. If the element does not have the
+// expected type, an error is returned and the Parser is not advanced, so the
+// caller can try another type, as when parsing a CHOICE or OPTIONAL element.
+type Parser struct {
+	data []byte
+}
+
+// NewParser returns a Parser that reads the DER-encoded elements in data.
+func NewParser(data []byte) *Parser {
+	return &Parser{data}
+}
+
+// NewBERParser returns a Parser that reads the BER-encoded elements in data.
+// The whole of data is normalized to DER first, so values returned by the
+// Parser do not alias data.
+func NewBERParser(data []byte) (*Parser, error) {
+	var der []byte
+	for len(data) > 0 {
+		var n int
+		var err error
+		der, n, err = berToDER(der, data, 0)
+		if err != nil {
+			return nil, err
+		}
+		data = data[n:]
+	}
+	return &Parser{der}, nil
+}
+
+// Empty reports whether all elements have been read.
+func (p *Parser) Empty() bool {
+	return len(p.data) == 0
+}
+
+// Remaining returns the bytes that have not yet been read.
+func (p *Parser) Remaining() []byte {
+	return p.data
+}
+
+// PeekTag reports whether the next element has the given class and tag.
+func (p *Parser) PeekTag(class, tag int) bool {
+	if len(p.data) == 0 {
+		return false
+	}
+	t, _, err := parseTagAndLength(p.data, 0)
+	return err == nil && t.class == class && t.tag == tag
+}
+
+// ReadElement reads the next element, whatever its type.
+func (p *Parser) ReadElement() (RawValue, error) {
+	if len(p.data) == 0 {
+		return RawValue{}, SyntaxError{"no elements left"}
+	}
+	t, offset, err := parseTagAndLength(p.data, 0)
+	if err != nil {
+		return RawValue{}, err
+	}
+	if invalidLength(offset, t.length, len(p.data)) {
+		return RawValue{}, SyntaxError{"data truncated"}
+	}
+	end := offset + t.length
+	v := RawValue{t.class, t.tag, t.isCompound, p.data[offset:end], p.data[:end]}
+	p.data = p.data[end:]
+	return v, nil
+}
+
+// read reads the next element, which must have the given identifier, and
+// returns its contents.
+func (p *Parser) read(class, tag int, isCompound bool) ([]byte, error) {
+	if len(p.data) == 0 {
+		return nil, SyntaxError{"no elements left"}
+	}
+	t, _, err := parseTagAndLength(p.data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if t.class != class || t.tag != tag || t.isCompound != isCompound {
+		return nil, StructuralError{fmt.Sprintf("tags don't match (%d vs %+v)", tag, t)}
+	}
+	v, err := p.ReadElement()
+	if err != nil {
+		return nil, err
+	}
+	return v.Bytes, nil
+}
+
+// ReadConstructed reads a constructed element with the given class and tag
+// and returns a Parser for its contents.
+func (p *Parser) ReadConstructed(class, tag int) (*Parser, error) {
+	body, err := p.read(class, tag, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{body}, nil
+}
+
+// ReadSequence reads a SEQUENCE and returns a Parser for its elements.
+func (p *Parser) ReadSequence() (*Parser, error) {
+	return p.ReadConstructed(ClassUniversal, TagSequence)
+}
+
+// ReadSet reads a SET and returns a Parser for its elements.
+func (p *Parser) ReadSet() (*Parser, error) {
+	return p.ReadConstructed(ClassUniversal, TagSet)
+}
+
+// ReadExplicit reads a context-specific, explicitly tagged element and
+// returns a Parser for its contents.
+func (p *Parser) ReadExplicit(tag int) (*Parser, error) {
+	return p.ReadConstructed(ClassContextSpecific, tag)
+}
+
+// ReadOptionalExplicit is like ReadExplicit but reports whether the element
+// was present instead of failing when the next element has a different tag.
+func (p *Parser) ReadOptionalExplicit(tag int) (q *Parser, present bool, err error) {
+	if !p.PeekTag(ClassContextSpecific, tag) {
+		return nil, false, nil
+	}
+	q, err = p.ReadExplicit(tag)
+	return q, err == nil, err
+}
+
+// ReadPrimitive reads a primitive element with the given class and tag and
+// returns its contents. It can be used for implicitly tagged values.
+func (p *Parser) ReadPrimitive(class, tag int) ([]byte, error) {
+	return p.read(class, tag, false)
+}
+
+// readUniversal reads a primitive universal element with the given tag and
+// parses its contents with parse, only advancing if parse succeeds.
+func (p *Parser) readUniversal(tag int, parse func([]byte) error) error {
+	saved := p.data
+	body, err := p.read(ClassUniversal, tag, false)
+	if err != nil {
+		return err
+	}
+	if err = parse(body); err != nil {
+		p.data = saved
+	}
+	return err
+}
+
+// ReadBool reads a BOOLEAN.
+func (p *Parser) ReadBool() (v bool, err error) {
+	err = p.readUniversal(TagBoolean, func(body []byte) (err error) {
+		v, err = parseBool(body)
+		return
+	})
+	return
+}
+
+// ReadInt64 reads an INTEGER that fits in an int64.
+func (p *Parser) ReadInt64() (v int64, err error) {
+	err = p.readUniversal(TagInteger, func(body []byte) (err error) {
+		v, err = parseInt64(body)
+		return
+	})
+	return
+}
+
+// ReadBigInt reads an INTEGER.
+func (p *Parser) ReadBigInt() (v *big.Int, err error) {
+	err = p.readUniversal(TagInteger, func(body []byte) (err error) {
+		v, err = parseBigInt(body)
+		return
+	})
+	return
+}
+
+// ReadEnum reads an ENUMERATED.
+func (p *Parser) ReadEnum() (v int64, err error) {
+	err = p.readUniversal(TagEnum, func(body []byte) (err error) {
+		v, err = parseInt64(body)
+		return
+	})
+	return
+}
+
+// ReadReal reads a REAL.
+func (p *Parser) ReadReal() (v float64, err error) {
+	err = p.readUniversal(TagReal, func(body []byte) (err error) {
+		v, err = parseReal(body)
+		return
+	})
+	return
+}
+
+// ReadNull reads a NULL.
+func (p *Parser) ReadNull() error {
+	return p.readUniversal(TagNull, func(body []byte) error {
+		if len(body) != 0 {
+			return SyntaxError{"NULL with contents"}
+		}
+		return nil
+	})
+}
+
+// ReadOctetString reads an OCTET STRING. The result aliases the input.
+func (p *Parser) ReadOctetString() (v []byte, err error) {
+	err = p.readUniversal(TagOctetString, func(body []byte) error {
+		v = body
+		return nil
+	})
+	return
+}
+
+// ReadBitString reads a BIT STRING.
+func (p *Parser) ReadBitString() (v BitString, err error) {
+	err = p.readUniversal(TagBitString, func(body []byte) (err error) {
+		v, err = parseBitString(body)
+		return
+	})
+	return
+}
+
+// ReadObjectIdentifier reads an OBJECT IDENTIFIER.
+func (p *Parser) ReadObjectIdentifier() (v ObjectIdentifier, err error) {
+	err = p.readUniversal(TagOID, func(body []byte) (err error) {
+		v, err = parseObjectIdentifier(body)
+		return
+	})
+	return
+}
+
+// ReadString reads any of the string types that Unmarshal can write to a
+// string and returns it as UTF-8.
+func (p *Parser) ReadString() (string, error) {
+	if len(p.data) == 0 {
+		return "", SyntaxError{"no elements left"}
+	}
+	t, _, err := parseTagAndLength(p.data, 0)
+	if err != nil {
+		return "", err
+	}
+	if t.class != ClassUniversal || !isStringTag(t.tag) {
+		return "", StructuralError{fmt.Sprintf("expected a string but found tag %d (class %d)", t.tag, t.class)}
+	}
+	var s string
+	err = p.readUniversal(t.tag, func(body []byte) (err error) {
+		switch t.tag {
+		case TagPrintableString:
+			s, err = parsePrintableString(body)
+		case TagIA5String:
+			s, err = parseIA5String(body)
+		case TagUTF8String:
+			s, err = parseUTF8String(body)
+		case TagBMPString:
+			s, err = parseBMPString(body)
+		case TagUniversalString:
+			s, err = parseUniversalString(body)
+		default:
+			s, err = parseT61String(body)
+		}
+		return
+	})
+	return s, err
+}
+
+// ReadTime reads a UTCTime or GeneralizedTime.
+func (p *Parser) ReadTime() (v time.Time, err error) {
+	if p.PeekTag(ClassUniversal, TagGeneralizedTime) {
+		err = p.readUniversal(TagGeneralizedTime, func(body []byte) (err error) {
+			v, err = parseGeneralizedTime(body)
+			return
+		})
+		return
+	}
+	err = p.readUniversal(TagUTCTime, func(body []byte) (err error) {
+		v, err = parseUTCTime(body)
+		return
+	})
+	return
+}
+
+// Unmarshal reads the next element into val, as UnmarshalWithParams would.
+func (p *Parser) Unmarshal(val interface{}, params string) error {
+	rest, err := UnmarshalWithParams(p.data, val, params)
+	if err != nil {
+		return err
+	}
+	p.data = rest
+	return nil
+}
@@ -0,0 +1,851 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"encoding"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshal parses the CBOR-encoded data and stores the result
+// in the value pointed to by v. The data must hold exactly one
+// data item.
+//
+// Unmarshal uses the inverse of the encodings that
+// Marshal uses, allocating maps, slices, and pointers as necessary,
+// with the following additional rules:
+//
+// To unmarshal CBOR into a pointer, Unmarshal first handles the case of
+// the CBOR being null or undefined. In that case, Unmarshal sets
+// the pointer to nil. Otherwise, Unmarshal unmarshals the CBOR into
+// the value pointed at by the pointer. If the pointer is nil, Unmarshal
+// allocates a new value for it to point to.
+//
+// To unmarshal CBOR into a value implementing the Unmarshaler interface,
+// Unmarshal calls that value's UnmarshalCBOR method with the complete
+// data item. A CBOR byte string unmarshals into a value implementing
+// encoding.BinaryUnmarshaler by calling its UnmarshalBinary method
+// with the contents of the string.
+//
+// To unmarshal a CBOR map into a struct, Unmarshal matches text string
+// keys to the keys used by Marshal (either the struct field name or its tag),
+// preferring an exact match but also accepting a case-insensitive match.
+// Entries with other keys are ignored.
+// Unmarshal will only set exported fields of the struct.
+//
+// To unmarshal CBOR into an interface value,
+// Unmarshal stores one of these in the interface value:
+//
+//	uint64, for CBOR unsigned integers
+//	int64, for CBOR negative integers, or *big.Int if they overflow int64
+//	float64, for CBOR floats
+//	bool, for CBOR booleans
+//	[]byte, for CBOR byte strings
+//	string, for CBOR text strings
+//	[]interface{}, for CBOR arrays
+//	map[interface{}]interface{}, for CBOR maps
+//	time.Time, for CBOR tags 0 and 1
+//	*big.Int, for CBOR tags 2 and 3
+//	Tag, for other CBOR tags
+//	nil, for CBOR null and undefined
+//
+// CBOR integers and bignums unmarshal into any Go integer or floating
+// point type that holds their value, and into big.Int.
+// Byte strings unmarshal into []byte and [N]byte, text strings into string.
+// A time.Time accepts a text string with tag 0, which must be in
+// RFC 3339 format, and an integer or float with tag 1, which counts
+// seconds since the Unix epoch. The tags of other tagged items are
+// ignored unless the value is a Tag.
+//
+// To unmarshal a CBOR array into a slice, Unmarshal resets the slice length
+// to zero and then appends each element to the slice.
+// To unmarshal a CBOR array into a Go array, Unmarshal decodes
+// CBOR array elements into corresponding Go array elements.
+// If the Go array is smaller than the CBOR array,
+// the additional CBOR array elements are discarded.
+// If the CBOR array is smaller than the Go array,
+// the additional Go array elements are set to zero values.
+//
+// To unmarshal a CBOR map into a Go map, Unmarshal first
+// establishes a map to use. If the map is nil, Unmarshal allocates a new map.
+// Otherwise Unmarshal reuses the existing map, keeping existing entries.
+// Unmarshal then stores key-value pairs from the CBOR map into the map.
+//
+// If a CBOR value is not appropriate for a given target type,
+// or if a CBOR number overflows the target type, Unmarshal
+// skips that value and completes the unmarshaling as best it can.
+// If no more serious errors are encountered, Unmarshal returns
+// an UnmarshalTypeError describing the earliest such error.
+//
+// CBOR null and undefined unmarshal into an interface, map, pointer,
+// or slice by setting that Go value to nil. Unmarshaling them into any
+// other Go type has no effect on the value and produces no error.
+//
+// Both definite and indefinite length strings, arrays and maps are
+// accepted. If data is not a single well-formed CBOR data item,
+// Unmarshal returns a SyntaxError without modifying v.
+//
+func Unmarshal(data []byte, v interface{}) error {
+	// Check for well-formedness.
+	// Avoids filling out half a data structure
+	// before discovering a CBOR syntax error.
+	n, err := checkValid(data)
+	if err != nil {
+		return syntaxError(err, len(data))
+	}
+	if n != len(data) {
+		return &SyntaxError{errExtraData.Error(), int64(n)}
+	}
+	var d decodeState
+	d.init(data)
+	return d.unmarshal(v)
+}
+
+// syntaxError converts an error returned by checkValid on data
+// of length n into a *SyntaxError.
+func syntaxError(err error, n int) error {
+	if err == errShort {
+		return &SyntaxError{"unexpected end of CBOR input", int64(n)}
+	}
+	return err
+}
+
+// Unmarshaler is the interface implemented by objects
+// that can unmarshal a CBOR description of themselves.
+// The input can be assumed to be a well-formed CBOR data item.
+// UnmarshalCBOR must copy the CBOR data
+// if it wishes to retain the data after returning.
+type Unmarshaler interface {
+	UnmarshalCBOR([]byte) error
+}
+
+// An UnmarshalTypeError describes a CBOR value that was
+// not appropriate for a value of a specific Go type.
+type UnmarshalTypeError struct {
+	Value  string       // description of CBOR value - "bool", "array", "negative integer -5"
+	Type   reflect.Type // type of Go value it could not be assigned to
+	Offset int64        // error occurred after reading Offset bytes
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "cbor: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String()
+}
+
+// An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
+// (The argument to Unmarshal must be a non-nil pointer.)
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "cbor: Unmarshal(nil)"
+	}
+
+	if e.Type.Kind() != reflect.Ptr {
+		return "cbor: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "cbor: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// decodeState represents the state while decoding a CBOR value.
+// The data it decodes has been checked to be well-formed.
+type decodeState struct {
+	data       []byte
+	off        int // read offset in data
+	savedError error
+	numErrors  int // number of calls to saveError, to detect failed stores
+}
+
+func (d *decodeState) init(data []byte) *decodeState {
+	d.data = data
+	d.off = 0
+	d.savedError = nil
+	d.numErrors = 0
+	return d
+}
+
+func (d *decodeState) unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+
+	// We decode rv not rv.Elem because the Unmarshaler interface
+	// test must be applied at the top level of the value.
+	d.value(rv)
+	return d.savedError
+}
+
+// saveError saves the first err it is called with,
+// for reporting at the end of the unmarshal.
+func (d *decodeState) saveError(err error) {
+	d.numErrors++
+	if d.savedError == nil {
+		d.savedError = err
+	}
+}
+
+// typeError records that the data item at d.data[start:] could not be
+// stored in v and moves past the data item.
+func (d *decodeState) typeError(what string, v reflect.Value, start int) {
+	d.saveError(&UnmarshalTypeError{what, v.Type(), int64(start)})
+	d.off = start
+	d.skip()
+}
+
+// head consumes the head of the next data item.
+func (d *decodeState) head() (major, info byte, arg uint64) {
+	major, info, arg, d.off, _ = readHead(d.data, d.off)
+	return
+}
+
+// skip consumes the next data item.
+func (d *decodeState) skip() {
+	d.off, _ = itemEnd(d.data, d.off, 0)
+}
+
+// more reports whether the array or map being decoded has an item
+// after the first i of its n items. For an indefinite length array or map
+// it consumes the break that ends it instead.
+func (d *decodeState) more(indefinite bool, i, n uint64) bool {
+	if !indefinite {
+		return i < n
+	}
+	if d.data[d.off] == breakCode {
+		d.off++
+		return false
+	}
+	return true
+}
+
+// value decodes the next data item into v.
+func (d *decodeState) value(v reflect.Value) {
+	if !v.IsValid() {
+		d.skip()
+		return
+	}
+
+	start := d.off
+	b := d.data[d.off]
+	u, bu, pv := d.indirect(v, b == simpleNull || b == simpleUndefined, b>>5 == 2)
+	if u != nil {
+		d.skip()
+		if err := u.UnmarshalCBOR(d.data[start:d.off]); err != nil {
+			d.saveError(err)
+		}
+		return
+	}
+	if bu != nil {
+		s, _ := d.stringContent()
+		if err := bu.UnmarshalBinary(s); err != nil {
+			d.saveError(err)
+		}
+		return
+	}
+	v = pv
+
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		if x := d.valueInterface(); x != nil {
+			v.Set(reflect.ValueOf(x))
+		} else {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return
+	}
+
+	switch b >> 5 {
+	case 0, 1:
+		d.integer(v)
+	case 2, 3:
+		d.str(v)
+	case 4:
+		d.array(v)
+	case 5:
+		d.object(v)
+	case 6:
+		d.tag(v)
+	default:
+		d.simple(v)
+	}
+}
+
+// indirect walks down v allocating pointers as needed,
+// until it gets to a non-pointer.
+// if it encounters an Unmarshaler, indirect stops and returns that.
+// if decodingBytes is true and it encounters an encoding.BinaryUnmarshaler,
+// indirect stops and returns that.
+// if decodingNull is true, indirect stops at the last pointer so it can be set to nil.
+func (d *decodeState) indirect(v reflect.Value, decodingNull, decodingBytes bool) (Unmarshaler, encoding.BinaryUnmarshaler, reflect.Value) {
+	// If v is a named type and is addressable,
+	// start with its address, so that if the type has pointer methods,
+	// we find them.
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		v = v.Addr()
+	}
+	for {
+		// Load value from interface, but only if the result will be
+		// usefully addressable.
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() && (!decodingNull || e.Elem().Kind() == reflect.Ptr) {
+				v = e
+				continue
+			}
+		}
+
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+
+		if v.Elem().Kind() != reflect.Ptr && decodingNull && v.CanSet() {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 {
+			if u, ok := v.Interface().(Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if decodingBytes {
+				if u, ok := v.Interface().(encoding.BinaryUnmarshaler); ok {
+					return nil, u, reflect.Value{}
+				}
+			}
+		}
+		v = v.Elem()
+	}
+	return nil, nil, v
+}
+
+// integer decodes an unsigned or negative integer into v.
+func (d *decodeState) integer(v reflect.Value) {
+	start := d.off
+	major, _, arg := d.head()
+	neg := major == 1
+	what := func() string {
+		if neg {
+			if arg < math.MaxUint64 {
+				return "negative integer -" + strconv.FormatUint(arg+1, 10)
+			}
+			return "negative integer -18446744073709551616"
+		}
+		return "positive integer " + strconv.FormatUint(arg, 10)
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if arg > math.MaxInt64 {
+			d.typeError(what(), v, start)
+			return
+		}
+		n := int64(arg)
+		if neg {
+			n = ^n
+		}
+		if v.OverflowInt(n) {
+			d.typeError(what(), v, start)
+			return
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if neg || v.OverflowUint(arg) {
+			d.typeError(what(), v, start)
+			return
+		}
+		v.SetUint(arg)
+
+	case reflect.Float32, reflect.Float64:
+		f := float64(arg)
+		if neg {
+			f = -1 - f
+		}
+		v.SetFloat(f)
+
+	default:
+		if v.Type() != bigIntType {
+			d.typeError(what(), v, start)
+			return
+		}
+		x := new(big.Int).SetUint64(arg)
+		if neg {
+			x.Not(x)
+		}
+		v.Set(reflect.ValueOf(x).Elem())
+	}
+}
+
+// bigInt stores the value x of the bignum at d.data[start:] in v.
+func (d *decodeState) bigInt(v reflect.Value, x *big.Int, start int) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if x.BitLen() > 63 || v.OverflowInt(x.Int64()) {
+			break
+		}
+		v.SetInt(x.Int64())
+		return
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if x.Sign() < 0 || x.BitLen() > 64 || v.OverflowUint(x.Uint64()) {
+			break
+		}
+		v.SetUint(x.Uint64())
+		return
+
+	case reflect.Float32, reflect.Float64:
+		f, _ := new(big.Float).SetInt(x).Float64()
+		if v.OverflowFloat(f) {
+			break
+		}
+		v.SetFloat(f)
+		return
+
+	default:
+		if v.Type() == bigIntType {
+			v.Set(reflect.ValueOf(x).Elem())
+			return
+		}
+	}
+	d.saveError(&UnmarshalTypeError{"bignum " + x.String(), v.Type(), int64(start)})
+}
+
+// stringContent consumes a byte or text string and returns a copy of its
+// contents, joining the chunks of an indefinite length string.
+func (d *decodeState) stringContent() (s []byte, text bool) {
+	major, info, n := d.head()
+	text = major == 3
+	if info != infoIndefinite {
+		s = make([]byte, n)
+		d.off += copy(s, d.data[d.off:])
+		return s, text
+	}
+	s = []byte{}
+	for d.data[d.off] != breakCode {
+		_, _, n := d.head()
+		s = append(s, d.data[d.off:d.off+int(n)]...)
+		d.off += int(n)
+	}
+	d.off++
+	return s, text
+}
+
+// str decodes a byte or text string into v.
+func (d *decodeState) str(v reflect.Value) {
+	start := d.off
+	s, text := d.stringContent()
+	what := "byte string"
+	if text {
+		what = "text string"
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !text {
+			break
+		}
+		v.SetString(string(s))
+		return
+
+	case reflect.Slice:
+		if text || v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		v.SetBytes(s)
+		return
+
+	case reflect.Array:
+		if text || v.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		n := reflect.Copy(v, reflect.ValueOf(s))
+		for i := n; i < v.Len(); i++ {
+			v.Index(i).SetUint(0)
+		}
+		return
+	}
+	d.typeError(what, v, start)
+}
+
+// array decodes an array into v.
+func (d *decodeState) array(v reflect.Value) {
+	start := d.off
+	_, info, n := d.head()
+	indefinite := info == infoIndefinite
+
+	// Check type of target.
+	switch v.Kind() {
+	case reflect.Array, reflect.Slice:
+	default:
+		d.typeError("array", v, start)
+		return
+	}
+
+	i := 0
+	for ; d.more(indefinite, uint64(i), n); i++ {
+		// Get element of array, growing if necessary.
+		if v.Kind() == reflect.Slice {
+			// Grow slice if necessary
+			if i >= v.Cap() {
+				newcap := v.Cap() + v.Cap()/2
+				if newcap < 4 {
+					newcap = 4
+				}
+				newv := reflect.MakeSlice(v.Type(), v.Len(), newcap)
+				reflect.Copy(newv, v)
+				v.Set(newv)
+			}
+			if i >= v.Len() {
+				v.SetLen(i + 1)
+			}
+		}
+
+		if i < v.Len() {
+			// Decode into element.
+			d.value(v.Index(i))
+		} else {
+			// Ran out of fixed array: skip.
+			d.value(reflect.Value{})
+		}
+	}
+
+	if i < v.Len() {
+		if v.Kind() == reflect.Array {
+			// Array. Zero the rest.
+			z := reflect.Zero(v.Type().Elem())
+			for ; i < v.Len(); i++ {
+				v.Index(i).Set(z)
+			}
+		} else {
+			v.SetLen(i)
+		}
+	}
+	if i == 0 && v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+}
+
+// object decodes a map into v.
+func (d *decodeState) object(v reflect.Value) {
+	start := d.off
+	_, info, n := d.head()
+	indefinite := info == infoIndefinite
+	t := v.Type()
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		for i := uint64(0); d.more(indefinite, i, n); i++ {
+			keyStart := d.off
+			kv := reflect.New(t.Key()).Elem()
+			numErrors := d.numErrors
+			d.value(kv)
+			if d.numErrors != numErrors {
+				// The key could not be stored, so neither
+				// can the entry.
+				d.skip()
+				continue
+			}
+			if t.Key().Kind() == reflect.Interface && !isHashable(kv.Interface()) {
+				d.saveError(&UnmarshalTypeError{"unhashable map key", t, int64(keyStart)})
+				d.skip()
+				continue
+			}
+			ev := reflect.New(t.Elem()).Elem()
+			d.value(ev)
+			v.SetMapIndex(kv, ev)
+		}
+
+	case reflect.Struct:
+		fields := cachedTypeFields(t)
+		for i := uint64(0); d.more(indefinite, i, n); i++ {
+			if d.data[d.off]>>5 != 3 {
+				// Only text string keys name struct fields.
+				d.skip()
+				d.skip()
+				continue
+			}
+			key, _ := d.stringContent()
+
+			// Figure out field corresponding to key.
+			var f *field
+			for j := range fields {
+				ff := &fields[j]
+				if ff.name == string(key) {
+					f = ff
+					break
+				}
+				if f == nil && strings.EqualFold(ff.name, string(key)) {
+					f = ff
+				}
+			}
+			if f == nil {
+				d.skip()
+				continue
+			}
+			subv := v
+			for _, j := range f.index {
+				if subv.Kind() == reflect.Ptr {
+					if subv.IsNil() {
+						subv.Set(reflect.New(subv.Type().Elem()))
+					}
+					subv = subv.Elem()
+				}
+				subv = subv.Field(j)
+			}
+			d.value(subv)
+		}
+
+	default:
+		d.typeError("map", v, start)
+	}
+}
+
+// isHashable reports whether x may be used as a map key.
+func isHashable(x interface{}) bool {
+	if t, ok := x.(Tag); ok {
+		return isHashable(t.Content)
+	}
+	return x == nil || reflect.TypeOf(x).Comparable()
+}
+
+// tag decodes a tagged data item into v.
+func (d *decodeState) tag(v reflect.Value) {
+	start := d.off
+	_, _, num := d.head()
+	switch {
+	case v.Type() == tagType:
+		v.Set(reflect.ValueOf(Tag{num, d.valueInterface()}))
+
+	case (num == tagPosBignum || num == tagNegBignum) && d.data[d.off]>>5 == 2:
+		d.bigInt(v, d.bignum(num), start)
+
+	case (num == tagDateTime || num == tagEpochTime) && v.Type() == timeType:
+		d.time(v, num, start)
+
+	default:
+		// Tags that do not apply to v are ignored.
+		d.value(v)
+	}
+}
+
+// bignum consumes the byte string content of a bignum with tag num.
+func (d *decodeState) bignum(num uint64) *big.Int {
+	s, _ := d.stringContent()
+	x := new(big.Int).SetBytes(s)
+	if num == tagNegBignum {
+		x.Not(x)
+	}
+	return x
+}
+
+// time decodes the content of a date/time item with tag num into v,
+// which is a time.Time.
+func (d *decodeState) time(v reflect.Value, num uint64, start int) {
+	var t time.Time
+	switch b := d.data[d.off]; {
+	case num == tagDateTime && b>>5 == 3:
+		s, _ := d.stringContent()
+		var err error
+		if t, err = time.Parse(time.RFC3339, string(s)); err != nil {
+			d.saveError(err)
+			return
+		}
+
+	case num == tagEpochTime && b>>5 <= 1:
+		major, _, arg := d.head()
+		if arg > math.MaxInt64 {
+			d.typeError("epoch-based date/time", v, start)
+			return
+		}
+		sec := int64(arg)
+		if major == 1 {
+			sec = ^sec
+		}
+		t = time.Unix(sec, 0).UTC()
+
+	case num == tagEpochTime && (b == simpleFloat16 || b == simpleFloat32 || b == simpleFloat64):
+		f := d.float()
+		if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > 1<<62 {
+			d.typeError("epoch-based date/time", v, start)
+			return
+		}
+		sec, frac := math.Modf(f)
+		t = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+
+	default:
+		d.typeError("tag "+strconv.FormatUint(num, 10), v, start)
+		return
+	}
+	v.Set(reflect.ValueOf(t))
+}
+
+// float consumes a half, single or double precision float.
+func (d *decodeState) float() float64 {
+	b := d.data[d.off]
+	_, _, bits := d.head()
+	switch b {
+	case simpleFloat16:
+		return float16ToFloat64(uint16(bits))
+	case simpleFloat32:
+		return float64(math.Float32frombits(uint32(bits)))
+	}
+	return math.Float64frombits(bits)
+}
+
+// float16ToFloat64 converts an IEEE 754 half-precision float to float64.
+func float16ToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// simple decodes a simple value or float into v.
+func (d *decodeState) simple(v reflect.Value) {
+	start := d.off
+	switch b := d.data[d.off]; b {
+	case simpleFalse, simpleTrue:
+		d.off++
+		if v.Kind() != reflect.Bool {
+			d.typeError("bool", v, start)
+			return
+		}
+		v.SetBool(b == simpleTrue)
+
+	case simpleNull, simpleUndefined:
+		d.off++
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+			// otherwise, ignore null for primitives/string
+		}
+
+	case simpleFloat16, simpleFloat32, simpleFloat64:
+		f := d.float()
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if v.OverflowFloat(f) {
+				break
+			}
+			v.SetFloat(f)
+			return
+		}
+		d.typeError("float "+strconv.FormatFloat(f, 'g', -1, 64), v, start)
+
+	default:
+		d.typeError("simple value", v, start)
+	}
+}
+
+// valueInterface consumes the next data item and returns it
+// as a Go value of the type documented in Unmarshal.
+func (d *decodeState) valueInterface() interface{} {
+	start := d.off
+	b := d.data[d.off]
+	switch b >> 5 {
+	case 0:
+		_, _, arg := d.head()
+		return arg
+	case 1:
+		_, _, arg := d.head()
+		if arg <= math.MaxInt64 {
+			return ^int64(arg)
+		}
+		x := new(big.Int).SetUint64(arg)
+		return x.Not(x)
+	case 2, 3:
+		s, text := d.stringContent()
+		if text {
+			return string(s)
+		}
+		return s
+	case 4:
+		return d.arrayInterface()
+	case 5:
+		return d.mapInterface()
+	case 6:
+		return d.tagInterface()
+	}
+
+	switch b {
+	case simpleFalse, simpleTrue:
+		d.off++
+		return b == simpleTrue
+	case simpleNull, simpleUndefined:
+		d.off++
+		return nil
+	case simpleFloat16, simpleFloat32, simpleFloat64:
+		return d.float()
+	}
+	d.skip()
+	d.saveError(&UnmarshalTypeError{"simple value", reflect.TypeOf(new(interface{})).Elem(), int64(start)})
+	return nil
+}
+
+// arrayInterface is like array but returns []interface{}.
+func (d *decodeState) arrayInterface() []interface{} {
+	_, info, n := d.head()
+	v := make([]interface{}, 0)
+	for i := uint64(0); d.more(info == infoIndefinite, i, n); i++ {
+		v = append(v, d.valueInterface())
+	}
+	return v
+}
+
+// mapInterface is like object but returns map[interface{}]interface{}.
+func (d *decodeState) mapInterface() map[interface{}]interface{} {
+	_, info, n := d.head()
+	m := make(map[interface{}]interface{})
+	for i := uint64(0); d.more(info == infoIndefinite, i, n); i++ {
+		keyStart := d.off
+		k := d.valueInterface()
+		if !isHashable(k) {
+			d.saveError(&UnmarshalTypeError{"unhashable map key", reflect.TypeOf(m), int64(keyStart)})
+			d.skip()
+			continue
+		}
+		m[k] = d.valueInterface()
+	}
+	return m
+}
+
+// tagInterface is like tag but returns an interface{}.
+func (d *decodeState) tagInterface() interface{} {
+	start := d.off
+	_, _, num := d.head()
+	switch b := d.data[d.off]; {
+	case (num == tagPosBignum || num == tagNegBignum) && b>>5 == 2:
+		return d.bignum(num)
+	case num == tagDateTime || num == tagEpochTime:
+		v := reflect.New(timeType).Elem()
+		d.time(v, num, start)
+		return v.Interface()
+	}
+	return Tag{num, d.valueInterface()}
+}
@@ -0,0 +1,383 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Decoding into interface{}, mostly from RFC 7049 Appendix A.
+var decodeInterfaceTests = []struct {
+	in  string // hex
+	out interface{}
+}{
+	{"00", uint64(0)},
+	{"17", uint64(23)},
+	{"1818", uint64(24)},
+	{"1903e8", uint64(1000)},
+	{"1a000f4240", uint64(1000000)},
+	{"1b000000e8d4a51000", uint64(1000000000000)},
+	{"1bffffffffffffffff", uint64(18446744073709551615)},
+	{"c249010000000000000000", bigInt("18446744073709551616")},
+	{"3bffffffffffffffff", bigInt("-18446744073709551616")},
+	{"c349010000000000000000", bigInt("-18446744073709551617")},
+	{"20", int64(-1)},
+	{"3863", int64(-100)},
+	{"3903e7", int64(-1000)},
+	{"f90000", 0.0},
+	{"f93c00", 1.0},
+	{"fb3ff199999999999a", 1.1},
+	{"f93e00", 1.5},
+	{"f97bff", 65504.0},
+	{"fa47c35000", 100000.0},
+	{"fa7f7fffff", 3.4028234663852886e+38},
+	{"fb7e37e43c8800759c", 1.0e+300},
+	{"f90001", 5.960464477539063e-8},
+	{"f90400", 0.00006103515625},
+	{"f9c400", -4.0},
+	{"fbc010666666666666", -4.1},
+	{"f97c00", math.Inf(1)},
+	{"f9fc00", math.Inf(-1)},
+	{"fa7f800000", math.Inf(1)},
+	{"f4", false},
+	{"f5", true},
+	{"f6", nil},
+	{"f7", nil},
+	{"c074323031332d30332d32315432303a30343a30305a", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+	{"c11a514b67b0", time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)},
+	{"c1fb41d452d9ec200000", time.Date(2013, 3, 21, 20, 4, 0, 500000000, time.UTC)},
+	{"d74401020304", Tag{23, []byte{1, 2, 3, 4}}},
+	{"d82076687474703a2f2f7777772e6578616d706c652e636f6d", Tag{32, "http://www.example.com"}},
+	{"40", []byte{}},
+	{"4401020304", []byte{1, 2, 3, 4}},
+	{"60", ""},
+	{"6449455446", "IETF"},
+	{"62c3bc", "ü"},
+	{"64f0908591", "\U00010151"},
+	{"80", []interface{}{}},
+	{"8301820203820405", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+	{"a0", map[interface{}]interface{}{}},
+	{"a201020304", map[interface{}]interface{}{uint64(1): uint64(2), uint64(3): uint64(4)}},
+	{"a26161016162820203", map[interface{}]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}},
+	{"826161a161626163", []interface{}{"a", map[interface{}]interface{}{"b": "c"}}},
+	{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+	{"7f657374726561646d696e67ff", "streaming"},
+	{"9fff", []interface{}{}},
+	{"9f018202039f0405ffff", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+	{"83018202039f0405ff", []interface{}{uint64(1), []interface{}{uint64(2), uint64(3)}, []interface{}{uint64(4), uint64(5)}}},
+	{"bf61610161629f0203ffff", map[interface{}]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}},
+	{"bf6346756ef563416d7421ff", map[interface{}]interface{}{"Fun": true, "Amt": int64(-2)}},
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	for _, tt := range decodeInterfaceTests {
+		var v interface{}
+		if err := Unmarshal(mustHex(tt.in), &v); err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.out) {
+			t.Errorf("Unmarshal(%s) = %#v, want %#v", tt.in, v, tt.out)
+		}
+	}
+}
+
+func TestUnmarshalNaN(t *testing.T) {
+	for _, in := range []string{"f97e00", "fa7fc00000", "fb7ff8000000000000"} {
+		var f float64
+		if err := Unmarshal(mustHex(in), &f); err != nil {
+			t.Errorf("Unmarshal(%s): %v", in, err)
+			continue
+		}
+		if !math.IsNaN(f) {
+			t.Errorf("Unmarshal(%s) = %v, want NaN", in, f)
+		}
+	}
+}
+
+type Point struct {
+	X, Y int
+	Name string `json:"name"`
+	Ptr  *int
+	Tags []string
+	Meta map[string]float64
+	Skip string `cbor:"-"`
+}
+
+type withTime struct {
+	When time.Time
+	Big  big.Int
+	Ptr  *big.Int
+}
+
+func TestUnmarshalTyped(t *testing.T) {
+	three := 3
+	tests := []struct {
+		in  string
+		ptr interface{}
+		out interface{}
+	}{
+		{"01", new(int), 1},
+		{"3863", new(int8), int8(-100)},
+		{"1bffffffffffffffff", new(uint64), uint64(18446744073709551615)},
+		{"1903e8", new(float32), float32(1000)},
+		{"3903e7", new(float64), -1000.0},
+		{"c24101", new(int), 1},
+		{"c349010000000000000000", new(big.Int), *bigInt("-18446744073709551617")},
+		{"20", new(big.Int), *big.NewInt(-1)},
+		{"c2420100", new(uint16), uint16(256)},
+		{"f93e00", new(float32), float32(1.5)},
+		{"f5", new(bool), true},
+		{"6449455446", new(string), "IETF"},
+		{"7f657374726561646d696e67ff", new(string), "streaming"},
+		{"4401020304", new([]byte), []byte{1, 2, 3, 4}},
+		{"4401020304", new([2]byte), [2]byte{1, 2}},
+		{"4401020304", new([6]byte), [6]byte{1, 2, 3, 4}},
+		{"83010203", new([]int), []int{1, 2, 3}},
+		{"83010203", new([2]int), [2]int{1, 2}},
+		{"9f0102ff", new([3]int), [3]int{1, 2, 0}},
+		{"80", new([]int), []int{}},
+		{"f6", new([]int), []int(nil)},
+		{"f6", new(*int), (*int)(nil)},
+		{"03", new(*int), &three},
+		{"d81803", new(int), 3},
+		{"a201020304", new(map[int]string), nil},
+		{"a101420203", new(map[uint]interface{}), map[uint]interface{}{1: []byte{2, 3}}},
+		{"a2617803617902", new(map[string]int), map[string]int{"x": 3, "y": 2}},
+		{
+			"a7617803" + "6179" + "20" + "646e616d65" + "6170" + "63507472" + "03" + "6454616773" + "816161" + "644d657461" + "a16161fb3ff8000000000000" + "64536b6970" + "6161",
+			new(Point),
+			Point{X: 3, Y: -1, Name: "p", Ptr: &three, Tags: []string{"a"}, Meta: map[string]float64{"a": 1.5}},
+		},
+		{"bf644e414d456170ff", new(Point), Point{Name: "p"}},
+		{"a2016161644e414d456170", new(Point), Point{Name: "p"}},
+		{
+			"a3645768656ec11a514b67b063426967c2420100635074720a",
+			new(withTime),
+			withTime{When: time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), Big: *big.NewInt(256), Ptr: big.NewInt(10)},
+		},
+		{"d7426162", new(binaryValue), binaryValue{"ab"}},
+		{"426162", new(binaryValue), binaryValue{"ab"}},
+		{"d76161", new(Tag), Tag{23, "a"}},
+		{"820102", new(RawMessage), RawMessage{0x82, 0x01, 0x02}},
+	}
+	for _, tt := range tests {
+		in := mustHex(tt.in)
+		if err := Unmarshal(in, tt.ptr); err != nil {
+			if tt.out != nil {
+				t.Errorf("Unmarshal(%s, %T): %v", tt.in, tt.ptr, err)
+			}
+			continue
+		}
+		if tt.out == nil {
+			t.Errorf("Unmarshal(%s, %T): expected error", tt.in, tt.ptr)
+			continue
+		}
+		if got := reflect.ValueOf(tt.ptr).Elem().Interface(); !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("Unmarshal(%s, %T) = %#v, want %#v", tt.in, tt.ptr, got, tt.out)
+		}
+	}
+}
+
+type unmarshalerText struct {
+	data string
+}
+
+func (u *unmarshalerText) UnmarshalCBOR(b []byte) error {
+	u.data = hex.EncodeToString(b)
+	return nil
+}
+
+func TestUnmarshaler(t *testing.T) {
+	var v struct {
+		A unmarshalerText
+		B *unmarshalerText
+		C []unmarshalerText
+	}
+	in := mustHex("a361418201026142f661438140")
+	if err := Unmarshal(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.data != "820102" || v.B != nil || len(v.C) != 1 || v.C[0].data != "40" {
+		t.Errorf("Unmarshal = %+v", v)
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	tests := []struct {
+		in  string
+		ptr interface{}
+		err string
+	}{
+		{"1901f4", new(int8), "cbor: cannot unmarshal positive integer 500 into Go value of type int8"},
+		{"20", new(uint), "cbor: cannot unmarshal negative integer -1 into Go value of type uint"},
+		{"3bffffffffffffffff", new(int64), "cbor: cannot unmarshal negative integer -18446744073709551616 into Go value of type int64"},
+		{"c249010000000000000000", new(int64), "cbor: cannot unmarshal bignum 18446744073709551616 into Go value of type int64"},
+		{"6161", new([]byte), "cbor: cannot unmarshal text string into Go value of type []uint8"},
+		{"4161", new(string), "cbor: cannot unmarshal byte string into Go value of type string"},
+		{"80", new(map[string]int), "cbor: cannot unmarshal array into Go value of type map[string]int"},
+		{"a0", new([]int), "cbor: cannot unmarshal map into Go value of type []int"},
+		{"f5", new(int), "cbor: cannot unmarshal bool into Go value of type int"},
+		{"fb3ff199999999999a", new(int), "cbor: cannot unmarshal float 1.1 into Go value of type int"},
+		{"fb7e37e43c8800759c", new(float32), "cbor: cannot unmarshal float 1e+300 into Go value of type float32"},
+		{"c001", new(time.Time), "cbor: cannot unmarshal tag 0 into Go value of type time.Time"},
+		{"a1410102", new(map[interface{}]int), "cbor: cannot unmarshal unhashable map key into Go value of type map[interface {}]int"},
+		{"a1410102", new(interface{}), "cbor: cannot unmarshal unhashable map key into Go value of type map[interface {}]interface {}"},
+	}
+	for _, tt := range tests {
+		err := Unmarshal(mustHex(tt.in), tt.ptr)
+		if _, ok := err.(*UnmarshalTypeError); !ok || err.Error() != tt.err {
+			t.Errorf("Unmarshal(%s, %T) error = %v, want %s", tt.in, tt.ptr, err, tt.err)
+		}
+	}
+}
+
+func TestUnmarshalContinuesAfterTypeError(t *testing.T) {
+	var v struct {
+		A int8
+		B string
+	}
+	err := Unmarshal(mustHex("a2614119ffff614262625f"), &v)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("Unmarshal error = %v, want *UnmarshalTypeError", err)
+	}
+	if v.B != "b_" {
+		t.Errorf("B = %q, want %q", v.B, "b_")
+	}
+}
+
+func TestUnmarshalSkipsBadMapKeys(t *testing.T) {
+	// {[]: 1, "a": 2, 5: 3}
+	m := map[string]int{}
+	err := Unmarshal(mustHex("a380016161020503"), &m)
+	if _, ok := err.(*UnmarshalTypeError); !ok {
+		t.Fatalf("Unmarshal error = %v, want *UnmarshalTypeError", err)
+	}
+	if want := map[string]int{"a": 2}; !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
+
+func TestUnmarshalSyntaxError(t *testing.T) {
+	tests := []struct {
+		in     string
+		err    string
+		offset int64
+	}{
+		{"", "unexpected end of CBOR input", 0},
+		{"18", "unexpected end of CBOR input", 1},
+		{"830102", "unexpected end of CBOR input", 3},
+		{"62c3", "unexpected end of CBOR input", 2},
+		{"0102", "extra data after data item", 1},
+		{"1c", "reserved additional information 28 in initial byte", 1},
+		{"1f", "indefinite length positive integer", 0},
+		{"ff", "unexpected break", 0},
+		{"df00", "indefinite length tag", 0},
+		{"5f6161ff", "invalid chunk in indefinite length byte string", 1},
+		{"7f7f6161ffff", "invalid chunk in indefinite length text string", 1},
+		{"bf01ff", "indefinite length map with odd number of items", 2},
+		{"9f01", "unexpected end of CBOR input", 2},
+		{"bb8000000000000000", "unexpected end of CBOR input", 9},
+		{strings.Repeat("81", maxNestingDepth+1) + "00", "exceeded max nesting depth", maxNestingDepth + 1},
+	}
+	for _, tt := range tests {
+		var v interface{}
+		err := Unmarshal(mustHex(tt.in), &v)
+		se, ok := err.(*SyntaxError)
+		if !ok || se.Error() != tt.err || se.Offset != tt.offset {
+			t.Errorf("Unmarshal(%s) error = %#v, want %q at offset %d", tt.in, err, tt.err, tt.offset)
+		}
+	}
+}
+
+func TestInvalidUnmarshal(t *testing.T) {
+	buf := mustHex("01")
+	for _, tt := range []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "cbor: Unmarshal(nil)"},
+		{struct{}{}, "cbor: Unmarshal(non-pointer struct {})"},
+		{(*int)(nil), "cbor: Unmarshal(nil *int)"},
+	} {
+		err := Unmarshal(buf, tt.v)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Unmarshal expecting error %q, got %v", tt.want, err)
+		}
+	}
+}
+
+type roundTrip struct {
+	Int     int
+	Neg     int64
+	Uint    uint64
+	F32     float32
+	F64     float64
+	Str     string
+	Bytes   []byte
+	Arr     [3]byte
+	Slice   []Point
+	Map     map[string]*Point
+	IntMap  map[int]string
+	Any     interface{}
+	Time    time.Time
+	Big     *big.Int
+	Binary  binaryValue
+	Raw     RawMessage
+	Omitted string `cbor:",omitempty"`
+	Point
+}
+
+func TestRoundTrip(t *testing.T) {
+	two := 2
+	in := roundTrip{
+		Int:    -3,
+		Neg:    math.MinInt64,
+		Uint:   math.MaxUint64,
+		F32:    1.25,
+		F64:    math.Pi,
+		Str:    "héllo",
+		Bytes:  []byte("bytes"),
+		Arr:    [3]byte{7, 8, 9},
+		Slice:  []Point{{X: 1}, {Y: 2, Ptr: &two}},
+		Map:    map[string]*Point{"p": {Name: "n"}, "nil": nil},
+		IntMap: map[int]string{-1: "a", 300: "b"},
+		Any:    []interface{}{"x", uint64(1), int64(-1), true, nil, []byte{1}},
+		Time:   time.Date(2016, 2, 29, 12, 30, 1, 987654321, time.FixedZone("", -7*3600)),
+		Big:    bigInt("-123456789012345678901234567890"),
+		Binary: binaryValue{"bin"},
+		Raw:    RawMessage{0xa1, 0x01, 0x02},
+		Point:  Point{X: 5, Tags: []string{"t"}},
+	}
+	b, err := Marshal(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out roundTrip
+	if err := Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Time.Equal(in.Time) {
+		t.Errorf("Time = %v, want %v", out.Time, in.Time)
+	}
+	in.Time, out.Time = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip mismatch:\nhave %#v\nwant %#v", out, in)
+	}
+}
@@ -0,0 +1,1043 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cbor implements encoding and decoding of CBOR, the Concise Binary
+// Object Representation, as defined in RFC 7049. The mapping between CBOR
+// and Go values is described in the documentation for the Marshal and
+// Unmarshal functions.
+//
+// Unlike encoding/gob, CBOR is not specific to Go: data written by this
+// package can be read by CBOR implementations in other languages and
+// vice versa.
+package cbor
+
+import (
+	"bytes"
+	"encoding"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Marshal returns the CBOR encoding of v.
+//
+// Marshal traverses the value v recursively.
+// If an encountered value implements the Marshaler interface
+// and is not a nil pointer, Marshal calls its MarshalCBOR method
+// to produce CBOR. If no MarshalCBOR method is present but the
+// value implements encoding.BinaryMarshaler instead, Marshal calls
+// its MarshalBinary method and encodes the result as a byte string.
+// The nil pointer exception is not strictly necessary
+// but mimics a similar, necessary exception in the behavior of
+// UnmarshalCBOR.
+//
+// Otherwise, Marshal uses the following type-dependent default encodings:
+//
+// Boolean values encode as CBOR true and false.
+//
+// Signed integers encode as CBOR unsigned or negative integers, and
+// unsigned integers as CBOR unsigned integers, always using the
+// shortest form that holds the value.
+//
+// Floating point values encode as CBOR floats of the same precision:
+// float32 as single-precision and float64 as double-precision.
+//
+// String values encode as CBOR text strings.
+//
+// Array and slice values encode as CBOR arrays, except that
+// []byte and [N]byte encode as CBOR byte strings, and a nil slice
+// encodes as null.
+//
+// Struct values encode as CBOR maps with text string keys.
+// Each exported struct field becomes a member of the map unless
+//   - the field's tag is "-", or
+//   - the field is empty and its tag specifies the "omitempty" option.
+// The field's tag is read from the "cbor" key of the struct field's tag,
+// or, if there is none, from the "json" key, so that types already
+// annotated for encoding/json encode the same way in CBOR.
+// The name and option syntax, and the rules for embedded structs
+// and for selecting among conflicting names, are those of encoding/json.
+//
+// Map values encode as CBOR maps. The keys may be of any type
+// that Marshal can encode. Map entries are written in the canonical
+// order of RFC 7049 section 3.9: sorted by the length of the encoded
+// key and then by its encoded bytes.
+//
+// Pointer values encode as the value pointed to.
+// A nil pointer encodes as null.
+//
+// Interface values encode as the value contained in the interface.
+// A nil interface value encodes as null.
+//
+// time.Time values encode as a text string in RFC 3339 format
+// with tag 0 (standard date/time string).
+//
+// big.Int values encode as a byte string with tag 2 (positive bignum)
+// or tag 3 (negative bignum), even when the value would fit in a CBOR
+// integer, so that they decode back into a *big.Int.
+//
+// A Tag value encodes as its Content preceded by its tag Number.
+//
+// Channel, complex, and function values cannot be encoded in CBOR.
+// Attempting to encode such a value causes Marshal to return
+// an UnsupportedTypeError.
+//
+// CBOR cannot represent cyclic data structures and Marshal does not
+// handle them. Passing cyclic structures to Marshal will result in
+// an infinite recursion.
+//
+func Marshal(v interface{}) ([]byte, error) {
+	e := &encodeState{}
+	err := e.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// Marshaler is the interface implemented by types that
+// can marshal themselves into valid CBOR.
+type Marshaler interface {
+	MarshalCBOR() ([]byte, error)
+}
+
+// A Tag is a CBOR data item with a semantic tag, as described in
+// RFC 7049 section 2.4. Unmarshal returns a Tag when decoding into an
+// interface value a tagged item whose tag number it does not interpret.
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// An UnsupportedTypeError is returned by Marshal when attempting
+// to encode an unsupported value type.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "cbor: unsupported type: " + e.Type.String()
+}
+
+// A MarshalerError is returned by Marshal when a MarshalCBOR or
+// MarshalBinary method returns an error or, for MarshalCBOR,
+// data that is not a single well-formed CBOR data item.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (e *MarshalerError) Error() string {
+	return "cbor: error marshaling type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Major types, already shifted into the top three bits of the initial byte.
+const (
+	majorUint   = 0 << 5
+	majorNegInt = 1 << 5
+	majorBytes  = 2 << 5
+	majorText   = 3 << 5
+	majorArray  = 4 << 5
+	majorMap    = 5 << 5
+	majorTag    = 6 << 5
+	majorSimple = 7 << 5
+)
+
+// Tag numbers with a meaning in this package.
+const (
+	tagDateTime  = 0
+	tagEpochTime = 1
+	tagPosBignum = 2
+	tagNegBignum = 3
+)
+
+// Initial bytes with a fixed meaning.
+const (
+	infoIndefinite  = 31
+	simpleFalse     = majorSimple | 20
+	simpleTrue      = majorSimple | 21
+	simpleNull      = majorSimple | 22
+	simpleUndefined = majorSimple | 23
+	simpleFloat16   = majorSimple | 25
+	simpleFloat32   = majorSimple | 26
+	simpleFloat64   = majorSimple | 27
+	breakCode       = majorSimple | infoIndefinite
+)
+
+// An encodeState encodes CBOR into a bytes.Buffer.
+type encodeState struct {
+	bytes.Buffer // accumulated output
+	scratch      [9]byte
+}
+
+func (e *encodeState) marshal(v interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(runtime.Error); ok {
+				panic(r)
+			}
+			if s, ok := r.(string); ok {
+				panic(s)
+			}
+			err = r.(error)
+		}
+	}()
+	e.reflectValue(reflect.ValueOf(v))
+	return nil
+}
+
+func (e *encodeState) error(err error) {
+	panic(err)
+}
+
+// writeHead writes the initial byte for major type major
+// followed by the argument n in its shortest form.
+func (e *encodeState) writeHead(major byte, n uint64) {
+	b := e.scratch[:]
+	switch {
+	case n < 24:
+		b[0] = major | byte(n)
+		b = b[:1]
+	case n <= math.MaxUint8:
+		b[0] = major | 24
+		b[1] = byte(n)
+		b = b[:2]
+	case n <= math.MaxUint16:
+		b[0] = major | 25
+		b[1] = byte(n >> 8)
+		b[2] = byte(n)
+		b = b[:3]
+	case n <= math.MaxUint32:
+		b[0] = major | 26
+		b[1] = byte(n >> 24)
+		b[2] = byte(n >> 16)
+		b[3] = byte(n >> 8)
+		b[4] = byte(n)
+		b = b[:5]
+	default:
+		b[0] = major | 27
+		for i := uint(0); i < 8; i++ {
+			b[1+i] = byte(n >> (56 - 8*i))
+		}
+	}
+	e.Write(b)
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (e *encodeState) reflectValue(v reflect.Value) {
+	valueEncoder(v)(e, v)
+}
+
+type encoderFunc func(e *encodeState, v reflect.Value)
+
+var encoderCache struct {
+	sync.RWMutex
+	m map[reflect.Type]encoderFunc
+}
+
+func valueEncoder(v reflect.Value) encoderFunc {
+	if !v.IsValid() {
+		return invalidValueEncoder
+	}
+	return typeEncoder(v.Type())
+}
+
+func typeEncoder(t reflect.Type) encoderFunc {
+	encoderCache.RLock()
+	f := encoderCache.m[t]
+	encoderCache.RUnlock()
+	if f != nil {
+		return f
+	}
+
+	// To deal with recursive types, populate the map with an
+	// indirect func before we build it. This type waits on the
+	// real func (f) to be ready and then calls it. This indirect
+	// func is only used for recursive types.
+	encoderCache.Lock()
+	if encoderCache.m == nil {
+		encoderCache.m = make(map[reflect.Type]encoderFunc)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	encoderCache.m[t] = func(e *encodeState, v reflect.Value) {
+		wg.Wait()
+		f(e, v)
+	}
+	encoderCache.Unlock()
+
+	// Compute fields without lock.
+	// Might duplicate effort but won't hold other computations back.
+	f = newTypeEncoder(t, true)
+	wg.Done()
+	encoderCache.Lock()
+	encoderCache.m[t] = f
+	encoderCache.Unlock()
+	return f
+}
+
+var (
+	marshalerType       = reflect.TypeOf(new(Marshaler)).Elem()
+	binaryMarshalerType = reflect.TypeOf(new(encoding.BinaryMarshaler)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+	bigIntType          = reflect.TypeOf(big.Int{})
+	tagType             = reflect.TypeOf(Tag{})
+)
+
+// newTypeEncoder constructs an encoderFunc for a type.
+// The returned encoder only checks CanAddr when allowAddr is true.
+func newTypeEncoder(t reflect.Type, allowAddr bool) encoderFunc {
+	// Types with a standard CBOR representation take precedence
+	// over the methods they happen to implement.
+	switch t {
+	case timeType:
+		return timeEncoder
+	case bigIntType:
+		return bigIntEncoder
+	case tagType:
+		return tagEncoder
+	}
+	if t.Implements(marshalerType) {
+		return marshalerEncoder
+	}
+	if t.Kind() != reflect.Ptr && allowAddr {
+		if reflect.PtrTo(t).Implements(marshalerType) {
+			return newCondAddrEncoder(addrMarshalerEncoder, newTypeEncoder(t, false))
+		}
+	}
+
+	if t.Implements(binaryMarshalerType) {
+		return binaryMarshalerEncoder
+	}
+	if t.Kind() != reflect.Ptr && allowAddr {
+		if reflect.PtrTo(t).Implements(binaryMarshalerType) {
+			return newCondAddrEncoder(addrBinaryMarshalerEncoder, newTypeEncoder(t, false))
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolEncoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intEncoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintEncoder
+	case reflect.Float32:
+		return float32Encoder
+	case reflect.Float64:
+		return float64Encoder
+	case reflect.String:
+		return stringEncoder
+	case reflect.Interface:
+		return interfaceEncoder
+	case reflect.Struct:
+		return newStructEncoder(t)
+	case reflect.Map:
+		return newMapEncoder(t)
+	case reflect.Slice:
+		return newSliceEncoder(t)
+	case reflect.Array:
+		return newArrayEncoder(t)
+	case reflect.Ptr:
+		return newPtrEncoder(t)
+	default:
+		return unsupportedTypeEncoder
+	}
+}
+
+func invalidValueEncoder(e *encodeState, v reflect.Value) {
+	e.WriteByte(simpleNull)
+}
+
+func marshalerEncoder(e *encodeState, v reflect.Value) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	m := v.Interface().(Marshaler)
+	e.writeMarshaler(v, m)
+}
+
+func addrMarshalerEncoder(e *encodeState, v reflect.Value) {
+	va := v.Addr()
+	if va.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	m := va.Interface().(Marshaler)
+	e.writeMarshaler(v, m)
+}
+
+func (e *encodeState) writeMarshaler(v reflect.Value, m Marshaler) {
+	b, err := m.MarshalCBOR()
+	if err == nil {
+		// Check that the method produced exactly one data item.
+		var n int
+		if n, err = checkValid(b); err != nil {
+			err = syntaxError(err, len(b))
+		} else if n != len(b) {
+			err = errExtraData
+		}
+	}
+	if err != nil {
+		e.error(&MarshalerError{v.Type(), err})
+	}
+	e.Write(b)
+}
+
+func binaryMarshalerEncoder(e *encodeState, v reflect.Value) {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	m := v.Interface().(encoding.BinaryMarshaler)
+	e.writeBinaryMarshaler(v, m)
+}
+
+func addrBinaryMarshalerEncoder(e *encodeState, v reflect.Value) {
+	va := v.Addr()
+	if va.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	m := va.Interface().(encoding.BinaryMarshaler)
+	e.writeBinaryMarshaler(v, m)
+}
+
+func (e *encodeState) writeBinaryMarshaler(v reflect.Value, m encoding.BinaryMarshaler) {
+	b, err := m.MarshalBinary()
+	if err != nil {
+		e.error(&MarshalerError{v.Type(), err})
+	}
+	e.writeHead(majorBytes, uint64(len(b)))
+	e.Write(b)
+}
+
+func boolEncoder(e *encodeState, v reflect.Value) {
+	if v.Bool() {
+		e.WriteByte(simpleTrue)
+	} else {
+		e.WriteByte(simpleFalse)
+	}
+}
+
+func intEncoder(e *encodeState, v reflect.Value) {
+	e.writeInt(v.Int())
+}
+
+func (e *encodeState) writeInt(n int64) {
+	if n < 0 {
+		// The argument of a negative integer is -1-n, which is ^n.
+		e.writeHead(majorNegInt, uint64(^n))
+		return
+	}
+	e.writeHead(majorUint, uint64(n))
+}
+
+func uintEncoder(e *encodeState, v reflect.Value) {
+	e.writeHead(majorUint, v.Uint())
+}
+
+func float32Encoder(e *encodeState, v reflect.Value) {
+	bits := math.Float32bits(float32(v.Float()))
+	b := e.scratch[:5]
+	b[0] = simpleFloat32
+	b[1] = byte(bits >> 24)
+	b[2] = byte(bits >> 16)
+	b[3] = byte(bits >> 8)
+	b[4] = byte(bits)
+	e.Write(b)
+}
+
+func float64Encoder(e *encodeState, v reflect.Value) {
+	e.writeFloat64(v.Float())
+}
+
+func (e *encodeState) writeFloat64(f float64) {
+	bits := math.Float64bits(f)
+	b := e.scratch[:9]
+	b[0] = simpleFloat64
+	for i := uint(0); i < 8; i++ {
+		b[1+i] = byte(bits >> (56 - 8*i))
+	}
+	e.Write(b)
+}
+
+func stringEncoder(e *encodeState, v reflect.Value) {
+	s := v.String()
+	e.writeHead(majorText, uint64(len(s)))
+	e.WriteString(s)
+}
+
+func interfaceEncoder(e *encodeState, v reflect.Value) {
+	if v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	e.reflectValue(v.Elem())
+}
+
+func unsupportedTypeEncoder(e *encodeState, v reflect.Value) {
+	e.error(&UnsupportedTypeError{v.Type()})
+}
+
+func timeEncoder(e *encodeState, v reflect.Value) {
+	s := v.Interface().(time.Time).Format(time.RFC3339Nano)
+	e.writeHead(majorTag, tagDateTime)
+	e.writeHead(majorText, uint64(len(s)))
+	e.WriteString(s)
+}
+
+func bigIntEncoder(e *encodeState, v reflect.Value) {
+	x := v.Interface().(big.Int)
+	e.writeBigInt(&x)
+}
+
+func (e *encodeState) writeBigInt(x *big.Int) {
+	if x.Sign() < 0 {
+		// A negative bignum holds -1-x, which is ^x.
+		e.writeHead(majorTag, tagNegBignum)
+		x = new(big.Int).Not(x)
+	} else {
+		e.writeHead(majorTag, tagPosBignum)
+	}
+	b := x.Bytes()
+	e.writeHead(majorBytes, uint64(len(b)))
+	e.Write(b)
+}
+
+func tagEncoder(e *encodeState, v reflect.Value) {
+	t := v.Interface().(Tag)
+	e.writeHead(majorTag, t.Number)
+	e.reflectValue(reflect.ValueOf(t.Content))
+}
+
+type structEncoder struct {
+	fields    []field
+	fieldEncs []encoderFunc
+}
+
+func (se *structEncoder) encode(e *encodeState, v reflect.Value) {
+	// Collect the fields first: the map header needs their number.
+	fvs := make([]reflect.Value, len(se.fields))
+	n := 0
+	for i, f := range se.fields {
+		fv := fieldByIndex(v, f.index)
+		if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		fvs[i] = fv
+		n++
+	}
+	e.writeHead(majorMap, uint64(n))
+	for i, fv := range fvs {
+		if !fv.IsValid() {
+			continue
+		}
+		e.Write(se.fields[i].nameBytes)
+		se.fieldEncs[i](e, fv)
+	}
+}
+
+func newStructEncoder(t reflect.Type) encoderFunc {
+	fields := cachedTypeFields(t)
+	se := &structEncoder{
+		fields:    fields,
+		fieldEncs: make([]encoderFunc, len(fields)),
+	}
+	for i, f := range fields {
+		se.fieldEncs[i] = typeEncoder(typeByIndex(t, f.index))
+	}
+	return se.encode
+}
+
+type mapEncoder struct {
+	keyEnc, elemEnc encoderFunc
+}
+
+// A mapEntry locates an encoded key and value
+// within the output buffer.
+type mapEntry struct {
+	start, keyEnd, end int
+}
+
+func (me *mapEncoder) encode(e *encodeState, v reflect.Value) {
+	if v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	// Encode all entries at the end of the buffer, then sort
+	// them into canonical order and copy them back in place
+	// after the map header.
+	keys := v.MapKeys()
+	start := e.Len()
+	entries := make([]mapEntry, len(keys))
+	for i, k := range keys {
+		entries[i].start = e.Len() - start
+		me.keyEnc(e, k)
+		entries[i].keyEnd = e.Len() - start
+		me.elemEnc(e, v.MapIndex(k))
+		entries[i].end = e.Len() - start
+	}
+	buf := make([]byte, e.Len()-start)
+	copy(buf, e.Bytes()[start:])
+	sort.Sort(byEncodedKey{buf, entries})
+	e.Truncate(start)
+	e.writeHead(majorMap, uint64(len(keys)))
+	for _, ent := range entries {
+		e.Write(buf[ent.start:ent.end])
+	}
+}
+
+// byEncodedKey sorts map entries into the canonical order of
+// RFC 7049 section 3.9: shorter keys first, then by bytes.
+type byEncodedKey struct {
+	buf     []byte
+	entries []mapEntry
+}
+
+func (x byEncodedKey) Len() int { return len(x.entries) }
+
+func (x byEncodedKey) Swap(i, j int) { x.entries[i], x.entries[j] = x.entries[j], x.entries[i] }
+
+func (x byEncodedKey) Less(i, j int) bool {
+	ki := x.buf[x.entries[i].start:x.entries[i].keyEnd]
+	kj := x.buf[x.entries[j].start:x.entries[j].keyEnd]
+	if len(ki) != len(kj) {
+		return len(ki) < len(kj)
+	}
+	return bytes.Compare(ki, kj) < 0
+}
+
+func newMapEncoder(t reflect.Type) encoderFunc {
+	me := &mapEncoder{typeEncoder(t.Key()), typeEncoder(t.Elem())}
+	return me.encode
+}
+
+func encodeByteSlice(e *encodeState, v reflect.Value) {
+	if v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	b := v.Bytes()
+	e.writeHead(majorBytes, uint64(len(b)))
+	e.Write(b)
+}
+
+// sliceEncoder just wraps an arrayEncoder, checking to make sure the value isn't nil.
+type sliceEncoder struct {
+	arrayEnc encoderFunc
+}
+
+func (se *sliceEncoder) encode(e *encodeState, v reflect.Value) {
+	if v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	se.arrayEnc(e, v)
+}
+
+// isByteElem reports whether a slice or array with elements of type t
+// should be encoded as a byte string.
+func isByteElem(t reflect.Type) bool {
+	if t.Kind() != reflect.Uint8 {
+		return false
+	}
+	p := reflect.PtrTo(t)
+	return !p.Implements(marshalerType) && !p.Implements(binaryMarshalerType)
+}
+
+func newSliceEncoder(t reflect.Type) encoderFunc {
+	if isByteElem(t.Elem()) {
+		return encodeByteSlice
+	}
+	enc := &sliceEncoder{newArrayEncoder(t)}
+	return enc.encode
+}
+
+type arrayEncoder struct {
+	elemEnc encoderFunc
+}
+
+func (ae *arrayEncoder) encode(e *encodeState, v reflect.Value) {
+	n := v.Len()
+	e.writeHead(majorArray, uint64(n))
+	for i := 0; i < n; i++ {
+		ae.elemEnc(e, v.Index(i))
+	}
+}
+
+func encodeByteArray(e *encodeState, v reflect.Value) {
+	n := v.Len()
+	e.writeHead(majorBytes, uint64(n))
+	for i := 0; i < n; i++ {
+		e.WriteByte(byte(v.Index(i).Uint()))
+	}
+}
+
+func newArrayEncoder(t reflect.Type) encoderFunc {
+	if t.Kind() == reflect.Array && isByteElem(t.Elem()) {
+		return encodeByteArray
+	}
+	enc := &arrayEncoder{typeEncoder(t.Elem())}
+	return enc.encode
+}
+
+type ptrEncoder struct {
+	elemEnc encoderFunc
+}
+
+func (pe *ptrEncoder) encode(e *encodeState, v reflect.Value) {
+	if v.IsNil() {
+		e.WriteByte(simpleNull)
+		return
+	}
+	pe.elemEnc(e, v.Elem())
+}
+
+func newPtrEncoder(t reflect.Type) encoderFunc {
+	enc := &ptrEncoder{typeEncoder(t.Elem())}
+	return enc.encode
+}
+
+type condAddrEncoder struct {
+	canAddrEnc, elseEnc encoderFunc
+}
+
+func (ce *condAddrEncoder) encode(e *encodeState, v reflect.Value) {
+	if v.CanAddr() {
+		ce.canAddrEnc(e, v)
+	} else {
+		ce.elseEnc(e, v)
+	}
+}
+
+// newCondAddrEncoder returns an encoder that checks whether its value
+// CanAddr and delegates to canAddrEnc if so, else to elseEnc.
+func newCondAddrEncoder(canAddrEnc, elseEnc encoderFunc) encoderFunc {
+	enc := &condAddrEncoder{canAddrEnc: canAddrEnc, elseEnc: elseEnc}
+	return enc.encode
+}
+
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		default:
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+func typeByIndex(t reflect.Type, index []int) reflect.Type {
+	for _, i := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		t = t.Field(i).Type
+	}
+	return t
+}
+
+// A field represents a single field found in a struct.
+type field struct {
+	name      string
+	nameBytes []byte // name encoded as a CBOR text string
+
+	tag       bool
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+}
+
+func fillField(f field) field {
+	var e encodeState
+	e.writeHead(majorText, uint64(len(f.name)))
+	e.WriteString(f.name)
+	f.nameBytes = e.Bytes()
+	return f
+}
+
+// byName sorts field by name, breaking ties with depth,
+// then breaking ties with "name came from tag", then
+// breaking ties with index sequence.
+type byName []field
+
+func (x byName) Len() int { return len(x) }
+
+func (x byName) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byName) Less(i, j int) bool {
+	if x[i].name != x[j].name {
+		return x[i].name < x[j].name
+	}
+	if len(x[i].index) != len(x[j].index) {
+		return len(x[i].index) < len(x[j].index)
+	}
+	if x[i].tag != x[j].tag {
+		return x[i].tag
+	}
+	return byIndex(x).Less(i, j)
+}
+
+// byIndex sorts field by index sequence.
+type byIndex []field
+
+func (x byIndex) Len() int { return len(x) }
+
+func (x byIndex) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+
+func (x byIndex) Less(i, j int) bool {
+	for k, xik := range x[i].index {
+		if k >= len(x[j].index) {
+			return false
+		}
+		if xik != x[j].index[k] {
+			return xik < x[j].index[k]
+		}
+	}
+	return len(x[i].index) < len(x[j].index)
+}
+
+// fieldTag returns the tag that controls the encoding of the struct field:
+// its "cbor" tag if it has one, and its "json" tag otherwise.
+func fieldTag(sf reflect.StructField) string {
+	if tag := sf.Tag.Get("cbor"); tag != "" {
+		return tag
+	}
+	return sf.Tag.Get("json")
+}
+
+// typeFields returns a list of fields that CBOR should recognize for the given type.
+// The algorithm is breadth-first search over the set of structs to include - the top struct
+// and then any reachable anonymous structs.
+func typeFields(t reflect.Type) []field {
+	// Anonymous fields to explore at the current level and the next.
+	current := []field{}
+	next := []field{{typ: t}}
+
+	// Count of queued names for current level and the next.
+	count := map[reflect.Type]int{}
+	nextCount := map[reflect.Type]int{}
+
+	// Types already visited at an earlier level.
+	visited := map[reflect.Type]bool{}
+
+	// Fields found.
+	var fields []field
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, f := range current {
+			if visited[f.typ] {
+				continue
+			}
+			visited[f.typ] = true
+
+			// Scan f.typ for fields to include.
+			for i := 0; i < f.typ.NumField(); i++ {
+				sf := f.typ.Field(i)
+				if sf.PkgPath != "" && !sf.Anonymous { // unexported
+					continue
+				}
+				tag := fieldTag(sf)
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				if !isValidTag(name) {
+					name = ""
+				}
+				index := make([]int, len(f.index)+1)
+				copy(index, f.index)
+				index[len(f.index)] = i
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					// Follow pointer.
+					ft = ft.Elem()
+				}
+
+				// Record found field and index sequence.
+				if name != "" || !sf.Anonymous || ft.Kind() != reflect.Struct {
+					tagged := name != ""
+					if name == "" {
+						name = sf.Name
+					}
+					fields = append(fields, fillField(field{
+						name:      name,
+						tag:       tagged,
+						index:     index,
+						typ:       ft,
+						omitEmpty: opts.Contains("omitempty"),
+					}))
+					if count[f.typ] > 1 {
+						// If there were multiple instances, add a second,
+						// so that the annihilation code will see a duplicate.
+						// It only cares about the distinction between 1 or 2,
+						// so don't bother generating any more copies.
+						fields = append(fields, fields[len(fields)-1])
+					}
+					continue
+				}
+
+				// Record new anonymous struct to explore in next round.
+				nextCount[ft]++
+				if nextCount[ft] == 1 {
+					next = append(next, field{name: ft.Name(), index: index, typ: ft})
+				}
+			}
+		}
+	}
+
+	sort.Sort(byName(fields))
+
+	// Delete all fields that are hidden by the Go rules for embedded fields,
+	// except that fields with tags are promoted.
+
+	// The fields are sorted in primary order of name, secondary order
+	// of field index length. Loop over names; for each name, delete
+	// hidden fields by choosing the one dominant field that survives.
+	out := fields[:0]
+	for advance, i := 0, 0; i < len(fields); i += advance {
+		// One iteration per name.
+		// Find the sequence of fields with the name of this first field.
+		fi := fields[i]
+		name := fi.name
+		for advance = 1; i+advance < len(fields); advance++ {
+			fj := fields[i+advance]
+			if fj.name != name {
+				break
+			}
+		}
+		if advance == 1 { // Only one field with this name
+			out = append(out, fi)
+			continue
+		}
+		dominant, ok := dominantField(fields[i : i+advance])
+		if ok {
+			out = append(out, dominant)
+		}
+	}
+
+	fields = out
+	sort.Sort(byIndex(fields))
+
+	return fields
+}
+
+// dominantField looks through the fields, all of which are known to
+// have the same name, to find the single field that dominates the
+// others using Go's embedding rules, modified by the presence of
+// tags. If there are multiple top-level fields, the boolean
+// will be false: This condition is an error in Go and we skip all
+// the fields.
+func dominantField(fields []field) (field, bool) {
+	// The fields are sorted in increasing index-length order. The winner
+	// must therefore be one with the shortest index length. Drop all
+	// longer entries, which is easy: just truncate the slice.
+	length := len(fields[0].index)
+	tagged := -1 // Index of first tagged field.
+	for i, f := range fields {
+		if len(f.index) > length {
+			fields = fields[:i]
+			break
+		}
+		if f.tag {
+			if tagged >= 0 {
+				// Multiple tagged fields at the same level: conflict.
+				// Return no field.
+				return field{}, false
+			}
+			tagged = i
+		}
+	}
+	if tagged >= 0 {
+		return fields[tagged], true
+	}
+	// All remaining fields have the same length. If there's more than one,
+	// we have a conflict (two fields named "X" at the same level) and we
+	// return no field.
+	if len(fields) > 1 {
+		return field{}, false
+	}
+	return fields[0], true
+}
+
+var fieldCache struct {
+	sync.RWMutex
+	m map[reflect.Type][]field
+}
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+func cachedTypeFields(t reflect.Type) []field {
+	fieldCache.RLock()
+	f := fieldCache.m[t]
+	fieldCache.RUnlock()
+	if f != nil {
+		return f
+	}
+
+	// Compute fields without lock.
+	// Might duplicate effort but won't hold other computations back.
+	f = typeFields(t)
+	if f == nil {
+		f = []field{}
+	}
+
+	fieldCache.Lock()
+	if fieldCache.m == nil {
+		fieldCache.m = map[reflect.Type][]field{}
+	}
+	fieldCache.m[t] = f
+	fieldCache.Unlock()
+	return f
+}
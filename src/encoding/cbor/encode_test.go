@@ -0,0 +1,197 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func bigInt(s string) *big.Int {
+	x, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("bad big.Int " + s)
+	}
+	return x
+}
+
+type Tagged struct {
+	A int    `cbor:"a"`
+	B string `json:"b,omitempty"`
+	C string `cbor:"c" json:"ignored"`
+	D int    `cbor:"-"`
+	E []int  `cbor:",omitempty"`
+	F *int
+}
+
+type Embedded struct {
+	X int
+	Y int
+}
+
+type Outer struct {
+	Embedded
+	Y string
+	Z int `cbor:"X"` // conflicts with Embedded.X and wins by its tag
+}
+
+type binaryValue struct{ s string }
+
+func (b binaryValue) MarshalBinary() ([]byte, error) { return []byte(b.s), nil }
+
+func (b *binaryValue) UnmarshalBinary(data []byte) error {
+	b.s = string(data)
+	return nil
+}
+
+type cborValue int
+
+func (c cborValue) MarshalCBOR() ([]byte, error) {
+	return []byte{0x63, 'f', 'o', 'o'}, nil
+}
+
+type badMarshaler struct{}
+
+func (badMarshaler) MarshalCBOR() ([]byte, error) { return []byte{0x82, 0x01}, nil }
+
+type errMarshaler struct{}
+
+func (errMarshaler) MarshalCBOR() ([]byte, error) { return nil, errors.New("no") }
+
+var encodeTests = []struct {
+	in  interface{}
+	out string // hex
+}{
+	// RFC 7049 Appendix A.
+	{uint(0), "00"},
+	{1, "01"},
+	{10, "0a"},
+	{23, "17"},
+	{24, "1818"},
+	{uint8(25), "1819"},
+	{100, "1864"},
+	{1000, "1903e8"},
+	{1000000, "1a000f4240"},
+	{int64(1000000000000), "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{bigInt("18446744073709551616"), "c249010000000000000000"},
+	{bigInt("-18446744073709551617"), "c349010000000000000000"},
+	{-1, "20"},
+	{-10, "29"},
+	{int16(-100), "3863"},
+	{-1000, "3903e7"},
+	{int64(math.MinInt64), "3b7fffffffffffffff"},
+	{1.1, "fb3ff199999999999a"},
+	{float32(100000.0), "fa47c35000"},
+	{float32(3.4028234663852886e+38), "fa7f7fffff"},
+	{1.0e+300, "fb7e37e43c8800759c"},
+	{-4.1, "fbc010666666666666"},
+	{math.Inf(1), "fb7ff0000000000000"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), "c074323031332d30332d32315432303a30343a30305a"},
+	{Tag{23, []byte{1, 2, 3, 4}}, "d74401020304"},
+	{Tag{24, []byte{0x64, 0x49, 0x45, 0x54, 0x46}}, "d818456449455446"},
+	{[]byte{}, "40"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{"", "60"},
+	{"a", "6161"},
+	{"IETF", "6449455446"},
+	{"\"\\", "62225c"},
+	{"ü", "62c3bc"},
+	{"水", "63e6b0b4"},
+	{[]int{}, "80"},
+	{[]int{1, 2, 3}, "83010203"},
+	{[]interface{}{1, []int{2, 3}, [2]int{4, 5}}, "8301820203820405"},
+	{map[int]int{}, "a0"},
+	{map[int]int{3: 4, 1: 2}, "a201020304"},
+	{map[string]interface{}{"b": []int{2, 3}, "a": 1}, "a26161016162820203"},
+	{map[string]string{"e": "E", "b": "B", "a": "A", "d": "D", "c": "C"}, "a56161614161626142616361436164614461656145"},
+
+	// Canonical map key order: shorter keys first.
+	{map[string]int{"aa": 1, "b": 2}, "a261620262616101"},
+	{map[interface{}]int{"a": 1, 10: 2, -1: 3}, "a30a022003616101"},
+
+	// Bignums always use tags.
+	{big.NewInt(0), "c240"},
+	{*big.NewInt(1), "c24101"},
+	{big.NewInt(-1), "c340"},
+	{big.NewInt(-256), "c341ff"},
+
+	// Go specifics.
+	{[]byte(nil), "f6"},
+	{[]int(nil), "f6"},
+	{map[string]int(nil), "f6"},
+	{(*int)(nil), "f6"},
+	{[4]byte{1, 2, 3, 4}, "4401020304"},
+	{[]string{"a"}, "816161"},
+	{binaryValue{"ab"}, "426162"},
+	{&binaryValue{"ab"}, "426162"},
+	{cborValue(1), "63666f6f"},
+	{[]cborValue{1}, "8163666f6f"},
+	{RawMessage{0x82, 0x01, 0x02}, "820102"},
+	{RawMessage(nil), "f6"},
+	{Tagged{A: 1, C: "c", D: 4}, "a3616101616361636146f6"},
+	{Tagged{B: "b", E: []int{1}}, "a561610061626162616360614581016146f6"},
+	{Outer{Embedded{1, 2}, "y", 3}, "a261596179615803"},
+	{struct{ T time.Time }{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC)}, "a16154c074323031332d30332d32315432303a30343a30305a"},
+}
+
+func TestMarshal(t *testing.T) {
+	for _, tt := range encodeTests {
+		b, err := Marshal(tt.in)
+		if err != nil {
+			t.Errorf("Marshal(%#v): %v", tt.in, err)
+			continue
+		}
+		if got := hex.EncodeToString(b); got != tt.out {
+			t.Errorf("Marshal(%#v) = %s, want %s", tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestMarshalAddressableMarshaler(t *testing.T) {
+	v := struct{ B binaryValue }{binaryValue{"x"}}
+	b, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := hex.EncodeToString(b), "a161424178"; got != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		err string
+	}{
+		{make(chan int), "cbor: unsupported type: chan int"},
+		{complex(1, 2), "cbor: unsupported type: complex128"},
+		{map[string]interface{}{"f": func() {}}, "cbor: unsupported type: func()"},
+		{badMarshaler{}, "cbor: error marshaling type cbor.badMarshaler: unexpected end of CBOR input"},
+		{errMarshaler{}, "cbor: error marshaling type cbor.errMarshaler: no"},
+		{RawMessage{0x01, 0x02}, "cbor: error marshaling type cbor.RawMessage: extra data after data item"},
+	}
+	for _, tt := range tests {
+		_, err := Marshal(tt.in)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Marshal(%#v) error = %v, want %s", tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestMarshalerErrorType(t *testing.T) {
+	_, err := Marshal(errMarshaler{})
+	if e, ok := err.(*MarshalerError); !ok || e.Type != reflect.TypeOf(errMarshaler{}) {
+		t.Errorf("Marshal error = %#v, want *MarshalerError for errMarshaler", err)
+	}
+}
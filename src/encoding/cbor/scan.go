@@ -0,0 +1,186 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+// CBOR well-formedness checking.
+//
+// Before decoding, the input is checked to hold a complete, well-formed
+// data item, so that the decoder proper can assume well-formed input and
+// never needs to backtrack. The check also tells the streaming Decoder
+// how many bytes the next data item occupies.
+
+import (
+	"errors"
+	"io"
+	"strconv"
+)
+
+// A SyntaxError is a description of a CBOR syntax error.
+type SyntaxError struct {
+	msg    string // description of error
+	Offset int64  // error occurred after reading Offset bytes
+}
+
+func (e *SyntaxError) Error() string { return e.msg }
+
+var (
+	// errShort reports that the input ended within a data item.
+	errShort = io.ErrUnexpectedEOF
+
+	errExtraData = errors.New("extra data after data item")
+)
+
+// checkValid verifies that data begins with a well-formed CBOR data item
+// and returns its length. It returns errShort if data ends before the item
+// is complete and a *SyntaxError if the item is malformed.
+func checkValid(data []byte) (int, error) {
+	return itemEnd(data, 0, 0)
+}
+
+// maxNestingDepth bounds the nesting of arrays, maps and tags, so that
+// small malicious inputs cannot exhaust the stack.
+const maxNestingDepth = 10000
+
+// readHead parses the initial byte of the data item at data[off] and the
+// argument that follows it. For indefinite-length items, info is 31 and arg
+// is zero. It returns the offset just past the head.
+func readHead(data []byte, off int) (major, info byte, arg uint64, next int, err error) {
+	if off >= len(data) {
+		return 0, 0, 0, off, errShort
+	}
+	b := data[off]
+	major, info = b>>5, b&0x1f
+	off++
+	var n int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), off, nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	case info == infoIndefinite:
+		return major, info, 0, off, nil
+	default:
+		return 0, 0, 0, off, &SyntaxError{"reserved additional information " + strconv.Itoa(int(info)) + " in initial byte", int64(off)}
+	}
+	if len(data)-off < n {
+		return 0, 0, 0, off, errShort
+	}
+	for _, c := range data[off : off+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	return major, info, arg, off + n, nil
+}
+
+// itemEnd returns the offset just past the data item at data[off],
+// which is nested depth levels deep.
+func itemEnd(data []byte, off, depth int) (int, error) {
+	if depth > maxNestingDepth {
+		return 0, &SyntaxError{"exceeded max nesting depth", int64(off)}
+	}
+	start := off
+	major, info, arg, off, err := readHead(data, off)
+	if err != nil {
+		return 0, err
+	}
+	indefinite := info == infoIndefinite
+	switch major {
+	case 0, 1, 6:
+		if indefinite {
+			return 0, &SyntaxError{"indefinite length " + majorName[major], int64(start)}
+		}
+		if major == 6 {
+			return itemEnd(data, off, depth+1)
+		}
+		return off, nil
+
+	case 2, 3:
+		if !indefinite {
+			return stringEnd(data, off, arg)
+		}
+		// An indefinite-length string is a sequence of definite-length
+		// strings of the same major type, ended by a break.
+		for {
+			if off >= len(data) {
+				return 0, errShort
+			}
+			if data[off] == breakCode {
+				return off + 1, nil
+			}
+			chunk := off
+			m, i, n, next, err := readHead(data, off)
+			if err != nil {
+				return 0, err
+			}
+			if m != major || i == infoIndefinite {
+				return 0, &SyntaxError{"invalid chunk in indefinite length " + majorName[major], int64(chunk)}
+			}
+			if off, err = stringEnd(data, next, n); err != nil {
+				return 0, err
+			}
+		}
+
+	case 4, 5:
+		if !indefinite {
+			// Each item takes at least one byte.
+			if arg > uint64(len(data)-off) {
+				return 0, errShort
+			}
+			if major == 5 {
+				// A map holds a key and a value per entry.
+				arg *= 2
+			}
+		}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite {
+				if off >= len(data) {
+					return 0, errShort
+				}
+				if data[off] == breakCode {
+					if major == 5 && i%2 != 0 {
+						return 0, &SyntaxError{"indefinite length map with odd number of items", int64(off)}
+					}
+					return off + 1, nil
+				}
+			}
+			if off, err = itemEnd(data, off, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+
+	default: // 7
+		if indefinite {
+			return 0, &SyntaxError{"unexpected break", int64(start)}
+		}
+		return off, nil
+	}
+}
+
+// stringEnd returns the offset just past the n bytes of string
+// content that begin at data[off].
+func stringEnd(data []byte, off int, n uint64) (int, error) {
+	if uint64(len(data)-off) < n {
+		return 0, errShort
+	}
+	return off + int(n), nil
+}
+
+// majorName describes the items of each major type for error messages.
+var majorName = [...]string{
+	"positive integer",
+	"negative integer",
+	"byte string",
+	"text string",
+	"array",
+	"map",
+	"tag",
+	"simple value",
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+var streamTest = []interface{}{
+	uint64(1),
+	"a string",
+	[]interface{}{uint64(1), "b", nil},
+	map[interface{}]interface{}{"x": []byte{1, 2}},
+	nil,
+	bigInt("-18446744073709551617"),
+	Tag{100, "t"},
+}
+
+const streamEncoded = "01" +
+	"686120737472696e67" +
+	"83016162f6" +
+	"a16178420102" +
+	"f6" +
+	"c349010000000000000000" +
+	"d8646174"
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, v := range streamTest {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("Encode(%#v): %v", v, err)
+		}
+	}
+	if got := hex.EncodeToString(buf.Bytes()); got != streamEncoded {
+		t.Errorf("encoding mismatch:\nhave %s\nwant %s", got, streamEncoded)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	in := mustHex(streamEncoded)
+	for _, r := range []io.Reader{
+		bytes.NewReader(in),
+		iotest.OneByteReader(bytes.NewReader(in)),
+		iotest.DataErrReader(bytes.NewReader(in)),
+	} {
+		dec := NewDecoder(r)
+		for i, want := range streamTest {
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("Decode #%d: %v", i, err)
+			}
+			if !reflect.DeepEqual(v, want) {
+				t.Errorf("Decode #%d = %#v, want %#v", i, v, want)
+			}
+		}
+		var v interface{}
+		if err := dec.Decode(&v); err != io.EOF {
+			t.Errorf("Decode at end = %v, want io.EOF", err)
+		}
+	}
+}
+
+func TestDecoderUnexpectedEOF(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(mustHex("0183016162")))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(mustHex("01ff01")))
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	err := dec.Decode(&v)
+	if _, ok := err.(*SyntaxError); !ok {
+		t.Fatalf("Decode = %v, want *SyntaxError", err)
+	}
+	// The error is sticky.
+	if err2 := dec.Decode(&v); err2 != err {
+		t.Errorf("second Decode = %v, want %v", err2, err)
+	}
+}
+
+func TestDecoderTypeErrorIsNotSticky(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(mustHex("616102")))
+	var n int
+	if err := dec.Decode(&n); err == nil {
+		t.Fatal("Decode of text string into int succeeded")
+	}
+	if err := dec.Decode(&n); err != nil || n != 2 {
+		t.Errorf("Decode = %d, %v, want 2, nil", n, err)
+	}
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	dec := NewDecoder(bytes.NewReader(mustHex("820102ffee")))
+	var v []int
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := ioutil.ReadAll(dec.Buffered())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(rest); got != "ffee" {
+		t.Errorf("Buffered = %s, want ffee", got)
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	var v struct {
+		A RawMessage
+		B *RawMessage
+		C int
+	}
+	in := mustHex("a3614182010261429f01ff614303")
+	if err := Unmarshal(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(v.A); got != "820102" {
+		t.Errorf("A = %s, want 820102", got)
+	}
+	if v.B == nil || hex.EncodeToString(*v.B) != "9f01ff" {
+		t.Errorf("B = %v, want 9f01ff", v.B)
+	}
+	out, err := Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, in) {
+		t.Errorf("Marshal = %x, want %x", out, in)
+	}
+}
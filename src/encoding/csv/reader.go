@@ -60,13 +60,19 @@ import (
 
 // A ParseError is returned for parsing errors.
 // The first line is 1.  The first column is 0.
+// StartLine differs from Line when the error occurs in a record that
+// spans several lines, such as one with a multi-line quoted-field.
 type ParseError struct {
-	Line   int   // Line where the error occurred
-	Column int   // Column (rune index) where the error occurred
-	Err    error // The actual error
+	StartLine int   // Line where the record starts
+	Line      int   // Line where the error occurred
+	Column    int   // Column (rune index) where the error occurred
+	Err       error // The actual error
 }
 
 func (e *ParseError) Error() string {
+	if e.StartLine != 0 && e.StartLine != e.Line {
+		return fmt.Sprintf("record on line %d; line %d, column %d: %s", e.StartLine, e.Line, e.Column, e.Err)
+	}
 	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err)
 }
 
@@ -99,6 +105,11 @@ var (
 // non-doubled quote may appear in a quoted field.
 //
 // If TrimLeadingSpace is true, leading white space in a field is ignored.
+//
+// If ReuseRecord is true, calls to Read may return a slice sharing the
+// backing array of the previous call's returned slice, to avoid an
+// allocation per record. By default, each call to Read returns newly
+// allocated memory owned by the caller.
 type Reader struct {
 	Comma            rune // field delimiter (set to ',' by NewReader)
 	Comment          rune // comment character for start of line
@@ -106,10 +117,26 @@ type Reader struct {
 	LazyQuotes       bool // allow lazy quotes
 	TrailingComma    bool // ignored; here for backwards compatibility
 	TrimLeadingSpace bool // trim leading space
+	ReuseRecord      bool // reuse the record slice between calls to Read
 	line             int
+	recordLine       int // line where the current record starts
 	column           int
 	r                *bufio.Reader
-	field            bytes.Buffer
+
+	// field holds the text of every field of the current record.
+	// Each field ends at the corresponding offset in fieldIndexes
+	// and starts at the corresponding position in fieldPositions.
+	field          bytes.Buffer
+	fieldIndexes   []int
+	fieldPositions []position
+	pos            position // start of the field being parsed
+
+	lastRecord []string
+}
+
+// position is a line and column in the input.
+type position struct {
+	line, column int
 }
 
 // NewReader returns a new Reader that reads from r.
@@ -123,17 +150,49 @@ func NewReader(r io.Reader) *Reader {
 // error creates a new ParseError based on err.
 func (r *Reader) error(err error) error {
 	return &ParseError{
-		Line:   r.line,
-		Column: r.column,
-		Err:    err,
+		StartLine: r.recordLine,
+		Line:      r.line,
+		Column:    r.column,
+		Err:       err,
 	}
 }
 
 // Read reads one record from r.  The record is a slice of strings with each
 // string representing one field.
+//
+// If r.ReuseRecord is true, the returned slice may be changed by the
+// next call to Read.
 func (r *Reader) Read() (record []string, err error) {
+	if r.ReuseRecord {
+		record, err = r.readRecord(r.lastRecord)
+		if record != nil {
+			r.lastRecord = record
+		}
+	} else {
+		record, err = r.readRecord(nil)
+	}
+	return record, err
+}
+
+// FieldPos returns the line and column of the start of the field with
+// the given index in the slice most recently returned by Read.
+// Lines and columns are numbered as in ParseError. The start of a
+// quoted-field is its opening quote.
+//
+// If FieldPos is called with an out-of-bounds index, it panics.
+func (r *Reader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.fieldPositions) {
+		panic("csv: out of range index passed to FieldPos")
+	}
+	p := &r.fieldPositions[field]
+	return p.line, p.column
+}
+
+// readRecord reads one record from r, storing its fields in dst
+// if dst has enough capacity.
+func (r *Reader) readRecord(dst []string) (record []string, err error) {
 	for {
-		record, err = r.parseRecord()
+		record, err = r.parseRecord(dst)
 		if record != nil {
 			break
 		}
@@ -160,7 +219,7 @@ func (r *Reader) Read() (record []string, err error) {
 // reported.
 func (r *Reader) ReadAll() (records [][]string, err error) {
 	for {
-		record, err := r.Read()
+		record, err := r.readRecord(nil)
 		if err == io.EOF {
 			return records, nil
 		}
@@ -206,12 +265,15 @@ func (r *Reader) skip(delim rune) error {
 	}
 }
 
-// parseRecord reads and parses a single csv record from r.
-func (r *Reader) parseRecord() (fields []string, err error) {
+// parseRecord reads and parses a single csv record from r,
+// storing its fields in dst if dst has enough capacity.
+// It returns a nil record for blank and comment lines.
+func (r *Reader) parseRecord(dst []string) (fields []string, err error) {
 	// Each record starts on a new line.  We increment our line
 	// number (lines start at 1, not 0) and set column to -1
 	// so as we increment in readRune it points to the character we read.
 	r.line++
+	r.recordLine = r.line
 	r.column = -1
 
 	// Peek at the first rune.  If it is an error we are done.
@@ -228,35 +290,55 @@ func (r *Reader) parseRecord() (fields []string, err error) {
 	}
 	r.r.UnreadRune()
 
+	r.field.Reset()
+	r.fieldIndexes = r.fieldIndexes[:0]
+	r.fieldPositions = r.fieldPositions[:0]
+
 	// At this point we have at least one field.
 	for {
 		haveField, delim, err := r.parseField()
 		if haveField {
-			// If FieldsPerRecord is greater then 0 we can assume the final
-			// length of fields to be equal to FieldsPerRecord.
-			if r.FieldsPerRecord > 0 && fields == nil {
-				fields = make([]string, 0, r.FieldsPerRecord)
-			}
-			fields = append(fields, r.field.String())
+			r.fieldIndexes = append(r.fieldIndexes, r.field.Len())
+			r.fieldPositions = append(r.fieldPositions, r.pos)
 		}
 		if delim == '\n' || err == io.EOF {
-			return fields, err
+			return r.makeRecord(dst), err
 		} else if err != nil {
 			return nil, err
 		}
 	}
 }
 
+// makeRecord splits the fields accumulated in r.field into a record,
+// reusing dst if it has enough capacity. All the fields share a single
+// string. It returns nil if the record has no fields.
+func (r *Reader) makeRecord(dst []string) []string {
+	n := len(r.fieldIndexes)
+	if n == 0 {
+		return nil
+	}
+	if cap(dst) < n {
+		dst = make([]string, n)
+	}
+	dst = dst[:n]
+	str := r.field.String()
+	prev := 0
+	for i, idx := range r.fieldIndexes {
+		dst[i] = str[prev:idx]
+		prev = idx
+	}
+	return dst
+}
+
 // parseField parses the next field in the record.  The read field is
-// located in r.field.  Delim is the first character not part of the field
-// (r.Comma or '\n').
+// appended to r.field and its starting position is stored in r.pos.
+// Delim is the first character not part of the field (r.Comma or '\n').
 func (r *Reader) parseField() (haveField bool, delim rune, err error) {
-	r.field.Reset()
-
 	r1, err := r.readRune()
 	for err == nil && r.TrimLeadingSpace && r1 != '\n' && unicode.IsSpace(r1) {
 		r1, err = r.readRune()
 	}
+	r.pos = position{r.line, r.column}
 
 	if err == io.EOF && r.column != 0 {
 		return true, 0, err
@@ -5,6 +5,7 @@
 package csv
 
 import (
+	"io"
 	"reflect"
 	"strings"
 	"testing"
@@ -292,8 +293,107 @@ func TestRead(t *testing.T) {
 	}
 }
 
-func BenchmarkRead(b *testing.B) {
-	data := `x,y,z,w
+func TestReadReuseRecord(t *testing.T) {
+	r := NewReader(strings.NewReader("a,b\n\nc,d,e\nf\n"))
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+	want := [][]string{{"a", "b"}, {"c", "d", "e"}, {"f"}}
+	var prev []string
+	for i, w := range want {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read #%d: %v", i, err)
+		}
+		if !reflect.DeepEqual(rec, w) {
+			t.Errorf("Read #%d = %q, want %q", i, rec, w)
+		}
+		if i == 2 && &rec[0] != &prev[0] {
+			t.Errorf("Read #%d did not reuse the previous record", i)
+		}
+		prev = rec
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("final Read error = %v, want io.EOF", err)
+	}
+
+	// ReadAll never reuses records.
+	r = NewReader(strings.NewReader("a,b\nc,d\n"))
+	r.ReuseRecord = true
+	out, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if want := [][]string{{"a", "b"}, {"c", "d"}}; !reflect.DeepEqual(out, want) {
+		t.Errorf("ReadAll = %q, want %q", out, want)
+	}
+}
+
+func TestFieldPos(t *testing.T) {
+	input := "a,bc,\"d\ne\",f\n\n# comment\n g,\"h\"\n"
+	r := NewReader(strings.NewReader(input))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	want := [][][2]int{
+		{{1, 0}, {1, 2}, {1, 5}, {2, 3}},
+		{{5, 1}, {5, 3}},
+	}
+	for i, positions := range want {
+		rec, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read #%d: %v", i, err)
+		}
+		if len(rec) != len(positions) {
+			t.Fatalf("Read #%d = %q, want %d fields", i, rec, len(positions))
+		}
+		for j, p := range positions {
+			line, col := r.FieldPos(j)
+			if line != p[0] || col != p[1] {
+				t.Errorf("record %d: FieldPos(%d) = %d:%d, want %d:%d", i, j, line, col, p[0], p[1])
+			}
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("FieldPos with out of range index did not panic")
+		}
+	}()
+	r.FieldPos(2)
+}
+
+func TestParseErrorStartLine(t *testing.T) {
+	tests := []struct {
+		input             string
+		fields            int
+		startLine, line   int
+		err               error
+		wantErrorContains string
+	}{
+		{"a,\"b\nc\"d,e\n", -1, 1, 2, ErrQuote, "record on line 1; line 2, column 1"},
+		{"a,b\nc,\"d\n\ne\"x\n", -1, 2, 4, ErrQuote, "record on line 2; line 4, column 1"},
+		{"a,b\n\"c\nd\"\n", 2, 2, 3, ErrFieldCount, "record on line 2; line 3, column 0"},
+		{"a,b\nc\"\n", -1, 2, 2, ErrBareQuote, "line 2, column 1"},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.input))
+		r.FieldsPerRecord = tt.fields
+		_, err := r.ReadAll()
+		perr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q: ReadAll error = %v, want *ParseError", tt.input, err)
+			continue
+		}
+		if perr.StartLine != tt.startLine || perr.Line != tt.line || perr.Err != tt.err {
+			t.Errorf("%q: ReadAll error = %+v, want StartLine %d, Line %d, Err %v", tt.input, perr, tt.startLine, tt.line, tt.err)
+		}
+		if !strings.Contains(perr.Error(), tt.wantErrorContains) {
+			t.Errorf("%q: error %q does not contain %q", tt.input, perr.Error(), tt.wantErrorContains)
+		}
+	}
+}
+
+const benchmarkCSVData = `x,y,z,w
 x,y,z,
 x,y,,
 x,,,
@@ -305,11 +405,28 @@ x,,,
 "","","",""
 `
 
+func BenchmarkRead(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		_, err := NewReader(strings.NewReader(data)).ReadAll()
+		_, err := NewReader(strings.NewReader(benchmarkCSVData)).ReadAll()
 
 		if err != nil {
 			b.Fatalf("could not read data: %s", err)
 		}
 	}
 }
+
+func BenchmarkReadReuseRecord(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		r := NewReader(strings.NewReader(benchmarkCSVData))
+		r.ReuseRecord = true
+		for {
+			_, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatalf("could not read data: %s", err)
+			}
+		}
+	}
+}
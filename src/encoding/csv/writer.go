@@ -6,6 +6,7 @@ package csv
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"strings"
 	"unicode"
@@ -20,17 +21,30 @@ import (
 //
 // Comma is the field delimiter.
 //
+// Quote is the character that encloses quoted fields. A Quote character
+// within a quoted field is written twice. Quote must be a valid rune
+// other than Comma, \r and \n.
+//
+// If QuoteAll is true, every field is quoted, including empty fields.
+//
 // If UseCRLF is true, the Writer ends each record with \r\n instead of \n.
 type Writer struct {
-	Comma   rune // Field delimiter (set to ',' by NewWriter)
-	UseCRLF bool // True to use \r\n as the line terminator
-	w       *bufio.Writer
+	Comma    rune // Field delimiter (set to ',' by NewWriter)
+	Quote    rune // Quote character (set to '"' by NewWriter)
+	QuoteAll bool // True to quote every field
+	UseCRLF  bool // True to use \r\n as the line terminator
+	w        *bufio.Writer
 }
 
+// ErrQuoteChar is returned by Write when the Writer's Quote is not a
+// usable quote character.
+var ErrQuoteChar = errors.New("csv: invalid quote character")
+
 // NewWriter returns a new Writer that writes to w.
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
 		Comma: ',',
+		Quote: '"',
 		w:     bufio.NewWriter(w),
 	}
 }
@@ -38,6 +52,9 @@ func NewWriter(w io.Writer) *Writer {
 // Writer writes a single CSV record to w along with any necessary quoting.
 // A record is a slice of strings with each string being one field.
 func (w *Writer) Write(record []string) (err error) {
+	if w.Quote == w.Comma || w.Quote == '\r' || w.Quote == '\n' || !utf8.ValidRune(w.Quote) {
+		return ErrQuoteChar
+	}
 	for n, field := range record {
 		if n > 0 {
 			if _, err = w.w.WriteRune(w.Comma); err != nil {
@@ -53,14 +70,16 @@ func (w *Writer) Write(record []string) (err error) {
 			}
 			continue
 		}
-		if err = w.w.WriteByte('"'); err != nil {
+		if _, err = w.w.WriteRune(w.Quote); err != nil {
 			return
 		}
 
 		for _, r1 := range field {
 			switch r1 {
-			case '"':
-				_, err = w.w.WriteString(`""`)
+			case w.Quote:
+				if _, err = w.w.WriteRune(r1); err == nil {
+					_, err = w.w.WriteRune(r1)
+				}
 			case '\r':
 				if !w.UseCRLF {
 					err = w.w.WriteByte('\r')
@@ -79,7 +98,7 @@ func (w *Writer) Write(record []string) (err error) {
 			}
 		}
 
-		if err = w.w.WriteByte('"'); err != nil {
+		if _, err = w.w.WriteRune(w.Quote); err != nil {
 			return
 		}
 	}
@@ -115,8 +134,9 @@ func (w *Writer) WriteAll(records [][]string) (err error) {
 }
 
 // fieldNeedsQuotes reports whether our field must be enclosed in quotes.
-// Fields with a Comma, fields with a quote or newline, and
-// fields which start with a space must be enclosed in quotes.
+// With QuoteAll set every field is quoted. Otherwise, fields with a Comma,
+// fields with a Quote or newline, and fields which start with a space
+// must be enclosed in quotes.
 // We used to quote empty strings, but we do not anymore (as of Go 1.4).
 // The two representations should be equivalent, but Postgres distinguishes
 // quoted vs non-quoted empty string during database imports, and it has
@@ -127,10 +147,13 @@ func (w *Writer) WriteAll(records [][]string) (err error) {
 // of Microsoft Excel and Google Drive.
 // For Postgres, quote the data terminating string `\.`.
 func (w *Writer) fieldNeedsQuotes(field string) bool {
+	if w.QuoteAll {
+		return true
+	}
 	if field == "" {
 		return false
 	}
-	if field == `\.` || strings.IndexRune(field, w.Comma) >= 0 || strings.IndexAny(field, "\"\r\n") >= 0 {
+	if field == `\.` || strings.IndexRune(field, w.Comma) >= 0 || strings.IndexRune(field, w.Quote) >= 0 || strings.IndexAny(field, "\r\n") >= 0 {
 		return true
 	}
 
@@ -11,9 +11,11 @@ import (
 )
 
 var writeTests = []struct {
-	Input   [][]string
-	Output  string
-	UseCRLF bool
+	Input    [][]string
+	Output   string
+	UseCRLF  bool
+	Quote    rune
+	QuoteAll bool
 }{
 	{Input: [][]string{{"abc"}}, Output: "abc\n"},
 	{Input: [][]string{{"abc"}}, Output: "abc\r\n", UseCRLF: true},
@@ -39,6 +41,11 @@ var writeTests = []struct {
 	{Input: [][]string{{"a", "a", ""}}, Output: "a,a,\n"},
 	{Input: [][]string{{"a", "a", "a"}}, Output: "a,a,a\n"},
 	{Input: [][]string{{`\.`}}, Output: "\"\\.\"\n"},
+	{Input: [][]string{{"a", "", "b c"}}, Output: `"a","","b c"` + "\n", QuoteAll: true},
+	{Input: [][]string{{`a"b`, "c"}}, Output: `"a""b","c"` + "\n", QuoteAll: true},
+	{Input: [][]string{{`a"b`, "c'd", "e,f"}}, Output: `a"b,'c''d','e,f'` + "\n", Quote: '\''},
+	{Input: [][]string{{"a", ""}}, Output: "'a',''\n", Quote: '\'', QuoteAll: true},
+	{Input: [][]string{{"aé", "b"}}, Output: "éaééé,b\n", Quote: 'é'},
 }
 
 func TestWrite(t *testing.T) {
@@ -46,6 +53,10 @@ func TestWrite(t *testing.T) {
 		b := &bytes.Buffer{}
 		f := NewWriter(b)
 		f.UseCRLF = tt.UseCRLF
+		if tt.Quote != 0 {
+			f.Quote = tt.Quote
+		}
+		f.QuoteAll = tt.QuoteAll
 		err := f.WriteAll(tt.Input)
 		if err != nil {
 			t.Errorf("Unexpected error: %s\n", err)
@@ -83,3 +94,14 @@ func TestError(t *testing.T) {
 		t.Error("Error should not be nil")
 	}
 }
+
+func TestWriteInvalidQuote(t *testing.T) {
+	for _, q := range []rune{',', '\r', '\n', -1} {
+		b := &bytes.Buffer{}
+		f := NewWriter(b)
+		f.Quote = q
+		if err := f.Write([]string{"a"}); err != ErrQuoteChar {
+			t.Errorf("Quote %q: Write error = %v, want %v", q, err, ErrQuoteChar)
+		}
+	}
+}
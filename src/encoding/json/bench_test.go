@@ -221,3 +221,50 @@ func BenchmarkIssue10335(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkCodeUnmarshalInterface(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	for i := 0; i < b.N; i++ {
+		var r interface{}
+		if err := Unmarshal(codeJSON, &r); err != nil {
+			b.Fatal("Unmarshal:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func BenchmarkCodeUnmarshalParallel(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var r codeResponse
+			if err := Unmarshal(codeJSON, &r); err != nil {
+				b.Fatal("Unmarshal:", err)
+			}
+		}
+	})
+	b.SetBytes(int64(len(codeJSON)))
+}
+
+func BenchmarkCodeValid(b *testing.B) {
+	if codeJSON == nil {
+		b.StopTimer()
+		codeInit()
+		b.StartTimer()
+	}
+	var scan scanner
+	for i := 0; i < b.N; i++ {
+		if err := checkValid(codeJSON, &scan); err != nil {
+			b.Fatal("checkValid:", err)
+		}
+	}
+	b.SetBytes(int64(len(codeJSON)))
+}
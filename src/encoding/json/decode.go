@@ -8,7 +8,6 @@
 package json
 
 import (
-	"bytes"
 	"encoding"
 	"encoding/base64"
 	"errors"
@@ -16,6 +15,7 @@ import (
 	"reflect"
 	"runtime"
 	"strconv"
+	"sync"
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
@@ -77,21 +77,17 @@ import (
 // ``not present,'' unmarshaling a JSON null into any other Go type has no effect
 // on the value and produces no error.
 //
+// If data is not valid JSON, Unmarshal returns a SyntaxError.
+// The input is decoded in a single pass, so values that precede
+// the syntax error may already have been stored in v.
+//
 // When unmarshaling quoted strings, invalid UTF-8 or
 // invalid UTF-16 surrogate pairs are not treated as an error.
 // Instead, they are replaced by the Unicode replacement
 // character U+FFFD.
 //
 func Unmarshal(data []byte, v interface{}) error {
-	// Check for well-formedness.
-	// Avoids filling out half a data structure
-	// before discovering a JSON syntax error.
 	var d decodeState
-	err := checkValid(data, &d.scan)
-	if err != nil {
-		return err
-	}
-
 	d.init(data)
 	return d.unmarshal(v)
 }
@@ -154,6 +150,14 @@ func (d *decodeState) unmarshal(v interface{}) (err error) {
 				panic(r)
 			}
 			err = r.(error)
+			// Decoding stopped at the first serious error, which
+			// may lie before a syntax error later in the input.
+			// Invalid JSON is always reported as such.
+			if _, ok := err.(*SyntaxError); !ok {
+				if serr := checkValid(d.data, &d.scan); serr != nil {
+					err = serr
+				}
+			}
 		}
 	}()
 
@@ -162,10 +166,15 @@ func (d *decodeState) unmarshal(v interface{}) (err error) {
 		return &InvalidUnmarshalError{reflect.TypeOf(v)}
 	}
 
-	d.scan.reset()
 	// We decode rv not rv.Elem because the Unmarshaler interface
 	// test must be applied at the top level of the value.
 	d.value(rv)
+
+	// Only white space may follow the top-level value.
+	d.peek()
+	if d.off < len(d.data) {
+		d.syntaxError()
+	}
 	return d.savedError
 }
 
@@ -248,11 +257,14 @@ func isValidNumber(s string) bool {
 // decodeState represents the state while decoding a JSON value.
 type decodeState struct {
 	data       []byte
-	off        int // read offset in data
-	scan       scanner
-	nextscan   scanner // for calls to nextValue
+	off        int     // read offset in data
+	scan       scanner // for reporting syntax errors
 	savedError error
 	useNumber  bool
+
+	disallowUnknownFields bool
+	disallowDuplicateKeys bool
+	caseSensitive         bool
 }
 
 // errPhase is used for errors that should not happen unless
@@ -280,93 +292,258 @@ func (d *decodeState) saveError(err error) {
 	}
 }
 
-// next cuts off and returns the next full JSON value in d.data[d.off:].
-// The next value is known to be an object or array, not a literal.
-func (d *decodeState) next() []byte {
-	c := d.data[d.off]
-	item, rest, err := nextValue(d.data[d.off:], &d.nextscan)
-	if err != nil {
+// syntaxError aborts the decoding because d.data is not valid JSON.
+// The decoder does not check the input in a separate pass before
+// decoding it, so the input is rescanned with the scanner state machine
+// to report the same SyntaxError that a full scan would have.
+func (d *decodeState) syntaxError() {
+	if err := checkValid(d.data, &d.scan); err != nil {
 		d.error(err)
 	}
-	d.off = len(d.data) - len(rest)
+	d.error(errPhase)
+}
 
-	// Our scanner has seen the opening brace/bracket
-	// and thinks we're still in the middle of the object.
-	// invent a closing brace/bracket to get it out.
-	if c == '{' {
-		d.scan.step(&d.scan, '}')
-	} else {
-		d.scan.step(&d.scan, ']')
+// peek skips white space and returns the next byte of input
+// without consuming it. It returns 0 at the end of the input.
+func (d *decodeState) peek() byte {
+	for d.off < len(d.data) {
+		c := d.data[d.off]
+		if !isSpace(c) {
+			return c
+		}
+		d.off++
 	}
+	return 0
+}
 
-	return item
+// next consumes the next full JSON value in d.data[d.off:] and returns it.
+func (d *decodeState) next() []byte {
+	d.peek()
+	start := d.off
+	d.skipValue()
+	return d.data[start:d.off]
 }
 
-// scanWhile processes bytes in d.data[d.off:] until it
-// receives a scan code not equal to op.
-// It updates d.off and returns the new scan code.
-func (d *decodeState) scanWhile(op int) int {
-	var newOp int
-	for {
-		if d.off >= len(d.data) {
-			newOp = d.scan.eof()
-			d.off = len(d.data) + 1 // mark processed EOF with len+1
-		} else {
-			c := d.data[d.off]
+// skipValue consumes the next JSON value in d.data[d.off:]
+// without decoding it.
+func (d *decodeState) skipValue() {
+	switch d.peek() {
+	case '{':
+		d.off++
+		if d.peek() == '}' {
 			d.off++
-			newOp = d.scan.step(&d.scan, c)
+			return
 		}
-		if newOp != op {
-			break
+		for {
+			d.objectKey()
+			d.skipValue()
+			if d.objectNext() {
+				return
+			}
+		}
+	case '[':
+		d.off++
+		if d.peek() == ']' {
+			d.off++
+			return
+		}
+		for {
+			d.skipValue()
+			if d.arrayNext() {
+				return
+			}
 		}
+	default:
+		d.literalBytes()
 	}
-	return newOp
 }
 
-// value decodes a JSON value from d.data[d.off:] into the value.
-// it updates d.off to point past the decoded value.
-func (d *decodeState) value(v reflect.Value) {
-	if !v.IsValid() {
-		_, rest, err := nextValue(d.data[d.off:], &d.nextscan)
-		if err != nil {
-			d.error(err)
-		}
-		d.off = len(d.data) - len(rest)
+// objectKey consumes an object key and the ':' that follows it.
+// It returns the key still in its quoted form.
+func (d *decodeState) objectKey() []byte {
+	if d.peek() != '"' {
+		d.syntaxError()
+	}
+	start := d.off
+	d.scanString()
+	item := d.data[start:d.off]
+	if d.peek() != ':' {
+		d.syntaxError()
+	}
+	d.off++
+	return item
+}
+
+// objectNext consumes the ',' or '}' that follows an object value
+// and reports whether it was the closing '}'.
+func (d *decodeState) objectNext() bool {
+	switch d.peek() {
+	case ',':
+		d.off++
+		return false
+	case '}':
+		d.off++
+		return true
+	}
+	d.syntaxError()
+	panic("unreachable")
+}
+
+// arrayNext consumes the ',' or ']' that follows an array element
+// and reports whether it was the closing ']'.
+func (d *decodeState) arrayNext() bool {
+	switch d.peek() {
+	case ',':
+		d.off++
+		return false
+	case ']':
+		d.off++
+		return true
+	}
+	d.syntaxError()
+	panic("unreachable")
+}
 
-		// d.scan thinks we're still at the beginning of the item.
-		// Feed in an empty string - the shortest, simplest value -
-		// so that it knows we got to the end of the value.
-		if d.scan.redo {
-			// rewind.
-			d.scan.redo = false
-			d.scan.step = stateBeginValue
+// literalBytes consumes the literal at d.data[d.off:] and returns it.
+func (d *decodeState) literalBytes() []byte {
+	start := d.off
+	if start >= len(d.data) {
+		d.syntaxError()
+	}
+	switch c := d.data[start]; {
+	case c == '"':
+		d.scanString()
+	case c == 't':
+		d.scanWord("true")
+	case c == 'f':
+		d.scanWord("false")
+	case c == 'n':
+		d.scanWord("null")
+	case c == '-' || '0' <= c && c <= '9':
+		d.scanNumber()
+	default:
+		d.syntaxError()
+	}
+	return d.data[start:d.off]
+}
+
+// scanWord consumes the keyword w, which must be next in the input.
+func (d *decodeState) scanWord(w string) {
+	end := d.off + len(w)
+	if end > len(d.data) || string(d.data[d.off:end]) != w {
+		d.syntaxError()
+	}
+	d.off = end
+}
+
+// scanString consumes the quoted string beginning at d.data[d.off].
+// Invalid UTF-8 is accepted here and replaced later by unquote.
+func (d *decodeState) scanString() {
+	data := d.data
+	i := d.off + 1
+	for i < len(data) {
+		switch c := data[i]; {
+		case c == '"':
+			d.off = i + 1
+			return
+		case c == '\\':
+			if i+1 >= len(data) {
+				d.syntaxError()
+			}
+			switch data[i+1] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				i += 2
+			case 'u':
+				if i+6 > len(data) {
+					d.syntaxError()
+				}
+				for _, h := range data[i+2 : i+6] {
+					if !('0' <= h && h <= '9' || 'a' <= h && h <= 'f' || 'A' <= h && h <= 'F') {
+						d.syntaxError()
+					}
+				}
+				i += 6
+			default:
+				d.syntaxError()
+			}
+		case c < 0x20:
+			d.syntaxError()
+		default:
+			i++
 		}
-		d.scan.step(&d.scan, '"')
-		d.scan.step(&d.scan, '"')
+	}
+	d.syntaxError()
+}
 
-		n := len(d.scan.parseState)
-		if n > 0 && d.scan.parseState[n-1] == parseObjectKey {
-			// d.scan thinks we just read an object key; finish the object
-			d.scan.step(&d.scan, ':')
-			d.scan.step(&d.scan, '"')
-			d.scan.step(&d.scan, '"')
-			d.scan.step(&d.scan, '}')
+// scanNumber consumes the number beginning at d.data[d.off].
+// It accepts the same grammar as isValidNumber.
+func (d *decodeState) scanNumber() {
+	data := d.data
+	i := d.off
+	if data[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(data) && data[i] == '0':
+		i++
+	case i < len(data) && '1' <= data[i] && data[i] <= '9':
+		i = skipDigits(data, i+1)
+	default:
+		d.syntaxError()
+	}
+	if i < len(data) && data[i] == '.' {
+		i++
+		if i >= len(data) || data[i] < '0' || data[i] > '9' {
+			d.syntaxError()
 		}
+		i = skipDigits(data, i)
+	}
+	if i < len(data) && (data[i] == 'e' || data[i] == 'E') {
+		i++
+		if i < len(data) && (data[i] == '+' || data[i] == '-') {
+			i++
+		}
+		if i >= len(data) || data[i] < '0' || data[i] > '9' {
+			d.syntaxError()
+		}
+		i = skipDigits(data, i)
+	}
+	d.off = i
+}
 
-		return
+// skipDigits returns the index of the first non-digit in data[i:].
+func skipDigits(data []byte, i int) int {
+	for i < len(data) && '0' <= data[i] && data[i] <= '9' {
+		i++
 	}
+	return i
+}
 
-	switch op := d.scanWhile(scanSkipSpace); op {
-	default:
-		d.error(errPhase)
+// value decodes a JSON value from d.data[d.off:] into the value.
+// it updates d.off to point past the decoded value.
+// If v is not valid, the JSON value is skipped.
+func (d *decodeState) value(v reflect.Value) {
+	if !v.IsValid() {
+		d.skipValue()
+		return
+	}
+	typeDecoder(v.Type())(d, v)
+}
 
-	case scanBeginArray:
+// indirectValue is the general decoder used for types that have no
+// specialized decoderFunc and for input that does not match the
+// decoder's type. It handles Unmarshaler and TextUnmarshaler
+// implementations, pointer and interface indirection, and reports
+// mismatches between the JSON value and v as errors.
+func (d *decodeState) indirectValue(v reflect.Value) {
+	switch d.peek() {
+	case '[':
+		d.off++
 		d.array(v)
-
-	case scanBeginObject:
+	case '{':
+		d.off++
 		d.object(v)
-
-	case scanBeginLiteral:
+	default:
 		d.literal(v)
 	}
 }
@@ -378,17 +555,10 @@ type unquotedValue struct{}
 // If it finds anything other than a quoted string literal or null,
 // valueQuoted returns unquotedValue{}.
 func (d *decodeState) valueQuoted() interface{} {
-	switch op := d.scanWhile(scanSkipSpace); op {
+	switch d.peek() {
+	case '[', '{':
+		d.skipValue()
 	default:
-		d.error(errPhase)
-
-	case scanBeginArray:
-		d.array(reflect.Value{})
-
-	case scanBeginObject:
-		d.object(reflect.Value{})
-
-	case scanBeginLiteral:
 		switch v := d.literalInterface().(type) {
 		case nil, string:
 			return v
@@ -484,68 +654,10 @@ func (d *decodeState) array(v reflect.Value) {
 		break
 	}
 
-	i := 0
-	for {
-		// Look ahead for ] - can only happen on first iteration.
-		op := d.scanWhile(scanSkipSpace)
-		if op == scanEndArray {
-			break
-		}
-
-		// Back up so d.value can have the byte we just read.
-		d.off--
-		d.scan.undo(op)
-
-		// Get element of array, growing if necessary.
-		if v.Kind() == reflect.Slice {
-			// Grow slice if necessary
-			if i >= v.Cap() {
-				newcap := v.Cap() + v.Cap()/2
-				if newcap < 4 {
-					newcap = 4
-				}
-				newv := reflect.MakeSlice(v.Type(), v.Len(), newcap)
-				reflect.Copy(newv, v)
-				v.Set(newv)
-			}
-			if i >= v.Len() {
-				v.SetLen(i + 1)
-			}
-		}
-
-		if i < v.Len() {
-			// Decode into element.
-			d.value(v.Index(i))
-		} else {
-			// Ran out of fixed array: skip.
-			d.value(reflect.Value{})
-		}
-		i++
-
-		// Next token must be , or ].
-		op = d.scanWhile(scanSkipSpace)
-		if op == scanEndArray {
-			break
-		}
-		if op != scanArrayValue {
-			d.error(errPhase)
-		}
-	}
-
-	if i < v.Len() {
-		if v.Kind() == reflect.Array {
-			// Array.  Zero the rest.
-			z := reflect.Zero(v.Type().Elem())
-			for ; i < v.Len(); i++ {
-				v.Index(i).Set(z)
-			}
-		} else {
-			v.SetLen(i)
-		}
-	}
-	if i == 0 && v.Kind() == reflect.Slice {
-		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
-	}
+	// Having resolved any indirection, v is now handled
+	// by its type's arrayDecoder.
+	d.off--
+	typeDecoder(v.Type())(d, v)
 }
 
 var nullLiteral = []byte("null")
@@ -588,9 +700,6 @@ func (d *decodeState) object(v reflect.Value) {
 			d.next() // skip over { } in input
 			return
 		}
-		if v.IsNil() {
-			v.Set(reflect.MakeMap(t))
-		}
 	case reflect.Struct:
 
 	default:
@@ -600,121 +709,15 @@ func (d *decodeState) object(v reflect.Value) {
 		return
 	}
 
-	var mapElem reflect.Value
-
-	for {
-		// Read opening " of string key or closing }.
-		op := d.scanWhile(scanSkipSpace)
-		if op == scanEndObject {
-			// closing } - can only happen on first iteration.
-			break
-		}
-		if op != scanBeginLiteral {
-			d.error(errPhase)
-		}
-
-		// Read key.
-		start := d.off - 1
-		op = d.scanWhile(scanContinue)
-		item := d.data[start : d.off-1]
-		key, ok := unquoteBytes(item)
-		if !ok {
-			d.error(errPhase)
-		}
-
-		// Figure out field corresponding to key.
-		var subv reflect.Value
-		destring := false // whether the value is wrapped in a string to be decoded first
-
-		if v.Kind() == reflect.Map {
-			elemType := v.Type().Elem()
-			if !mapElem.IsValid() {
-				mapElem = reflect.New(elemType).Elem()
-			} else {
-				mapElem.Set(reflect.Zero(elemType))
-			}
-			subv = mapElem
-		} else {
-			var f *field
-			fields := cachedTypeFields(v.Type())
-			for i := range fields {
-				ff := &fields[i]
-				if bytes.Equal(ff.nameBytes, key) {
-					f = ff
-					break
-				}
-				if f == nil && ff.equalFold(ff.nameBytes, key) {
-					f = ff
-				}
-			}
-			if f != nil {
-				subv = v
-				destring = f.quoted
-				for _, i := range f.index {
-					if subv.Kind() == reflect.Ptr {
-						if subv.IsNil() {
-							subv.Set(reflect.New(subv.Type().Elem()))
-						}
-						subv = subv.Elem()
-					}
-					subv = subv.Field(i)
-				}
-			}
-		}
-
-		// Read : before value.
-		if op == scanSkipSpace {
-			op = d.scanWhile(scanSkipSpace)
-		}
-		if op != scanObjectKey {
-			d.error(errPhase)
-		}
-
-		// Read value.
-		if destring {
-			switch qv := d.valueQuoted().(type) {
-			case nil:
-				d.literalStore(nullLiteral, subv, false)
-			case string:
-				d.literalStore([]byte(qv), subv, true)
-			default:
-				d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", subv.Type()))
-			}
-		} else {
-			d.value(subv)
-		}
-
-		// Write value back to map;
-		// if using struct, subv points into struct already.
-		if v.Kind() == reflect.Map {
-			kv := reflect.ValueOf(key).Convert(v.Type().Key())
-			v.SetMapIndex(kv, subv)
-		}
-
-		// Next token must be , or }.
-		op = d.scanWhile(scanSkipSpace)
-		if op == scanEndObject {
-			break
-		}
-		if op != scanObjectValue {
-			d.error(errPhase)
-		}
-	}
+	// Having resolved any indirection, v is now handled
+	// by its type's mapDecoder or structDecoder.
+	d.off--
+	typeDecoder(v.Type())(d, v)
 }
 
-// literal consumes a literal from d.data[d.off-1:], decoding into the value v.
-// The first byte of the literal has been read already
-// (that's how the caller knows it's a literal).
+// literal consumes a literal from d.data[d.off:], decoding into the value v.
 func (d *decodeState) literal(v reflect.Value) {
-	// All bytes inside literal return scanContinue op code.
-	start := d.off - 1
-	op := d.scanWhile(scanContinue)
-
-	// Scan read one byte too far; back up.
-	d.off--
-	d.scan.undo(op)
-
-	d.literalStore(d.data[start:d.off], v, false)
+	d.literalStore(d.literalBytes(), v, false)
 }
 
 // convertNumber converts the number literal s to a float64 or a Number
@@ -860,7 +863,7 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 			if fromQuoted {
 				d.error(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", item, v.Type()))
 			} else {
-				d.error(&UnmarshalTypeError{"number", v.Type(), int64(d.off)})
+				d.saveError(&UnmarshalTypeError{"number", v.Type(), int64(d.off)})
 			}
 		case reflect.Interface:
 			n, err := d.convertNumber(s)
@@ -901,21 +904,487 @@ func (d *decodeState) literalStore(item []byte, v reflect.Value, fromQuoted bool
 	}
 }
 
+// A decoderFunc decodes the JSON value at d.data[d.off:] into v,
+// skipping any white space that precedes it. Each type gets its own
+// decoderFunc, built once and cached by typeDecoder, so decoding does
+// not have to rediscover the shape of v for every value in the input.
+// Input that the specialized decoders do not handle, such as a string
+// for an int or null for a slice, is passed to indirectValue.
+type decoderFunc func(d *decodeState, v reflect.Value)
+
+var decoderCache struct {
+	sync.RWMutex
+	m map[reflect.Type]decoderFunc
+}
+
+func typeDecoder(t reflect.Type) decoderFunc {
+	decoderCache.RLock()
+	f := decoderCache.m[t]
+	decoderCache.RUnlock()
+	if f != nil {
+		return f
+	}
+
+	// To deal with recursive types, populate the map with an
+	// indirect func before we build it. This type waits on the
+	// real func (f) to be ready and then calls it.  This indirect
+	// func is only used for recursive types.
+	decoderCache.Lock()
+	if decoderCache.m == nil {
+		decoderCache.m = make(map[reflect.Type]decoderFunc)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	decoderCache.m[t] = func(d *decodeState, v reflect.Value) {
+		wg.Wait()
+		f(d, v)
+	}
+	decoderCache.Unlock()
+
+	// Compute fields without lock.
+	// Might duplicate effort but won't hold other computations back.
+	f = newTypeDecoder(t)
+	wg.Done()
+	decoderCache.Lock()
+	decoderCache.m[t] = f
+	decoderCache.Unlock()
+	return f
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf(new(Unmarshaler)).Elem()
+	textUnmarshalerType = reflect.TypeOf(new(encoding.TextUnmarshaler)).Elem()
+)
+
+// newTypeDecoder constructs a decoderFunc for a type.
+func newTypeDecoder(t reflect.Type) decoderFunc {
+	// Types with unmarshal methods, on the value or on its address,
+	// are left to indirect.
+	if t.Implements(unmarshalerType) || t.Implements(textUnmarshalerType) {
+		return (*decodeState).indirectValue
+	}
+	if t.Kind() != reflect.Ptr {
+		if pt := reflect.PtrTo(t); pt.Implements(unmarshalerType) || pt.Implements(textUnmarshalerType) {
+			return (*decodeState).indirectValue
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolDecoder
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intDecoder
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintDecoder
+	case reflect.Float32, reflect.Float64:
+		return floatDecoder
+	case reflect.String:
+		if t != numberType {
+			return stringDecoder
+		}
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return interfaceDecoder
+		}
+	case reflect.Struct:
+		return newStructDecoder(t)
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return newMapDecoder(t)
+		}
+	case reflect.Slice, reflect.Array:
+		return newArrayDecoder(t)
+	case reflect.Ptr:
+		return newPtrDecoder(t)
+	}
+	return (*decodeState).indirectValue
+}
+
+func boolDecoder(d *decodeState, v reflect.Value) {
+	switch d.peek() {
+	case 't':
+		d.scanWord("true")
+		v.SetBool(true)
+	case 'f':
+		d.scanWord("false")
+		v.SetBool(false)
+	default:
+		d.indirectValue(v)
+	}
+}
+
+// isNumberStart reports whether c can begin a JSON number.
+func isNumberStart(c byte) bool {
+	return c == '-' || '0' <= c && c <= '9'
+}
+
+// parseInt parses item, a JSON number literal, as a decimal integer.
+// It reports false if item is not an integer or may not fit in an int64,
+// leaving it to literalStore to decode or reject it.
+func parseInt(item []byte) (int64, bool) {
+	neg := item[0] == '-'
+	if neg {
+		item = item[1:]
+	}
+	if len(item) > 18 {
+		return 0, false
+	}
+	var n int64
+	for _, c := range item {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int64(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+func intDecoder(d *decodeState, v reflect.Value) {
+	if !isNumberStart(d.peek()) {
+		d.indirectValue(v)
+		return
+	}
+	item := d.literalBytes()
+	n, ok := parseInt(item)
+	if !ok || v.OverflowInt(n) {
+		d.literalStore(item, v, false)
+		return
+	}
+	v.SetInt(n)
+}
+
+func uintDecoder(d *decodeState, v reflect.Value) {
+	if !isNumberStart(d.peek()) {
+		d.indirectValue(v)
+		return
+	}
+	item := d.literalBytes()
+	n, ok := parseInt(item)
+	if !ok || item[0] == '-' || v.OverflowUint(uint64(n)) {
+		d.literalStore(item, v, false)
+		return
+	}
+	v.SetUint(uint64(n))
+}
+
+func floatDecoder(d *decodeState, v reflect.Value) {
+	if !isNumberStart(d.peek()) {
+		d.indirectValue(v)
+		return
+	}
+	item := d.literalBytes()
+	n, err := strconv.ParseFloat(string(item), v.Type().Bits())
+	if err != nil || v.OverflowFloat(n) {
+		d.literalStore(item, v, false)
+		return
+	}
+	v.SetFloat(n)
+}
+
+func stringDecoder(d *decodeState, v reflect.Value) {
+	if d.peek() != '"' {
+		d.indirectValue(v)
+		return
+	}
+	s, ok := unquoteBytes(d.literalBytes())
+	if !ok {
+		d.error(errPhase)
+	}
+	v.SetString(string(s))
+}
+
+func interfaceDecoder(d *decodeState, v reflect.Value) {
+	// A non-nil pointer stored in the interface is decoded into
+	// in place; indirect knows how.
+	if !v.IsNil() {
+		if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() {
+			d.indirectValue(v)
+			return
+		}
+	}
+	switch d.peek() {
+	case '[':
+		d.off++
+		v.Set(reflect.ValueOf(d.arrayInterface()))
+	case '{':
+		d.off++
+		v.Set(reflect.ValueOf(d.objectInterface()))
+	default:
+		d.literal(v)
+	}
+}
+
+type structDecoder struct {
+	fields    []field
+	fieldDecs []decoderFunc
+	byName    map[string]int // index in fields of the field with each exact name
+}
+
+func (sd *structDecoder) decode(d *decodeState, v reflect.Value) {
+	if d.peek() != '{' {
+		d.indirectValue(v)
+		return
+	}
+	d.off++
+	if d.peek() == '}' {
+		d.off++
+		return
+	}
+
+	var seen map[string]bool
+	if d.disallowDuplicateKeys {
+		seen = make(map[string]bool)
+	}
+	for {
+		key, ok := unquoteBytes(d.objectKey())
+		if !ok {
+			d.error(errPhase)
+		}
+		if seen != nil {
+			if seen[string(key)] {
+				d.saveError(fmt.Errorf("json: duplicate key %q in object", key))
+			}
+			seen[string(key)] = true
+		}
+
+		i := sd.lookup(key, d.caseSensitive)
+		if i < 0 {
+			if d.disallowUnknownFields {
+				d.saveError(fmt.Errorf("json: unknown field %q", key))
+			}
+			d.skipValue()
+		} else {
+			f := &sd.fields[i]
+			subv := v
+			for _, j := range f.index {
+				if subv.Kind() == reflect.Ptr {
+					if subv.IsNil() {
+						subv.Set(reflect.New(subv.Type().Elem()))
+					}
+					subv = subv.Elem()
+				}
+				subv = subv.Field(j)
+			}
+			if f.quoted {
+				// The value is wrapped in a string to be decoded first.
+				switch qv := d.valueQuoted().(type) {
+				case nil:
+					d.literalStore(nullLiteral, subv, false)
+				case string:
+					d.literalStore([]byte(qv), subv, true)
+				default:
+					d.saveError(fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal unquoted value into %v", subv.Type()))
+				}
+			} else {
+				sd.fieldDecs[i](d, subv)
+			}
+		}
+
+		if d.objectNext() {
+			break
+		}
+	}
+}
+
+// lookup returns the index of the field matching key, or -1.
+// An exact match is preferred; unless caseSensitive is set,
+// the first case-insensitive match is accepted otherwise.
+func (sd *structDecoder) lookup(key []byte, caseSensitive bool) int {
+	if i, ok := sd.byName[string(key)]; ok {
+		return i
+	}
+	if !caseSensitive {
+		for i := range sd.fields {
+			ff := &sd.fields[i]
+			if ff.equalFold(ff.nameBytes, key) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func newStructDecoder(t reflect.Type) decoderFunc {
+	fields := cachedTypeFields(t)
+	sd := &structDecoder{
+		fields:    fields,
+		fieldDecs: make([]decoderFunc, len(fields)),
+		byName:    make(map[string]int, len(fields)),
+	}
+	for i, f := range fields {
+		sd.fieldDecs[i] = typeDecoder(typeByIndex(t, f.index))
+		if _, ok := sd.byName[f.name]; !ok {
+			sd.byName[f.name] = i
+		}
+	}
+	return sd.decode
+}
+
+type mapDecoder struct {
+	elemDec decoderFunc
+}
+
+func (md *mapDecoder) decode(d *decodeState, v reflect.Value) {
+	if d.peek() != '{' {
+		d.indirectValue(v)
+		return
+	}
+	d.off++
+	t := v.Type()
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	if d.peek() == '}' {
+		d.off++
+		return
+	}
+
+	var seen map[string]bool
+	if d.disallowDuplicateKeys {
+		seen = make(map[string]bool)
+	}
+	elemType := t.Elem()
+	var mapElem reflect.Value
+	for {
+		key, ok := unquoteBytes(d.objectKey())
+		if !ok {
+			d.error(errPhase)
+		}
+		if seen != nil {
+			if seen[string(key)] {
+				d.saveError(fmt.Errorf("json: duplicate key %q in object", key))
+			}
+			seen[string(key)] = true
+		}
+
+		if !mapElem.IsValid() {
+			mapElem = reflect.New(elemType).Elem()
+		} else {
+			mapElem.Set(reflect.Zero(elemType))
+		}
+		md.elemDec(d, mapElem)
+		kv := reflect.ValueOf(key).Convert(t.Key())
+		v.SetMapIndex(kv, mapElem)
+
+		if d.objectNext() {
+			break
+		}
+	}
+}
+
+func newMapDecoder(t reflect.Type) decoderFunc {
+	md := &mapDecoder{typeDecoder(t.Elem())}
+	return md.decode
+}
+
+// arrayDecoder decodes JSON arrays into both slices and arrays.
+type arrayDecoder struct {
+	elemDec decoderFunc
+}
+
+func (ad *arrayDecoder) decode(d *decodeState, v reflect.Value) {
+	if d.peek() != '[' {
+		d.indirectValue(v)
+		return
+	}
+	d.off++
+
+	i := 0
+	if d.peek() == ']' {
+		d.off++
+	} else {
+		for {
+			// Get element of array, growing if necessary.
+			if v.Kind() == reflect.Slice {
+				// Grow slice if necessary
+				if i >= v.Cap() {
+					newcap := v.Cap() + v.Cap()/2
+					if newcap < 4 {
+						newcap = 4
+					}
+					newv := reflect.MakeSlice(v.Type(), v.Len(), newcap)
+					reflect.Copy(newv, v)
+					v.Set(newv)
+				}
+				if i >= v.Len() {
+					v.SetLen(i + 1)
+				}
+			}
+
+			if i < v.Len() {
+				// Decode into element.
+				ad.elemDec(d, v.Index(i))
+			} else {
+				// Ran out of fixed array: skip.
+				d.skipValue()
+			}
+			i++
+
+			if d.arrayNext() {
+				break
+			}
+		}
+	}
+
+	if i < v.Len() {
+		if v.Kind() == reflect.Array {
+			// Array.  Zero the rest.
+			z := reflect.Zero(v.Type().Elem())
+			for ; i < v.Len(); i++ {
+				v.Index(i).Set(z)
+			}
+		} else {
+			v.SetLen(i)
+		}
+	}
+	if i == 0 && v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+}
+
+func newArrayDecoder(t reflect.Type) decoderFunc {
+	ad := &arrayDecoder{typeDecoder(t.Elem())}
+	return ad.decode
+}
+
+type ptrDecoder struct {
+	elemDec decoderFunc
+}
+
+func (pd *ptrDecoder) decode(d *decodeState, v reflect.Value) {
+	// null may set the pointer to nil, or may not: leave it to indirect.
+	if d.peek() == 'n' {
+		d.literal(v)
+		return
+	}
+	if v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	pd.elemDec(d, v.Elem())
+}
+
+func newPtrDecoder(t reflect.Type) decoderFunc {
+	pd := &ptrDecoder{typeDecoder(t.Elem())}
+	return pd.decode
+}
+
 // The xxxInterface routines build up a value to be stored
 // in an empty interface.  They are not strictly necessary,
 // but they avoid the weight of reflection in this common case.
 
 // valueInterface is like value but returns interface{}
 func (d *decodeState) valueInterface() interface{} {
-	switch d.scanWhile(scanSkipSpace) {
-	default:
-		d.error(errPhase)
-		panic("unreachable")
-	case scanBeginArray:
+	switch d.peek() {
+	case '[':
+		d.off++
 		return d.arrayInterface()
-	case scanBeginObject:
+	case '{':
+		d.off++
 		return d.objectInterface()
-	case scanBeginLiteral:
+	default:
 		return d.literalInterface()
 	}
 }
@@ -923,27 +1392,15 @@ func (d *decodeState) valueInterface() interface{} {
 // arrayInterface is like array but returns []interface{}.
 func (d *decodeState) arrayInterface() []interface{} {
 	var v = make([]interface{}, 0)
+	if d.peek() == ']' {
+		d.off++
+		return v
+	}
 	for {
-		// Look ahead for ] - can only happen on first iteration.
-		op := d.scanWhile(scanSkipSpace)
-		if op == scanEndArray {
-			break
-		}
-
-		// Back up so d.value can have the byte we just read.
-		d.off--
-		d.scan.undo(op)
-
 		v = append(v, d.valueInterface())
-
-		// Next token must be , or ].
-		op = d.scanWhile(scanSkipSpace)
-		if op == scanEndArray {
+		if d.arrayNext() {
 			break
 		}
-		if op != scanArrayValue {
-			d.error(errPhase)
-		}
 	}
 	return v
 }
@@ -951,59 +1408,33 @@ func (d *decodeState) arrayInterface() []interface{} {
 // objectInterface is like object but returns map[string]interface{}.
 func (d *decodeState) objectInterface() map[string]interface{} {
 	m := make(map[string]interface{})
+	if d.peek() == '}' {
+		d.off++
+		return m
+	}
 	for {
-		// Read opening " of string key or closing }.
-		op := d.scanWhile(scanSkipSpace)
-		if op == scanEndObject {
-			// closing } - can only happen on first iteration.
-			break
-		}
-		if op != scanBeginLiteral {
-			d.error(errPhase)
-		}
-
-		// Read string key.
-		start := d.off - 1
-		op = d.scanWhile(scanContinue)
-		item := d.data[start : d.off-1]
-		key, ok := unquote(item)
+		key, ok := unquote(d.objectKey())
 		if !ok {
 			d.error(errPhase)
 		}
-
-		// Read : before value.
-		if op == scanSkipSpace {
-			op = d.scanWhile(scanSkipSpace)
-		}
-		if op != scanObjectKey {
-			d.error(errPhase)
+		if d.disallowDuplicateKeys {
+			if _, dup := m[key]; dup {
+				d.saveError(fmt.Errorf("json: duplicate key %q in object", key))
+			}
 		}
 
-		// Read value.
 		m[key] = d.valueInterface()
 
-		// Next token must be , or }.
-		op = d.scanWhile(scanSkipSpace)
-		if op == scanEndObject {
+		if d.objectNext() {
 			break
 		}
-		if op != scanObjectValue {
-			d.error(errPhase)
-		}
 	}
 	return m
 }
 
 // literalInterface is like literal but returns an interface value.
 func (d *decodeState) literalInterface() interface{} {
-	// All bytes inside literal return scanContinue op code.
-	start := d.off - 1
-	op := d.scanWhile(scanContinue)
-
-	// Scan read one byte too far; back up.
-	d.off--
-	d.scan.undo(op)
-	item := d.data[start:d.off]
+	item := d.literalBytes()
 
 	switch c := item[0]; c {
 	case 'n': // null
@@ -1020,9 +1451,6 @@ func (d *decodeState) literalInterface() interface{} {
 		return s
 
 	default: // number
-		if c != '-' && (c < '0' || c > '9') {
-			d.error(errPhase)
-		}
 		n, err := d.convertNumber(string(item))
 		if err != nil {
 			d.saveError(err)
@@ -1317,6 +1317,54 @@ func TestUnmarshalSyntax(t *testing.T) {
 	}
 }
 
+var syntaxErrorTests = []string{
+	`{"a":1}x`,
+	`[1,]`,
+	`{"a":1,}`,
+	`{"a" 1}`,
+	`{1:2}`,
+	`[01]`,
+	`-`,
+	`1.`,
+	`1e+`,
+	`"\x"`,
+	`"\u12g4"`,
+	"\"a\x01\"",
+	`trUe`,
+	`[nul]`,
+	`{"a":[1,2}`,
+	` `,
+	``,
+}
+
+// The decoder does not run the scanner over its input before decoding it,
+// so check that it still rejects the same inputs with the same errors.
+func TestUnmarshalSyntaxMatchesScanner(t *testing.T) {
+	var inputs []string
+	inputs = append(inputs, syntaxErrorTests...)
+	inputs = append(inputs, unmarshalSyntaxTests...)
+	// Values of the wrong type for the targets below, followed by
+	// a syntax error.
+	inputs = append(inputs, `1a`, `{"A": "x"} x`, `[true, 1a]`)
+	for i := range allValueIndent {
+		// Every proper prefix of allValueIndent is invalid.
+		inputs = append(inputs, allValueIndent[:i])
+	}
+	for _, in := range inputs {
+		var scan scanner
+		want := checkValid([]byte(in), &scan)
+		if want == nil {
+			t.Errorf("checkValid(%q) = nil, want error", in)
+			continue
+		}
+		for _, v := range []interface{}{new(interface{}), new(All), new(map[string]interface{}), new([]int), new(struct{ A int }), new(string)} {
+			if err := Unmarshal([]byte(in), v); !reflect.DeepEqual(err, want) {
+				t.Errorf("Unmarshal(%q, %T) = %#v, want %#v", in, v, err, want)
+			}
+		}
+	}
+}
+
 // Test handling of unexported fields that should be ignored.
 // Issue 4660
 type unexportedFields struct {
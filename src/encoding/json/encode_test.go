@@ -381,10 +381,10 @@ func TestStringBytes(t *testing.T) {
 		r = append(r, i)
 	}
 	s := string(r) + "\xff\xff\xffhello" // some invalid UTF-8 too
-	es.string(s)
+	es.string(s, true)
 
 	esBytes := &encodeState{}
-	esBytes.stringBytes([]byte(s))
+	esBytes.stringBytes([]byte(s), true)
 
 	enc := es.Buffer.String()
 	encBytes := esBytes.Buffer.String()
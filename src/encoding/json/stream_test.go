@@ -57,6 +57,77 @@ func TestEncoder(t *testing.T) {
 	}
 }
 
+var streamEncodedIndent = `0.1
+"hello"
+null
+true
+false
+[
+>."a",
+>."b",
+>."c"
+>]
+{
+>."ß": "long s",
+>."K": "Kelvin"
+>}
+3.14
+`
+
+func TestEncoderIndent(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.SetIndent(">", ".")
+	for _, v := range streamTest {
+		enc.Encode(v)
+	}
+	if have, want := buf.String(), streamEncodedIndent; have != want {
+		t.Error("indented encoding mismatch")
+		diff(t, []byte(have), []byte(want))
+	}
+}
+
+func TestEncoderSetEscapeHTML(t *testing.T) {
+	var c C
+	var ct CText
+	for _, tt := range []struct {
+		name       string
+		v          interface{}
+		wantEscape string
+		want       string
+	}{
+		{"c", c, `"\u003c\u0026\u003e"`, `"<&>"`},
+		{"ct", ct, `"\"\u003c\u0026\u003e\""`, `"\"<&>\""`},
+		{`"<&>"`, "<&>", `"\u003c\u0026\u003e"`, `"<&>"`},
+		{
+			"tagStruct",
+			struct {
+				S string `json:"<>&#! "`
+			}{"a&b"},
+			`{"\u003c\u003e\u0026#! ":"a\u0026b"}`,
+			`{"<>&#! ":"a&b"}`,
+		},
+	} {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		if err := enc.Encode(tt.v); err != nil {
+			t.Fatalf("Encode(%s): %s", tt.name, err)
+		}
+		if got := strings.TrimSpace(buf.String()); got != tt.wantEscape {
+			t.Errorf("Encode(%s) = %#q, want %#q", tt.name, got, tt.wantEscape)
+		}
+		buf.Reset()
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(tt.v); err != nil {
+			t.Fatalf("SetEscapeHTML(false) Encode(%s): %s", tt.name, err)
+		}
+		if got := strings.TrimSpace(buf.String()); got != tt.want {
+			t.Errorf("SetEscapeHTML(false) Encode(%s) = %#q, want %#q",
+				tt.name, got, tt.want)
+		}
+	}
+}
+
 func TestDecoder(t *testing.T) {
 	for i := 0; i <= len(streamTest); i++ {
 		// Use stream without newlines as input,
@@ -111,6 +182,87 @@ func TestDecoderBuffered(t *testing.T) {
 	}
 }
 
+type strictT struct {
+	Name string
+	Age  int `json:"age"`
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	in := `{"Name":"x","age":1,"extra":true}`
+	var v strictT
+	if err := NewDecoder(strings.NewReader(in)).Decode(&v); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	dec := NewDecoder(strings.NewReader(in))
+	dec.DisallowUnknownFields()
+	v = strictT{}
+	err := dec.Decode(&v)
+	if err == nil || err.Error() != `json: unknown field "extra"` {
+		t.Fatalf("Decode error = %v, want unknown field error", err)
+	}
+	// Known fields are still stored.
+	if want := (strictT{"x", 1}); v != want {
+		t.Errorf("Decode = %+v, want %+v", v, want)
+	}
+}
+
+func TestDecoderDisallowDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		in string
+		v  interface{}
+	}{
+		{`{"Name":"a","Name":"b"}`, new(strictT)},
+		{`{"a":1,"b":2,"a":3}`, new(map[string]int)},
+		{`{"a":{"b":1,"b":2}}`, new(interface{})},
+	}
+	for _, tt := range tests {
+		if err := NewDecoder(strings.NewReader(tt.in)).Decode(tt.v); err != nil {
+			t.Errorf("Decode(%#q): %v", tt.in, err)
+			continue
+		}
+		dec := NewDecoder(strings.NewReader(tt.in))
+		dec.DisallowDuplicateKeys()
+		err := dec.Decode(tt.v)
+		if err == nil || !strings.Contains(err.Error(), "duplicate key") {
+			t.Errorf("DisallowDuplicateKeys Decode(%#q) error = %v, want duplicate key error", tt.in, err)
+		}
+	}
+
+	dec := NewDecoder(strings.NewReader(`{"a":{"x":1},"b":{"x":2}}`))
+	dec.DisallowDuplicateKeys()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Errorf("keys repeated in distinct objects: %v", err)
+	}
+}
+
+func TestDecoderCaseSensitive(t *testing.T) {
+	in := `{"name":"x","AGE":1}`
+	var v strictT
+	if err := NewDecoder(strings.NewReader(in)).Decode(&v); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if want := (strictT{"x", 1}); v != want {
+		t.Errorf("Decode = %+v, want %+v", v, want)
+	}
+
+	dec := NewDecoder(strings.NewReader(in + `{"Name":"y","age":2}`))
+	dec.CaseSensitive()
+	v = strictT{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("CaseSensitive Decode: %v", err)
+	}
+	if want := (strictT{}); v != want {
+		t.Errorf("CaseSensitive Decode = %+v, want %+v", v, want)
+	}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("CaseSensitive Decode: %v", err)
+	}
+	if want := (strictT{"y", 2}); v != want {
+		t.Errorf("CaseSensitive Decode = %+v, want %+v", v, want)
+	}
+}
+
 func nlines(s string, n int) string {
 	if n <= 0 {
 		return ""
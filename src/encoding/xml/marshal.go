@@ -145,6 +145,22 @@ func (enc *Encoder) Indent(prefix, indent string) {
 	enc.p.indent = indent
 }
 
+// NamespacePrefixes sets the prefixes the encoder uses for name spaces.
+// The map keys are name space URLs and the values are the prefixes to
+// bind them to. When an element or attribute in one of these name spaces
+// is written and the prefix is not already bound to it, the encoder
+// declares the prefix on that element instead of generating one.
+// Prefixes that are not valid XML names, contain a colon, or begin with
+// "xml" are ignored. The map is copied; later changes to it have no effect.
+func (enc *Encoder) NamespacePrefixes(prefixes map[string]string) {
+	enc.p.nsPrefix = make(map[string]string, len(prefixes))
+	for url, prefix := range prefixes {
+		if url != "" && isPrefixName(prefix) {
+			enc.p.nsPrefix[url] = prefix
+		}
+	}
+}
+
 // Encode writes the XML encoding of v to the stream.
 //
 // See the documentation for Marshal for details about the conversion
@@ -193,6 +209,11 @@ var (
 //
 // EncodeToken allows writing a ProcInst with Target set to "xml" only as the first token
 // in the stream.
+//
+// Name space declarations in a StartElement's attributes, such as those
+// returned by Decoder.Token, are written as given and remain in scope
+// until the matching EndElement, so that elements and attributes in
+// those name spaces reuse the declared prefixes.
 func (enc *Encoder) EncodeToken(t Token) error {
 
 	p := &enc.p
@@ -305,34 +326,83 @@ type printer struct {
 	putNewline bool
 	attrNS     map[string]string // map prefix -> name space
 	attrPrefix map[string]string // map name space -> prefix
-	prefixes   []string
+	nsPrefix   map[string]string // preferred prefixes, map name space -> prefix
+	defaultNS  string            // default name space in scope
+	prefixes   []nsBinding
+	scopes     []elemScope
 	tags       []Name
 }
 
-// createAttrPrefix finds the name space prefix attribute to use for the given name space,
-// defining a new prefix if necessary. It returns the prefix.
-func (p *printer) createAttrPrefix(url string) string {
-	if prefix := p.attrPrefix[url]; prefix != "" {
-		return prefix
-	}
+// An nsBinding records a prefix bound by a start element and the
+// bindings it shadowed, so that they can be restored at the end tag.
+// A zero nsBinding marks the start of an element's bindings.
+type nsBinding struct {
+	prefix, url         string
+	prevURL, prevPrefix string
+}
+
+// An elemScope records the state needed to close an element:
+// the prefix its name was written with and the enclosing
+// default name space.
+type elemScope struct {
+	prefix    string
+	defaultNS string
+}
+
+// isPrefixName reports whether s may be declared as a name space prefix.
+func isPrefixName(s string) bool {
+	return s != "" && isName([]byte(s)) && !strings.Contains(s, ":") && !strings.HasPrefix(strings.ToLower(s), "xml")
+}
 
-	// The "http://www.w3.org/XML/1998/namespace" name space is predefined as "xml"
-	// and must be referred to that way.
-	// (The "http://www.w3.org/2000/xmlns/" name space is also predefined as "xmlns",
-	// but users should not be trying to use that one directly - that's our job.)
+// lookupPrefix returns the prefix bound to the given name space in the
+// current scope, or the empty string if there is none.
+func (p *printer) lookupPrefix(url string) string {
 	if url == xmlURL {
 		return "xml"
 	}
+	if prefix := p.attrPrefix[url]; prefix != "" && p.attrNS[prefix] == url {
+		return prefix
+	}
+	return ""
+}
 
-	// Need to define a new name space.
+// bindPrefix binds prefix to the name space url until the end
+// of the current element.
+func (p *printer) bindPrefix(prefix, url string) {
 	if p.attrPrefix == nil {
 		p.attrPrefix = make(map[string]string)
 		p.attrNS = make(map[string]string)
 	}
+	p.prefixes = append(p.prefixes, nsBinding{
+		prefix:     prefix,
+		url:        url,
+		prevURL:    p.attrNS[prefix],
+		prevPrefix: p.attrPrefix[url],
+	})
+	p.attrNS[prefix] = url
+	p.attrPrefix[url] = prefix
+}
+
+// createPrefix finds the name space prefix to use for the given name space,
+// binding a new prefix if necessary. It returns the prefix and whether
+// the caller must write a declaration for it.
+func (p *printer) createPrefix(url string) (prefix string, declare bool) {
+	if prefix := p.lookupPrefix(url); prefix != "" {
+		return prefix, false
+	}
+
+	// Use the prefix the user asked for, unless it is already
+	// bound to something else in this scope. Rebinding it could
+	// change the meaning of names that use it, including the
+	// name of the element being written.
+	if prefix := p.nsPrefix[url]; prefix != "" && p.attrNS[prefix] == "" {
+		p.bindPrefix(prefix, url)
+		return prefix, true
+	}
 
 	// Pick a name. We try to use the final element of the path
 	// but fall back to _.
-	prefix := strings.TrimRight(url, "/")
+	prefix = strings.TrimRight(url, "/")
 	if i := strings.LastIndex(prefix, "/"); i >= 0 {
 		prefix = prefix[i+1:]
 	}
@@ -353,38 +423,59 @@ func (p *printer) createAttrPrefix(url string) string {
 		}
 	}
 
-	p.attrPrefix[url] = prefix
-	p.attrNS[prefix] = url
-
-	p.WriteString(`xmlns:`)
-	p.WriteString(prefix)
-	p.WriteString(`="`)
-	EscapeText(p, []byte(url))
-	p.WriteString(`" `)
-
-	p.prefixes = append(p.prefixes, prefix)
+	p.bindPrefix(prefix, url)
+	return prefix, true
+}
 
+// createAttrPrefix finds the name space prefix attribute to use for the given name space,
+// defining a new prefix if necessary. It returns the prefix.
+// The "http://www.w3.org/XML/1998/namespace" name space is predefined as "xml"
+// and must be referred to that way.
+// (The "http://www.w3.org/2000/xmlns/" name space is also predefined as "xmlns",
+// but users should not be trying to use that one directly - that's our job.)
+func (p *printer) createAttrPrefix(url string) string {
+	prefix, declare := p.createPrefix(url)
+	if declare {
+		p.writeNSDecl(prefix, url)
+		p.WriteByte(' ')
+	}
 	return prefix
 }
 
-// deleteAttrPrefix removes an attribute name space prefix.
-func (p *printer) deleteAttrPrefix(prefix string) {
-	delete(p.attrPrefix, p.attrNS[prefix])
-	delete(p.attrNS, prefix)
+// writeNSDecl writes the declaration of prefix as name space url.
+// An empty prefix declares the default name space.
+func (p *printer) writeNSDecl(prefix, url string) {
+	p.WriteString("xmlns")
+	if prefix != "" {
+		p.WriteByte(':')
+		p.WriteString(prefix)
+	}
+	p.WriteString(`="`)
+	p.EscapeString(url)
+	p.WriteByte('"')
 }
 
 func (p *printer) markPrefix() {
-	p.prefixes = append(p.prefixes, "")
+	p.prefixes = append(p.prefixes, nsBinding{})
 }
 
 func (p *printer) popPrefix() {
 	for len(p.prefixes) > 0 {
-		prefix := p.prefixes[len(p.prefixes)-1]
+		b := p.prefixes[len(p.prefixes)-1]
 		p.prefixes = p.prefixes[:len(p.prefixes)-1]
-		if prefix == "" {
+		if b.prefix == "" {
 			break
 		}
-		p.deleteAttrPrefix(prefix)
+		if b.prevURL == "" {
+			delete(p.attrNS, b.prefix)
+		} else {
+			p.attrNS[b.prefix] = b.prevURL
+		}
+		if b.prevPrefix == "" {
+			delete(p.attrPrefix, b.url)
+		} else {
+			p.attrPrefix[b.url] = b.prevPrefix
+		}
 	}
 }
 
@@ -653,6 +744,13 @@ func (p *printer) marshalTextInterface(val encoding.TextMarshaler, start StartEl
 }
 
 // writeStart writes the given start element.
+//
+// Name space declarations in start.Attr, as returned by Decoder.Token,
+// are written as given and are in scope for the element and its content.
+// The element name is written unprefixed if its name space is the default
+// one, with a prefix if one is bound to its name space, and otherwise
+// with a declaration of a new default name space. An element with no
+// name space inherits the default name space of its parent.
 func (p *printer) writeStart(start *StartElement) error {
 	if start.Name.Local == "" {
 		return fmt.Errorf("xml: start tag with no name")
@@ -660,15 +758,48 @@ func (p *printer) writeStart(start *StartElement) error {
 
 	p.tags = append(p.tags, start.Name)
 	p.markPrefix()
+	scope := elemScope{defaultNS: p.defaultNS}
+
+	// Bind the name space declarations first so that
+	// the element name and attributes can use them.
+	explicitDefault := false
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			explicitDefault = true
+			p.defaultNS = attr.Value
+		case attr.Name.Space == "xmlns" && isPrefixName(attr.Name.Local) && attr.Value != "":
+			p.bindPrefix(attr.Name.Local, attr.Value)
+		}
+	}
+
+	space := start.Name.Space
+	declareDefault, declarePrefix := false, false
+	switch {
+	case space == "" || space == p.defaultNS:
+	case !explicitDefault && p.lookupPrefix(space) == "" && p.nsPrefix[space] == "":
+		declareDefault = true
+		p.defaultNS = space
+	default:
+		scope.prefix, declarePrefix = p.createPrefix(space)
+	}
+	p.scopes = append(p.scopes, scope)
 
 	p.writeIndent(1)
 	p.WriteByte('<')
+	if scope.prefix != "" {
+		p.WriteString(scope.prefix)
+		p.WriteByte(':')
+	}
 	p.WriteString(start.Name.Local)
 
-	if start.Name.Space != "" {
-		p.WriteString(` xmlns="`)
-		p.EscapeString(start.Name.Space)
-		p.WriteByte('"')
+	if declareDefault {
+		p.WriteByte(' ')
+		p.writeNSDecl("", space)
+	}
+	if declarePrefix {
+		p.WriteByte(' ')
+		p.writeNSDecl(scope.prefix, space)
 	}
 
 	// Attributes
@@ -677,7 +808,15 @@ func (p *printer) writeStart(start *StartElement) error {
 		if name.Local == "" {
 			continue
 		}
+		if name.Space == "xmlns" && (!isPrefixName(name.Local) || attr.Value == "") {
+			// Not a declaration we can honor.
+			continue
+		}
 		p.WriteByte(' ')
+		if name.Space == "xmlns" {
+			p.writeNSDecl(name.Local, attr.Value)
+			continue
+		}
 		if name.Space != "" {
 			p.WriteString(p.createAttrPrefix(name.Space))
 			p.WriteByte(':')
@@ -705,13 +844,20 @@ func (p *printer) writeEnd(name Name) error {
 		return fmt.Errorf("xml: end tag </%s> in namespace %s does not match start tag <%s> in namespace %s", name.Local, name.Space, top.Local, top.Space)
 	}
 	p.tags = p.tags[:len(p.tags)-1]
+	scope := p.scopes[len(p.scopes)-1]
+	p.scopes = p.scopes[:len(p.scopes)-1]
 
 	p.writeIndent(-1)
 	p.WriteByte('<')
 	p.WriteByte('/')
+	if scope.prefix != "" {
+		p.WriteString(scope.prefix)
+		p.WriteByte(':')
+	}
 	p.WriteString(name.Local)
 	p.WriteByte('>')
 	p.popPrefix()
+	p.defaultNS = scope.defaultNS
 	return nil
 }
 
@@ -659,7 +659,7 @@ var marshalTests = []struct {
 			D1: "d1",
 		},
 		ExpectXML: `<top xmlns="space">` +
-			`<x><a>a</a><b>b</b><c xmlns="space">c</c>` +
+			`<x><a>a</a><b>b</b><c>c</c>` +
 			`<c xmlns="space1">c1</c>` +
 			`<d xmlns="space1">d1</d>` +
 			`</x>` +
@@ -1455,7 +1455,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "foo"}, "value"},
 		}},
 	},
-	want: `<local xmlns="space" xmlns:_xmlns="xmlns" _xmlns:x="space" xmlns:space="space" space:foo="value">`,
+	want: `<x:local xmlns:x="space" x:foo="value">`,
 }, {
 	desc: "start element with explicit namespace and colliding prefix",
 	toks: []Token{
@@ -1465,7 +1465,7 @@ var encodeTokenTests = []struct {
 			{Name{"x", "bar"}, "other"},
 		}},
 	},
-	want: `<local xmlns="space" xmlns:_xmlns="xmlns" _xmlns:x="space" xmlns:space="space" space:foo="value" xmlns:x="x" x:bar="other">`,
+	want: `<x:local xmlns:x="space" x:foo="value" xmlns:x_1="x" x_1:bar="other">`,
 }, {
 	desc: "start element using previously defined namespace",
 	toks: []Token{
@@ -1476,7 +1476,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "y"},
 		}},
 	},
-	want: `<local xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" xmlns:space="space" space:x="y">`,
+	want: `<local xmlns:x="space"><x:foo x:x="y">`,
 }, {
 	desc: "nested name space with same prefix",
 	toks: []Token{
@@ -1497,7 +1497,7 @@ var encodeTokenTests = []struct {
 			{Name{"space2", "b"}, "space2 value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space1"><foo _xmlns:x="space2"><foo xmlns:space1="space1" space1:a="space1 value" xmlns:space2="space2" space2:b="space2 value"></foo></foo><foo xmlns:space1="space1" space1:a="space1 value" xmlns:space2="space2" space2:b="space2 value">`,
+	want: `<foo xmlns:x="space1"><foo xmlns:x="space2"><foo xmlns:space1="space1" space1:a="space1 value" x:b="space2 value"></foo></foo><foo x:a="space1 value" xmlns:space2="space2" space2:b="space2 value">`,
 }, {
 	desc: "start element defining several prefixes for the same name space",
 	toks: []Token{
@@ -1507,7 +1507,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns:_xmlns="xmlns" _xmlns:a="space" _xmlns:b="space" xmlns:space="space" space:x="value">`,
+	want: `<b:foo xmlns:a="space" xmlns:b="space" b:x="value">`,
 }, {
 	desc: "nested element redefines name space",
 	toks: []Token{
@@ -1519,7 +1519,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" _xmlns:y="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns:x="space"><y:foo xmlns:y="space" y:a="value">`,
 }, {
 	desc: "nested element creates alias for default name space",
 	toks: []Token{
@@ -1531,7 +1531,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="space" xmlns:_xmlns="xmlns" _xmlns:y="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns="space"><foo xmlns:y="space" y:a="value">`,
 }, {
 	desc: "nested element defines default name space with existing prefix",
 	toks: []Token{
@@ -1543,7 +1543,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "a"}, "value"},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:x="space"><foo xmlns="space" xmlns="space" xmlns:space="space" space:a="value">`,
+	want: `<foo xmlns:x="space"><foo xmlns="space" x:a="value">`,
 }, {
 	desc: "nested element uses empty attribute name space when default ns defined",
 	toks: []Token{
@@ -1554,7 +1554,7 @@ var encodeTokenTests = []struct {
 			{Name{"", "attr"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="space" attr="value">`,
+	want: `<foo xmlns="space"><foo attr="value">`,
 }, {
 	desc: "redefine xmlns",
 	toks: []Token{
@@ -1586,7 +1586,7 @@ var encodeTokenTests = []struct {
 			{Name{"xmlns", "foo"}, ""},
 		}},
 	},
-	want: `<foo xmlns:_xmlns="xmlns" _xmlns:foo="">`,
+	want: `<foo>`,
 }, {
 	desc: "attribute with no name is ignored",
 	toks: []Token{
@@ -1615,7 +1615,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "x"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo xmlns="" x="value" xmlns:space="space" space:x="value">`,
+	want: `<foo xmlns="space"><foo xmlns="" x="value" xmlns:space="space" space:x="value">`,
 }, {
 	desc: "nested element requires empty default name space",
 	toks: []Token{
@@ -1624,7 +1624,7 @@ var encodeTokenTests = []struct {
 		}},
 		StartElement{Name{"", "foo"}, nil},
 	},
-	want: `<foo xmlns="space" xmlns="space"><foo>`,
+	want: `<foo xmlns="space"><foo>`,
 }, {
 	desc: "attribute uses name space from xmlns",
 	toks: []Token{
@@ -1646,7 +1646,7 @@ var encodeTokenTests = []struct {
 		EndElement{Name{"space", "baz"}},
 		EndElement{Name{"space", "foo"}},
 	},
-	want: `<foo xmlns="space" xmlns="space" xmlns:_xmlns="xmlns" _xmlns:bar="space" xmlns:space="space" space:baz="foo"><baz xmlns="space"></baz></foo>`,
+	want: `<foo xmlns="space" xmlns:bar="space" bar:baz="foo"><baz></baz></foo>`,
 }, {
 	desc: "default name space not used by attributes, not explicitly defined",
 	toks: []Token{
@@ -1658,7 +1658,7 @@ var encodeTokenTests = []struct {
 		EndElement{Name{"space", "baz"}},
 		EndElement{Name{"space", "foo"}},
 	},
-	want: `<foo xmlns="space" xmlns="space" xmlns:space="space" space:baz="foo"><baz xmlns="space"></baz></foo>`,
+	want: `<foo xmlns="space" xmlns:space="space" space:baz="foo"><baz></baz></foo>`,
 }, {
 	desc: "impossible xmlns declaration",
 	toks: []Token{
@@ -1669,7 +1669,7 @@ var encodeTokenTests = []struct {
 			{Name{"space", "attr"}, "value"},
 		}},
 	},
-	want: `<foo xmlns="space"><bar xmlns="space" xmlns:space="space" space:attr="value">`,
+	want: `<foo xmlns="space"><bar xmlns:space="space" space:attr="value">`,
 }}
 
 func TestEncodeToken(t *testing.T) {
@@ -1744,6 +1744,151 @@ func TestDecodeEncode(t *testing.T) {
 	}
 }
 
+var namespacePrefixTests = []struct {
+	desc string
+	toks []Token
+	want string
+}{{
+	desc: "element uses configured prefix",
+	toks: []Token{
+		StartElement{Name{"http://schemas.xmlsoap.org/soap/envelope/", "Envelope"}, nil},
+		StartElement{Name{"http://schemas.xmlsoap.org/soap/envelope/", "Body"}, nil},
+		EndElement{Name{"http://schemas.xmlsoap.org/soap/envelope/", "Body"}},
+		EndElement{Name{"http://schemas.xmlsoap.org/soap/envelope/", "Envelope"}},
+	},
+	want: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body></soap:Body></soap:Envelope>`,
+}, {
+	desc: "attribute uses configured prefix",
+	toks: []Token{
+		StartElement{Name{"", "stream"}, []Attr{
+			{Name{"jabber:client", "to"}, "example.com"},
+		}},
+		StartElement{Name{"", "iq"}, []Attr{
+			{Name{"jabber:client", "id"}, "1"},
+		}},
+	},
+	want: `<stream xmlns:c="jabber:client" c:to="example.com"><iq c:id="1">`,
+}, {
+	desc: "explicit declaration is reused",
+	toks: []Token{
+		StartElement{Name{"", "stream"}, []Attr{
+			{Name{"xmlns", "env"}, "http://schemas.xmlsoap.org/soap/envelope/"},
+		}},
+		StartElement{Name{"http://schemas.xmlsoap.org/soap/envelope/", "Body"}, nil},
+	},
+	want: `<stream xmlns:env="http://schemas.xmlsoap.org/soap/envelope/"><env:Body>`,
+}, {
+	desc: "configured prefix already bound by element",
+	toks: []Token{
+		StartElement{Name{"", "x"}, []Attr{
+			{Name{"xmlns", "soap"}, "other"},
+			{Name{"http://schemas.xmlsoap.org/soap/envelope/", "a"}, "b"},
+		}},
+	},
+	want: `<x xmlns:soap="other" xmlns:envelope="http://schemas.xmlsoap.org/soap/envelope/" envelope:a="b">`,
+}, {
+	desc: "configured prefix already bound by ancestor",
+	toks: []Token{
+		StartElement{Name{"", "x"}, []Attr{{Name{"xmlns", "soap"}, "other"}}},
+		StartElement{Name{"other", "y"}, []Attr{
+			{Name{"http://schemas.xmlsoap.org/soap/envelope/", "a"}, "b"},
+		}},
+	},
+	want: `<x xmlns:soap="other"><soap:y xmlns:envelope="http://schemas.xmlsoap.org/soap/envelope/" envelope:a="b">`,
+}, {
+	desc: "configured prefix is redeclared after it goes out of scope",
+	toks: []Token{
+		StartElement{Name{"", "a"}, nil},
+		StartElement{Name{"jabber:client", "b"}, nil},
+		EndElement{Name{"jabber:client", "b"}},
+		StartElement{Name{"jabber:client", "b"}, nil},
+		EndElement{Name{"jabber:client", "b"}},
+		EndElement{Name{"", "a"}},
+	},
+	want: `<a><c:b xmlns:c="jabber:client"></c:b><c:b xmlns:c="jabber:client"></c:b></a>`,
+}}
+
+func TestEncoderNamespacePrefixes(t *testing.T) {
+	prefixes := map[string]string{
+		"http://schemas.xmlsoap.org/soap/envelope/": "soap",
+		"jabber:client":                             "c",
+		"ignored":                                   "xmlfoo",
+	}
+	for i, tt := range namespacePrefixTests {
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.NamespacePrefixes(prefixes)
+		for j, tok := range tt.toks {
+			if err := enc.EncodeToken(tok); err != nil {
+				t.Fatalf("#%d %s token #%d: %v", i, tt.desc, j, err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatalf("#%d %s: %v", i, tt.desc, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("#%d %s:\ngot  %v\nwant %v", i, tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestEncoderNamespacePrefixesMarshal(t *testing.T) {
+	type Body struct {
+		XMLName Name   `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+		Value   string `xml:"urn:example value"`
+	}
+	type Envelope struct {
+		XMLName Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+		Body    Body
+	}
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.NamespacePrefixes(map[string]string{
+		"http://schemas.xmlsoap.org/soap/envelope/": "soap",
+	})
+	if err := enc.Encode(Envelope{Body: Body{Value: "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><value xmlns="urn:example">v</value></soap:Body></soap:Envelope>`
+	if got := buf.String(); got != want {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestDecodeEncodeNamespaces(t *testing.T) {
+	for _, in := range []string{
+		`<stream:stream xmlns="jabber:client" xmlns:stream="http://etherx.jabber.org/streams" to="example.com" version="1.0">` +
+			`<message xml:lang="en"><body>hi</body></message>` +
+			`<stream:features></stream:features></stream:stream>`,
+		`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+			`<soap:Body><m:Get xmlns:m="urn:example" xsi:nil="true"></m:Get></soap:Body></soap:Envelope>`,
+		`<a xmlns:p="one"><p:b xmlns:p="two" p:x="1"></p:b><p:c p:x="2"></p:c></a>`,
+		`<a xmlns="one"><b xmlns="two"><c></c></b><d></d></a>`,
+	} {
+		dec := NewDecoder(strings.NewReader(in))
+		var out bytes.Buffer
+		enc := NewEncoder(&out)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Token: %v", err)
+			}
+			if err := enc.EncodeToken(tok); err != nil {
+				t.Fatalf("EncodeToken(%#v): %v", tok, err)
+			}
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != in {
+			t.Errorf("round trip changed document:\ngot  %s\nwant %s", got, in)
+		}
+	}
+}
+
 // Issue 9796. Used to fail with GORACE="halt_on_error=1" -race.
 func TestRace9796(t *testing.T) {
 	type A struct{}
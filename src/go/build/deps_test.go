@@ -59,7 +59,7 @@ var pkgDeps = map[string][]string{
 	"math":          {"unsafe"},
 	"math/cmplx":    {"math"},
 	"math/rand":     {"L0", "math"},
-	"sort":          {},
+	"sort":          {"reflect"},
 	"strconv":       {"L0", "unicode/utf8", "math"},
 	"unicode/utf16": {},
 	"unicode/utf8":  {},
@@ -215,8 +215,9 @@ var pkgDeps = map[string][]string{
 	"compress/gzip":            {"L4", "compress/flate"},
 	"compress/lzw":             {"L4"},
 	"compress/zlib":            {"L4", "compress/flate"},
-	"database/sql":             {"L4", "container/list", "database/sql/driver"},
-	"database/sql/driver":      {"L4", "time"},
+	"context":                  {"errors", "fmt", "reflect", "sync", "time"},
+	"database/sql":             {"L4", "container/list", "context", "database/sql/driver", "database/sql/internal/dbstats"},
+	"database/sql/driver":      {"L4", "context", "time"},
 	"debug/dwarf":              {"L4"},
 	"debug/elf":                {"L4", "OS", "debug/dwarf", "compress/zlib"},
 	"debug/gosym":              {"L4"},
@@ -226,6 +227,7 @@ var pkgDeps = map[string][]string{
 	"encoding":                 {"L4"},
 	"encoding/ascii85":         {"L4"},
 	"encoding/asn1":            {"L4", "math/big"},
+	"encoding/cbor":            {"L4", "encoding", "math/big"},
 	"encoding/csv":             {"L4"},
 	"encoding/gob":             {"L4", "OS", "encoding"},
 	"encoding/hex":             {"L4"},
@@ -241,8 +243,10 @@ var pkgDeps = map[string][]string{
 	"image/jpeg":               {"L4", "image/internal/imageutil"},
 	"image/png":                {"L4", "compress/zlib"},
 	"index/suffixarray":        {"L4", "regexp"},
+	"internal/nettrace":        {},
 	"internal/singleflight":    {"sync"},
 	"internal/trace":           {"L4", "OS"},
+	"log/slog":                 {"L4", "encoding/json"},
 	"math/big":                 {"L4"},
 	"mime":                     {"L4", "OS", "syscall", "internal/syscall/windows/registry"},
 	"mime/quotedprintable":     {"L4"},
@@ -279,7 +283,7 @@ var pkgDeps = map[string][]string{
 	// Basic networking.
 	// Because net must be used by any package that wants to
 	// do networking portably, it must have a small dependency set: just L0+basic os.
-	"net": {"L0", "CGO", "math/rand", "os", "sort", "syscall", "time", "internal/syscall/windows", "internal/singleflight", "internal/race"},
+	"net": {"L0", "CGO", "context", "math/rand", "os", "sort", "syscall", "time", "internal/syscall/windows", "internal/nettrace", "internal/singleflight", "internal/race"},
 
 	// NET enables use of basic network-related packages.
 	"NET": {
@@ -290,7 +294,7 @@ var pkgDeps = map[string][]string{
 	},
 
 	// Uses of networking.
-	"log/syslog":    {"L4", "OS", "net"},
+	"log/syslog":    {"L4", "OS", "log/slog", "net"},
 	"net/mail":      {"L4", "NET", "OS", "mime"},
 	"net/textproto": {"L4", "OS", "net"},
 
@@ -304,6 +308,8 @@ var pkgDeps = map[string][]string{
 	"crypto/sha256": {"L3"},
 	"crypto/sha512": {"L3"},
 
+	"crypto/ed25519/internal/edwards25519": {"L3"},
+
 	"CRYPTO": {
 		"crypto/aes",
 		"crypto/des",
@@ -323,6 +329,7 @@ var pkgDeps = map[string][]string{
 	// Mathematical crypto: dependencies on fmt (L4) and math/big.
 	// We could avoid some of the fmt, but math/big imports fmt anyway.
 	"crypto/dsa":      {"L4", "CRYPTO", "math/big"},
+	"crypto/ed25519":  {"L3", "CRYPTO", "crypto/rand", "crypto/ed25519/internal/edwards25519"},
 	"crypto/ecdsa":    {"L4", "CRYPTO", "crypto/elliptic", "math/big", "encoding/asn1"},
 	"crypto/elliptic": {"L4", "CRYPTO", "math/big"},
 	"crypto/rsa":      {"L4", "CRYPTO", "crypto/rand", "math/big"},
@@ -331,6 +338,7 @@ var pkgDeps = map[string][]string{
 		"CRYPTO",
 		"crypto/dsa",
 		"crypto/ecdsa",
+		"crypto/ed25519",
 		"crypto/elliptic",
 		"crypto/rand",
 		"crypto/rsa",
@@ -342,11 +350,14 @@ var pkgDeps = map[string][]string{
 	"crypto/tls": {
 		"L4", "CRYPTO-MATH", "OS",
 		"container/list", "crypto/x509", "encoding/pem", "net", "syscall",
+		"internal/golang.org/x/crypto/chacha20poly1305",
+		"internal/golang.org/x/crypto/curve25519",
 	},
 	"crypto/x509": {
 		"L4", "CRYPTO-MATH", "OS", "CGO",
 		"crypto/x509/pkix", "encoding/pem", "encoding/hex", "net", "syscall",
 	},
+	"crypto/x509/ocsp": {"L4", "CRYPTO-MATH", "crypto/x509", "crypto/x509/pkix"},
 	"crypto/x509/pkix": {"L4", "CRYPTO-MATH"},
 
 	// Simple net+crypto-aware packages.
@@ -356,22 +367,28 @@ var pkgDeps = map[string][]string{
 	// HTTP, kingpin of dependencies.
 	"net/http": {
 		"L4", "NET", "OS",
-		"compress/gzip", "crypto/tls", "mime/multipart", "runtime/debug",
+		"compress/gzip", "container/list", "context", "crypto/tls", "mime/multipart", "runtime/debug",
+		"net/http/httptrace",
 		"net/http/internal",
 		"internal/golang.org/x/net/http2/hpack",
 	},
-	"net/http/internal": {"L4"},
+	"net/http/internal":  {"L4"},
+	"net/http/httptrace": {"context", "crypto/tls", "internal/nettrace", "net", "reflect", "time"},
 
 	// HTTP-using packages.
 	"expvar":             {"L4", "OS", "encoding/json", "net/http"},
 	"net/http/cgi":       {"L4", "NET", "OS", "crypto/tls", "net/http", "regexp"},
-	"net/http/cookiejar": {"L4", "NET", "net/http"},
+	"net/http/cookiejar": {"L4", "NET", "OS", "encoding/json", "net/http"},
 	"net/http/fcgi":      {"L4", "NET", "OS", "net/http", "net/http/cgi"},
-	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "flag", "net/http", "net/http/internal"},
-	"net/http/httputil":  {"L4", "NET", "OS", "net/http", "net/http/internal"},
+	"net/http/httptest":  {"L4", "NET", "OS", "crypto/tls", "crypto/x509", "flag", "net/http", "net/http/internal"},
+	"net/http/httputil":  {"L4", "NET", "OS", "context", "crypto/tls", "net/http", "net/http/internal"},
 	"net/http/pprof":     {"L4", "OS", "html/template", "net/http", "runtime/pprof", "runtime/trace"},
 	"net/rpc":            {"L4", "NET", "encoding/gob", "html/template", "net/http"},
 	"net/rpc/jsonrpc":    {"L4", "NET", "encoding/json", "net/rpc"},
+
+	// Connection pool statistics, published through expvar.
+	"database/sql/internal/dbstats": {},
+	"database/sql/sqlexpvar":        {"L4", "database/sql/internal/dbstats", "expvar"},
 }
 
 // isMacro reports whether p is a package dependency macro
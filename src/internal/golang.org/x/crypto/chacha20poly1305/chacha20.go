@@ -0,0 +1,95 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chacha20poly1305
+
+import "encoding/binary"
+
+// This file implements the ChaCha20 stream cipher as specified in RFC 7539,
+// with a 96-bit nonce and a 32-bit block counter.
+
+const chachaBlockSize = 64
+
+// chachaState holds the key and nonce words of a ChaCha20 instance.
+type chachaState struct {
+	key   [8]uint32
+	nonce [3]uint32
+}
+
+func newChachaState(key *[KeySize]byte, nonce []byte) *chachaState {
+	s := new(chachaState)
+	for i := range s.key {
+		s.key[i] = binary.LittleEndian.Uint32(key[4*i:])
+	}
+	for i := range s.nonce {
+		s.nonce[i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+	return s
+}
+
+func quarterRound(a, b, c, d uint32) (uint32, uint32, uint32, uint32) {
+	a += b
+	d ^= a
+	d = d<<16 | d>>16
+	c += d
+	b ^= c
+	b = b<<12 | b>>20
+	a += b
+	d ^= a
+	d = d<<8 | d>>24
+	c += d
+	b ^= c
+	b = b<<7 | b>>25
+	return a, b, c, d
+}
+
+// block writes the keystream block for the given counter to out.
+func (s *chachaState) block(out *[chachaBlockSize]byte, counter uint32) {
+	// "expand 32-byte k"
+	in := [16]uint32{
+		0x61707865, 0x3320646e, 0x79622d32, 0x6b206574,
+		s.key[0], s.key[1], s.key[2], s.key[3],
+		s.key[4], s.key[5], s.key[6], s.key[7],
+		counter, s.nonce[0], s.nonce[1], s.nonce[2],
+	}
+
+	x := in
+	for i := 0; i < 10; i++ {
+		// Column round.
+		x[0], x[4], x[8], x[12] = quarterRound(x[0], x[4], x[8], x[12])
+		x[1], x[5], x[9], x[13] = quarterRound(x[1], x[5], x[9], x[13])
+		x[2], x[6], x[10], x[14] = quarterRound(x[2], x[6], x[10], x[14])
+		x[3], x[7], x[11], x[15] = quarterRound(x[3], x[7], x[11], x[15])
+
+		// Diagonal round.
+		x[0], x[5], x[10], x[15] = quarterRound(x[0], x[5], x[10], x[15])
+		x[1], x[6], x[11], x[12] = quarterRound(x[1], x[6], x[11], x[12])
+		x[2], x[7], x[8], x[13] = quarterRound(x[2], x[7], x[8], x[13])
+		x[3], x[4], x[9], x[14] = quarterRound(x[3], x[4], x[9], x[14])
+	}
+
+	for i := range x {
+		binary.LittleEndian.PutUint32(out[4*i:], x[i]+in[i])
+	}
+}
+
+// xorKeyStream sets dst to the XOR of src and the keystream starting at the
+// given block counter. dst and src may overlap exactly.
+func (s *chachaState) xorKeyStream(dst, src []byte, counter uint32) {
+	var buf [chachaBlockSize]byte
+	for len(src) > 0 {
+		s.block(&buf, counter)
+		counter++
+
+		n := len(src)
+		if n > chachaBlockSize {
+			n = chachaBlockSize
+		}
+		for i := 0; i < n; i++ {
+			dst[i] = src[i] ^ buf[i]
+		}
+		dst = dst[n:]
+		src = src[n:]
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package chacha20poly1305 implements the ChaCha20-Poly1305 AEAD as specified
+// in RFC 7539.
+package chacha20poly1305
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"internal/golang.org/x/crypto/poly1305"
+)
+
+const (
+	// KeySize is the size of the key used by this AEAD, in bytes.
+	KeySize = 32
+	// NonceSize is the size of the nonce used with this AEAD, in bytes.
+	NonceSize = 12
+)
+
+type chacha20poly1305 struct {
+	key [KeySize]byte
+}
+
+// New returns a ChaCha20-Poly1305 AEAD that uses the given 256-bit key.
+func New(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("chacha20poly1305: bad key length")
+	}
+	ret := new(chacha20poly1305)
+	copy(ret.key[:], key)
+	return ret, nil
+}
+
+func (c *chacha20poly1305) NonceSize() int {
+	return NonceSize
+}
+
+func (c *chacha20poly1305) Overhead() int {
+	return poly1305.TagSize
+}
+
+var errOpen = errors.New("chacha20poly1305: message authentication failed")
+
+func (c *chacha20poly1305) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length passed to Seal")
+	}
+	// The block counter is 32 bits and the first block is used for the
+	// Poly1305 key.
+	if uint64(len(plaintext)) > (1<<38)-64 {
+		panic("chacha20poly1305: plaintext too large")
+	}
+
+	s := newChachaState(&c.key, nonce)
+	ret, out := sliceForAppend(dst, len(plaintext)+poly1305.TagSize)
+	s.xorKeyStream(out, plaintext, 1)
+
+	var tag [poly1305.TagSize]byte
+	polyKey := s.polyKey()
+	poly1305.Sum(&tag, macData(additionalData, out[:len(plaintext)]), &polyKey)
+	copy(out[len(plaintext):], tag[:])
+
+	return ret
+}
+
+func (c *chacha20poly1305) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		panic("chacha20poly1305: bad nonce length passed to Open")
+	}
+	if len(ciphertext) < poly1305.TagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > (1<<38)-48 {
+		panic("chacha20poly1305: ciphertext too large")
+	}
+
+	var tag [poly1305.TagSize]byte
+	copy(tag[:], ciphertext[len(ciphertext)-poly1305.TagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-poly1305.TagSize]
+
+	s := newChachaState(&c.key, nonce)
+	polyKey := s.polyKey()
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	if !poly1305.Verify(&tag, macData(additionalData, ciphertext), &polyKey) {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+
+	s.xorKeyStream(out, ciphertext, 1)
+	return ret, nil
+}
+
+// polyKey returns the one-time Poly1305 key, which is the first half of the
+// keystream block with counter zero.
+func (s *chachaState) polyKey() [32]byte {
+	var block [chachaBlockSize]byte
+	s.block(&block, 0)
+	var key [32]byte
+	copy(key[:], block[:32])
+	return key
+}
+
+// macData returns the Poly1305 input for the given additional data and
+// ciphertext, as specified in RFC 7539, Section 2.8.
+func macData(additionalData, ciphertext []byte) []byte {
+	n := padded16(len(additionalData)) + padded16(len(ciphertext)) + 16
+	data := make([]byte, n)
+	copy(data, additionalData)
+	copy(data[padded16(len(additionalData)):], ciphertext)
+	binary.LittleEndian.PutUint64(data[n-16:], uint64(len(additionalData)))
+	binary.LittleEndian.PutUint64(data[n-8:], uint64(len(ciphertext)))
+	return data
+}
+
+func padded16(n int) int {
+	return (n + 15) &^ 15
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes. If the
+// original slice has sufficient capacity then no allocation is performed.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,203 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package curve25519 provides an implementation of scalar multiplication on
+// the elliptic curve known as curve25519. See https://cr.yp.to/ecdh.html and
+// RFC 7748.
+package curve25519
+
+// The field arithmetic follows TweetNaCl: an element of GF(2^255-19) is held
+// in sixteen signed 64-bit limbs of 16 bits each, which keeps the code short
+// and free of secret-dependent branches and memory accesses.
+//
+// crypto/ed25519/internal/edwards25519 has its own copy of fieldElement and
+// of every fe function in this file, since packages vendored from
+// golang.org/x cannot import the standard library's internal packages. The
+// duplication is intentional; keep the two copies in sync.
+
+// fieldElement represents an element of the field GF(2^255-19). Limb i
+// holds bits 16*i to 16*i+15 of the value, but may temporarily exceed that
+// range between carries.
+type fieldElement [16]int64
+
+// feCarry propagates the carries of h, so that every limb is reduced to 16
+// bits (except limb 0, which absorbs the 2^256 = 38 wrap-around).
+func feCarry(h *fieldElement) {
+	for i := 0; i < 16; i++ {
+		h[i] += 1 << 16
+		c := h[i] >> 16
+		if i < 15 {
+			h[i+1] += c - 1
+		} else {
+			h[0] += 38 * (c - 1)
+		}
+		h[i] -= c << 16
+	}
+}
+
+// feCSwap swaps f and g if b == 1 and leaves them unchanged if b == 0, in
+// constant time.
+func feCSwap(f, g *fieldElement, b int64) {
+	mask := ^(b - 1)
+	for i := range f {
+		t := mask & (f[i] ^ g[i])
+		f[i] ^= t
+		g[i] ^= t
+	}
+}
+
+// feFromBytes sets h to the little-endian value of s, ignoring the top bit.
+func feFromBytes(h *fieldElement, s *[32]byte) {
+	for i := range h {
+		h[i] = int64(s[2*i]) | int64(s[2*i+1])<<8
+	}
+	h[15] &= 0x7fff
+}
+
+// feToBytes writes the canonical little-endian encoding of h to s.
+func feToBytes(s *[32]byte, h *fieldElement) {
+	t := *h
+	feCarry(&t)
+	feCarry(&t)
+	feCarry(&t)
+
+	// Subtract p = 2^255-19 twice, keeping the result only if it does not
+	// go negative, to fully reduce t.
+	var m fieldElement
+	for j := 0; j < 2; j++ {
+		m[0] = t[0] - 0xffed
+		for i := 1; i < 15; i++ {
+			m[i] = t[i] - 0xffff - ((m[i-1] >> 16) & 1)
+			m[i-1] &= 0xffff
+		}
+		m[15] = t[15] - 0x7fff - ((m[14] >> 16) & 1)
+		b := (m[15] >> 16) & 1
+		m[14] &= 0xffff
+		feCSwap(&t, &m, 1-b)
+	}
+
+	for i := range t {
+		s[2*i] = byte(t[i])
+		s[2*i+1] = byte(t[i] >> 8)
+	}
+}
+
+// feAdd sets h = f + g.
+func feAdd(h, f, g *fieldElement) {
+	for i := range h {
+		h[i] = f[i] + g[i]
+	}
+}
+
+// feSub sets h = f - g.
+func feSub(h, f, g *fieldElement) {
+	for i := range h {
+		h[i] = f[i] - g[i]
+	}
+}
+
+// feMul sets h = f * g. h may alias f or g.
+func feMul(h, f, g *fieldElement) {
+	var t [31]int64
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			t[i+j] += f[i] * g[j]
+		}
+	}
+	// 2^256 = 38 (mod p), so fold the high limbs back into the low ones.
+	for i := 0; i < 15; i++ {
+		t[i] += 38 * t[i+16]
+	}
+	copy(h[:], t[:16])
+	feCarry(h)
+	feCarry(h)
+}
+
+// feSquare sets h = f * f.
+func feSquare(h, f *fieldElement) {
+	feMul(h, f, f)
+}
+
+// feInvert sets out = z^-1, computed as z^(p-2).
+func feInvert(out, z *fieldElement) {
+	c := *z
+	for i := 253; i >= 0; i-- {
+		feSquare(&c, &c)
+		if i != 2 && i != 4 {
+			feMul(&c, &c, z)
+		}
+	}
+	*out = c
+}
+
+// a24 is (486662 - 2) / 4, the constant of the Montgomery ladder.
+var a24 = fieldElement{0xdb41, 1}
+
+// scalarMult sets out to the x-coordinate of scalar times the point with
+// x-coordinate point, using the Montgomery ladder of RFC 7748, Section 5.
+func scalarMult(out, scalar, point *[32]byte) {
+	var e [32]byte
+	copy(e[:], scalar[:])
+	e[0] &= 248
+	e[31] &= 127
+	e[31] |= 64
+
+	var x1, x2, z2, x3, z3, tmp0, tmp1 fieldElement
+	feFromBytes(&x1, point)
+	x2[0] = 1
+	x3 = x1
+	z3[0] = 1
+
+	for pos := 254; pos >= 0; pos-- {
+		b := int64(e[pos>>3]>>uint(pos&7)) & 1
+		feCSwap(&x2, &x3, b)
+		feCSwap(&z2, &z3, b)
+
+		feAdd(&tmp0, &x2, &z2) // A = x2 + z2
+		feSub(&x2, &x2, &z2)   // B = x2 - z2
+		feAdd(&z2, &x3, &z3)   // C = x3 + z3
+		feSub(&x3, &x3, &z3)   // D = x3 - z3
+		feSquare(&z3, &tmp0)   // AA = A^2
+		feSquare(&tmp1, &x2)   // BB = B^2
+		feMul(&x2, &z2, &x2)   // CB = C * B
+		feMul(&z2, &x3, &tmp0) // DA = D * A
+		feAdd(&tmp0, &x2, &z2) // DA + CB
+		feSub(&x2, &x2, &z2)   // CB - DA
+		feSquare(&x3, &x2)     // (CB - DA)^2
+		feSub(&z2, &z3, &tmp1) // E = AA - BB
+		feMul(&x2, &z2, &a24)  // a24 * E
+		feAdd(&x2, &x2, &z3)   // AA + a24 * E
+		feMul(&z2, &z2, &x2)   // z2 = E * (AA + a24 * E)
+		feMul(&x2, &z3, &tmp1) // x2 = AA * BB
+		feMul(&z3, &x3, &x1)   // z3 = x1 * (CB - DA)^2
+		feSquare(&x3, &tmp0)   // x3 = (DA + CB)^2
+
+		feCSwap(&x2, &x3, b)
+		feCSwap(&z2, &z3, b)
+	}
+
+	feInvert(&z2, &z2)
+	feMul(&x2, &x2, &z2)
+	feToBytes(out, &x2)
+}
+
+// Basepoint is the x-coordinate of the generator of the curve25519 group.
+var Basepoint [32]byte
+
+func init() {
+	Basepoint[0] = 9
+}
+
+// ScalarMult sets dst to the product in*base where dst and base are the x
+// coordinates of group points and all values are in little-endian form.
+func ScalarMult(dst, in, base *[32]byte) {
+	scalarMult(dst, in, base)
+}
+
+// ScalarBaseMult sets dst to the product in*base where dst and base are the x
+// coordinates of group points, base is the standard generator and all values
+// are in little-endian form.
+func ScalarBaseMult(dst, in *[32]byte) {
+	scalarMult(dst, in, &Basepoint)
+}
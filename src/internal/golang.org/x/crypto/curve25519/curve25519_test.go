@@ -0,0 +1,106 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package curve25519
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func decodeHex32(t *testing.T, s string) *[32]byte {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("bad test vector %q", s)
+	}
+	var out [32]byte
+	copy(out[:], b)
+	return &out
+}
+
+// Test vectors from RFC 7748, Section 5.2.
+var scalarMultTests = []struct {
+	scalar, point, out string
+}{
+	{
+		"a546e36bf0527c9d3b16154b82465edd62144c0ac1fc5a18506a2244ba449ac4",
+		"e6db6867583030db3594c1a424b15f7c726624ec26b3353b10a903a6d0ab1c4c",
+		"c3da55379de9c6908e94ea4df28d084f32eccf03491c71f754b4075577a28552",
+	},
+	{
+		"4b66e9d4d1b4673c5ad22691957d6af5c11b6421e0ea01d42ca4169e7918ba0d",
+		"e5210f12786811d3f4b7959d0538ae2c31dbe7106fc03c3efc4cd549c715a493",
+		"95cbde9476e8907d7aade45cb4b873f88b595a68799fa152e6f8f7647aac7957",
+	},
+}
+
+func TestScalarMult(t *testing.T) {
+	for i, test := range scalarMultTests {
+		var out [32]byte
+		ScalarMult(&out, decodeHex32(t, test.scalar), decodeHex32(t, test.point))
+		if got := hex.EncodeToString(out[:]); got != test.out {
+			t.Errorf("#%d: got %s, want %s", i, got, test.out)
+		}
+	}
+}
+
+func TestScalarMultIterated(t *testing.T) {
+	// Repeatedly compute k, u = X25519(k, u), k starting as the base point,
+	// as in RFC 7748, Section 5.2.
+	k, u := Basepoint, Basepoint
+	want := map[int]string{
+		1:    "422c8e7a6227d7bca1350b3e2bb7279f7897b87bb6854b783c60e80311ae3079",
+		1000: "684cf59ba83309552800ef566f2f4d3c1c3887c49360e3875f2eb94d99532c51",
+	}
+	n := 1000
+	if testing.Short() {
+		n = 1
+	}
+	for i := 1; i <= n; i++ {
+		var out [32]byte
+		ScalarMult(&out, &k, &u)
+		u, k = k, out
+		if w, ok := want[i]; ok {
+			if got := hex.EncodeToString(k[:]); got != w {
+				t.Fatalf("after %d iterations: got %s, want %s", i, got, w)
+			}
+		}
+	}
+}
+
+func TestDiffieHellman(t *testing.T) {
+	// Test vectors from RFC 7748, Section 6.1.
+	alicePrivate := decodeHex32(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPrivate := decodeHex32(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	wantAlicePublic := decodeHex32(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	wantBobPublic := decodeHex32(t, "de9edb7d7b7dc1b4d35b61c2ece435373f8343c85b78674dadfc7e146f882b4f")
+	wantShared := decodeHex32(t, "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742")
+
+	var alicePublic, bobPublic, aliceShared, bobShared [32]byte
+	ScalarBaseMult(&alicePublic, alicePrivate)
+	ScalarBaseMult(&bobPublic, bobPrivate)
+	if alicePublic != *wantAlicePublic {
+		t.Errorf("Alice's public key: got %x, want %x", alicePublic, *wantAlicePublic)
+	}
+	if bobPublic != *wantBobPublic {
+		t.Errorf("Bob's public key: got %x, want %x", bobPublic, *wantBobPublic)
+	}
+
+	ScalarMult(&aliceShared, alicePrivate, &bobPublic)
+	ScalarMult(&bobShared, bobPrivate, &alicePublic)
+	if !bytes.Equal(aliceShared[:], wantShared[:]) || !bytes.Equal(bobShared[:], wantShared[:]) {
+		t.Errorf("shared secrets: got %x and %x, want %x", aliceShared, bobShared, *wantShared)
+	}
+}
+
+func BenchmarkScalarBaseMult(b *testing.B) {
+	var in, out [32]byte
+	in[0] = 1
+
+	b.SetBytes(32)
+	for i := 0; i < b.N; i++ {
+		ScalarBaseMult(&out, &in)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package poly1305 implements Poly1305 one-time message authentication code as
+specified in https://cr.yp.to/mac/poly1305-20050329.pdf and RFC 7539.
+
+Poly1305 is a fast, one-time authentication function. It is infeasible for an
+attacker to generate an authenticator for a message without the key. However, a
+key must only be used for a single message. Authenticating two different
+messages with the same key allows an attacker to forge authenticators for other
+messages with the same key.
+*/
+package poly1305
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// TagSize is the size, in bytes, of a poly1305 authenticator.
+const TagSize = 16
+
+// Verify returns true if mac is a valid authenticator for m with the given
+// key.
+func Verify(mac *[TagSize]byte, m []byte, key *[32]byte) bool {
+	var tmp [TagSize]byte
+	Sum(&tmp, m, key)
+	return subtle.ConstantTimeCompare(tmp[:], mac[:]) == 1
+}
+
+// Sum generates an authenticator for m using a one-time key and puts the
+// 16-byte result into out. Authenticating two different messages with the same
+// key allows an attacker to forge messages at will.
+func Sum(out *[TagSize]byte, m []byte, key *[32]byte) {
+	// The accumulator h and the clamped key r are held in five 26-bit limbs,
+	// following poly1305-donna.
+	const mask = 0x3ffffff
+
+	r0 := binary.LittleEndian.Uint32(key[0:]) & 0x3ffffff
+	r1 := (binary.LittleEndian.Uint32(key[3:]) >> 2) & 0x3ffff03
+	r2 := (binary.LittleEndian.Uint32(key[6:]) >> 4) & 0x3ffc0ff
+	r3 := (binary.LittleEndian.Uint32(key[9:]) >> 6) & 0x3f03fff
+	r4 := (binary.LittleEndian.Uint32(key[12:]) >> 8) & 0x00fffff
+
+	s1, s2, s3, s4 := r1*5, r2*5, r3*5, r4*5
+
+	var h0, h1, h2, h3, h4 uint32
+	var block [TagSize]byte
+	for len(m) > 0 {
+		// Each full block is extended with a 1 bit at position 128; a final
+		// partial block is instead padded with a single 1 byte.
+		hibit := uint32(1 << 24)
+		if len(m) >= TagSize {
+			copy(block[:], m[:TagSize])
+			m = m[TagSize:]
+		} else {
+			block = [TagSize]byte{}
+			n := copy(block[:], m)
+			block[n] = 1
+			hibit = 0
+			m = nil
+		}
+
+		// h += m
+		h0 += binary.LittleEndian.Uint32(block[0:]) & mask
+		h1 += (binary.LittleEndian.Uint32(block[3:]) >> 2) & mask
+		h2 += (binary.LittleEndian.Uint32(block[6:]) >> 4) & mask
+		h3 += (binary.LittleEndian.Uint32(block[9:]) >> 6) & mask
+		h4 += (binary.LittleEndian.Uint32(block[12:]) >> 8) | hibit
+
+		// h *= r
+		d0 := uint64(h0)*uint64(r0) + uint64(h1)*uint64(s4) + uint64(h2)*uint64(s3) + uint64(h3)*uint64(s2) + uint64(h4)*uint64(s1)
+		d1 := uint64(h0)*uint64(r1) + uint64(h1)*uint64(r0) + uint64(h2)*uint64(s4) + uint64(h3)*uint64(s3) + uint64(h4)*uint64(s2)
+		d2 := uint64(h0)*uint64(r2) + uint64(h1)*uint64(r1) + uint64(h2)*uint64(r0) + uint64(h3)*uint64(s4) + uint64(h4)*uint64(s3)
+		d3 := uint64(h0)*uint64(r3) + uint64(h1)*uint64(r2) + uint64(h2)*uint64(r1) + uint64(h3)*uint64(r0) + uint64(h4)*uint64(s4)
+		d4 := uint64(h0)*uint64(r4) + uint64(h1)*uint64(r3) + uint64(h2)*uint64(r2) + uint64(h3)*uint64(r1) + uint64(h4)*uint64(r0)
+
+		// h %= p, partially
+		c := uint32(d0 >> 26)
+		h0 = uint32(d0) & mask
+		d1 += uint64(c)
+		c = uint32(d1 >> 26)
+		h1 = uint32(d1) & mask
+		d2 += uint64(c)
+		c = uint32(d2 >> 26)
+		h2 = uint32(d2) & mask
+		d3 += uint64(c)
+		c = uint32(d3 >> 26)
+		h3 = uint32(d3) & mask
+		d4 += uint64(c)
+		c = uint32(d4 >> 26)
+		h4 = uint32(d4) & mask
+		h0 += c * 5
+		c = h0 >> 26
+		h0 &= mask
+		h1 += c
+	}
+
+	// Fully carry h.
+	c := h1 >> 26
+	h1 &= mask
+	h2 += c
+	c = h2 >> 26
+	h2 &= mask
+	h3 += c
+	c = h3 >> 26
+	h3 &= mask
+	h4 += c
+	c = h4 >> 26
+	h4 &= mask
+	h0 += c * 5
+	c = h0 >> 26
+	h0 &= mask
+	h1 += c
+
+	// Compute g = h + -p = h - (2^130 - 5).
+	g0 := h0 + 5
+	c = g0 >> 26
+	g0 &= mask
+	g1 := h1 + c
+	c = g1 >> 26
+	g1 &= mask
+	g2 := h2 + c
+	c = g2 >> 26
+	g2 &= mask
+	g3 := h3 + c
+	c = g3 >> 26
+	g3 &= mask
+	g4 := h4 + c - (1 << 26)
+
+	// Select h if h < p, or g otherwise, in constant time. If g4 underflowed
+	// its top bit is set and the mask is zero.
+	selectG := (g4 >> 31) - 1
+	g0 &= selectG
+	g1 &= selectG
+	g2 &= selectG
+	g3 &= selectG
+	g4 &= selectG
+	selectH := ^selectG
+	h0 = (h0 & selectH) | g0
+	h1 = (h1 & selectH) | g1
+	h2 = (h2 & selectH) | g2
+	h3 = (h3 & selectH) | g3
+	h4 = (h4 & selectH) | g4
+
+	// h = h % 2^128
+	h0 = h0 | h1<<26
+	h1 = h1>>6 | h2<<20
+	h2 = h2>>12 | h3<<14
+	h3 = h3>>18 | h4<<8
+
+	// out = (h + s) % 2^128, where s is the second half of the key.
+	f := uint64(h0) + uint64(binary.LittleEndian.Uint32(key[16:]))
+	h0 = uint32(f)
+	f = uint64(h1) + uint64(binary.LittleEndian.Uint32(key[20:])) + f>>32
+	h1 = uint32(f)
+	f = uint64(h2) + uint64(binary.LittleEndian.Uint32(key[24:])) + f>>32
+	h2 = uint32(f)
+	f = uint64(h3) + uint64(binary.LittleEndian.Uint32(key[28:])) + f>>32
+	h3 = uint32(f)
+
+	binary.LittleEndian.PutUint32(out[0:], h0)
+	binary.LittleEndian.PutUint32(out[4:], h1)
+	binary.LittleEndian.PutUint32(out[8:], h2)
+	binary.LittleEndian.PutUint32(out[12:], h3)
+}
@@ -0,0 +1,117 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package poly1305
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+var testData = []struct {
+	in       []byte
+	key, tag string
+}{
+	{
+		// RFC 7539, Section 2.5.2.
+		[]byte("Cryptographic Forum Research Group"),
+		"85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b",
+		"a8061dc1305136c6c22b8baf0c0127a9",
+	},
+	{
+		bytes.Repeat([]byte{0x00}, 0),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"ffffffffffffffffffffffffffffffff",
+	},
+	{
+		bytes.Repeat([]byte{0x00}, 0),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"1112131415161718191a1b1c1d1e1f20",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 15),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"fbff27e6030028e6030028e6030028ee",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 15),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"95171a1aa0212424aa2b2e2eb4351022",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 16),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"fbffff17faffff17faffff17faffff17",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 16),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"1095171a1b9f212425a92b2e2fb33520",
+	},
+	{
+		bytes.Repeat([]byte{0xab}, 17),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"72aa2bcc8ed8be89c5145b50055a0020",
+	},
+	{
+		bytes.Repeat([]byte{0xab}, 17),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"19a3989ff331b30d04cafc7d950de80d",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 64),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"900fe32bc15fa8d7bca8efe4c7e37eb1",
+	},
+	{
+		bytes.Repeat([]byte{0xff}, 64),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"3b50241fe0bc868c42bbf005b038be76",
+	},
+	{
+		bytes.Repeat([]byte{0x5a}, 131),
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		"a9ec92891d6037dc01b852b3486df332",
+	},
+	{
+		bytes.Repeat([]byte{0x5a}, 131),
+		"0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20",
+		"edf7a36da169b76da3cdf1cebd51fe48",
+	},
+}
+
+func TestSum(t *testing.T) {
+	for i, v := range testData {
+		var key [32]byte
+		k, _ := hex.DecodeString(v.key)
+		copy(key[:], k)
+
+		var out [TagSize]byte
+		Sum(&out, v.in, &key)
+		if got := hex.EncodeToString(out[:]); got != v.tag {
+			t.Errorf("#%d: got %s, want %s", i, got, v.tag)
+		}
+		if !Verify(&out, v.in, &key) {
+			t.Errorf("#%d: Verify failed", i)
+		}
+		out[0] ^= 1
+		if Verify(&out, v.in, &key) {
+			t.Errorf("#%d: Verify accepted a bad tag", i)
+		}
+	}
+}
+
+func benchmark(b *testing.B, size int) {
+	var out [TagSize]byte
+	var key [32]byte
+	in := make([]byte, size)
+	b.SetBytes(int64(len(in)))
+	for i := 0; i < b.N; i++ {
+		Sum(&out, in, &key)
+	}
+}
+
+func Benchmark64(b *testing.B) { benchmark(b, 64) }
+func Benchmark1K(b *testing.B) { benchmark(b, 1024) }
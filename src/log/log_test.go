@@ -121,6 +121,19 @@ func TestFlagAndPrefixSetting(t *testing.T) {
 	}
 }
 
+func TestWriter(t *testing.T) {
+	var b bytes.Buffer
+	l := New(&b, "", 0)
+	if w := l.Writer(); w != &b {
+		t.Errorf("Writer: got %v, want %v", w, &b)
+	}
+	var b2 bytes.Buffer
+	l.SetOutput(&b2)
+	if w := l.Writer(); w != &b2 {
+		t.Errorf("Writer after SetOutput: got %v, want %v", w, &b2)
+	}
+}
+
 func TestUTCFlag(t *testing.T) {
 	var b bytes.Buffer
 	l := New(&b, "Test:", LstdFlags)
@@ -0,0 +1,283 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// A Handler handles log records produced by a Logger.
+//
+// Any of the Handler's methods may be called concurrently with itself
+// or with other methods. It is the responsibility of the Handler to
+// manage this concurrency.
+type Handler interface {
+	// Enabled reports whether the handler handles records at the given
+	// level. The Logger calls it before creating a Record, and discards
+	// the event if it returns false.
+	Enabled(level Level) bool
+
+	// Handle handles the Record.
+	// It will only be called if Enabled returned true.
+	Handle(r Record) error
+
+	// WithAttrs returns a new Handler whose records include both the
+	// receiver's attributes and the arguments.
+	// The Handler owns the slice: it may retain, modify or discard it.
+	WithAttrs(attrs []Attr) Handler
+}
+
+// HandlerOptions are options for a TextHandler or JSONHandler.
+// A zero HandlerOptions consists entirely of default values.
+type HandlerOptions struct {
+	// Level is the minimum level of the records to handle.
+	// The zero value is LevelInfo.
+	Level Level
+}
+
+// timeFormat is the format used for times by the built-in handlers:
+// RFC 3339 with millisecond precision.
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// commonHandler implements the formatting shared by TextHandler and
+// JSONHandler.
+type commonHandler struct {
+	json bool
+	opts HandlerOptions
+
+	// preformatted holds the attributes added by WithAttrs, already
+	// formatted, each preceded by a separator.
+	preformatted []byte
+
+	mu *sync.Mutex // serializes writes to w
+	w  io.Writer
+}
+
+func newCommonHandler(w io.Writer, opts *HandlerOptions, json bool) *commonHandler {
+	h := &commonHandler{json: json, mu: new(sync.Mutex), w: w}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *commonHandler) enabled(level Level) bool {
+	return level >= h.opts.Level
+}
+
+func (h *commonHandler) withAttrs(attrs []Attr) *commonHandler {
+	h2 := *h
+	// Force a copy of preformatted so that h and h2 don't share it.
+	h2.preformatted = append([]byte(nil), h.preformatted...)
+	for _, a := range attrs {
+		h2.preformatted = h2.appendAttr(h2.preformatted, a)
+	}
+	return &h2
+}
+
+// handle formats r as a single line and writes it to h.w.
+// The built-in attributes come first: time (unless r.Time is zero),
+// level and msg.
+func (h *commonHandler) handle(r Record) error {
+	buf := make([]byte, 0, 1024)
+	if !r.Time.IsZero() {
+		buf = h.appendAttr(buf, Attr{"time", r.Time})
+	}
+	buf = h.appendAttr(buf, Attr{"level", r.Level.String()})
+	buf = h.appendAttr(buf, Attr{"msg", r.Message})
+	buf = append(buf, h.preformatted...)
+	for _, a := range r.Attrs {
+		buf = h.appendAttr(buf, a)
+	}
+
+	// Every attribute is preceded by a separator. The first one is
+	// either dropped or turned into the start of the JSON object.
+	if h.json {
+		buf[0] = '{'
+		buf = append(buf, '}')
+	} else {
+		buf = buf[1:]
+	}
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+// appendAttr appends a separator followed by a formatted to buf.
+func (h *commonHandler) appendAttr(buf []byte, a Attr) []byte {
+	if h.json {
+		buf = append(buf, ',')
+		buf = appendJSONValue(buf, a.Key)
+		buf = append(buf, ':')
+		return appendJSONValue(buf, a.Value)
+	}
+	buf = append(buf, ' ')
+	return appendTextAttr(buf, a)
+}
+
+// appendTextAttr appends a formatted as key=value to buf, quoting the key
+// and value if necessary.
+func appendTextAttr(buf []byte, a Attr) []byte {
+	buf = append(buf, quoteIfNeeded(a.Key)...)
+	buf = append(buf, '=')
+	var s string
+	switch v := a.Value.(type) {
+	case string:
+		s = v
+	case time.Time:
+		s = v.Format(timeFormat)
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	return append(buf, quoteIfNeeded(s)...)
+}
+
+// appendJSONValue appends the JSON encoding of v to buf. Times are encoded
+// as strings, durations as an integer number of nanoseconds and errors as
+// their message. Values that cannot be encoded are replaced by a string
+// describing the error.
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case time.Time:
+		v = x.Format(timeFormat)
+	case time.Duration:
+		v = int64(x)
+	case error:
+		v = x.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal("!ERROR:" + err.Error())
+	}
+	return append(buf, b...)
+}
+
+// TextHandler is a Handler that writes Records to an io.Writer as a
+// sequence of key=value pairs separated by spaces and followed by a newline.
+type TextHandler struct {
+	c *commonHandler
+}
+
+// NewTextHandler creates a TextHandler that writes to w,
+// using the given options.
+// If opts is nil, the default options are used.
+func NewTextHandler(w io.Writer, opts *HandlerOptions) *TextHandler {
+	return &TextHandler{newCommonHandler(w, opts, false)}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *TextHandler) Enabled(level Level) bool {
+	return h.c.enabled(level)
+}
+
+// WithAttrs returns a new TextHandler whose attributes consist
+// of h's attributes followed by attrs.
+func (h *TextHandler) WithAttrs(attrs []Attr) Handler {
+	return &TextHandler{h.c.withAttrs(attrs)}
+}
+
+// Handle formats its argument Record as a single line of space-separated
+// key=value items.
+//
+// If the Record's time is zero, the time is omitted. Otherwise, the key
+// is "time" and the value is formatted as RFC 3339 with millisecond
+// precision. The level and message follow with the keys "level" and
+// "msg".
+//
+// Keys and values are quoted with strconv.Quote if they are empty or
+// contain spaces, quotes, equals signs or non-printable characters.
+// Values are formatted with fmt.Sprint, except for errors, which are
+// formatted with their Error method.
+//
+// Each call to Handle results in a single serialized call to io.Writer.Write.
+func (h *TextHandler) Handle(r Record) error {
+	return h.c.handle(r)
+}
+
+// JSONHandler is a Handler that writes Records to an io.Writer as
+// line-delimited JSON objects.
+type JSONHandler struct {
+	c *commonHandler
+}
+
+// NewJSONHandler creates a JSONHandler that writes to w,
+// using the given options.
+// If opts is nil, the default options are used.
+func NewJSONHandler(w io.Writer, opts *HandlerOptions) *JSONHandler {
+	return &JSONHandler{newCommonHandler(w, opts, true)}
+}
+
+// Enabled reports whether the handler handles records at the given level.
+func (h *JSONHandler) Enabled(level Level) bool {
+	return h.c.enabled(level)
+}
+
+// WithAttrs returns a new JSONHandler whose attributes consist
+// of h's attributes followed by attrs.
+func (h *JSONHandler) WithAttrs(attrs []Attr) Handler {
+	return &JSONHandler{h.c.withAttrs(attrs)}
+}
+
+// Handle formats its argument Record as a JSON object on a single line.
+//
+// If the Record's time is zero, the time is omitted. Otherwise, the key
+// is "time" and the value is formatted as RFC 3339 with millisecond
+// precision. The level and message follow with the keys "level" and
+// "msg".
+//
+// Values are encoded with encoding/json, except for durations, which are
+// encoded as an integer number of nanoseconds, and errors, which are
+// encoded as their message.
+//
+// Each call to Handle results in a single serialized call to io.Writer.Write.
+func (h *JSONHandler) Handle(r Record) error {
+	return h.c.handle(r)
+}
+
+// defaultHandler is the handler of the initial default Logger. It formats
+// records as "LEVEL message key=value ..." and passes them to the log
+// package's standard logger, so they are subject to log.SetOutput,
+// log.SetFlags and log.SetPrefix.
+type defaultHandler struct {
+	preformatted []byte
+}
+
+func (h *defaultHandler) Enabled(level Level) bool {
+	return level >= LevelInfo
+}
+
+func (h *defaultHandler) WithAttrs(attrs []Attr) Handler {
+	h2 := &defaultHandler{append([]byte(nil), h.preformatted...)}
+	for _, a := range attrs {
+		h2.preformatted = append(h2.preformatted, ' ')
+		h2.preformatted = appendTextAttr(h2.preformatted, a)
+	}
+	return h2
+}
+
+func (h *defaultHandler) Handle(r Record) error {
+	buf := make([]byte, 0, 1024)
+	buf = append(buf, r.Level.String()...)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	buf = append(buf, h.preformatted...)
+	for _, a := range r.Attrs {
+		buf = append(buf, ' ')
+		buf = appendTextAttr(buf, a)
+	}
+	// The frames above log.Output are Handle, Logger.log, the exported
+	// output method and its caller.
+	return log.Output(4, string(buf))
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2016, 11, 8, 15, 28, 26, 0, time.UTC)
+
+func TestHandlers(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		with     []Attr
+		args     []interface{}
+		wantText string
+		wantJSON string
+	}{
+		{
+			name:     "basic",
+			args:     []interface{}{"a", 1, "b", "two"},
+			wantText: `time=2016-11-08T15:28:26.000Z level=INFO msg=message a=1 b=two`,
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","a":1,"b":"two"}`,
+		},
+		{
+			name:     "quoting",
+			args:     []interface{}{"a b", "x\ty", "", "=", "c", `"q"`},
+			wantText: `time=2016-11-08T15:28:26.000Z level=INFO msg=message "a b"="x\ty" ""="=" c="\"q\""`,
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","a b":"x\ty","":"=","c":"\"q\""}`,
+		},
+		{
+			name:     "with",
+			with:     []Attr{Int("p", 1), String("q", "r")},
+			args:     []interface{}{"a", true},
+			wantText: `time=2016-11-08T15:28:26.000Z level=INFO msg=message p=1 q=r a=true`,
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","p":1,"q":"r","a":true}`,
+		},
+		{
+			name: "special values",
+			args: []interface{}{
+				Duration("d", 2*time.Second),
+				Time("t", testTime.Add(time.Millisecond)),
+				"err", errors.New("bad thing"),
+				"nil", nil,
+			},
+			wantText: `time=2016-11-08T15:28:26.000Z level=INFO msg=message d=2s t=2016-11-08T15:28:26.001Z err="bad thing" nil=<nil>`,
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","d":2000000000,"t":"2016-11-08T15:28:26.001Z","err":"bad thing","nil":null}`,
+		},
+		{
+			name:     "bad keys",
+			args:     []interface{}{1, "a", 2, "last"},
+			wantText: `time=2016-11-08T15:28:26.000Z level=INFO msg=message !BADKEY=1 a=2 !BADKEY=last`,
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","!BADKEY":1,"a":2,"!BADKEY":"last"}`,
+		},
+		{
+			name:     "unmarshalable",
+			args:     []interface{}{"f", func() {}},
+			wantJSON: `{"time":"2016-11-08T15:28:26.000Z","level":"INFO","msg":"message","f":"!ERROR:json: unsupported type: func()"}`,
+		},
+	} {
+		r := NewRecord(testTime, LevelInfo, "message", test.args...)
+		for _, h := range []struct {
+			name string
+			new  func(*bytes.Buffer) Handler
+			want string
+		}{
+			{"text", func(b *bytes.Buffer) Handler { return NewTextHandler(b, nil) }, test.wantText},
+			{"json", func(b *bytes.Buffer) Handler { return NewJSONHandler(b, nil) }, test.wantJSON},
+		} {
+			if h.want == "" {
+				continue
+			}
+			var buf bytes.Buffer
+			var handler Handler = h.new(&buf)
+			if test.with != nil {
+				handler = handler.WithAttrs(test.with)
+			}
+			if err := handler.Handle(r); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := buf.String(), h.want+"\n"; got != want {
+				t.Errorf("%s, %s:\ngot  %s\nwant %s", test.name, h.name, got, want)
+			}
+		}
+	}
+}
+
+func TestHandlerZeroTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, nil)
+	if err := h.Handle(NewRecord(time.Time{}, LevelWarn+1, "m")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "level=WARN+1 msg=m\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHandlerWithAttrsIsolated(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, nil)
+	h1 := h.WithAttrs([]Attr{Int("a", 1)})
+	h2 := h1.WithAttrs([]Attr{Int("b", 2)})
+	h3 := h1.WithAttrs([]Attr{Int("c", 3)})
+	for _, h := range []Handler{h, h1, h2, h3} {
+		if err := h.Handle(NewRecord(time.Time{}, LevelInfo, "m")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "level=INFO msg=m\n" +
+		"level=INFO msg=m a=1\n" +
+		"level=INFO msg=m a=1 b=2\n" +
+		"level=INFO msg=m a=1 c=3\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandlerEnabled(t *testing.T) {
+	for _, test := range []struct {
+		opts  *HandlerOptions
+		level Level
+		want  bool
+	}{
+		{nil, LevelDebug, false},
+		{nil, LevelInfo, true},
+		{&HandlerOptions{Level: LevelDebug}, LevelDebug, true},
+		{&HandlerOptions{Level: LevelWarn}, LevelInfo, false},
+		{&HandlerOptions{Level: LevelWarn}, LevelError, true},
+	} {
+		for _, h := range []Handler{NewTextHandler(nil, test.opts), NewJSONHandler(nil, test.opts)} {
+			if got := h.Enabled(test.level); got != test.want {
+				t.Errorf("%T with %v: Enabled(%v) = %t, want %t", h, test.opts, test.level, got, test.want)
+			}
+		}
+	}
+}
+
+func TestLevelString(t *testing.T) {
+	for _, test := range []struct {
+		in   Level
+		want string
+	}{
+		{LevelDebug, "DEBUG"},
+		{LevelDebug - 2, "DEBUG-2"},
+		{LevelInfo, "INFO"},
+		{LevelInfo + 1, "INFO+1"},
+		{LevelWarn, "WARN"},
+		{LevelError, "ERROR"},
+		{LevelError + 3, "ERROR+3"},
+	} {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("Level(%d).String() = %q, want %q", int(test.in), got, test.want)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package slog provides structured logging, in which log records include
+// a message, a severity level, and various other attributes expressed as
+// key-value pairs.
+//
+// It defines a type, Logger, which provides several methods (such as
+// Logger.Info and Logger.Error) for reporting events of interest.
+//
+// Each Logger is associated with a Handler. A Logger output method creates
+// a Record from the method arguments and passes it to the Handler, which
+// decides how to handle it. There is a default Logger accessible through
+// top-level functions (such as Info and Error) that call the corresponding
+// Logger methods.
+//
+// A log record consists of a time, a level, a message, and a set of
+// key-value pairs, where the keys are strings and the values may be of any
+// type. As an example,
+//
+//	slog.Info("hello", "count", 3)
+//
+// creates a record containing the time of the call, a level of Info, the
+// message "hello", and a single pair with key "count" and value 3.
+//
+// The default handler formats the record's message, level and attributes
+// as a string and passes it to the log package, so that it is written
+// wherever the log package's standard logger writes:
+//
+//	2016/11/08 15:28:26 INFO hello count=3
+//
+// For more control over the output format, create a Logger with a
+// different Handler. This package provides TextHandler, which writes
+// key=value pairs, and JSONHandler, which writes line-delimited JSON:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+//	logger.Info("hello", "count", 3)
+//
+// produces
+//
+//	{"time":"2016-11-08T15:28:26.000-05:00","level":"INFO","msg":"hello","count":3}
+//
+// SetDefault makes a Logger the default, and also routes the output of the
+// log package's top-level functions, such as log.Printf, through its
+// Handler. NewLogLogger does the same for a new log.Logger, for use with
+// APIs that accept one.
+package slog
+
+import (
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A Logger records structured information about each call to its
+// Log, Debug, Info, Warn, and Error methods.
+// For each call, it creates a Record and passes it to a Handler.
+//
+// To create a new Logger, call New or a Logger method
+// that begins "With".
+type Logger struct {
+	handler Handler // for structured logging
+}
+
+// New creates a new Logger with the given non-nil Handler.
+func New(h Handler) *Logger {
+	if h == nil {
+		panic("slog: nil Handler")
+	}
+	return &Logger{handler: h}
+}
+
+// Handler returns l's Handler.
+func (l *Logger) Handler() Handler { return l.handler }
+
+// With returns a Logger that includes the given attributes
+// in each output operation. Arguments are converted to
+// attributes as if by Logger.Log.
+func (l *Logger) With(args ...interface{}) *Logger {
+	if len(args) == 0 {
+		return l
+	}
+	return &Logger{handler: l.handler.WithAttrs(argsToAttrs(args))}
+}
+
+// Enabled reports whether l emits log records at the given level.
+func (l *Logger) Enabled(level Level) bool {
+	return l.handler.Enabled(level)
+}
+
+// Log emits a log record with the current time and the given level and
+// message.
+//
+// The attribute arguments are processed as follows:
+//   - If an argument is an Attr, it is used as is.
+//   - If an argument is a string and this is not the last argument,
+//     the following argument is treated as the value and the two are
+//     combined into an Attr.
+//   - Otherwise, the argument is treated as a value with key "!BADKEY".
+func (l *Logger) Log(level Level, msg string, args ...interface{}) {
+	l.log(level, msg, args)
+}
+
+// Debug logs at LevelDebug.
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+// Info logs at LevelInfo.
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+// Warn logs at LevelWarn.
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+// Error logs at LevelError.
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+// log is the low-level logging method for methods that take ...interface{}.
+// It must always be called directly by an exported logging method
+// or function, because the default handler relies on the call depth.
+func (l *Logger) log(level Level, msg string, args []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	r := Record{
+		Time:    time.Now(),
+		Message: msg,
+		Level:   level,
+		Attrs:   argsToAttrs(args),
+	}
+	_ = l.handler.Handle(r)
+}
+
+var defaultLogger atomic.Value // *Logger
+
+func init() {
+	defaultLogger.Store(New(new(defaultHandler)))
+}
+
+// Default returns the default Logger.
+func Default() *Logger { return defaultLogger.Load().(*Logger) }
+
+// logOutput records the standard logger's destination and flags while
+// its output is routed to a Handler by SetDefault.
+var logOutput struct {
+	sync.Mutex
+	redirected bool
+	w          io.Writer
+	flags      int
+}
+
+// SetDefault makes l the default Logger.
+//
+// After this call, output from the log package's standard logger (as
+// with log.Print, etc.) will be logged at LevelInfo using l's Handler,
+// and the standard logger's flags are cleared. If l uses the default
+// handler, the standard logger's original output and flags are restored.
+func SetDefault(l *Logger) {
+	defaultLogger.Store(l)
+
+	logOutput.Lock()
+	defer logOutput.Unlock()
+	if _, ok := l.Handler().(*defaultHandler); ok {
+		// The default handler writes to the standard logger, which
+		// must not write back to a handler.
+		if logOutput.redirected {
+			log.SetOutput(logOutput.w)
+			log.SetFlags(logOutput.flags)
+			logOutput.redirected = false
+		}
+		return
+	}
+	if !logOutput.redirected {
+		logOutput.w = log.Writer()
+		logOutput.flags = log.Flags()
+		logOutput.redirected = true
+	}
+	log.SetOutput(&handlerWriter{l.Handler(), LevelInfo})
+	log.SetFlags(0)
+}
+
+// handlerWriter is an io.Writer that calls a Handler.
+// It is used to link the log package to a Handler.
+type handlerWriter struct {
+	h     Handler
+	level Level
+}
+
+func (w *handlerWriter) Write(buf []byte) (int, error) {
+	if !w.h.Enabled(w.level) {
+		return len(buf), nil
+	}
+	// Remove the final newline added by log.Logger.
+	msg := strings.TrimSuffix(string(buf), "\n")
+	r := Record{Time: time.Now(), Message: msg, Level: w.level}
+	return len(buf), w.h.Handle(r)
+}
+
+// NewLogLogger returns a new log.Logger such that each call to its Output
+// method dispatches a Record to the specified handler at the given level.
+// The logger acts as a bridge from the older log API to newer structured
+// logging handlers.
+func NewLogLogger(h Handler, level Level) *log.Logger {
+	return log.New(&handlerWriter{h, level}, "", 0)
+}
+
+// Debug calls Logger.Debug on the default logger.
+func Debug(msg string, args ...interface{}) {
+	Default().log(LevelDebug, msg, args)
+}
+
+// Info calls Logger.Info on the default logger.
+func Info(msg string, args ...interface{}) {
+	Default().log(LevelInfo, msg, args)
+}
+
+// Warn calls Logger.Warn on the default logger.
+func Warn(msg string, args ...interface{}) {
+	Default().log(LevelWarn, msg, args)
+}
+
+// Error calls Logger.Error on the default logger.
+func Error(msg string, args ...interface{}) {
+	Default().log(LevelError, msg, args)
+}
+
+// Log calls Logger.Log on the default logger.
+func Log(level Level, msg string, args ...interface{}) {
+	Default().log(level, msg, args)
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const timeRE = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{3}(Z|[+-]\d{2}:\d{2})`
+
+func checkOutput(t *testing.T, buf *bytes.Buffer, want string) {
+	got := buf.String()
+	buf.Reset()
+	re := "^" + want + "$"
+	if !regexp.MustCompile(re).MatchString(got) {
+		t.Errorf("\ngot  %q\nwant %q", got, re)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(NewTextHandler(&buf, &HandlerOptions{Level: LevelDebug}))
+
+	l.Debug("d", "a", 1)
+	checkOutput(t, &buf, "time="+timeRE+` level=DEBUG msg=d a=1\n`)
+	l.Info("i", "a", 1)
+	checkOutput(t, &buf, "time="+timeRE+` level=INFO msg=i a=1\n`)
+	l.Warn("w", "a", 1)
+	checkOutput(t, &buf, "time="+timeRE+` level=WARN msg=w a=1\n`)
+	l.Error("e", "a", 1)
+	checkOutput(t, &buf, "time="+timeRE+` level=ERROR msg=e a=1\n`)
+	l.Log(LevelInfo+1, "l", String("b", "c"))
+	checkOutput(t, &buf, "time="+timeRE+` level=INFO\+1 msg=l b=c\n`)
+
+	l2 := l.With("x", "y")
+	l2.Info("with")
+	checkOutput(t, &buf, "time="+timeRE+` level=INFO msg=with x=y\n`)
+	l.Info("without")
+	checkOutput(t, &buf, "time="+timeRE+` level=INFO msg=without\n`)
+
+	l = New(NewTextHandler(&buf, &HandlerOptions{Level: LevelWarn}))
+	if l.Enabled(LevelInfo) {
+		t.Error("Enabled(LevelInfo) = true for a logger at LevelWarn")
+	}
+	l.Info("dropped")
+	checkOutput(t, &buf, "")
+}
+
+func TestDefaultLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetOutput(&buf)
+	log.SetFlags(log.Lshortfile)
+
+	Info("msg", "a", 1)
+	checkOutput(t, &buf, `logger_test.go:\d+: INFO msg a=1\n`)
+	Default().Warn("msg", "b", "c d")
+	checkOutput(t, &buf, `logger_test.go:\d+: WARN msg b="c d"\n`)
+	Default().With("e", 2).Error("msg")
+	checkOutput(t, &buf, `logger_test.go:\d+: ERROR msg e=2\n`)
+	Debug("dropped")
+	checkOutput(t, &buf, "")
+}
+
+func TestSetDefault(t *testing.T) {
+	var logBuf, slogBuf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	log.SetOutput(&logBuf)
+	log.SetFlags(log.Ldate)
+
+	orig := Default()
+	defer SetDefault(orig)
+
+	SetDefault(New(NewTextHandler(&slogBuf, nil)))
+	log.Printf("hello, %s", "world")
+	checkOutput(t, &slogBuf, "time="+timeRE+` level=INFO msg="hello, world"\n`)
+	Info("structured", "k", "v")
+	checkOutput(t, &slogBuf, "time="+timeRE+` level=INFO msg=structured k=v\n`)
+	checkOutput(t, &logBuf, "")
+
+	// Setting a second handler must not lose the original log output.
+	SetDefault(New(NewJSONHandler(&slogBuf, nil)))
+	log.Print("json")
+	checkOutput(t, &slogBuf, `\{"time":"`+timeRE+`","level":"INFO","msg":"json"\}\n`)
+
+	// Restoring the original default logger restores log's output and
+	// flags, and slog output goes back to the log package.
+	SetDefault(orig)
+	if got := log.Flags(); got != log.Ldate {
+		t.Errorf("log.Flags() = %d, want %d", got, log.Ldate)
+	}
+	Info("back")
+	checkOutput(t, &logBuf, `\d{4}/\d{2}/\d{2} INFO back\n`)
+	checkOutput(t, &slogBuf, "")
+}
+
+func TestNewLogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf, nil)
+	ll := NewLogLogger(h, LevelWarn)
+	ll.Print("hello")
+	checkOutput(t, &buf, "time="+timeRE+` level=WARN msg=hello\n`)
+
+	ll = NewLogLogger(h, LevelDebug)
+	ll.Print("dropped")
+	checkOutput(t, &buf, "")
+
+	ll = NewLogLogger(New(NewJSONHandler(&buf, nil)).With("a", 1).Handler(), LevelError)
+	ll.Print(strings.Repeat("x", 3))
+	checkOutput(t, &buf, `\{"time":"`+timeRE+`","level":"ERROR","msg":"xxx","a":1\}\n`)
+}
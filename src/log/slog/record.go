@@ -0,0 +1,181 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slog
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// A Level is the importance or severity of a log event.
+// The higher the level, the more important or severe the event.
+//
+// The named levels are spaced apart so that levels between them can be
+// used by applications needing finer distinctions.
+type Level int
+
+// Names for common levels.
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String returns a name for the level. If the level has a name, it is
+// returned in upper case. Levels between named levels are printed as the
+// name of the level below, followed by the difference, as in "INFO+2".
+func (l Level) String() string {
+	str := func(base string, delta Level) string {
+		if delta == 0 {
+			return base
+		}
+		return base + fmt.Sprintf("%+d", int(delta))
+	}
+	switch {
+	case l < LevelInfo:
+		return str("DEBUG", l-LevelDebug)
+	case l < LevelWarn:
+		return str("INFO", l-LevelInfo)
+	case l < LevelError:
+		return str("WARN", l-LevelWarn)
+	default:
+		return str("ERROR", l-LevelError)
+	}
+}
+
+// An Attr is a key-value pair attached to a log record.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// Any returns an Attr for an arbitrary value.
+func Any(key string, value interface{}) Attr {
+	return Attr{key, value}
+}
+
+// String returns an Attr for a string value.
+func String(key, value string) Attr {
+	return Attr{key, value}
+}
+
+// Int returns an Attr for an int value.
+func Int(key string, value int) Attr {
+	return Attr{key, value}
+}
+
+// Int64 returns an Attr for an int64 value.
+func Int64(key string, value int64) Attr {
+	return Attr{key, value}
+}
+
+// Float64 returns an Attr for a float64 value.
+func Float64(key string, value float64) Attr {
+	return Attr{key, value}
+}
+
+// Bool returns an Attr for a bool value.
+func Bool(key string, value bool) Attr {
+	return Attr{key, value}
+}
+
+// Duration returns an Attr for a time.Duration value.
+func Duration(key string, value time.Duration) Attr {
+	return Attr{key, value}
+}
+
+// Time returns an Attr for a time.Time value.
+func Time(key string, value time.Time) Attr {
+	return Attr{key, value}
+}
+
+// String returns the Attr formatted as key=value.
+func (a Attr) String() string {
+	return a.Key + "=" + fmt.Sprint(a.Value)
+}
+
+// badKey is the key used for arguments that are not part of a key-value
+// pair.
+const badKey = "!BADKEY"
+
+// argsToAttrs converts the alternating keys and values in args, which may
+// also contain Attrs, to a list of Attrs.
+//
+// A string followed by a value is treated as a key-value pair. An Attr is
+// used as is. Any other argument, including a string without a following
+// value, is recorded under the key "!BADKEY".
+func argsToAttrs(args []interface{}) []Attr {
+	var attrs []Attr
+	for len(args) > 0 {
+		switch x := args[0].(type) {
+		case string:
+			if len(args) == 1 {
+				attrs = append(attrs, Attr{badKey, x})
+				args = nil
+				continue
+			}
+			attrs = append(attrs, Attr{x, args[1]})
+			args = args[2:]
+		case Attr:
+			attrs = append(attrs, x)
+			args = args[1:]
+		default:
+			attrs = append(attrs, Attr{badKey, x})
+			args = args[1:]
+		}
+	}
+	return attrs
+}
+
+// A Record holds information about a log event.
+// Handlers must not retain or modify the Attrs slice.
+type Record struct {
+	// The time at which the output method (Log, Info, etc.) was called.
+	// Handlers omit the time if it is the zero time.
+	Time time.Time
+
+	// The log message.
+	Message string
+
+	// The level of the event.
+	Level Level
+
+	// The attributes of the event, in the order they were given.
+	Attrs []Attr
+}
+
+// NewRecord creates a Record from the given arguments.
+// The args are interpreted as in Logger.Log.
+func NewRecord(t time.Time, level Level, msg string, args ...interface{}) Record {
+	return Record{
+		Time:    t,
+		Message: msg,
+		Level:   level,
+		Attrs:   argsToAttrs(args),
+	}
+}
+
+// quoteIfNeeded returns s quoted with strconv.Quote if it is empty or
+// contains spaces, quotes, equals signs or non-printable characters.
+func quoteIfNeeded(s string) string {
+	if needsQuoting(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuoting(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\u007f' || !strconv.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
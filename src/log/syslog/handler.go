@@ -0,0 +1,71 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !windows,!nacl,!plan9
+
+package syslog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewHandler returns a slog.Handler that formats records as
+// key=value pairs, like slog.TextHandler, and writes them to w.
+// The record's time is omitted, since the system log service adds
+// its own timestamp. The severity of each message is derived from the
+// record's level: LOG_ERR for slog.LevelError and above, LOG_WARNING for
+// slog.LevelWarn, LOG_INFO for slog.LevelInfo and LOG_DEBUG below that,
+// ignoring the severity passed to New or Dial.
+// If opts is nil, the default options are used.
+func NewHandler(w *Writer, opts *slog.HandlerOptions) slog.Handler {
+	h := &handler{w: w, mu: new(sync.Mutex), buf: new(bytes.Buffer)}
+	h.text = slog.NewTextHandler(h.buf, opts)
+	return h
+}
+
+type handler struct {
+	w *Writer
+
+	mu   *sync.Mutex // guards buf, which text writes to
+	buf  *bytes.Buffer
+	text slog.Handler
+}
+
+func (h *handler) Enabled(level slog.Level) bool {
+	return h.text.Enabled(level)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.text = h.text.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *handler) Handle(r slog.Record) error {
+	r.Time = time.Time{}
+
+	h.mu.Lock()
+	h.buf.Reset()
+	err := h.text.Handle(r)
+	msg := strings.TrimSuffix(h.buf.String(), "\n")
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case r.Level >= slog.LevelError:
+		return h.w.Err(msg)
+	case r.Level >= slog.LevelWarn:
+		return h.w.Warning(msg)
+	case r.Level >= slog.LevelInfo:
+		return h.w.Info(msg)
+	default:
+		return h.w.Debug(msg)
+	}
+}
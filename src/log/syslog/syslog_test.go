@@ -12,9 +12,11 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"net"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -302,6 +304,39 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		pri   Priority
+	}{
+		{slog.LevelDebug, LOG_USER | LOG_DEBUG},
+		{slog.LevelInfo, LOG_USER | LOG_INFO},
+		{slog.LevelWarn, LOG_USER | LOG_WARNING},
+		{slog.LevelError, LOG_USER | LOG_ERR},
+		{slog.LevelError + 4, LOG_USER | LOG_ERR},
+	}
+
+	for _, test := range tests {
+		done := make(chan string)
+		addr, sock, srvWG := startServer("udp", "", done)
+		defer srvWG.Wait()
+		defer sock.Close()
+		w, err := Dial("udp", addr, LOG_USER|LOG_EMERG, "syslog_test")
+		if err != nil {
+			t.Fatalf("syslog.Dial() failed: %v", err)
+		}
+		defer w.Close()
+		l := slog.New(NewHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})).With("a", 1)
+		l.Log(test.level, "handler test", "b", "c d")
+		rcvd := <-done
+		prefix := fmt.Sprintf("<%d>", test.pri)
+		suffix := fmt.Sprintf(" syslog_test[%d]: level=%v msg=\"handler test\" a=1 b=\"c d\"\n", os.Getpid(), test.level)
+		if !strings.HasPrefix(rcvd, prefix) || !strings.HasSuffix(rcvd, suffix) {
+			t.Errorf("got %q, want prefix %q and suffix %q", rcvd, prefix, suffix)
+		}
+	}
+}
+
 func TestConcurrentWrite(t *testing.T) {
 	addr, sock, srvWG := startServer("udp", "", make(chan string, 1))
 	defer srvWG.Wait()
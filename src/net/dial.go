@@ -5,7 +5,9 @@
 package net
 
 import (
+	"context"
 	"errors"
+	"internal/nettrace"
 	"time"
 )
 
@@ -61,6 +63,8 @@ type Dialer struct {
 	// Cancel is an optional channel whose closure indicates that
 	// the dial should be canceled. Not all types of dials support
 	// cancelation.
+	//
+	// Deprecated: Use DialContext instead.
 	Cancel <-chan struct{}
 }
 
@@ -139,7 +143,7 @@ func parseNetwork(net string) (afnet string, proto int, err error) {
 	return "", 0, UnknownNetworkError(net)
 }
 
-func resolveAddrList(op, net, addr string, deadline time.Time) (addrList, error) {
+func resolveAddrList(ctx context.Context, op, net, addr string, deadline time.Time) (addrList, error) {
 	afnet, _, err := parseNetwork(net)
 	if err != nil {
 		return nil, err
@@ -155,7 +159,7 @@ func resolveAddrList(op, net, addr string, deadline time.Time) (addrList, error)
 		}
 		return addrList{addr}, nil
 	}
-	return internetAddrList(afnet, addr, deadline)
+	return internetAddrList(ctx, afnet, addr, deadline)
 }
 
 // Dial connects to the address on the named network.
@@ -200,11 +204,12 @@ func DialTimeout(network, address string, timeout time.Duration) (Conn, error) {
 	return d.Dial(network, address)
 }
 
-// dialContext holds common state for all dial operations.
-type dialContext struct {
+// dialParam holds common state for all dial operations.
+type dialParam struct {
 	Dialer
 	network, address string
 	finalDeadline    time.Time
+	trace            *nettrace.Trace // from the DialContext context; may be nil
 }
 
 // Dial connects to the address on the named network.
@@ -212,18 +217,65 @@ type dialContext struct {
 // See func Dial for a description of the network and address
 // parameters.
 func (d *Dialer) Dial(network, address string) (Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to the address on the named network using
+// the provided context.
+//
+// The provided Context must be non-nil. If the context expires before
+// the connection is complete, an error is returned. Once successfully
+// connected, any expiration of the context will not affect the
+// connection.
+//
+// The context's deadline, if any, is combined with the Dialer's
+// Timeout and Deadline; the earliest of them applies.
+//
+// See func Dial for a description of the network and address
+// parameters.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (Conn, error) {
+	if ctx == nil {
+		panic("nil context")
+	}
 	finalDeadline := d.deadline(time.Now())
-	addrs, err := resolveAddrList("dial", network, address, finalDeadline)
+	if deadline, ok := ctx.Deadline(); ok && (finalDeadline.IsZero() || deadline.Before(finalDeadline)) {
+		finalDeadline = deadline
+	}
+	select {
+	case <-ctx.Done():
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: mapErr(ctx.Err())}
+	default:
+	}
+
+	addrs, err := resolveAddrList(ctx, "dial", network, address, finalDeadline)
 	if err != nil {
-		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: err}
+		return nil, &OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: mapErr(err)}
 	}
 
-	ctx := &dialContext{
+	dp := &dialParam{
 		Dialer:        *d,
 		network:       network,
 		address:       address,
 		finalDeadline: finalDeadline,
 	}
+	dp.trace, _ = ctx.Value(nettrace.TraceKey{}).(*nettrace.Trace)
+	if done := ctx.Done(); done != nil {
+		// Merge the context's cancelation with the Dialer's
+		// legacy Cancel channel, if any.
+		cancel := make(chan struct{})
+		stop := make(chan struct{})
+		defer close(stop)
+		go func(legacy <-chan struct{}) {
+			select {
+			case <-done:
+			case <-legacy:
+			case <-stop:
+				return
+			}
+			close(cancel)
+		}(d.Cancel)
+		dp.Cancel = cancel
+	}
 
 	var primaries, fallbacks addrList
 	if d.DualStack && network == "tcp" {
@@ -236,36 +288,60 @@ func (d *Dialer) Dial(network, address string) (Conn, error) {
 	if len(fallbacks) == 0 {
 		// dialParallel can accept an empty fallbacks list,
 		// but this shortcut avoids the goroutine/channel overhead.
-		c, err = dialSerial(ctx, primaries, nil)
+		c, err = dialSerial(dp, primaries, nil)
 	} else {
-		c, err = dialParallel(ctx, primaries, fallbacks)
+		c, err = dialParallel(dp, primaries, fallbacks)
 	}
 
-	if d.KeepAlive > 0 && err == nil {
+	if err != nil {
+		select {
+		case <-ctx.Done():
+			if oe, ok := err.(*OpError); ok {
+				oe.Err = mapErr(ctx.Err())
+			}
+		default:
+		}
+		return nil, err
+	}
+
+	if d.KeepAlive > 0 {
 		if tc, ok := c.(*TCPConn); ok {
 			setKeepAlive(tc.fd, true)
 			setKeepAlivePeriod(tc.fd, d.KeepAlive)
 			testHookSetKeepAlive()
 		}
 	}
-	return c, err
+	return c, nil
+}
+
+// mapErr maps from the context errors to the historical internal net
+// error values.
+func mapErr(err error) error {
+	switch err {
+	case context.Canceled:
+		return errCanceled
+	case context.DeadlineExceeded:
+		return errTimeout
+	default:
+		return err
+	}
 }
 
 // dialParallel races two copies of dialSerial, giving the first a
 // head start. It returns the first established connection and
 // closes the others. Otherwise it returns an error from the first
 // primary address.
-func dialParallel(ctx *dialContext, primaries, fallbacks addrList) (Conn, error) {
+func dialParallel(dp *dialParam, primaries, fallbacks addrList) (Conn, error) {
 	results := make(chan dialResult) // unbuffered, so dialSerialAsync can detect race loss & cleanup
 	cancel := make(chan struct{})
 	defer close(cancel)
 
 	// Spawn the primary racer.
-	go dialSerialAsync(ctx, primaries, nil, cancel, results)
+	go dialSerialAsync(dp, primaries, nil, cancel, results)
 
 	// Spawn the fallback racer.
-	fallbackTimer := time.NewTimer(ctx.fallbackDelay())
-	go dialSerialAsync(ctx, fallbacks, fallbackTimer, cancel, results)
+	fallbackTimer := time.NewTimer(dp.fallbackDelay())
+	go dialSerialAsync(dp, fallbacks, fallbackTimer, cancel, results)
 
 	var primaryErr error
 	for nracers := 2; nracers > 0; nracers-- {
@@ -294,7 +370,7 @@ type dialResult struct {
 // dialSerialAsync runs dialSerial after some delay, and returns the
 // resulting connection through a channel. When racing two connections,
 // the primary goroutine uses a nil timer to omit the delay.
-func dialSerialAsync(ctx *dialContext, ras addrList, timer *time.Timer, cancel <-chan struct{}, results chan<- dialResult) {
+func dialSerialAsync(dp *dialParam, ras addrList, timer *time.Timer, cancel <-chan struct{}, results chan<- dialResult) {
 	if timer != nil {
 		// We're in the fallback goroutine; sleep before connecting.
 		select {
@@ -303,7 +379,7 @@ func dialSerialAsync(ctx *dialContext, ras addrList, timer *time.Timer, cancel <
 			return
 		}
 	}
-	c, err := dialSerial(ctx, ras, cancel)
+	c, err := dialSerial(dp, ras, cancel)
 	select {
 	case results <- dialResult{c, err, timer == nil}:
 		// We won the race.
@@ -317,21 +393,23 @@ func dialSerialAsync(ctx *dialContext, ras addrList, timer *time.Timer, cancel <
 
 // dialSerial connects to a list of addresses in sequence, returning
 // either the first successful connection, or the first error.
-func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, error) {
+func dialSerial(dp *dialParam, ras addrList, cancel <-chan struct{}) (Conn, error) {
 	var firstErr error // The error from the first address is most relevant.
 
 	for i, ra := range ras {
 		select {
 		case <-cancel:
-			return nil, &OpError{Op: "dial", Net: ctx.network, Source: ctx.LocalAddr, Addr: ra, Err: errCanceled}
+			return nil, &OpError{Op: "dial", Net: dp.network, Source: dp.LocalAddr, Addr: ra, Err: errCanceled}
+		case <-dp.Cancel:
+			return nil, &OpError{Op: "dial", Net: dp.network, Source: dp.LocalAddr, Addr: ra, Err: errCanceled}
 		default:
 		}
 
-		partialDeadline, err := partialDeadline(time.Now(), ctx.finalDeadline, len(ras)-i)
+		partialDeadline, err := partialDeadline(time.Now(), dp.finalDeadline, len(ras)-i)
 		if err != nil {
 			// Ran out of time.
 			if firstErr == nil {
-				firstErr = &OpError{Op: "dial", Net: ctx.network, Source: ctx.LocalAddr, Addr: ra, Err: err}
+				firstErr = &OpError{Op: "dial", Net: dp.network, Source: dp.LocalAddr, Addr: ra, Err: err}
 			}
 			break
 		}
@@ -340,9 +418,9 @@ func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, e
 		// so if cancel fires, we'll continue trying to connect until the next
 		// timeout, or return a spurious connection for the caller to close.
 		dialer := func(d time.Time) (Conn, error) {
-			return dialSingle(ctx, ra, d)
+			return dialSingle(dp, ra, d)
 		}
-		c, err := dial(ctx.network, ra, dialer, partialDeadline)
+		c, err := dial(dp.network, ra, dialer, partialDeadline)
 		if err == nil {
 			return c, nil
 		}
@@ -352,7 +430,7 @@ func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, e
 	}
 
 	if firstErr == nil {
-		firstErr = &OpError{Op: "dial", Net: ctx.network, Source: nil, Addr: nil, Err: errMissingAddress}
+		firstErr = &OpError{Op: "dial", Net: dp.network, Source: nil, Addr: nil, Err: errMissingAddress}
 	}
 	return nil, firstErr
 }
@@ -360,26 +438,35 @@ func dialSerial(ctx *dialContext, ras addrList, cancel <-chan struct{}) (Conn, e
 // dialSingle attempts to establish and returns a single connection to
 // the destination address. This must be called through the OS-specific
 // dial function, because some OSes don't implement the deadline feature.
-func dialSingle(ctx *dialContext, ra Addr, deadline time.Time) (c Conn, err error) {
-	la := ctx.LocalAddr
+func dialSingle(dp *dialParam, ra Addr, deadline time.Time) (c Conn, err error) {
+	if trace := dp.trace; trace != nil {
+		raStr := ra.String()
+		if trace.ConnectStart != nil {
+			trace.ConnectStart(dp.network, raStr)
+		}
+		if trace.ConnectDone != nil {
+			defer func() { trace.ConnectDone(dp.network, raStr, err) }()
+		}
+	}
+	la := dp.LocalAddr
 	if la != nil && la.Network() != ra.Network() {
-		return nil, &OpError{Op: "dial", Net: ctx.network, Source: la, Addr: ra, Err: errors.New("mismatched local address type " + la.Network())}
+		return nil, &OpError{Op: "dial", Net: dp.network, Source: la, Addr: ra, Err: errors.New("mismatched local address type " + la.Network())}
 	}
 	switch ra := ra.(type) {
 	case *TCPAddr:
 		la, _ := la.(*TCPAddr)
-		c, err = testHookDialTCP(ctx.network, la, ra, deadline, ctx.Cancel)
+		c, err = testHookDialTCP(dp.network, la, ra, deadline, dp.Cancel)
 	case *UDPAddr:
 		la, _ := la.(*UDPAddr)
-		c, err = dialUDP(ctx.network, la, ra, deadline)
+		c, err = dialUDP(dp.network, la, ra, deadline)
 	case *IPAddr:
 		la, _ := la.(*IPAddr)
-		c, err = dialIP(ctx.network, la, ra, deadline)
+		c, err = dialIP(dp.network, la, ra, deadline)
 	case *UnixAddr:
 		la, _ := la.(*UnixAddr)
-		c, err = dialUnix(ctx.network, la, ra, deadline)
+		c, err = dialUnix(dp.network, la, ra, deadline)
 	default:
-		return nil, &OpError{Op: "dial", Net: ctx.network, Source: la, Addr: ra, Err: &AddrError{Err: "unexpected address type", Addr: ctx.address}}
+		return nil, &OpError{Op: "dial", Net: dp.network, Source: la, Addr: ra, Err: &AddrError{Err: "unexpected address type", Addr: dp.address}}
 	}
 	if err != nil {
 		return nil, err // c is non-nil interface containing nil pointer
@@ -395,7 +482,7 @@ func dialSingle(ctx *dialContext, ra Addr, deadline time.Time) (c Conn, err erro
 // instead of just the interface with the given host address.
 // See Dial for more details about address syntax.
 func Listen(net, laddr string) (Listener, error) {
-	addrs, err := resolveAddrList("listen", net, laddr, noDeadline)
+	addrs, err := resolveAddrList(context.Background(), "listen", net, laddr, noDeadline)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
 	}
@@ -422,7 +509,7 @@ func Listen(net, laddr string) (Listener, error) {
 // instead of just the interface with the given host address.
 // See Dial for the syntax of laddr.
 func ListenPacket(net, laddr string) (PacketConn, error) {
-	addrs, err := resolveAddrList("listen", net, laddr, noDeadline)
+	addrs, err := resolveAddrList(context.Background(), "listen", net, laddr, noDeadline)
 	if err != nil {
 		return nil, &OpError{Op: "listen", Net: net, Source: nil, Addr: nil, Err: err}
 	}
@@ -5,6 +5,7 @@
 package net
 
 import (
+	"context"
 	"internal/testenv"
 	"io"
 	"net/internal/socktest"
@@ -391,14 +392,14 @@ func TestDialParallel(t *testing.T) {
 			FallbackDelay: fallbackDelay,
 			Timeout:       slowTimeout,
 		}
-		ctx := &dialContext{
+		dp := &dialParam{
 			Dialer:        d,
 			network:       "tcp",
 			address:       "?",
 			finalDeadline: d.deadline(time.Now()),
 		}
 		startTime := time.Now()
-		c, err := dialParallel(ctx, primaries, fallbacks)
+		c, err := dialParallel(dp, primaries, fallbacks)
 		elapsed := time.Now().Sub(startTime)
 
 		if c != nil {
@@ -520,7 +521,7 @@ func TestDialSerialAsyncSpuriousConnection(t *testing.T) {
 	defer ln.Close()
 
 	d := Dialer{}
-	ctx := &dialContext{
+	dp := &dialParam{
 		Dialer:        d,
 		network:       "tcp",
 		address:       "?",
@@ -531,7 +532,7 @@ func TestDialSerialAsyncSpuriousConnection(t *testing.T) {
 	cancel := make(chan struct{})
 
 	// Spawn a connection in the background.
-	go dialSerialAsync(ctx, addrList{ln.Addr()}, nil, cancel, results)
+	go dialSerialAsync(dp, addrList{ln.Addr()}, nil, cancel, results)
 
 	// Receive it at the server.
 	c, err := ln.Accept()
@@ -781,3 +782,89 @@ func TestDialCancel(t *testing.T) {
 		}
 	}
 }
+
+func TestDialContextCanceled(t *testing.T) {
+	ln, err := newLocalListener("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var d Dialer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c, err := d.DialContext(ctx, "tcp", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("unexpected successful connection")
+	}
+	if perr := parseDialError(err); perr != nil {
+		t.Error(perr)
+	}
+	if oe, ok := err.(*OpError); !ok || oe.Err != errCanceled {
+		t.Fatalf("dial error = %v (%T); want OpError with Err == errCanceled", err, err)
+	}
+}
+
+func TestDialContextCanceledDuringLookup(t *testing.T) {
+	origTestHookLookupIP := testHookLookupIP
+	defer func() { testHookLookupIP = origTestHookLookupIP }()
+	unblock := make(chan struct{})
+	defer close(unblock)
+	inLookup := make(chan bool, 1)
+	testHookLookupIP = func(fn func(string) ([]IPAddr, error), host string) ([]IPAddr, error) {
+		inLookup <- true
+		<-unblock
+		return nil, errNoSuchHost
+	}
+
+	var d Dialer
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-inLookup
+		cancel()
+	}()
+	c, err := d.DialContext(ctx, "tcp", "canceled.invalid:80")
+	if err == nil {
+		c.Close()
+		t.Fatal("unexpected successful connection")
+	}
+	if perr := parseDialError(err); perr != nil {
+		t.Error(perr)
+	}
+	if oe, ok := err.(*OpError); !ok || oe.Err != errCanceled {
+		t.Fatalf("dial error = %v (%T); want OpError with Err == errCanceled", err, err)
+	}
+}
+
+func TestDialContextDeadline(t *testing.T) {
+	ln, err := newLocalListener("tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	var d Dialer
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	c, err := d.DialContext(ctx, "tcp", ln.Addr().String())
+	if err == nil {
+		c.Close()
+		t.Fatal("unexpected successful connection")
+	}
+	if perr := parseDialError(err); perr != nil {
+		t.Error(perr)
+	}
+	if nerr, ok := err.(Error); !ok || !nerr.Timeout() {
+		t.Fatalf("dial error = %v (%T); want timeout", err, err)
+	}
+
+	// A live context dials as usual.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err = d.DialContext(ctx, "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
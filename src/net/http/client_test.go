@@ -752,6 +752,7 @@ func TestResponseSetsTLSConnectionState(t *testing.T) {
 
 	tr := newTLSTransport(t, ts)
 	tr.TLSClientConfig.CipherSuites = []uint16{tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA}
+	tr.TLSClientConfig.MaxVersion = tls.VersionTLS12 // the cipher suite is not configurable in TLS 1.3
 	tr.Dial = func(netw, addr string) (net.Conn, error) {
 		return net.Dial(netw, ts.Listener.Addr().String())
 	}
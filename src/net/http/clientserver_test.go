@@ -9,6 +9,7 @@ package http_test
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
@@ -657,6 +658,147 @@ func testTrailersServerToClient(t *testing.T, h2, flush bool) {
 	}
 }
 
+// Tests that Shutdown drains idle connections, including HTTP/2
+// connections, which it shuts down with a graceful GOAWAY.
+func TestServerShutdownIdle_h1(t *testing.T) { testServerShutdownIdle(t, h1Mode) }
+func TestServerShutdownIdle_h2(t *testing.T) { testServerShutdownIdle(t, h2Mode) }
+
+func testServerShutdownIdle(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wantBody(res, nil, "ok"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := cst.ts.Config.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+}
+
+// Tests that trailers set with TrailerPrefix after the header is
+// written are sent, without being declared in the Trailer header.
+func TestTrailerPrefix_h1(t *testing.T) { testTrailerPrefix(t, h1Mode) }
+func TestTrailerPrefix_h2(t *testing.T) { testTrailerPrefix(t, h2Mode) }
+
+func testTrailerPrefix(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	const body = "Some body"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set("Trailer", "Server-Trailer-A")
+		io.WriteString(w, body)
+		w.(Flusher).Flush()
+
+		w.Header().Set("Server-Trailer-A", "valuea")
+		w.Header().Set(TrailerPrefix+"Server-Trailer-B", "valueb")
+		w.Header().Set("Server-Trailer-C", "should be omitted")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h2 && !reflect.DeepEqual(res.TransferEncoding, []string{"chunked"}) {
+		t.Errorf("TransferEncoding = %q; want chunked", res.TransferEncoding)
+	}
+	if err := wantBody(res, nil, body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Trailer, (Header{
+		"Server-Trailer-A": {"valuea"},
+		"Server-Trailer-B": {"valueb"},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Trailer after body read = %v; want %v", got, want)
+	}
+}
+
+// Tests TrailerPrefix with no declared trailers and no Flush, so the
+// server must still choose framing that can carry trailers, and with
+// a prefixed key set before the header is written, which must not
+// appear in the header.
+func TestTrailerPrefixUndeclared_h1(t *testing.T) { testTrailerPrefixUndeclared(t, h1Mode) }
+func TestTrailerPrefixUndeclared_h2(t *testing.T) { testTrailerPrefixUndeclared(t, h2Mode) }
+
+func testTrailerPrefixUndeclared(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	const body = "Some body"
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		w.Header().Set(TrailerPrefix+"X-Before", "b")
+		io.WriteString(w, body)
+		w.Header().Set(TrailerPrefix+"X-After", "a")
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range res.Header {
+		if strings.HasPrefix(k, TrailerPrefix) || k == "X-Before" {
+			t.Errorf("unexpected header %q = %q", k, res.Header[k])
+		}
+	}
+	if !h2 && !reflect.DeepEqual(res.TransferEncoding, []string{"chunked"}) {
+		t.Errorf("TransferEncoding = %q; want chunked", res.TransferEncoding)
+	}
+	if err := wantBody(res, nil, body); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := res.Trailer, (Header{
+		"X-Before": {"b"},
+		"X-After":  {"a"},
+	}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Trailer after body read = %v; want %v", got, want)
+	}
+}
+
+// Tests that HTTP/1 ResponseWriters don't implement Pusher, and that
+// Push over HTTP/2 reports ErrNotSupported when the client has
+// disabled push, as Transport does.
+func TestPushNotSupported_h1(t *testing.T) { testPushNotSupported(t, h1Mode) }
+func TestPushNotSupported_h2(t *testing.T) { testPushNotSupported(t, h2Mode) }
+
+func testPushNotSupported(t *testing.T, h2 bool) {
+	defer afterTest(t)
+	errc := make(chan error, 1)
+	cst := newClientServerTest(t, h2, HandlerFunc(func(w ResponseWriter, r *Request) {
+		p, ok := w.(Pusher)
+		if ok != h2 {
+			errc <- fmt.Errorf("ResponseWriter implements Pusher = %v; want %v", ok, h2)
+			return
+		}
+		if !ok {
+			errc <- nil
+			return
+		}
+		if err := p.Push("/style.css", nil); err != ErrNotSupported {
+			errc <- fmt.Errorf("Push = %v; want ErrNotSupported", err)
+			return
+		}
+		errc <- nil
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if err := <-errc; err != nil {
+		t.Error(err)
+	}
+}
+
 // Don't allow a Body.Read after Body.Close. Issue 13648.
 func TestResponseBodyReadAfterClose_h1(t *testing.T) { testResponseBodyReadAfterClose(t, h1Mode) }
 func TestResponseBodyReadAfterClose_h2(t *testing.T) { testResponseBodyReadAfterClose(t, h2Mode) }
@@ -1054,3 +1196,75 @@ func (x noteCloseConn) Close() error {
 	x.closeFunc()
 	return x.Conn.Close()
 }
+
+// Tests that HTTP/2 request contexts carry the same values as
+// HTTP/1 ones.
+func TestServerContext_ServerContextKey_h2(t *testing.T) {
+	defer afterTest(t)
+	srvc := make(chan interface{}, 1)
+	cst := newClientServerTest(t, h2Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctx := r.Context()
+		srvc <- ctx.Value(ServerContextKey)
+		if got, ok := ctx.Value(LocalAddrContextKey).(net.Addr); !ok {
+			t.Errorf("local addr value = %T; want net.Addr", ctx.Value(LocalAddrContextKey))
+		} else if got.String() != r.Host {
+			t.Errorf("local addr = %v; want %v", got, r.Host)
+		}
+	}))
+	defer cst.close()
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if got := <-srvc; got != cst.ts.Config {
+		t.Errorf("server value = %v; want %v", got, cst.ts.Config)
+	}
+}
+
+// Tests that an HTTP/2 request's context is canceled when the client
+// resets the stream, and once the handler returns.
+func TestServerRequestContextCancel_h2(t *testing.T) {
+	defer afterTest(t)
+	inHandler := make(chan bool)
+	ctxc := make(chan context.Context, 2)
+	cst := newClientServerTest(t, h2Mode, HandlerFunc(func(w ResponseWriter, r *Request) {
+		ctxc <- r.Context()
+		if r.URL.Path != "/block" {
+			return
+		}
+		w.(Flusher).Flush()
+		inHandler <- true
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer cst.close()
+
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	select {
+	case <-(<-ctxc).Done():
+	case <-time.After(5 * time.Second):
+		t.Error("context not canceled after handler returned")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequest("GET", cst.ts.URL+"/block", nil)
+	res, err = cst.c.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-inHandler
+	cancel()
+	res.Body.Close()
+	select {
+	case <-(<-ctxc).Done():
+	case <-time.After(5 * time.Second):
+		t.Error("context not canceled after stream reset")
+	}
+}
@@ -56,6 +56,22 @@ var writeSetCookiesTests = []struct {
 		&Cookie{Name: "cookie-9", Value: "expiring", Expires: time.Unix(1257894000, 0)},
 		"cookie-9=expiring; Expires=Tue, 10 Nov 2009 23:00:00 GMT",
 	},
+	{
+		&Cookie{Name: "cookie-10", Value: "samesite-default", SameSite: SameSiteDefaultMode},
+		"cookie-10=samesite-default",
+	},
+	{
+		&Cookie{Name: "cookie-11", Value: "samesite-lax", SameSite: SameSiteLaxMode},
+		"cookie-11=samesite-lax; SameSite=Lax",
+	},
+	{
+		&Cookie{Name: "cookie-12", Value: "samesite-strict", SameSite: SameSiteStrictMode},
+		"cookie-12=samesite-strict; SameSite=Strict",
+	},
+	{
+		&Cookie{Name: "cookie-13", Value: "samesite-none", Secure: true, SameSite: SameSiteNoneMode, Partitioned: true},
+		"cookie-13=samesite-none; Secure; SameSite=None; Partitioned",
+	},
 	// The "special" cookies have values containing commas or spaces which
 	// are disallowed by RFC 6265 but are common in the wild.
 	{
@@ -232,6 +248,44 @@ var readSetCookiesTests = []struct {
 			Raw:      "ASP.NET_SessionId=foo; path=/; HttpOnly",
 		}},
 	},
+	{
+		Header{"Set-Cookie": {"samesitedefault=foo; SameSite"}},
+		[]*Cookie{{
+			Name:     "samesitedefault",
+			Value:    "foo",
+			SameSite: SameSiteDefaultMode,
+			Raw:      "samesitedefault=foo; SameSite",
+		}},
+	},
+	{
+		Header{"Set-Cookie": {"samesitelax=foo; SameSite=lax"}},
+		[]*Cookie{{
+			Name:     "samesitelax",
+			Value:    "foo",
+			SameSite: SameSiteLaxMode,
+			Raw:      "samesitelax=foo; SameSite=lax",
+		}},
+	},
+	{
+		Header{"Set-Cookie": {"samesitestrict=foo; SameSite=Strict"}},
+		[]*Cookie{{
+			Name:     "samesitestrict",
+			Value:    "foo",
+			SameSite: SameSiteStrictMode,
+			Raw:      "samesitestrict=foo; SameSite=Strict",
+		}},
+	},
+	{
+		Header{"Set-Cookie": {"partitioned=foo; Secure; SameSite=None; Partitioned"}},
+		[]*Cookie{{
+			Name:        "partitioned",
+			Value:       "foo",
+			Secure:      true,
+			SameSite:    SameSiteNoneMode,
+			Partitioned: true,
+			Raw:         "partitioned=foo; Secure; SameSite=None; Partitioned",
+		}},
+	},
 	// Make sure we can properly read back the Set-Cookie headers we create
 	// for values containing spaces or commas:
 	{
@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar.
+// Package cookiejar implements an in-memory RFC 6265-compliant http.CookieJar
+// that can optionally be saved to and loaded from a file.
 package cookiejar
 
 import (
@@ -54,11 +55,21 @@ type Options struct {
 	// secure: it means that the HTTP server for foo.co.uk can set a cookie
 	// for bar.co.uk.
 	PublicSuffixList PublicSuffixList
+
+	// Filename names the file in which the jar's cookies are
+	// persisted. If it is non-empty, New loads any cookies stored
+	// in the file, and Save writes the jar's cookies back to it.
+	// A missing file is treated as an empty jar.
+	//
+	// Only persistent cookies, those with an Expires or Max-Age
+	// attribute, are saved; session cookies are not.
+	Filename string
 }
 
 // Jar implements the http.CookieJar interface from the net/http package.
 type Jar struct {
-	psList PublicSuffixList
+	psList   PublicSuffixList
+	filename string
 
 	// mu locks the remaining fields.
 	mu sync.Mutex
@@ -74,12 +85,21 @@ type Jar struct {
 
 // New returns a new cookie jar. A nil *Options is equivalent to a zero
 // Options.
+//
+// If o.Filename is set, New loads the cookies stored in that file and
+// returns an error if the file exists but cannot be read.
 func New(o *Options) (*Jar, error) {
 	jar := &Jar{
 		entries: make(map[string]map[string]entry),
 	}
 	if o != nil {
 		jar.psList = o.PublicSuffixList
+		jar.filename = o.Filename
+	}
+	if jar.filename != "" {
+		if err := jar.load(time.Now()); err != nil {
+			return nil, err
+		}
 	}
 	return jar, nil
 }
@@ -0,0 +1,133 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var errNoFilename = errors.New("cookiejar: jar has no Filename")
+
+// Save writes the jar's unexpired persistent cookies to the file named
+// by Options.Filename, replacing its contents. The file is replaced
+// atomically where the operating system allows it, and is readable
+// only by its owner, as cookies often hold credentials.
+//
+// Save does not merge cookies saved by other Jars using the same file;
+// the last Save wins.
+func (j *Jar) Save() error {
+	return j.save(time.Now())
+}
+
+// save is like Save but takes the current time as a parameter.
+func (j *Jar) save(now time.Time) error {
+	if j.filename == "" {
+		return errNoFilename
+	}
+
+	j.mu.Lock()
+	var entries []entry
+	for _, submap := range j.entries {
+		for _, e := range submap {
+			if e.Persistent && e.Expires.After(now) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	j.mu.Unlock()
+
+	// Save in creation order so that load assigns sequence numbers
+	// that keep the order of Cookies stable across a reload.
+	sort.Sort(byCreation(entries))
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(j.filename, data)
+}
+
+// load adds the unexpired cookies stored in the jar's file to j.
+// A missing file is not an error.
+func (j *Jar) load(now time.Time) error {
+	data, err := ioutil.ReadFile(j.filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("cookiejar: cannot load cookies from %s: %v", j.filename, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, e := range entries {
+		if !e.Persistent || !e.Expires.After(now) {
+			continue
+		}
+		if e.Name == "" || e.Domain == "" || e.Path == "" || e.Path[0] != '/' {
+			// Not written by Save; ignore rather than
+			// guess what was meant.
+			continue
+		}
+		// The key depends on the public suffix list, which may
+		// have changed since the file was written, so recompute it.
+		key := jarKey(e.Domain, j.psList)
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		e.seqNum = j.nextSeqNum
+		j.nextSeqNum++
+		submap[e.id()] = e
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same
+// directory as filename and renames it over filename.
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// byCreation is a []entry sort.Interface that sorts by creation time
+// and then by sequence number.
+type byCreation []entry
+
+func (s byCreation) Len() int { return len(s) }
+
+func (s byCreation) Less(i, j int) bool {
+	if !s[i].Creation.Equal(s[j].Creation) {
+		return s[i].Creation.Before(s[j].Creation)
+	}
+	return s[i].seqNum < s[j].seqNum
+}
+
+func (s byCreation) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,140 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cookieString returns the cookies as a space separated list of
+// name=value pairs, in order.
+func cookieString(cookies []*http.Cookie) string {
+	var cs []string
+	for _, c := range cookies {
+		cs = append(cs, c.Name+"="+c.Value)
+	}
+	return strings.Join(cs, " ")
+}
+
+func newPersistTestJar(t *testing.T, filename string) *Jar {
+	jar, err := New(&Options{PublicSuffixList: testPSL{}, Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jar
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cookies")
+
+	// A missing file is an empty jar.
+	jar := newPersistTestJar(t, filename)
+	jar.SetCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{
+		{Name: "a", Value: "1", Domain: "host.test", MaxAge: 3600},
+		{Name: "session", Value: "2"},
+		{Name: "c", Value: "3", Path: "/foo", MaxAge: 3600},
+	})
+	jar.SetCookies(mustParseURL("http://www.bücher.test/"), []*http.Cookie{
+		{Name: "d", Value: "4", MaxAge: 3600},
+	})
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("file permissions = %v; want 0600", perm)
+		}
+	}
+
+	jar = newPersistTestJar(t, filename)
+	for _, tt := range []struct {
+		url, want string
+	}{
+		{"http://www.host.test/foo/bar", "c=3 a=1"},
+		{"http://other.host.test/", "a=1"},
+		{"http://www.xn--bcher-kva.test/", "d=4"},
+		{"http://www.bücher.test/", "d=4"},
+	} {
+		if got := cookieString(jar.Cookies(mustParseURL(tt.url))); got != tt.want {
+			t.Errorf("Cookies(%q) after load = %q; want %q", tt.url, got, tt.want)
+		}
+	}
+
+	// Saving again, after removing a cookie, replaces the file.
+	jar.SetCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{
+		{Name: "a", Domain: "host.test", MaxAge: -1},
+	})
+	if err := jar.Save(); err != nil {
+		t.Fatal(err)
+	}
+	jar = newPersistTestJar(t, filename)
+	if got, want := cookieString(jar.Cookies(mustParseURL("http://www.host.test/foo"))), "c=3"; got != want {
+		t.Errorf("Cookies after second load = %q; want %q", got, want)
+	}
+}
+
+func TestLoadDropsExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cookies")
+
+	jar := newPersistTestJar(t, filename)
+	u := mustParseURL("http://www.host.test/")
+	jar.setCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", MaxAge: 60},
+		{Name: "long", Value: "2", MaxAge: 3600},
+	}, tNow)
+	if err := jar.save(tNow); err != nil {
+		t.Fatal(err)
+	}
+
+	jar = newTestJar()
+	jar.filename = filename
+	later := tNow.Add(30 * time.Minute)
+	if err := jar.load(later); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cookieString(jar.cookies(u, later)), "long=2"; got != want {
+		t.Errorf("cookies = %q; want %q", got, want)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookiejar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cookies")
+	if err := ioutil.WriteFile(filename, []byte("not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(&Options{Filename: filename}); err == nil {
+		t.Errorf("New with corrupt file succeeded")
+	}
+
+	if err := newTestJar().Save(); err != errNoFilename {
+		t.Errorf("Save without Filename = %v; want %v", err, errNoFilename)
+	}
+}
@@ -32,6 +32,9 @@ func init() {
 	// When not under test, these values are always nil
 	// and never assigned to.
 	testHookMu = new(sync.Mutex)
+
+	// Poll for quiescence more often to speed up Shutdown tests.
+	shutdownPollInterval = 5 * time.Millisecond
 }
 
 var (
@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"encoding/binary"
 	"errors"
@@ -28,6 +29,7 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"math"
 	"net"
 	"net/textproto"
 	"net/url"
@@ -1712,7 +1714,12 @@ func http2summarizeFrame(f http2Frame) string {
 	return buf.String()
 }
 
-func http2requestCancel(req *Request) <-chan struct{} { return req.Cancel }
+func http2requestCancel(req *Request) <-chan struct{} {
+	if req.Cancel != nil {
+		return req.Cancel
+	}
+	return req.Context().Done()
+}
 
 var http2DebugGoroutines = os.Getenv("DEBUG_HTTP2_GOROUTINES") == "1"
 
@@ -2484,6 +2491,15 @@ var (
 	http2errStreamClosed       = errors.New("http2: stream closed")
 )
 
+// goAwayTimeout is how long the server waits, after sending a graceful
+// GOAWAY and finishing all open streams, before closing the connection.
+var http2goAwayTimeout = 1 * time.Second
+
+var (
+	http2ErrRecursivePush    = errors.New("http2: recursive push not allowed")
+	http2ErrPushLimitReached = errors.New("http2: push would exceed peer's SETTINGS_MAX_CONCURRENT_STREAMS")
+)
+
 var http2responseWriterStatePool = sync.Pool{
 	New: func() interface{} {
 		rws := &http2responseWriterState{}
@@ -2525,6 +2541,48 @@ type http2Server struct {
 	// PermitProhibitedCipherSuites, if true, permits the use of
 	// cipher suites prohibited by the HTTP/2 spec.
 	PermitProhibitedCipherSuites bool
+
+	// Internal state. This is a pointer (rather than embedded directly)
+	// so that we don't embed a Mutex in this struct, which will make the
+	// struct non-copyable, which might break some callers.
+	state *http2serverInternalState
+}
+
+// serverInternalState tracks the connections served by a Server
+// configured with ConfigureServer, so that they can be shut down
+// gracefully.
+type http2serverInternalState struct {
+	mu          sync.Mutex
+	activeConns map[*http2serverConn]struct{}
+}
+
+func (s *http2serverInternalState) registerConn(sc *http2serverConn) {
+	if s == nil {
+		return // if the Server was used without calling ConfigureServer
+	}
+	s.mu.Lock()
+	s.activeConns[sc] = struct{}{}
+	s.mu.Unlock()
+}
+
+func (s *http2serverInternalState) unregisterConn(sc *http2serverConn) {
+	if s == nil {
+		return // if the Server was used without calling ConfigureServer
+	}
+	s.mu.Lock()
+	delete(s.activeConns, sc)
+	s.mu.Unlock()
+}
+
+func (s *http2serverInternalState) startGracefulShutdown() {
+	if s == nil {
+		return // if the Server was used without calling ConfigureServer
+	}
+	s.mu.Lock()
+	for sc := range s.activeConns {
+		sc.startGracefulShutdown()
+	}
+	s.mu.Unlock()
 }
 
 func (s *http2Server) maxReadFrameSize() uint32 {
@@ -2550,6 +2608,8 @@ func http2ConfigureServer(s *Server, conf *http2Server) error {
 	if conf == nil {
 		conf = new(http2Server)
 	}
+	conf.state = &http2serverInternalState{activeConns: make(map[*http2serverConn]struct{})}
+	s.RegisterOnShutdown(conf.state.startGracefulShutdown)
 
 	if s.TLSConfig == nil {
 		s.TLSConfig = new(tls.Config)
@@ -2626,6 +2686,19 @@ func (o *http2ServeConnOpts) baseConfig() *Server {
 	return new(Server)
 }
 
+// serverConnBaseContext returns the context that the request
+// contexts of all streams on c derive from. Like the contexts of
+// HTTP/1 requests, it carries the local address and, if known, the
+// Server.
+func http2serverConnBaseContext(c net.Conn, opts *http2ServeConnOpts) (ctx context.Context, cancel func()) {
+	ctx, cancel = context.WithCancel(context.Background())
+	ctx = context.WithValue(ctx, LocalAddrContextKey, c.LocalAddr())
+	if opts != nil && opts.BaseConfig != nil {
+		ctx = context.WithValue(ctx, ServerContextKey, opts.BaseConfig)
+	}
+	return
+}
+
 func (o *http2ServeConnOpts) handler() Handler {
 	if o != nil {
 		if o.Handler != nil {
@@ -2653,9 +2726,13 @@ func (o *http2ServeConnOpts) handler() Handler {
 //
 // The opts parameter is optional. If nil, default values are used.
 func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
+	baseCtx, cancel := http2serverConnBaseContext(c, opts)
+	defer cancel()
+
 	sc := &http2serverConn{
 		srv:              s,
 		hs:               opts.baseConfig(),
+		baseCtx:          baseCtx,
 		conn:             c,
 		remoteAddrStr:    c.RemoteAddr().String(),
 		bw:               http2newBufferedWriter(c),
@@ -2663,6 +2740,8 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		streams:          make(map[uint32]*http2stream),
 		readFrameCh:      make(chan http2readFrameResult),
 		wantWriteFrameCh: make(chan http2frameWriteMsg, 8),
+		wantStartPushCh:  make(chan http2startPushRequest, 8),
+		shutdownCh:       make(chan struct{}),
 		wroteFrameCh:     make(chan http2frameWriteResult, 1),
 		bodyReadCh:       make(chan http2bodyReadMsg),
 		doneServing:      make(chan struct{}),
@@ -2674,7 +2753,11 @@ func (s *http2Server) ServeConn(c net.Conn, opts *http2ServeConnOpts) {
 		headerTableSize:   http2initialHeaderTableSize,
 		serveG:            http2newGoroutineLock(),
 		pushEnabled:       true,
+		clientMaxStreams:  math.MaxUint32, // Section 6.5.2: "Initially, there is no limit to this value"
 	}
+	s.state.registerConn(sc)
+	defer s.state.unregisterConn(sc)
+
 	sc.flow.add(http2initialWindowSize)
 	sc.inflow.add(http2initialWindowSize)
 	sc.hpackEncoder = hpack.NewEncoder(&sc.headerWriteBuf)
@@ -2744,6 +2827,7 @@ type http2serverConn struct {
 	// Immutable:
 	srv              *http2Server
 	hs               *Server
+	baseCtx          context.Context // parent of each stream's ctx
 	conn             net.Conn
 	bw               *http2bufferedWriter // writing to conn
 	handler          Handler
@@ -2752,6 +2836,9 @@ type http2serverConn struct {
 	doneServing      chan struct{}              // closed when serverConn.serve ends
 	readFrameCh      chan http2readFrameResult  // written by serverConn.readFrames
 	wantWriteFrameCh chan http2frameWriteMsg    // from handlers -> serve
+	wantStartPushCh  chan http2startPushRequest // from handlers -> serve
+	shutdownCh       chan struct{}              // closed by startGracefulShutdown
+	shutdownOnce     sync.Once
 	wroteFrameCh     chan http2frameWriteResult // from writeFrameAsync -> serve, tickles more frame writes
 	bodyReadCh       chan http2bodyReadMsg      // from handlers -> serve
 	testHookCh       chan func(int)             // code to run on the serve loop
@@ -2769,7 +2856,9 @@ type http2serverConn struct {
 	clientMaxStreams      uint32 // SETTINGS_MAX_CONCURRENT_STREAMS from client (our PUSH_PROMISE limit)
 	advMaxStreams         uint32 // our SETTINGS_MAX_CONCURRENT_STREAMS advertised the client
 	curOpenStreams        uint32 // client's number of open streams
+	curPushedStreams      uint32 // number of open streams we pushed
 	maxStreamID           uint32 // max ever seen
+	maxPushPromiseID      uint32 // ID of the last push promise, or 0 if there have been no pushes
 	streams               map[uint32]*http2stream
 	initialWindowSize     int32
 	headerTableSize       uint32
@@ -2840,6 +2929,9 @@ type http2stream struct {
 	body *http2pipe       // non-nil if expecting DATA frames
 	cw   http2closeWaiter // closed wait stream transitions to closed state
 
+	ctx       context.Context // the Request's context
+	cancelCtx func()          // cancels ctx; safe to call from any goroutine
+
 	// owned by serverConn's serve loop:
 	bodyBytes        int64        // body bytes seen so far
 	declBodyBytes    int64        // or -1 if undeclared
@@ -2857,6 +2949,10 @@ type http2stream struct {
 	reqTrailer Header // handler's Request.Trailer
 }
 
+// isPushed reports whether the stream was initiated by the server
+// with a PUSH_PROMISE. Server-initiated streams have even IDs.
+func (st *http2stream) isPushed() bool { return st.id%2 == 0 }
+
 func (sc *http2serverConn) Framer() *http2Framer { return sc.framer }
 
 func (sc *http2serverConn) CloseConn() error { return sc.conn.Close() }
@@ -3157,12 +3253,18 @@ func (sc *http2serverConn) serve() {
 	go sc.readFrames()
 
 	settingsTimer := time.NewTimer(http2firstSettingsTimeout)
+	shutdownCh := sc.shutdownCh
 	loopNum := 0
 	for {
 		loopNum++
 		select {
+		case <-shutdownCh:
+			shutdownCh = nil
+			sc.goAway(http2ErrCodeNo)
 		case wm := <-sc.wantWriteFrameCh:
 			sc.writeFrame(wm)
+		case msg := <-sc.wantStartPushCh:
+			sc.startPush(msg)
 		case res := <-sc.wroteFrameCh:
 			sc.wroteFrame(res)
 		case res := <-sc.readFrameCh:
@@ -3185,9 +3287,27 @@ func (sc *http2serverConn) serve() {
 		case fn := <-sc.testHookCh:
 			fn(loopNum)
 		}
+
+		// Start the shutdown timer after sending a GOAWAY. When sending
+		// GOAWAY with no error code (graceful shutdown), don't start the
+		// timer until all open streams have been completed.
+		sentGoAway := sc.inGoAway && !sc.needToSendGoAway && !sc.writingFrame
+		if sentGoAway && sc.shutdownTimer == nil && sc.curOpenStreams+sc.curPushedStreams == 0 {
+			sc.shutDownIn(http2goAwayTimeout)
+		}
 	}
 }
 
+// startGracefulShutdown gracefully shuts down a connection. This
+// sends GOAWAY with ErrCodeNo to tell the client we're gracefully
+// shutting down. The connection isn't closed until all current
+// streams are done.
+//
+// It may be called from any goroutine.
+func (sc *http2serverConn) startGracefulShutdown() {
+	sc.shutdownOnce.Do(func() { close(sc.shutdownCh) })
+}
+
 // readPreface reads the ClientPreface greeting from the peer
 // or returns an error on timeout or an invalid greeting.
 func (sc *http2serverConn) readPreface() error {
@@ -3318,6 +3438,18 @@ func (sc *http2serverConn) startFrameWrite(wm http2frameWriteMsg) {
 		}
 	}
 
+	if wpp, ok := wm.write.(*http2writePushPromise); ok {
+		var err error
+		wpp.promisedID, err = wpp.allocatePromisedID()
+		if err != nil {
+			if wm.done != nil {
+				wm.done <- err
+			}
+			sc.scheduleFrameWrite()
+			return
+		}
+	}
+
 	sc.writingFrame = true
 	sc.needsFrameFlush = true
 	go sc.writeFrameAsync(wm)
@@ -3425,9 +3557,6 @@ func (sc *http2serverConn) goAway(code http2ErrCode) {
 	}
 	if code != http2ErrCodeNo {
 		sc.shutDownIn(250 * time.Millisecond)
-	} else {
-
-		sc.shutDownIn(1 * time.Second)
 	}
 	sc.inGoAway = true
 	sc.needToSendGoAway = true
@@ -3590,14 +3719,19 @@ func (sc *http2serverConn) closeStream(st *http2stream, err error) {
 		panic(fmt.Sprintf("invariant; can't close stream in state %v", st.state))
 	}
 	st.state = http2stateClosed
-	sc.curOpenStreams--
-	if sc.curOpenStreams == 0 {
+	if st.isPushed() {
+		sc.curPushedStreams--
+	} else {
+		sc.curOpenStreams--
+	}
+	if sc.curOpenStreams+sc.curPushedStreams == 0 {
 		sc.setConnState(StateIdle)
 	}
 	delete(sc.streams, st.id)
 	if p := st.body; p != nil {
 		p.CloseWithError(err)
 	}
+	st.cancelCtx()
 	st.cw.Close()
 	sc.writeSched.forgetStream(st.id)
 }
@@ -3756,22 +3890,11 @@ func (sc *http2serverConn) processHeaders(f *http2HeadersFrame) error {
 	if id > sc.maxStreamID {
 		sc.maxStreamID = id
 	}
-	st = &http2stream{
-		sc:    sc,
-		id:    id,
-		state: http2stateOpen,
-	}
+	state := http2stateOpen
 	if f.StreamEnded() {
-		st.state = http2stateHalfClosedRemote
+		state = http2stateHalfClosedRemote
 	}
-	st.cw.Init()
-
-	st.flow.conn = &sc.flow
-	st.flow.add(sc.initialWindowSize)
-	st.inflow.conn = &sc.inflow
-	st.inflow.add(http2initialWindowSize)
-
-	sc.streams[id] = st
+	st = sc.newStream(id, state)
 	if f.HasPriority() {
 		http2adjustStreamPriority(sc.streams, st.id, f.Priority)
 	}
@@ -3788,6 +3911,29 @@ func (sc *http2serverConn) processHeaders(f *http2HeadersFrame) error {
 	return sc.processHeaderBlockFragment(st, f.HeaderBlockFragment(), f.HeadersEnded())
 }
 
+// newStream creates a stream with the given id and state and
+// registers it with the connection.
+func (sc *http2serverConn) newStream(id uint32, state http2streamState) *http2stream {
+	sc.serveG.check()
+	ctx, cancelCtx := context.WithCancel(sc.baseCtx)
+	st := &http2stream{
+		sc:        sc,
+		id:        id,
+		state:     state,
+		ctx:       ctx,
+		cancelCtx: cancelCtx,
+	}
+	st.cw.Init()
+
+	st.flow.conn = &sc.flow
+	st.flow.add(sc.initialWindowSize)
+	st.inflow.conn = &sc.inflow
+	st.inflow.add(http2initialWindowSize)
+
+	sc.streams[id] = st
+	return st
+}
+
 func (st *http2stream) processTrailerHeaders(f *http2HeadersFrame) error {
 	sc := st.sc
 	sc.serveG.check()
@@ -3833,7 +3979,7 @@ func (sc *http2serverConn) processHeaderBlockFragment(st *http2stream, frag []by
 		return http2StreamError{st.id, http2ErrCodeRefusedStream}
 	}
 
-	rw, req, err := sc.newWriterAndRequest()
+	rw, req, err := sc.newWriterAndRequest(&sc.req)
 	if err != nil {
 		return err
 	}
@@ -3920,9 +4066,10 @@ func (sc *http2serverConn) resetPendingRequest() {
 	sc.req = http2requestParam{}
 }
 
-func (sc *http2serverConn) newWriterAndRequest() (*http2responseWriter, *Request, error) {
+// newWriterAndRequest builds the Request and responseWriter for the
+// stream and headers described by rp.
+func (sc *http2serverConn) newWriterAndRequest(rp *http2requestParam) (*http2responseWriter, *Request, error) {
 	sc.serveG.check()
-	rp := &sc.req
 
 	if rp.invalidHeader {
 		return nil, nil, http2StreamError{rp.stream.id, http2ErrCodeProtocol}
@@ -4012,6 +4159,7 @@ func (sc *http2serverConn) newWriterAndRequest() (*http2responseWriter, *Request
 		Body:       body,
 		Trailer:    trailer,
 	}
+	req = req.WithContext(rp.stream.ctx)
 	if bodyOpen {
 		body.pipe = &http2pipe{
 			b: &http2fixedBuffer{buf: make([]byte, http2initialWindowSize)},
@@ -4040,6 +4188,9 @@ func (sc *http2serverConn) newWriterAndRequest() (*http2responseWriter, *Request
 
 // Run on its own goroutine.
 func (sc *http2serverConn) runHandler(rw *http2responseWriter, req *Request, handler func(ResponseWriter, *Request)) {
+	// The request's context is done once the handler returns, even
+	// if the client is still sending the request body.
+	defer rw.rws.stream.cancelCtx()
 	didPanic := true
 	defer func() {
 		if didPanic {
@@ -4225,6 +4376,7 @@ type http2responseWriter struct {
 var (
 	_ CloseNotifier     = (*http2responseWriter)(nil)
 	_ Flusher           = (*http2responseWriter)(nil)
+	_ Pusher            = (*http2responseWriter)(nil)
 	_ http2stringWriter = (*http2responseWriter)(nil)
 )
 
@@ -4534,6 +4686,197 @@ func (w *http2responseWriter) handlerDone() {
 	http2responseWriterStatePool.Put(rws)
 }
 
+func (w *http2responseWriter) Push(target string, opts *PushOptions) error {
+	st := w.rws.stream
+	sc := st.sc
+	sc.serveG.checkNotOn()
+
+	// No recursive pushes: "PUSH_PROMISE frames MUST only be sent on a peer-initiated stream."
+	// http://tools.ietf.org/html/rfc7540#section-6.6
+	if st.isPushed() {
+		return http2ErrRecursivePush
+	}
+
+	// Default options.
+	if opts == nil {
+		opts = new(PushOptions)
+	}
+	method := opts.Method
+	if method == "" {
+		method = "GET"
+	}
+	wantScheme := "http"
+	if w.rws.req.TLS != nil {
+		wantScheme = "https"
+	}
+
+	// Validate the request.
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		if !strings.HasPrefix(target, "/") {
+			return fmt.Errorf("target must be an absolute URL or an absolute path: %q", target)
+		}
+		u.Scheme = wantScheme
+		u.Host = w.rws.req.Host
+	} else {
+		if u.Scheme != wantScheme {
+			return fmt.Errorf("cannot push URL with scheme %q from request with scheme %q", u.Scheme, wantScheme)
+		}
+		if u.Host == "" {
+			return errors.New("URL must have a host")
+		}
+	}
+	for k, vv := range opts.Header {
+		if strings.HasPrefix(k, ":") {
+			return fmt.Errorf("promised request headers cannot include pseudo header %q", k)
+		}
+		// These headers are meaningful only if the request has a body,
+		// but PUSH_PROMISE requests cannot have a body.
+		// http://tools.ietf.org/html/rfc7540#section-8.2
+		// Also disallow Host, since the promised URL must be absolute.
+		switch strings.ToLower(k) {
+		case "content-length", "content-encoding", "trailer", "te", "expect", "host":
+			return fmt.Errorf("promised request headers cannot include %q", k)
+		}
+		if !http2validHeaderFieldName(strings.ToLower(k)) {
+			return fmt.Errorf("promised request header %q is invalid", k)
+		}
+		for _, v := range vv {
+			if !http2validHeaderFieldValue(v) {
+				return fmt.Errorf("promised request header %q has invalid value %q", k, v)
+			}
+		}
+	}
+
+	// The RFC effectively limits promised requests to GET and HEAD:
+	// "Promised requests MUST be cacheable [GET, HEAD, or POST], and MUST be safe [GET or HEAD]"
+	// http://tools.ietf.org/html/rfc7540#section-8.2
+	if method != "GET" && method != "HEAD" {
+		return fmt.Errorf("method %q must be GET or HEAD", method)
+	}
+
+	msg := http2startPushRequest{
+		parent: st,
+		method: method,
+		url:    u,
+		header: http2cloneHeader(opts.Header),
+		done:   http2errChanPool.Get().(chan error),
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case sc.wantStartPushCh <- msg:
+	}
+
+	select {
+	case <-sc.doneServing:
+		return http2errClientDisconnected
+	case <-st.cw:
+		return http2errStreamClosed
+	case err := <-msg.done:
+		http2errChanPool.Put(msg.done)
+		return err
+	}
+}
+
+// startPushRequest is the message sent from a handler's Push call to
+// the serve loop.
+type http2startPushRequest struct {
+	parent *http2stream
+	method string
+	url    *url.URL
+	header Header
+	done   chan error
+}
+
+func (sc *http2serverConn) startPush(msg http2startPushRequest) {
+	sc.serveG.check()
+
+	// http://tools.ietf.org/html/rfc7540#section-6.6.
+	// PUSH_PROMISE frames MUST only be sent on a peer-initiated stream that
+	// is in either the "open" or "half-closed (remote)" state.
+	if msg.parent.state != http2stateOpen && msg.parent.state != http2stateHalfClosedRemote {
+		// responseWriter.Push checks that the stream is peer-initiated.
+		msg.done <- http2errStreamClosed
+		return
+	}
+
+	// http://tools.ietf.org/html/rfc7540#section-6.6.
+	if !sc.pushEnabled {
+		msg.done <- ErrNotSupported
+		return
+	}
+
+	// PUSH_PROMISE frames must be sent in increasing order by stream ID, so
+	// we allocate an ID for the promised stream lazily, when the PUSH_PROMISE
+	// is written. Once the ID is allocated, we start the request handler.
+	allocatePromisedID := func() (uint32, error) {
+		sc.serveG.check()
+
+		// Check this again, just in case. Technically, we might have received
+		// an updated SETTINGS by the time we got around to writing this frame.
+		if !sc.pushEnabled {
+			return 0, ErrNotSupported
+		}
+		// http://tools.ietf.org/html/rfc7540#section-6.5.2.
+		if sc.curPushedStreams+1 > sc.clientMaxStreams {
+			return 0, http2ErrPushLimitReached
+		}
+
+		// http://tools.ietf.org/html/rfc7540#section-5.1.1.
+		// Streams initiated by the server MUST use even-numbered identifiers.
+		// A server that is unable to establish a new stream identifier can send a GOAWAY
+		// frame so that the client is forced to open a new connection for new streams.
+		if sc.maxPushPromiseID+2 >= 1<<31 {
+			sc.goAway(http2ErrCodeNo)
+			return 0, http2ErrPushLimitReached
+		}
+		sc.maxPushPromiseID += 2
+		promisedID := sc.maxPushPromiseID
+
+		// http://tools.ietf.org/html/rfc7540#section-8.2.
+		// Strictly speaking, the new stream should start in "reserved (local)", then
+		// transition to "half closed (remote)" after sending the initial HEADERS, but
+		// we start in "half closed (remote)" for simplicity.
+		promised := sc.newStream(promisedID, http2stateHalfClosedRemote)
+		promised.parent = msg.parent
+		sc.curPushedStreams++
+		rw, req, err := sc.newWriterAndRequest(&http2requestParam{
+			stream:    promised,
+			method:    msg.method,
+			scheme:    msg.url.Scheme,
+			authority: msg.url.Host,
+			path:      msg.url.RequestURI(),
+			header:    http2cloneHeader(msg.header), // clone since handler runs concurrently with writing the PUSH_PROMISE
+		})
+		if err != nil {
+			// Should not happen, since we've already validated msg.url.
+			panic(fmt.Sprintf("newWriterAndRequest(%+v): %v", msg.url, err))
+		}
+
+		go sc.runHandler(rw, req, sc.handler.ServeHTTP)
+		return promisedID, nil
+	}
+
+	sc.writeFrame(http2frameWriteMsg{
+		write: &http2writePushPromise{
+			streamID:           msg.parent.id,
+			method:             msg.method,
+			url:                msg.url,
+			h:                  msg.header,
+			allocatePromisedID: allocatePromisedID,
+		},
+		stream: msg.parent,
+		done:   msg.done,
+	})
+}
+
 // foreachHeaderElement splits v according to the "#rule" construction
 // in RFC 2616 section 2.1 and calls fn for each non-empty element.
 func http2foreachHeaderElement(v string, fn func(string)) {
@@ -6319,6 +6662,25 @@ func (w *http2writeResHeaders) writeFrame(ctx http2writeContext) error {
 		panic("unexpected empty hpack")
 	}
 
+	return http2splitHeaderBlock(ctx, headerBlock, w.writeHeaderBlock)
+}
+
+func (w *http2writeResHeaders) writeHeaderBlock(ctx http2writeContext, frag []byte, firstFrag, lastFrag bool) error {
+	if firstFrag {
+		return ctx.Framer().WriteHeaders(http2HeadersFrameParam{
+			StreamID:      w.streamID,
+			BlockFragment: frag,
+			EndStream:     w.endStream,
+			EndHeaders:    lastFrag,
+		})
+	}
+	return ctx.Framer().WriteContinuation(w.streamID, lastFrag, frag)
+}
+
+// splitHeaderBlock splits headerBlock into fragments so that each fragment fits
+// in a single frame, then calls fn for each fragment. firstFrag/lastFrag are true
+// for the first/last fragment, respectively.
+func http2splitHeaderBlock(ctx http2writeContext, headerBlock []byte, fn func(ctx http2writeContext, frag []byte, firstFrag, lastFrag bool) error) error {
 	// For now we're lazy and just pick the minimum MAX_FRAME_SIZE
 	// that all peers must support (16KB). Later we could care
 	// more and send larger frames if the peer advertised it, but
@@ -6334,26 +6696,57 @@ func (w *http2writeResHeaders) writeFrame(ctx http2writeContext) error {
 			frag = frag[:maxFrameSize]
 		}
 		headerBlock = headerBlock[len(frag):]
-		endHeaders := len(headerBlock) == 0
-		var err error
-		if first {
-			first = false
-			err = ctx.Framer().WriteHeaders(http2HeadersFrameParam{
-				StreamID:      w.streamID,
-				BlockFragment: frag,
-				EndStream:     w.endStream,
-				EndHeaders:    endHeaders,
-			})
-		} else {
-			err = ctx.Framer().WriteContinuation(w.streamID, endHeaders, frag)
-		}
-		if err != nil {
+		if err := fn(ctx, frag, first, len(headerBlock) == 0); err != nil {
 			return err
 		}
+		first = false
 	}
 	return nil
 }
 
+// writePushPromise is a request to write a PUSH_PROMISE and 0+ CONTINUATION frames.
+type http2writePushPromise struct {
+	streamID uint32   // pusher stream
+	method   string   // for :method
+	url      *url.URL // for :scheme, :authority, :path
+	h        Header
+
+	// Creates an ID for a pushed stream. This runs on serveG just before
+	// the frame is written. The returned ID is copied to promisedID.
+	allocatePromisedID func() (uint32, error)
+	promisedID         uint32
+}
+
+func (w *http2writePushPromise) writeFrame(ctx http2writeContext) error {
+	enc, buf := ctx.HeaderEncoder()
+	buf.Reset()
+
+	http2encKV(enc, ":method", w.method)
+	http2encKV(enc, ":scheme", w.url.Scheme)
+	http2encKV(enc, ":authority", w.url.Host)
+	http2encKV(enc, ":path", w.url.RequestURI())
+	http2encodeHeaders(enc, w.h, nil)
+
+	headerBlock := buf.Bytes()
+	if len(headerBlock) == 0 {
+		panic("unexpected empty hpack")
+	}
+
+	return http2splitHeaderBlock(ctx, headerBlock, w.writeHeaderBlock)
+}
+
+func (w *http2writePushPromise) writeHeaderBlock(ctx http2writeContext, frag []byte, firstFrag, lastFrag bool) error {
+	if firstFrag {
+		return ctx.Framer().WritePushPromise(http2PushPromiseParam{
+			StreamID:      w.streamID,
+			PromiseID:     w.promisedID,
+			BlockFragment: frag,
+			EndHeaders:    lastFrag,
+		})
+	}
+	return ctx.Framer().WriteContinuation(w.streamID, lastFrag, frag)
+}
+
 type http2write100ContinueHeadersFrame struct {
 	streamID uint32
 }
@@ -0,0 +1,238 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Tests of HTTP/2 server push in the bundled server. These speak raw
+// frames to the server, since Transport disables push.
+
+package http
+
+import (
+	"bytes"
+	"internal/golang.org/x/net/http2/hpack"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// h2PushTestConn is the client side of a connection to an HTTP/2
+// server, used to check the frames the server sends.
+type h2PushTestConn struct {
+	t    *testing.T
+	cc   net.Conn
+	fr   *http2Framer
+	henc *hpack.Encoder
+	hbuf bytes.Buffer
+	hdec *hpack.Decoder
+}
+
+func newH2PushTestConn(t *testing.T, h Handler, settings ...http2Setting) *h2PushTestConn {
+	cc, sc := net.Pipe()
+	go (&http2Server{}).ServeConn(sc, &http2ServeConnOpts{Handler: h})
+
+	tc := &h2PushTestConn{
+		t:    t,
+		cc:   cc,
+		fr:   http2NewFramer(cc, cc),
+		hdec: hpack.NewDecoder(http2initialHeaderTableSize, nil),
+	}
+	tc.henc = hpack.NewEncoder(&tc.hbuf)
+	if _, err := io.WriteString(cc, http2ClientPreface); err != nil {
+		t.Fatal(err)
+	}
+	if err := tc.fr.WriteSettings(settings...); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+func (tc *h2PushTestConn) close() { tc.cc.Close() }
+
+// get sends a GET request for path on the given stream.
+func (tc *h2PushTestConn) get(streamID uint32, path string) {
+	tc.hbuf.Reset()
+	for _, kv := range [][2]string{
+		{":method", "GET"},
+		{":scheme", "http"},
+		{":authority", "example.com"},
+		{":path", path},
+	} {
+		tc.henc.WriteField(hpack.HeaderField{Name: kv[0], Value: kv[1]})
+	}
+	err := tc.fr.WriteHeaders(http2HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: tc.hbuf.Bytes(),
+		EndStream:     true,
+		EndHeaders:    true,
+	})
+	if err != nil {
+		tc.t.Fatal(err)
+	}
+}
+
+// decode decodes a header block. All header blocks sent by the
+// server must be decoded in order, since they share one HPACK
+// dynamic table.
+func (tc *h2PushTestConn) decode(frag []byte) map[string]string {
+	fields, err := tc.hdec.DecodeFull(frag)
+	if err != nil {
+		tc.t.Fatal(err)
+	}
+	m := make(map[string]string)
+	for _, f := range fields {
+		m[f.Name] = f.Value
+	}
+	return m
+}
+
+// h2PushTestStream records what the server sent on one stream.
+type h2PushTestStream struct {
+	header map[string]string
+	body   string
+	ended  bool
+}
+
+// readStreams reads frames until all of the given streams have
+// ended, and returns what was sent on each stream along with the
+// PUSH_PROMISE frames, in order.
+func (tc *h2PushTestConn) readStreams(ids ...uint32) (map[uint32]*h2PushTestStream, []*h2PushTestPromise) {
+	streams := make(map[uint32]*h2PushTestStream)
+	for _, id := range ids {
+		streams[id] = new(h2PushTestStream)
+	}
+	var promises []*h2PushTestPromise
+	ended := 0
+	for ended < len(ids) {
+		f, err := tc.fr.ReadFrame()
+		if err != nil {
+			tc.t.Fatal(err)
+		}
+		switch f := f.(type) {
+		case *http2SettingsFrame:
+			if !f.IsAck() {
+				if err := tc.fr.WriteSettingsAck(); err != nil {
+					tc.t.Fatal(err)
+				}
+			}
+		case *http2PushPromiseFrame:
+			promises = append(promises, &h2PushTestPromise{
+				streamID:  f.StreamID,
+				promiseID: f.PromiseID,
+				header:    tc.decode(f.HeaderBlockFragment()),
+				sawData:   streams[f.StreamID] != nil && streams[f.StreamID].body != "",
+			})
+		case *http2HeadersFrame:
+			st := streams[f.StreamID]
+			if st == nil {
+				tc.t.Fatalf("unexpected HEADERS on stream %d", f.StreamID)
+			}
+			st.header = tc.decode(f.HeaderBlockFragment())
+			if f.StreamEnded() {
+				st.ended = true
+				ended++
+			}
+		case *http2DataFrame:
+			st := streams[f.StreamID]
+			if st == nil {
+				tc.t.Fatalf("unexpected DATA on stream %d", f.StreamID)
+			}
+			st.body += string(f.Data())
+			if f.StreamEnded() {
+				st.ended = true
+				ended++
+			}
+		case *http2RSTStreamFrame:
+			tc.t.Fatalf("unexpected RST_STREAM on stream %d: %v", f.StreamID, f.ErrCode)
+		case *http2GoAwayFrame:
+			tc.t.Fatalf("unexpected GOAWAY: %v", f.ErrCode)
+		}
+	}
+	return streams, promises
+}
+
+type h2PushTestPromise struct {
+	streamID, promiseID uint32
+	header              map[string]string
+	sawData             bool // DATA was already sent on the pushing stream
+}
+
+func TestHTTP2ServerPush(t *testing.T) {
+	h := HandlerFunc(func(w ResponseWriter, r *Request) {
+		switch r.URL.Path {
+		case "/":
+			err := w.(Pusher).Push("/pushed", &PushOptions{
+				Header: Header{"User-Agent": {"pusher"}},
+			})
+			if err != nil {
+				t.Errorf("Push = %v", err)
+			}
+			if err := w.(Pusher).Push("/post", &PushOptions{Method: "POST"}); err == nil {
+				t.Errorf("Push with method POST succeeded")
+			}
+			if err := w.(Pusher).Push("https://example.com/", nil); err == nil {
+				t.Errorf("Push with mismatched scheme succeeded")
+			}
+			io.WriteString(w, "index")
+		case "/pushed":
+			if err := w.(Pusher).Push("/again", nil); err != http2ErrRecursivePush {
+				t.Errorf("recursive Push = %v; want %v", err, http2ErrRecursivePush)
+			}
+			if r.Host != "example.com" || r.Header.Get("User-Agent") != "pusher" {
+				t.Errorf("pushed request Host = %q, User-Agent = %q", r.Host, r.Header.Get("User-Agent"))
+			}
+			io.WriteString(w, "pushed")
+		default:
+			t.Errorf("unexpected request for %q", r.URL.Path)
+		}
+	})
+	tc := newH2PushTestConn(t, h)
+	defer tc.close()
+
+	tc.get(1, "/")
+	streams, promises := tc.readStreams(1, 2)
+	if len(promises) != 1 {
+		t.Fatalf("got %d PUSH_PROMISE frames; want 1", len(promises))
+	}
+	p := promises[0]
+	if p.streamID != 1 || p.promiseID != 2 {
+		t.Errorf("PUSH_PROMISE on stream %d promising %d; want 1 promising 2", p.streamID, p.promiseID)
+	}
+	if p.sawData {
+		t.Errorf("PUSH_PROMISE sent after DATA on the pushing stream")
+	}
+	wantPromise := map[string]string{
+		":method":    "GET",
+		":scheme":    "http",
+		":authority": "example.com",
+		":path":      "/pushed",
+		"user-agent": "pusher",
+	}
+	if !reflect.DeepEqual(p.header, wantPromise) {
+		t.Errorf("promised headers = %v; want %v", p.header, wantPromise)
+	}
+	for id, want := range map[uint32]string{1: "index", 2: "pushed"} {
+		st := streams[id]
+		if st.header[":status"] != "200" {
+			t.Errorf("stream %d :status = %q; want 200", id, st.header[":status"])
+		}
+		if st.body != want {
+			t.Errorf("stream %d body = %q; want %q", id, st.body, want)
+		}
+	}
+}
+
+func TestHTTP2ServerPushLimit(t *testing.T) {
+	h := HandlerFunc(func(w ResponseWriter, r *Request) {
+		if err := w.(Pusher).Push("/pushed", nil); err != http2ErrPushLimitReached {
+			t.Errorf("Push = %v; want %v", err, http2ErrPushLimitReached)
+		}
+	})
+	tc := newH2PushTestConn(t, h, http2Setting{ID: http2SettingMaxConcurrentStreams, Val: 0})
+	defer tc.close()
+
+	tc.get(1, "/")
+	if _, promises := tc.readStreams(1); len(promises) != 0 {
+		t.Errorf("got %d PUSH_PROMISE frames; want 0", len(promises))
+	}
+}
@@ -7,6 +7,7 @@ package httptest
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"testing"
 )
@@ -47,6 +48,50 @@ func TestRecorder(t *testing.T) {
 			return nil
 		}
 	}
+	hasResultStatus := func(wantCode int) checkFunc {
+		return func(rec *ResponseRecorder) error {
+			if got := rec.Result().StatusCode; got != wantCode {
+				return fmt.Errorf("Result().StatusCode = %d; want %d", got, wantCode)
+			}
+			return nil
+		}
+	}
+	hasResultHeader := func(key, want string) checkFunc {
+		return func(rec *ResponseRecorder) error {
+			if got := rec.Result().Header.Get(key); got != want {
+				return fmt.Errorf("Result().Header[%q] = %q; want %q", key, got, want)
+			}
+			return nil
+		}
+	}
+	hasResultContents := func(want string) checkFunc {
+		return func(rec *ResponseRecorder) error {
+			got, err := ioutil.ReadAll(rec.Result().Body)
+			if err != nil {
+				return err
+			}
+			if string(got) != want {
+				return fmt.Errorf("Result().Body = %q; want %q", got, want)
+			}
+			return nil
+		}
+	}
+	hasTrailer := func(key, want string) checkFunc {
+		return func(rec *ResponseRecorder) error {
+			if got := rec.Result().Trailer.Get(key); got != want {
+				return fmt.Errorf("trailer %s = %q; want %q", key, got, want)
+			}
+			return nil
+		}
+	}
+	hasNotTrailer := func(key string) checkFunc {
+		return func(rec *ResponseRecorder) error {
+			if _, ok := rec.Result().Trailer[key]; ok {
+				return fmt.Errorf("trailer %s present; want absent", key)
+			}
+			return nil
+		}
+	}
 
 	tests := []struct {
 		name   string
@@ -130,6 +175,69 @@ func TestRecorder(t *testing.T) {
 			},
 			check(hasHeader("Content-Type", "text/html; charset=utf-8")),
 		},
+		{
+			"Header is not changed after write",
+			func(w http.ResponseWriter, r *http.Request) {
+				hdr := w.Header()
+				hdr.Set("Key", "correct")
+				w.WriteHeader(200)
+				hdr.Set("Key", "incorrect")
+			},
+			check(hasHeader("Key", "incorrect"), hasResultHeader("Key", "correct")),
+		},
+		{
+			"Result without a write",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Key", "value")
+			},
+			check(hasResultStatus(200), hasResultHeader("Key", "value"), hasResultContents("")),
+		},
+		{
+			"Result status and body",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(404)
+				io.WriteString(w, "not here")
+			},
+			check(hasResultStatus(404), hasResultContents("not here")),
+		},
+		{
+			"Trailer headers are correctly recorded",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Non-Trailer", "correct")
+				w.Header().Set("Trailer", "Trailer-A, trailer-b")
+				w.Header().Add("Trailer", "Trailer-C")
+				io.WriteString(w, "<html>")
+				w.Header().Set("Non-Trailer", "incorrect")
+				w.Header().Set("Trailer-A", "valuea")
+				w.Header().Set("Trailer-C", "valuec")
+				w.Header().Set("Trailer-NotDeclared", "should be omitted")
+			},
+			check(
+				hasStatus(200),
+				hasResultHeader("Non-Trailer", "correct"),
+				hasTrailer("Trailer-A", "valuea"),
+				hasTrailer("Trailer-C", "valuec"),
+				hasNotTrailer("Trailer-B"),
+				hasNotTrailer("Non-Trailer"),
+				hasNotTrailer("Trailer-NotDeclared"),
+			),
+		},
+		{
+			"Undeclared trailers are recorded with TrailerPrefix",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Trailer", "Trailer-A")
+				io.WriteString(w, "<html>")
+				w.Header().Set("Trailer-A", "valuea")
+				w.Header().Set(http.TrailerPrefix+"Trailer-B", "valueb")
+				w.Header().Set("Trailer-C", "should be omitted")
+			},
+			check(
+				hasStatus(200),
+				hasTrailer("Trailer-A", "valuea"),
+				hasTrailer("Trailer-B", "valueb"),
+				hasNotTrailer("Trailer-C"),
+			),
+		},
 	}
 	r, _ := http.NewRequest("GET", "http://foo.com/", nil)
 	for _, tt := range tests {
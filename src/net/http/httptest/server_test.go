@@ -7,6 +7,7 @@ package httptest
 import (
 	"bufio"
 	"io/ioutil"
+	"log"
 	"net"
 	"net/http"
 	"testing"
@@ -98,3 +99,35 @@ func TestServerCloseClientConnections(t *testing.T) {
 		t.Fatal("Unexpected response: %#v", res)
 	}
 }
+
+func TestServerClient(t *testing.T) {
+	for _, newServer := range []func(http.Handler) *Server{NewServer, NewTLSServer} {
+		ts := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}))
+		res, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", ts.URL, err)
+		}
+		got, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "hello" {
+			t.Errorf("%s: got %q, want hello", ts.URL, string(got))
+		}
+		ts.Close()
+	}
+}
+
+func TestTLSServerRejectsDefaultClient(t *testing.T) {
+	ts := NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet the TLS handshake error
+	defer ts.Close()
+	client := &http.Client{Transport: &http.Transport{}}
+	if res, err := client.Get(ts.URL); err == nil {
+		res.Body.Close()
+		t.Errorf("client without the test certificate connected to %s", ts.URL)
+	}
+}
@@ -7,6 +7,10 @@
 package httputil
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
 	"io"
 	"log"
 	"net"
@@ -24,11 +28,24 @@ var onExitFlushLoop func()
 // ReverseProxy is an HTTP Handler that takes an incoming request and
 // sends it to another server, proxying the response back to the
 // client.
+//
+// Requests asking to switch protocols with "Connection: Upgrade", such
+// as WebSocket handshakes, are sent to the backend on a connection of
+// their own. If the backend agrees with a 101 Switching Protocols
+// response, the client connection is hijacked and bytes are copied in
+// both directions until either side closes its connection. Upgrade
+// requests use the dial functions and TLS configuration of Transport
+// if it is an *http.Transport, but ignore its Proxy setting.
 type ReverseProxy struct {
 	// Director must be a function which modifies
 	// the request into a new request to be sent
 	// using Transport. Its response is then copied
-	// back to the original client unmodified.
+	// back to the original client, modified only by
+	// ModifyResponse.
+	//
+	// The proxy adds X-Forwarded-For and X-Forwarded-Proto
+	// headers after Director returns. To prevent that for
+	// either header, Director can set it to nil.
 	Director func(*http.Request)
 
 	// The transport used to perform proxy requests.
@@ -51,6 +68,21 @@ type ReverseProxy struct {
 	// get byte slices for use by io.CopyBuffer when
 	// copying HTTP response bodies.
 	BufferPool BufferPool
+
+	// ModifyResponse is an optional function that
+	// modifies the Response from the backend. It is
+	// called if the backend returns a response at all,
+	// with any HTTP status code. If the backend is
+	// unreachable, ErrorHandler is called without any
+	// call to ModifyResponse. If ModifyResponse returns
+	// an error, ErrorHandler is called with it.
+	ModifyResponse func(*http.Response) error
+
+	// ErrorHandler is an optional function that handles
+	// errors reaching the backend or errors from
+	// ModifyResponse. If nil, the error is logged and a
+	// 502 Bad Gateway response is sent.
+	ErrorHandler func(http.ResponseWriter, *http.Request, error)
 }
 
 // A BufferPool is an interface for getting and returning temporary
@@ -137,6 +169,33 @@ func (c *runOnFirstRead) Read(bs []byte) (int, error) {
 	return c.Reader.Read(bs)
 }
 
+func (p *ReverseProxy) defaultErrorHandler(rw http.ResponseWriter, req *http.Request, err error) {
+	p.logf("http: proxy error: %v", err)
+	rw.WriteHeader(http.StatusBadGateway)
+}
+
+func (p *ReverseProxy) getErrorHandler() func(http.ResponseWriter, *http.Request, error) {
+	if p.ErrorHandler != nil {
+		return p.ErrorHandler
+	}
+	return p.defaultErrorHandler
+}
+
+// modifyResponse runs ModifyResponse, if any, on res. It reports
+// whether the response should be sent to the client; if not, the
+// error has already been handled.
+func (p *ReverseProxy) modifyResponse(rw http.ResponseWriter, res *http.Response, req *http.Request) bool {
+	if p.ModifyResponse == nil {
+		return true
+	}
+	if err := p.ModifyResponse(res); err != nil {
+		res.Body.Close()
+		p.getErrorHandler()(rw, req, err)
+		return false
+	}
+	return true
+}
+
 func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	transport := p.Transport
 	if transport == nil {
@@ -144,9 +203,14 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	}
 
 	outreq := new(http.Request)
-	*outreq = *req // includes shallow copies of maps, but okay
-
-	if closeNotifier, ok := rw.(http.CloseNotifier); ok {
+	*outreq = *req // includes shallow copies of maps, but we copy Header below
+	outreq.Header = make(http.Header, len(req.Header))
+	copyHeader(outreq.Header, req.Header)
+
+	// CloseNotify reads from the client connection in the
+	// background, which would steal data from the upgraded
+	// connection of a protocol switch.
+	if closeNotifier, ok := rw.(http.CloseNotifier); ok && upgradeType(req.Header) == "" {
 		if requestCanceler, ok := transport.(requestCanceler); ok {
 			reqDone := make(chan struct{})
 			defer close(reqDone)
@@ -182,35 +246,56 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	// Remove hop-by-hop headers to the backend.  Especially
 	// important is "Connection" because we want a persistent
-	// connection, regardless of what the client sent to us.  This
-	// is modifying the same underlying map from req (shallow
-	// copied above) so we only copy it if necessary.
-	copiedHeaders := false
+	// connection, regardless of what the client sent to us.
+	// Upgrade requests keep the headers asking for the switch.
+	reqUpType := upgradeType(outreq.Header)
 	for _, h := range hopHeaders {
-		if outreq.Header.Get(h) != "" {
-			if !copiedHeaders {
-				outreq.Header = make(http.Header)
-				copyHeader(outreq.Header, req.Header)
-				copiedHeaders = true
-			}
-			outreq.Header.Del(h)
-		}
+		outreq.Header.Del(h)
+	}
+	if reqUpType != "" {
+		outreq.Header.Set("Connection", "Upgrade")
+		outreq.Header.Set("Upgrade", reqUpType)
 	}
 
 	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
 		// If we aren't the first proxy retain prior
 		// X-Forwarded-For information as a comma+space
 		// separated list and fold multiple headers into one.
-		if prior, ok := outreq.Header["X-Forwarded-For"]; ok {
+		prior, ok := outreq.Header["X-Forwarded-For"]
+		omit := ok && prior == nil // Director asked us not to add it
+		if len(prior) > 0 {
 			clientIP = strings.Join(prior, ", ") + ", " + clientIP
 		}
-		outreq.Header.Set("X-Forwarded-For", clientIP)
+		if !omit {
+			outreq.Header.Set("X-Forwarded-For", clientIP)
+		}
+	}
+	if prior, ok := outreq.Header["X-Forwarded-Proto"]; !ok || prior != nil {
+		proto := "http"
+		if req.TLS != nil {
+			proto = "https"
+		}
+		outreq.Header.Set("X-Forwarded-Proto", proto)
 	}
 
-	res, err := transport.RoundTrip(outreq)
+	var res *http.Response
+	var err error
+	if reqUpType != "" {
+		if _, ok := rw.(http.Hijacker); !ok {
+			p.getErrorHandler()(rw, req, fmt.Errorf("can't switch protocols using non-Hijacker ResponseWriter type %T", rw))
+			return
+		}
+		res, err = p.roundTripUpgrade(outreq)
+	} else {
+		res, err = transport.RoundTrip(outreq)
+	}
 	if err != nil {
-		p.logf("http: proxy error: %v", err)
-		rw.WriteHeader(http.StatusInternalServerError)
+		p.getErrorHandler()(rw, req, err)
+		return
+	}
+
+	if res.StatusCode == http.StatusSwitchingProtocols && reqUpType != "" {
+		p.handleUpgradeResponse(rw, req, res, reqUpType)
 		return
 	}
 
@@ -218,6 +303,10 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		res.Header.Del(h)
 	}
 
+	if !p.modifyResponse(rw, res, req) {
+		return
+	}
+
 	copyHeader(rw.Header(), res.Header)
 
 	// The "Trailer" header isn't included in the Transport's response,
@@ -244,6 +333,223 @@ func (p *ReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	copyHeader(rw.Header(), res.Trailer)
 }
 
+// upgradeType returns the protocol a request or response with header h
+// asks to switch to, or "" if it isn't asking to switch protocols.
+func upgradeType(h http.Header) string {
+	for _, v := range h["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return h.Get("Upgrade")
+			}
+		}
+	}
+	return ""
+}
+
+// roundTripUpgrade sends the upgrade request req to the backend on a new
+// connection and reads the response. If the backend switches protocols,
+// the Body of the response is the connection to it; otherwise closing the
+// Body closes the connection.
+func (p *ReverseProxy) roundTripUpgrade(req *http.Request) (*http.Response, error) {
+	conn, err := p.dialUpgrade(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if res.StatusCode == http.StatusSwitchingProtocols {
+		res.Body = &upgradedConn{br, conn}
+	} else {
+		res.Body = &closeConnBody{res.Body, conn}
+	}
+	return res, nil
+}
+
+// dialUpgrade dials the backend for the upgrade request req, using the
+// dial functions and TLS settings of the proxy's Transport where it has
+// them.
+func (p *ReverseProxy) dialUpgrade(req *http.Request) (net.Conn, error) {
+	tr, _ := p.Transport.(*http.Transport)
+	if p.Transport == nil {
+		tr, _ = http.DefaultTransport.(*http.Transport)
+	}
+	ctx := req.Context()
+	host := req.URL.Host
+	switch req.URL.Scheme {
+	case "http":
+		addr := host
+		if !hasPort(addr) {
+			addr += ":80"
+		}
+		return dialTCP(ctx, tr, addr)
+	case "https":
+		addr := host
+		if !hasPort(addr) {
+			addr += ":443"
+		} else if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tr != nil && tr.DialTLS != nil {
+			return tr.DialTLS("tcp", addr)
+		}
+		conn, err := dialTCP(ctx, tr, addr)
+		if err != nil {
+			return nil, err
+		}
+		var cfg *tls.Config
+		if tr != nil {
+			cfg = tr.TLSClientConfig
+		}
+		tlsConn := tls.Client(conn, upgradeTLSConfig(cfg, host))
+		errc := make(chan error, 1)
+		go func() {
+			errc <- tlsConn.Handshake()
+		}()
+		select {
+		case err = <-errc:
+		case <-ctx.Done():
+			// Closing conn makes the handshake give up.
+			conn.Close()
+			<-errc
+			err = ctx.Err()
+		}
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+	return nil, fmt.Errorf("httputil: unsupported protocol scheme %q", req.URL.Scheme)
+}
+
+// dialTCP dials addr with tr's DialContext or Dial function, or with a
+// plain net.Dialer if tr, which may be nil, has neither.
+func dialTCP(ctx context.Context, tr *http.Transport, addr string) (net.Conn, error) {
+	if tr != nil && tr.DialContext != nil {
+		return tr.DialContext(ctx, "tcp", addr)
+	}
+	if tr != nil && tr.Dial != nil {
+		return tr.Dial("tcp", addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+func hasPort(s string) bool { return strings.LastIndex(s, ":") > strings.LastIndex(s, "]") }
+
+// upgradeTLSConfig returns a copy of the client settings in cfg, which
+// may be nil, for a connection to serverName that only speaks HTTP/1.1,
+// as protocol upgrades don't exist in HTTP/2.
+func upgradeTLSConfig(cfg *tls.Config, serverName string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	c := &tls.Config{
+		Rand:               cfg.Rand,
+		Time:               cfg.Time,
+		Certificates:       cfg.Certificates,
+		RootCAs:            cfg.RootCAs,
+		NextProtos:         []string{"http/1.1"},
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		CipherSuites:       cfg.CipherSuites,
+		ClientSessionCache: cfg.ClientSessionCache,
+		MinVersion:         cfg.MinVersion,
+		MaxVersion:         cfg.MaxVersion,
+		CurvePreferences:   cfg.CurvePreferences,
+	}
+	if c.ServerName == "" {
+		c.ServerName = serverName
+	}
+	return c
+}
+
+// upgradedConn is the Body of a 101 Switching Protocols response from
+// roundTripUpgrade. Reads start with any bytes the backend sent right
+// after the response header.
+type upgradedConn struct {
+	br *bufio.Reader
+	net.Conn
+}
+
+func (c *upgradedConn) Read(p []byte) (int, error) { return c.br.Read(p) }
+
+// closeConnBody is the Body of any other response from roundTripUpgrade.
+type closeConnBody struct {
+	io.ReadCloser
+	conn net.Conn
+}
+
+func (b *closeConnBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.conn.Close()
+	return err
+}
+
+// handleUpgradeResponse relays the 101 Switching Protocols response res
+// to the client and then copies bytes between the hijacked client
+// connection and the backend until either side is done.
+func (p *ReverseProxy) handleUpgradeResponse(rw http.ResponseWriter, req *http.Request, res *http.Response, reqUpType string) {
+	backConn := res.Body.(*upgradedConn)
+	resUpType := upgradeType(res.Header)
+	if !strings.EqualFold(reqUpType, resUpType) {
+		backConn.Close()
+		p.getErrorHandler()(rw, req, fmt.Errorf("backend tried to switch protocol %q when %q was requested", resUpType, reqUpType))
+		return
+	}
+	for _, h := range hopHeaders {
+		res.Header.Del(h)
+	}
+	res.Header.Set("Connection", "Upgrade")
+	res.Header.Set("Upgrade", resUpType)
+
+	if !p.modifyResponse(rw, res, req) {
+		return
+	}
+
+	conn, brw, err := rw.(http.Hijacker).Hijack()
+	if err != nil {
+		backConn.Close()
+		p.getErrorHandler()(rw, req, fmt.Errorf("hijack failed on protocol switch: %v", err))
+		return
+	}
+	defer conn.Close()
+	defer backConn.Close()
+
+	fmt.Fprintf(brw, "HTTP/1.1 %s\r\n", res.Status)
+	res.Header.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		p.logf("httputil: response flush: %v", err)
+		return
+	}
+
+	errc := make(chan error, 2)
+	go p.copyConn(backConn, brw.Reader, errc)
+	go p.copyConn(conn, backConn, errc)
+	<-errc
+}
+
+// copyConn copies src to dst for a switched protocol and reports when
+// it's done on errc.
+func (p *ReverseProxy) copyConn(dst io.Writer, src io.Reader, errc chan<- error) {
+	var buf []byte
+	if p.BufferPool != nil {
+		buf = p.BufferPool.Get()
+		defer p.BufferPool.Put(buf)
+	}
+	_, err := io.CopyBuffer(dst, src, buf)
+	errc <- err
+}
+
 func (p *ReverseProxy) copyResponse(dst io.Writer, src io.Reader) {
 	if p.FlushInterval != 0 {
 		if wf, ok := dst.(writeFlusher); ok {
@@ -9,9 +9,14 @@ package httputil
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -19,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -156,6 +162,42 @@ func TestXForwardedFor(t *testing.T) {
 	}
 }
 
+func TestXForwardedProtoAndOmit(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g, e := r.Header.Get("X-Forwarded-Proto"), "http"; g != e {
+			t.Errorf("got X-Forwarded-Proto %q; expected %q", g, e)
+		}
+		if _, ok := r.Header["X-Forwarded-For"]; ok {
+			t.Errorf("got X-Forwarded-For %q; expected none", r.Header.Get("X-Forwarded-For"))
+		}
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	director := proxyHandler.Director
+	proxyHandler.Director = func(req *http.Request) {
+		director(req)
+		req.Header["X-Forwarded-For"] = nil
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	getReq, _ := http.NewRequest("GET", frontend.URL, nil)
+	getReq.Header.Set("X-Forwarded-For", "client ip")
+	getReq.Header.Set("X-Forwarded-Proto", "https")
+	res, err := http.DefaultClient.Do(getReq)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+	if g, e := getReq.Header.Get("X-Forwarded-For"), "client ip"; g != e {
+		t.Errorf("client request X-Forwarded-For changed to %q", g)
+	}
+}
+
 var proxyQueryTests = []struct {
 	baseSuffix string // suffix to add to backend URL
 	reqSuffix  string // suffix to add to frontend's request URL
@@ -432,3 +474,235 @@ func TestReverseProxy_Post(t *testing.T) {
 		t.Errorf("got body %q; expected %q", g, e)
 	}
 }
+
+func TestReverseProxyErrorHandler(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Close() // so that the proxy can't reach it
+
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	proxyHandler.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	res, err := http.Get(frontend.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+	if g, e := res.StatusCode, http.StatusBadGateway; g != e {
+		t.Errorf("default ErrorHandler: got res.StatusCode %d; expected %d", g, e)
+	}
+
+	var gotErr error
+	proxyHandler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	res, err = http.Get(frontend.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+	if g, e := res.StatusCode, http.StatusServiceUnavailable; g != e {
+		t.Errorf("custom ErrorHandler: got res.StatusCode %d; expected %d", g, e)
+	}
+	if gotErr == nil {
+		t.Errorf("ErrorHandler wasn't passed an error")
+	}
+}
+
+func TestReverseProxyModifyResponse(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Hit-Mod", fmt.Sprint(r.URL.Path == "/mod"))
+		w.Write([]byte("backend"))
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errModify := errors.New("response rejected")
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	proxyHandler.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	proxyHandler.ModifyResponse = func(res *http.Response) error {
+		if res.Header.Get("X-Hit-Mod") != "true" {
+			return errModify
+		}
+		res.Header.Set("X-Modified", "yes")
+		return nil
+	}
+	var gotErr error
+	proxyHandler.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	res, err := http.Get(frontend.URL + "/mod")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("X-Modified") != "yes" || string(body) != "backend" {
+		t.Errorf("modified response: got status %d, X-Modified %q, body %q", res.StatusCode, res.Header.Get("X-Modified"), body)
+	}
+
+	res, err = http.Get(frontend.URL + "/other")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadGateway {
+		t.Errorf("rejected response: got status %d; expected %d", res.StatusCode, http.StatusBadGateway)
+	}
+	if gotErr != errModify {
+		t.Errorf("ErrorHandler got error %v; expected %v", gotErr, errModify)
+	}
+}
+
+func TestReverseProxyWebSocket(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "websocket" {
+			t.Errorf("backend got Connection %q, Upgrade %q", r.Header.Get("Connection"), r.Header.Get("Upgrade"))
+			http.Error(w, "unexpected request", 400)
+			return
+		}
+		c, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+		line, err := brw.ReadString('\n')
+		if err != nil {
+			t.Errorf("backend read: %v", err)
+			return
+		}
+		io.WriteString(c, "backend got "+line)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	proxyHandler.ErrorLog = log.New(ioutil.Discard, "", 0) // quiet for tests
+	proxyHandler.ModifyResponse = func(res *http.Response) error {
+		res.Header.Set("X-Header", "X-Value")
+		return nil
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	c, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	req, _ := http.NewRequest("GET", frontend.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	if err := req.Write(c); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(c)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d; expected %d", res.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if g, e := upgradeType(res.Header), "WebSocket"; g != e {
+		t.Errorf("got Upgrade %q; expected %q", g, e)
+	}
+	if g, e := res.Header.Get("X-Header"), "X-Value"; g != e {
+		t.Errorf("got X-Header %q; expected %q", g, e)
+	}
+
+	io.WriteString(c, "Hello\n")
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if g, e := line, "backend got Hello\n"; g != e {
+		t.Errorf("got %q; expected %q", g, e)
+	}
+}
+
+func TestReverseProxyUpgradeDeclined(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("no upgrade for you"))
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frontend := httptest.NewServer(NewSingleHostReverseProxy(backendURL))
+	defer frontend.Close()
+
+	req, _ := http.NewRequest("GET", frontend.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "no upgrade for you" {
+		t.Errorf("got status %d, body %q", res.StatusCode, body)
+	}
+}
+
+func TestReverseProxyUpgradeTLSUsesTransportDial(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		io.WriteString(c, "HTTP/1.1 101 Switching Protocols\r\nConnection: upgrade\r\nUpgrade: WebSocket\r\n\r\n")
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dials int32
+	proxyHandler := NewSingleHostReverseProxy(backendURL)
+	proxyHandler.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+	frontend := httptest.NewServer(proxyHandler)
+	defer frontend.Close()
+
+	req, _ := http.NewRequest("GET", frontend.URL, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d; expected %d", res.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if n := atomic.LoadInt32(&dials); n != 1 {
+		t.Errorf("Transport.DialContext called %d times; expected 1", n)
+	}
+}
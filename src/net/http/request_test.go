@@ -7,6 +7,7 @@ package http_test
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
@@ -379,6 +380,31 @@ func TestRequestInvalidMethod(t *testing.T) {
 	}
 }
 
+func TestRequestWithContext(t *testing.T) {
+	req, err := NewRequest("GET", "http://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Context() != context.Background() {
+		t.Errorf("default Context = %v; want context.Background()", req.Context())
+	}
+	type key int
+	ctx := context.WithValue(context.Background(), key(0), "value")
+	req2 := req.WithContext(ctx)
+	if req2 == req {
+		t.Fatal("WithContext returned the same *Request")
+	}
+	if got := req2.Context().Value(key(0)); got != "value" {
+		t.Errorf("Context value = %v; want %q", got, "value")
+	}
+	if req.Context() != context.Background() {
+		t.Errorf("WithContext modified the original request's context")
+	}
+	if req2.URL != req.URL || req2.Method != req.Method {
+		t.Errorf("WithContext did not copy the request fields")
+	}
+}
+
 func TestNewRequestContentLength(t *testing.T) {
 	readByte := func(r io.Reader) io.Reader {
 		var b [1]byte
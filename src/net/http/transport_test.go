@@ -13,6 +13,7 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/rand"
 	"crypto/tls"
 	"errors"
@@ -23,15 +24,18 @@ import (
 	"net"
 	. "net/http"
 	"net/http/httptest"
+	"net/http/httptrace"
 	"net/http/httputil"
 	"net/http/internal"
 	"net/url"
 	"os"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -314,6 +318,181 @@ func TestTransportIdleCacheKeys(t *testing.T) {
 	}
 }
 
+func TestTransportMaxIdleConns(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		// No body for convenience.
+	}))
+	defer ts.Close()
+	tr := &Transport{
+		MaxIdleConns: 4,
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial(network, ts.Listener.Addr().String())
+		},
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	for i := 0; i < 10; i++ {
+		res, err := c.Get(fmt.Sprintf("http://host-%d.dns-is-faked.golang:80/", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+	keys := tr.IdleConnKeysForTesting()
+	sort.Strings(keys)
+	want := []string{
+		"|http|host-6.dns-is-faked.golang:80",
+		"|http|host-7.dns-is-faked.golang:80",
+		"|http|host-8.dns-is-faked.golang:80",
+		"|http|host-9.dns-is-faked.golang:80",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("idle conn keys mismatch.\n got: %q\nwant: %q\n", keys, want)
+	}
+}
+
+func TestTransportIdleConnTimeout(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	defer afterTest(t)
+
+	const timeout = 250 * time.Millisecond
+	var mu sync.Mutex
+	addrSeen := make(map[string]bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		mu.Lock()
+		addrSeen[r.RemoteAddr] = true
+		mu.Unlock()
+	}))
+	defer ts.Close()
+	tr := &Transport{IdleConnTimeout: timeout}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	numConns := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(addrSeen)
+	}
+	get := func() {
+		res, err := c.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	// Requests made within the timeout reuse the connection.
+	for i := 0; i < 3; i++ {
+		get()
+		time.Sleep(timeout / 4)
+	}
+	if n := numConns(); n != 1 {
+		t.Errorf("requests within the idle timeout used %d connections; want 1", n)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(tr.IdleConnKeysForTesting()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle connection was not closed after IdleConnTimeout")
+		}
+		time.Sleep(timeout / 4)
+	}
+
+	get()
+	if n := numConns(); n != 2 {
+		t.Errorf("request after the idle timeout used %d connections in total; want 2", n)
+	}
+}
+
+func TestTransportMaxConnsPerHost(t *testing.T) {
+	defer afterTest(t)
+	for _, disableKeepAlives := range []bool{false, true} {
+		testTransportMaxConnsPerHost(t, disableKeepAlives)
+	}
+}
+
+func testTransportMaxConnsPerHost(t *testing.T, disableKeepAlives bool) {
+	const maxConns = 2
+	var (
+		mu        sync.Mutex
+		active    int
+		maxActive int
+	)
+	release := make(chan bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	var dials int32
+	tr := &Transport{
+		MaxConnsPerHost:   maxConns,
+		DisableKeepAlives: disableKeepAlives,
+		Dial: func(network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return net.Dial(network, addr)
+		},
+	}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	const numReqs = 6
+	errc := make(chan error, numReqs)
+	for i := 0; i < numReqs; i++ {
+		go func() {
+			res, err := c.Get(ts.URL)
+			if err == nil {
+				_, err = ioutil.ReadAll(res.Body)
+				res.Body.Close()
+			}
+			errc <- err
+		}()
+	}
+
+	// Give the requests over the limit a chance to dial, if they
+	// (wrongly) do.
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&dials); n != maxConns {
+		t.Errorf("disableKeepAlives=%v: %d dials with all requests blocked; want %d", disableKeepAlives, n, maxConns)
+	}
+	for i := 0; i < numReqs; i++ {
+		release <- true
+	}
+	for i := 0; i < numReqs; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("disableKeepAlives=%v: %v", disableKeepAlives, err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxActive > maxConns {
+		t.Errorf("disableKeepAlives=%v: server saw %d concurrent requests; want at most %d", disableKeepAlives, maxActive, maxConns)
+	}
+	wantDials := int32(maxConns)
+	if disableKeepAlives {
+		wantDials = numReqs
+	}
+	if n := atomic.LoadInt32(&dials); n != wantDials {
+		t.Errorf("disableKeepAlives=%v: %d dials in total; want %d", disableKeepAlives, n, wantDials)
+	}
+}
+
 // Tests that the HTTP transport re-uses connections when a client
 // reads to the end of a response Body without closing it.
 func TestTransportReadToEndReusesConn(t *testing.T) {
@@ -1648,6 +1827,117 @@ func TestCancelRequestWithChannelBeforeDo(t *testing.T) {
 	}
 }
 
+func TestCancelRequestWithContext(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	if testing.Short() {
+		t.Skip("skipping test in -short mode")
+	}
+	unblockc := make(chan bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		fmt.Fprintf(w, "Hello")
+		w.(Flusher).Flush() // send headers and some body
+		<-unblockc
+	}))
+	defer ts.Close()
+	defer close(unblockc)
+
+	tr := &Transport{}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(ctx)
+
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(1 * time.Second)
+		cancel()
+	}()
+	t0 := time.Now()
+	body, err := ioutil.ReadAll(res.Body)
+	d := time.Since(t0)
+
+	if err != ExportErrRequestCanceled {
+		t.Errorf("Body.Read error = %v; want errRequestCanceled", err)
+	}
+	if string(body) != "Hello" {
+		t.Errorf("Body = %q; want Hello", body)
+	}
+	if d < 500*time.Millisecond {
+		t.Errorf("expected ~1 second delay; got %v", d)
+	}
+	// Verify no outstanding requests after readLoop/writeLoop
+	// goroutines shut down.
+	for tries := 5; tries > 0; tries-- {
+		n := tr.NumPendingRequestsForTesting()
+		if n == 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+		if tries == 1 {
+			t.Errorf("pending requests = %d; want 0", n)
+		}
+	}
+}
+
+func TestCancelRequestWithContextBeforeDo(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	unblockc := make(chan bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-unblockc
+	}))
+	defer ts.Close()
+	defer close(unblockc)
+
+	tr := &Transport{}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := NewRequest("GET", ts.URL, nil)
+	req = req.WithContext(ctx)
+
+	_, err := c.Do(req)
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Do error = %v; want cancelation", err)
+	}
+}
+
+// Tests that a request's context deadline aborts a response that
+// is still waiting for headers.
+func TestTransportContextDeadlineBeforeResponseHeaders(t *testing.T) {
+	setParallel(t)
+	defer afterTest(t)
+	unblockc := make(chan bool)
+	ts := httptest.NewServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		<-unblockc
+	}))
+	defer ts.Close()
+	defer close(unblockc)
+
+	tr := &Transport{}
+	defer tr.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := NewRequest("GET", ts.URL, nil)
+	res, err := tr.RoundTrip(req.WithContext(ctx))
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("RoundTrip succeeded; want cancelation")
+	}
+	if err != ExportErrRequestCanceled {
+		t.Errorf("RoundTrip error = %v; want errRequestCanceled", err)
+	}
+}
+
 // Issue 11020. The returned error message should be errRequestCanceled
 func TestTransportCancelBeforeResponseHeaders(t *testing.T) {
 	defer afterTest(t)
@@ -2579,6 +2869,122 @@ func TestTransportDialTLS(t *testing.T) {
 	}
 }
 
+func TestTransportEventTrace(t *testing.T) {
+	defer afterTest(t)
+	ts := httptest.NewTLSServer(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, "hello")
+	}))
+	defer ts.Close()
+	tr := &Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer tr.CloseIdleConnections()
+	c := &Client{Transport: tr}
+
+	// Use "localhost" rather than the listener's IP so that the
+	// dial does a name lookup.
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := net.JoinHostPort("localhost", port)
+
+	var mu sync.Mutex // guards buf
+	var buf bytes.Buffer
+	logf := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(&buf, format, args...)
+		buf.WriteByte('\n')
+	}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) { logf("Getting conn for %v", hostPort) },
+		GotConn: func(ci httptrace.GotConnInfo) {
+			logf("got conn: reused=%v wasIdle=%v", ci.Reused, ci.WasIdle)
+		},
+		DNSStart: func(e httptrace.DNSStartInfo) { logf("DNS start: %+v", e) },
+		DNSDone: func(e httptrace.DNSDoneInfo) {
+			logf("DNS done: err=%v addrs=%d", e.Err, len(e.Addrs))
+		},
+		ConnectStart: func(network, addr string) { logf("ConnectStart: Connecting to %s %s", network, addr) },
+		ConnectDone: func(network, addr string, err error) {
+			logf("ConnectDone: connected to %s %s = %v", network, addr, err)
+		},
+		TLSHandshakeStart: func() { logf("TLSHandshakeStart") },
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			logf("TLSHandshakeDone: complete=%v err=%v", cs.HandshakeComplete, err)
+		},
+		WroteHeaders:         func() { logf("WroteHeaders") },
+		WroteRequest:         func(e httptrace.WroteRequestInfo) { logf("WroteRequest: %+v", e) },
+		GotFirstResponseByte: func() { logf("first response byte") },
+	}
+	ctx := httptrace.WithClientTrace(context.Background(), trace)
+
+	for i := 0; i < 2; i++ {
+		req, _ := NewRequest("GET", "https://"+addr+"/", nil)
+		res, err := c.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		slurp, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(slurp) != "hello" {
+			t.Fatalf("body = %q; want hello", slurp)
+		}
+	}
+
+	mu.Lock()
+	got := buf.String()
+	mu.Unlock()
+
+	wantOnce := func(sub string) {
+		if strings.Count(got, sub) != 1 {
+			t.Errorf("expected substring %q exactly once in output.", sub)
+		}
+	}
+	wantOnceOrMore := func(sub string) {
+		if strings.Count(got, sub) == 0 {
+			t.Errorf("expected substring %q at least once in output.", sub)
+		}
+	}
+	wantTwice := func(sub string) {
+		if strings.Count(got, sub) != 2 {
+			t.Errorf("expected substring %q exactly twice in output.", sub)
+		}
+	}
+	wantTwice("Getting conn for " + addr)
+	wantOnce("DNS start: {Host:localhost}")
+	wantOnce("DNS done: err=<nil>")
+	wantOnceOrMore("ConnectStart: Connecting to tcp ")
+	wantOnceOrMore("ConnectDone: connected to tcp ")
+	wantOnce("TLSHandshakeStart")
+	wantOnce("TLSHandshakeDone: complete=true err=<nil>")
+	wantOnce("got conn: reused=false wasIdle=false")
+	wantOnce("got conn: reused=true wasIdle=true")
+	wantTwice("WroteHeaders")
+	wantTwice("WroteRequest: {Err:<nil>}")
+	wantTwice("first response byte")
+	if t.Failed() {
+		t.Errorf("Output:\n%s", got)
+	}
+
+	// The events of the first request must be in order.
+	order := []string{
+		"Getting conn", "DNS start", "DNS done", "ConnectStart", "ConnectDone",
+		"TLSHandshakeStart", "TLSHandshakeDone", "got conn", "WroteHeaders",
+		"WroteRequest",
+	}
+	pos := 0
+	for _, ev := range order {
+		i := strings.Index(got[pos:], ev)
+		if i < 0 {
+			t.Fatalf("event %q missing or out of order in output:\n%s", ev, got)
+		}
+		pos += i
+	}
+}
+
 // Test for issue 8755
 // Ensure that if a proxy returns an error, it is exposed by RoundTrip
 func TestRoundTripReturnsProxyError(t *testing.T) {
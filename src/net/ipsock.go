@@ -7,6 +7,7 @@
 package net
 
 import (
+	"context"
 	"errors"
 	"time"
 )
@@ -200,8 +201,9 @@ func JoinHostPort(host, port string) string {
 // internetAddrList resolves addr, which may be a literal IP
 // address or a DNS name, and returns a list of internet protocol
 // family addresses. The result contains at least one address when
-// error is nil.
-func internetAddrList(net, addr string, deadline time.Time) (addrList, error) {
+// error is nil. DNS lookups are reported to the *nettrace.Trace in ctx,
+// if any.
+func internetAddrList(ctx context.Context, net, addr string, deadline time.Time) (addrList, error) {
 	var (
 		err        error
 		host, port string
@@ -249,7 +251,7 @@ func internetAddrList(net, addr string, deadline time.Time) (addrList, error) {
 		return addrList{inetaddr(IPAddr{IP: ip, Zone: zone})}, nil
 	}
 	// Try as a DNS name.
-	ips, err := lookupIPDeadline(host, deadline)
+	ips, err := lookupIPDeadline(ctx, host, deadline)
 	if err != nil {
 		return nil, err
 	}
@@ -5,6 +5,8 @@
 package net
 
 import (
+	"context"
+	"internal/nettrace"
 	"internal/singleflight"
 	"time"
 )
@@ -85,40 +87,69 @@ func lookupIPReturn(addrsi interface{}, err error, shared bool) ([]IPAddr, error
 	return addrs, nil
 }
 
-// lookupIPDeadline looks up a hostname with a deadline.
-func lookupIPDeadline(host string, deadline time.Time) (addrs []IPAddr, err error) {
-	if deadline.IsZero() {
-		return lookupIPMerge(host)
+// ipAddrsEface returns an empty interface slice of addrs.
+func ipAddrsEface(addrs []IPAddr) []interface{} {
+	s := make([]interface{}, len(addrs))
+	for i, v := range addrs {
+		s[i] = v
 	}
+	return s
+}
+
+// lookupIPDeadline looks up a hostname with a deadline. It gives up
+// with ctx.Err() if ctx is done first. The lookup is reported to the
+// *nettrace.Trace in ctx, if any.
+func lookupIPDeadline(ctx context.Context, host string, deadline time.Time) (addrs []IPAddr, err error) {
+	trace, _ := ctx.Value(nettrace.TraceKey{}).(*nettrace.Trace)
 
 	// We could push the deadline down into the name resolution
 	// functions.  However, the most commonly used implementation
 	// calls getaddrinfo, which has no timeout.
 
-	timeout := deadline.Sub(time.Now())
-	if timeout <= 0 {
-		return nil, errTimeout
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		d := deadline.Sub(time.Now())
+		if d <= 0 {
+			return nil, errTimeout
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
 	}
-	t := time.NewTimer(timeout)
-	defer t.Stop()
 
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(host)
+	}
 	ch := lookupGroup.DoChan(host, func() (interface{}, error) {
 		return testHookLookupIP(lookupIP, host)
 	})
 
+	var r singleflight.Result
 	select {
-	case <-t.C:
+	case <-timeout:
 		// The DNS lookup timed out for some reason.  Force
 		// future requests to start the DNS lookup again
 		// rather than waiting for the current lookup to
 		// complete.  See issue 8602.
 		lookupGroup.Forget(host)
+		r.Err = errTimeout
+
+	case <-ctx.Done():
+		// Likewise if ctx's deadline passed. A lookup that was
+		// only canceled is left for any other callers waiting
+		// on it.
+		if ctx.Err() == context.DeadlineExceeded {
+			lookupGroup.Forget(host)
+		}
+		r.Err = ctx.Err()
 
-		return nil, errTimeout
-
-	case r := <-ch:
-		return lookupIPReturn(r.Val, r.Err, r.Shared)
+	case r = <-ch:
+	}
+	if trace != nil && trace.DNSDone != nil {
+		addrs, _ := r.Val.([]IPAddr)
+		trace.DNSDone(ipAddrsEface(addrs), r.Shared, r.Err)
 	}
+	return lookupIPReturn(r.Val, r.Err, r.Shared)
 }
 
 // LookupPort looks up the port for the given network and service.
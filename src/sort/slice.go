@@ -0,0 +1,56 @@
+// Copyright 2016 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sort
+
+import "reflect"
+
+// lessSwap adapts a less function and a swapper for a slice to Interface,
+// so that the slice functions share the algorithms used by Sort and Stable.
+type lessSwap struct {
+	length int
+	less   func(i, j int) bool
+	swap   func(i, j int)
+}
+
+func (x *lessSwap) Len() int           { return x.length }
+func (x *lessSwap) Less(i, j int) bool { return x.less(i, j) }
+func (x *lessSwap) Swap(i, j int)      { x.swap(i, j) }
+
+// Slice sorts the provided slice given the provided less function.
+//
+// The sort is not guaranteed to be stable. For a stable sort, use
+// SliceStable.
+//
+// The function panics if the provided interface is not a slice.
+func Slice(slice interface{}, less func(i, j int) bool) {
+	rv := reflect.ValueOf(slice)
+	length := rv.Len()
+	data := &lessSwap{length, less, reflect.Swapper(slice)}
+	quickSort(data, 0, length, maxDepth(length))
+}
+
+// SliceStable sorts the provided slice given the provided less
+// function while keeping the original order of equal elements.
+//
+// The function panics if the provided interface is not a slice.
+func SliceStable(slice interface{}, less func(i, j int) bool) {
+	rv := reflect.ValueOf(slice)
+	data := &lessSwap{rv.Len(), less, reflect.Swapper(slice)}
+	stable(data, data.length)
+}
+
+// SliceIsSorted tests whether a slice is sorted.
+//
+// The function panics if the provided interface is not a slice.
+func SliceIsSorted(slice interface{}, less func(i, j int) bool) bool {
+	rv := reflect.ValueOf(slice)
+	n := rv.Len()
+	for i := n - 1; i > 0; i-- {
+		if less(i, i-1) {
+			return false
+		}
+	}
+	return true
+}
@@ -74,6 +74,32 @@ func TestStrings(t *testing.T) {
 	}
 }
 
+func TestSlice(t *testing.T) {
+	data := strings
+	Slice(data[:], func(i, j int) bool {
+		return data[i] < data[j]
+	})
+	if !SliceIsSorted(data[:], func(i, j int) bool { return data[i] < data[j] }) {
+		t.Errorf("sorted %v", strings)
+		t.Errorf("   got %v", data)
+	}
+}
+
+func TestSliceIsSorted(t *testing.T) {
+	data := ints
+	less := func(i, j int) bool { return data[i] < data[j] }
+	if SliceIsSorted(data[:], less) {
+		t.Errorf("SliceIsSorted(%v) = true", data)
+	}
+	Ints(data[:])
+	if !SliceIsSorted(data[:], less) {
+		t.Errorf("SliceIsSorted(%v) = false", data)
+	}
+	if !SliceIsSorted([]int(nil), less) {
+		t.Errorf("SliceIsSorted(nil) = false")
+	}
+}
+
 func TestSortLarge_Random(t *testing.T) {
 	n := 1000000
 	if testing.Short() {
@@ -172,6 +198,19 @@ func BenchmarkStableString1K(b *testing.B) {
 	}
 }
 
+func BenchmarkSortString1K_Slice(b *testing.B) {
+	b.StopTimer()
+	for i := 0; i < b.N; i++ {
+		data := make([]string, 1<<10)
+		for i := 0; i < len(data); i++ {
+			data[i] = strconv.Itoa(i ^ 0x2cc)
+		}
+		b.StartTimer()
+		Slice(data, func(i, j int) bool { return data[i] < data[j] })
+		b.StopTimer()
+	}
+}
+
 func BenchmarkSortInt1K(b *testing.B) {
 	b.StopTimer()
 	for i := 0; i < b.N; i++ {
@@ -198,6 +237,32 @@ func BenchmarkStableInt1K(b *testing.B) {
 	}
 }
 
+func BenchmarkSortInt1K_Slice(b *testing.B) {
+	b.StopTimer()
+	for i := 0; i < b.N; i++ {
+		data := make([]int, 1<<10)
+		for i := 0; i < len(data); i++ {
+			data[i] = i ^ 0x2cc
+		}
+		b.StartTimer()
+		Slice(data, func(i, j int) bool { return data[i] < data[j] })
+		b.StopTimer()
+	}
+}
+
+func BenchmarkStableInt1K_Slice(b *testing.B) {
+	b.StopTimer()
+	for i := 0; i < b.N; i++ {
+		data := make([]int, 1<<10)
+		for i := 0; i < len(data); i++ {
+			data[i] = i ^ 0x2cc
+		}
+		b.StartTimer()
+		SliceStable(data, func(i, j int) bool { return data[i] < data[j] })
+		b.StopTimer()
+	}
+}
+
 func BenchmarkSortInt64K(b *testing.B) {
 	b.StopTimer()
 	for i := 0; i < b.N; i++ {
@@ -526,6 +591,25 @@ func TestStability(t *testing.T) {
 	}
 }
 
+func TestSliceStable(t *testing.T) {
+	n, m := 100000, 1000
+	if testing.Short() {
+		n, m = 1000, 100
+	}
+	data := make(intPairs, n)
+	for i := 0; i < len(data); i++ {
+		data[i].a = rand.Intn(m)
+	}
+	data.initB()
+	SliceStable(data, func(i, j int) bool { return data[i].a < data[j].a })
+	if !IsSorted(data) {
+		t.Errorf("SliceStable didn't sort %d ints", n)
+	}
+	if !data.inOrder() {
+		t.Errorf("SliceStable wasn't stable on %d ints", n)
+	}
+}
+
 var countOpsSizes = []int{1e2, 3e2, 1e3, 3e3, 1e4, 3e4, 1e5, 3e5, 1e6}
 
 func countOps(t *testing.T, algo func(Interface), name string) {